@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"strings"
+)
+
+// Weights of each kind of exported declaration in the API surface score.
+// Types and mutable package variables commit a package to more than a
+// single function does; constants commit it to the least.
+const (
+	apiWeightFunction = 2
+	apiWeightMethod   = 2
+	apiWeightType     = 3
+	apiWeightConstant = 1
+	apiWeightVariable = 2
+)
+
+// APIMetrics counts the exported declarations of a package tree
+type APIMetrics struct {
+	Functions int `json:"functions"`
+	Methods   int `json:"methods"` // Exported methods of exported types
+	Types     int `json:"types"`
+	Constants int `json:"constants"`
+	Variables int `json:"variables"`
+}
+
+// Score returns the weighted size of the API surface, a single number to
+// track across runs
+func (m APIMetrics) Score() int {
+	return m.Functions*apiWeightFunction +
+		m.Methods*apiWeightMethod +
+		m.Types*apiWeightType +
+		m.Constants*apiWeightConstant +
+		m.Variables*apiWeightVariable
+}
+
+// add sums two sets of metrics
+func (m APIMetrics) add(o APIMetrics) APIMetrics {
+	return APIMetrics{
+		Functions: m.Functions + o.Functions,
+		Methods:   m.Methods + o.Methods,
+		Types:     m.Types + o.Types,
+		Constants: m.Constants + o.Constants,
+		Variables: m.Variables + o.Variables,
+	}
+}
+
+// APISurface counts the exported functions, methods, types, constants and
+// variables declared under dir, leaving out test files, and adds them to
+// the totals reported by PrintSummary and the export. Files that do not
+// parse, such as broken fixtures under testdata, are left out.
+func (a *ASTAnalyzer) APISurface(dir string) (APIMetrics, error) {
+	files, err := a.goFiles(dir)
+	if err != nil {
+		return APIMetrics{}, err
+	}
+
+	var m APIMetrics
+	for _, path := range files {
+		if isTestName(path) {
+			continue
+		}
+		f, err := a.cache.Parse(path)
+		if err != nil {
+			continue
+		}
+		m = m.add(fileAPISurface(f))
+	}
+
+	a.mu.Lock()
+	total := m
+	if a.apiSurface != nil {
+		total = a.apiSurface.add(m)
+	}
+	a.apiSurface = &total
+	a.mu.Unlock()
+	return m, nil
+}
+
+// fileAPISurface counts the exported declarations of one file
+func fileAPISurface(f *ast.File) APIMetrics {
+	var m APIMetrics
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if !d.Name.IsExported() {
+				continue
+			}
+			if d.Recv == nil {
+				m.Functions++
+			} else if ast.IsExported(receiverTypeName(exprToString(d.Recv.List[0].Type))) {
+				m.Methods++
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Name.IsExported() {
+						m.Types++
+					}
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						if !name.IsExported() {
+							continue
+						}
+						if d.Tok == token.CONST {
+							m.Constants++
+						} else {
+							m.Variables++
+						}
+					}
+				}
+			}
+		}
+	}
+	return m
+}
+
+// printAPISurface prints the API surface totals
+func (a *ASTAnalyzer) printAPISurface() {
+	a.mu.Lock()
+	surface := a.apiSurface
+	a.mu.Unlock()
+	if surface == nil {
+		return
+	}
+	m := *surface
+
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println("PUBLIC API SURFACE")
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Printf("Functions:          %d\n", m.Functions)
+	fmt.Printf("Methods:            %d\n", m.Methods)
+	fmt.Printf("Types:              %d\n", m.Types)
+	fmt.Printf("Constants:          %d\n", m.Constants)
+	fmt.Printf("Variables:          %d\n", m.Variables)
+	fmt.Printf("Surface score:      %d\n", m.Score())
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println()
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"sync"
+	"testing"
+)
+
+func TestAPISurface(t *testing.T) {
+	tests := []struct {
+		name  string
+		files map[string]string
+		want  APIMetrics
+	}{
+		{
+			name: "exported declarations",
+			files: map[string]string{
+				"a.go": `package a
+
+const Max, min = 1, 2
+var Default = 3
+type Store struct{}
+type store struct{}
+func New() *Store { return nil }
+func (*Store) Get() {}
+func (store) Put() {}
+func helper() {}
+`,
+			},
+			want: APIMetrics{Functions: 1, Methods: 1, Types: 1, Constants: 1, Variables: 1},
+		},
+		{
+			name: "test files left out",
+			files: map[string]string{
+				"a.go":      "package a\n\nfunc A() {}\n",
+				"a_test.go": "package a\n\nfunc TestA() {}\n",
+			},
+			want: APIMetrics{Functions: 1},
+		},
+		{
+			name: "files that do not parse left out",
+			files: map[string]string{
+				"a.go":                              "package a\n\nfunc A() {}\n",
+				"empty.go":                          "",
+				"testdata/not_a_file.go/invalid.go": "This is not Go\n",
+			},
+			want: APIMetrics{Functions: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := quietAnalyzer().APISurface(writeTree(t, tt.files))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("APISurface() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAPIMetricsScore(t *testing.T) {
+	m := APIMetrics{Functions: 1, Methods: 1, Types: 1, Constants: 1, Variables: 1}
+	want := apiWeightFunction + apiWeightMethod + apiWeightType + apiWeightConstant + apiWeightVariable
+	if got := m.Score(); got != want {
+		t.Errorf("Score() = %d, want %d", got, want)
+	}
+}
+
+func TestPrintAPISurfaceConcurrent(t *testing.T) {
+	stdout := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	os.Stdout = devNull
+	defer func() { os.Stdout = stdout }()
+
+	dir := writeTree(t, map[string]string{"api/api.go": "package api\n\nfunc Open() {}\n"})
+	a := quietAnalyzer()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if _, err := a.APISurface(dir); err != nil {
+				t.Error(err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			a.printAPISurface()
+		}()
+	}
+	wg.Wait()
+	if a.apiSurface.Functions != 8 {
+		t.Errorf("recorded %d functions, want 8", a.apiSurface.Functions)
+	}
+}
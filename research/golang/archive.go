@@ -0,0 +1,184 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Archive formats recognized by BenchmarkArchive
+const (
+	archiveZip   = "zip"
+	archiveTar   = "tar"
+	archiveTarGz = "tar.gz"
+)
+
+// BenchmarkArchive benchmarks the Go files of a .zip, .tar or .tar.gz
+// archive, such as a release tarball, without extracting it. The format is
+// detected from the content, falling back to the extension. Result paths
+// are entry names prefixed with the archive's file name, vendor and hidden
+// directories are skipped as in a directory walk, and entries that cannot
+// be read fail on their own without stopping the run. Entries are read one
+// at a time, so memory is bounded by MaxFileSize rather than the archive.
+func (a *ASTAnalyzer) BenchmarkArchive(archivePath string) error {
+	f, err := os.Open(archivePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	kind, err := archiveKind(f, archivePath)
+	if err != nil {
+		return err
+	}
+
+	prefix := filepath.Base(archivePath)
+	switch kind {
+	case archiveZip:
+		zr, err := zip.NewReader(f, info.Size())
+		if err != nil {
+			return fmt.Errorf("%s: %w", archivePath, err)
+		}
+		return a.benchmarkZip(zr, prefix)
+	case archiveTarGz:
+		gz, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("%s: %w", archivePath, err)
+		}
+		defer gz.Close()
+		return a.benchmarkTar(gz, prefix)
+	default:
+		return a.benchmarkTar(f, prefix)
+	}
+}
+
+// archiveKind identifies an archive by its magic bytes, or by its
+// extension when the content is not conclusive
+func archiveKind(r io.ReaderAt, name string) (string, error) {
+	head := make([]byte, 512)
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	head = head[:n]
+
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		return archiveZip, nil
+	case bytes.HasPrefix(head, []byte("\x1f\x8b")):
+		return archiveTarGz, nil
+	case len(head) >= 262 && string(head[257:262]) == "ustar":
+		return archiveTar, nil
+	}
+
+	lower := strings.ToLower(name)
+	switch {
+	case strings.HasSuffix(lower, ".zip"):
+		return archiveZip, nil
+	case strings.HasSuffix(lower, ".tar.gz"), strings.HasSuffix(lower, ".tgz"):
+		return archiveTarGz, nil
+	case strings.HasSuffix(lower, ".tar"):
+		return archiveTar, nil
+	}
+	return "", fmt.Errorf("%s: unrecognized archive format", name)
+}
+
+// BenchmarkZip benchmarks the Go files of a zip archive held in r, using
+// entry names as file paths. Size guards apply to the sizes the archive
+// declares, which the zip reader enforces while decompressing, so an
+// upload cannot make a single entry expand past MaxFileSize.
+func (a *ASTAnalyzer) BenchmarkZip(r io.ReaderAt, size int64) error {
+	zr, err := zip.NewReader(r, size)
+	if err != nil {
+		return err
+	}
+	return a.benchmarkZip(zr, "")
+}
+
+// benchmarkZip benchmarks a zip archive, prefixing result paths
+func (a *ASTAnalyzer) benchmarkZip(zr *zip.Reader, prefix string) error {
+	root := prefix
+	if root == "" {
+		root = "."
+	}
+	_, err := a.benchmark(root, func(fn func(ParseResult) error) error {
+		sources, err := a.fsSources(zr, ".", prefix)
+		if err != nil {
+			return err
+		}
+		return a.streamSources(context.Background(), root, sources, false, fn)
+	})
+	return err
+}
+
+// BenchmarkTar benchmarks the Go files of a tar stream, such as a
+// decompressed .tar.gz upload, entry by entry as they are read. Entry
+// names are used as file paths; entries other than regular files are
+// ignored.
+func (a *ASTAnalyzer) BenchmarkTar(r io.Reader) error {
+	return a.benchmarkTar(r, "")
+}
+
+// benchmarkTar benchmarks a tar stream, prefixing result paths
+func (a *ASTAnalyzer) benchmarkTar(r io.Reader, prefix string) error {
+	root := prefix
+	if root == "" {
+		root = "."
+	}
+	tr := tar.NewReader(r)
+	_, err := a.benchmark(root, func(fn func(ParseResult) error) error {
+		for {
+			hdr, err := tr.Next()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if hdr.Typeflag != tar.TypeReg || path.Ext(hdr.Name) != ".go" || inSkippedDir(hdr.Name) {
+				continue
+			}
+
+			// The declared size bounds what the tar reader returns
+			name := path.Join(prefix, hdr.Name)
+			var result ParseResult
+			if err := a.checkFileSize(hdr.Size); err != nil {
+				result = a.skippedParse(name, hdr.Size, err)
+			} else if src, err := io.ReadAll(tr); err != nil {
+				result = a.failedParse(name, fmt.Errorf("%s: %w", name, err), 0)
+			} else {
+				result = a.ParseSource(name, src)
+			}
+			result.Root = root
+			if err := fn(result); err != nil {
+				return err
+			}
+		}
+	})
+	return err
+}
+
+// inSkippedDir reports whether any directory of a slash-separated path is
+// one the directory walk skips
+func inSkippedDir(name string) bool {
+	dirs := strings.Split(path.Dir(path.Clean(name)), "/")
+	for _, dir := range dirs {
+		if dir != "." && isSkippedDir(dir) {
+			return true
+		}
+	}
+	return false
+}
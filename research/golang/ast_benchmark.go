@@ -1,39 +1,140 @@
 package main
 
 import (
+	"bytes"
+	"context"
+	"flag"
 	"fmt"
 	"go/ast"
 	"go/parser"
 	"go/token"
+	"go/types"
+	"io"
+	"io/fs"
 	"log"
 	"os"
-	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 	"time"
+
+	"github.com/study-game/research/game"
+	"golang.org/x/tools/go/packages"
 )
 
 // ParseResult contains metrics from parsing a Go file
 type ParseResult struct {
 	FilePath      string
-	ParseTime     time.Duration
-	NumFunctions  int
+	Root          string        // Directory the file was found under
+	ParseTime     time.Duration // The median of Timing when it is set
+	NumFunctions  int           // Free functions other than init
 	NumMethods    int
 	NumInterfaces int
 	NumStructs    int
+	Generated     bool // Produced by a code generator
+	IsTest        bool // A _test.go file
 	Success       bool
 	Error         error
+	ErrorMessage  string // Error text, kept for serialization
+	FromCache     bool   // Reused from a cache loaded with LoadCache
+
+	// SkippedOversize marks files that were not parsed because they exceed
+	// ASTAnalyzer.MaxFileSize or MaxLineLength. They are neither successes
+	// nor failures; Error says which limit was hit.
+	SkippedOversize bool
+
+	// FileSizeBytes and LineCount describe the source, so that parse times
+	// can be compared against file size. LineCount counts a final line
+	// without a trailing newline.
+	FileSizeBytes int64
+	LineCount     int
+
+	// AllocBytes is the heap allocated while parsing, recorded only when
+	// ASTAnalyzer.MeasureMemory is set. It is a delta of process-wide
+	// counters, so it is only meaningful with a single worker, and it also
+	// includes allocations made by other goroutines during the parse.
+	AllocBytes uint64
+
+	// NodeCount is the number of AST nodes. It is recorded for every
+	// successful parse and stands in for the AST's memory footprint when
+	// AllocBytes is not measured; node sizes vary, so compare it only
+	// across files, not against AllocBytes.
+	NodeCount int
+
+	// Timing holds the statistics of repeated parses, recorded only when
+	// ASTAnalyzer.Runs is above one. The repeats time the parser alone,
+	// without reading the file or counting declarations.
+	Timing *ParseTiming
+
+	// UsesCgo marks files importing the C pseudo-package; CgoCalls counts
+	// their calls into C
+	UsesCgo  bool
+	CgoCalls int
+
+	// MapifiableSwitches locates the switch statements whose cases only
+	// map constants to constants and could be map lookups; see
+	// ASTAnalyzer.MinMapSwitchCases
+	MapifiableSwitches []token.Position
+
+	// StringConcatInLoop locates the += statements in loop bodies that
+	// append to a string and could use a strings.Builder instead. Strings
+	// are recognized by their operands and declarations, not by type.
+	StringConcatInLoop []token.Position
+
+	// PanicSites and RecoverSites locate the calls to the panic and
+	// recover builtins. UnrecoveredPanics names the functions, as Name or
+	// Type.Name, that call panic without deferring a recover.
+	PanicSites        []token.Position
+	RecoverSites      []token.Position
+	UnrecoveredPanics []string
+
+	// InitFuncs locates the init functions, which run before main and may
+	// be declared several times per file. They are not in NumFunctions.
+	InitFuncs []token.Position
+
+	// AST is the parsed file, kept only when ASTAnalyzer.RetainAST is set.
+	// Use ASTAnalyzer.Position to resolve its positions.
+	AST *ast.File
 }
 
 // FunctionInfo represents extracted function metadata
 type FunctionInfo struct {
-	Name       string
-	Receiver   string // For methods
-	Params     []ParamInfo
-	Results    []string
-	IsExported bool
-	LineStart  int
-	LineEnd    int
-	DocComment string
+	Name         string
+	Receiver     string      // For methods
+	ReceiverName string      // Receiver variable, empty when unnamed
+	TypeParams   []ParamInfo // For generic functions
+	Params       []ParamInfo
+	Results      []string
+	IsExported   bool
+	LineStart    int
+	LineEnd      int
+	DocComment   string
+	Complexity   int  // Cyclomatic complexity
+	IsStub       bool // Empty body or a lone panic("TODO")
+	LabeledJumps int  // break and continue statements naming a label
+	Halstead     HalsteadMetrics
+
+	// MaxNestingDepth is how deeply the control structures and function
+	// literals of the body nest
+	MaxNestingDepth int
+
+	// ResolvedParams and ResolvedResults are the fully qualified types of
+	// the parameters and results as go/types sees them, filled in only
+	// when ASTAnalyzer.TypeCheck is set and the file type-checks
+	ResolvedParams  []string
+	ResolvedResults []string
+
+	// Reachable and ReachDepth are set by Reachability: whether a call
+	// path from main or an init function leads to the function, and the
+	// number of calls on the shortest one
+	Reachable  bool
+	ReachDepth int
+
+	// CallDepth and Recursive are set by CallDepths: the number of calls
+	// on the longest chain of local calls the function can start, and
+	// whether it takes part in direct or mutual recursion
+	CallDepth int
+	Recursive bool
 }
 
 // ParamInfo represents a function parameter
@@ -42,40 +143,320 @@ type ParamInfo struct {
 	Type string
 }
 
-// ASTAnalyzer analyzes Go source code
+// ASTAnalyzer analyzes Go source code. Its methods may be called from
+// several goroutines: parsing shares a FileSet, which is safe for
+// concurrent use, and the recorded results are guarded by a mutex.
+// Configuration fields must be set before use and not changed afterwards.
+//
+// Every benchmark call starts a new run: it replaces the results recorded
+// by the previous one and returns its own, so summaries and exports cover
+// one run. BenchmarkDirectories records all its roots as a single run.
+// The other analyses accumulate until Reset, which also replaces the
+// FileSet so that a long-lived analyzer does not keep the positions of
+// every file it ever parsed.
 type ASTAnalyzer struct {
-	fset    *token.FileSet
-	results []ParseResult
+	// Initialisms lists the words naming checks expect in a single case
+	Initialisms []string
+
+	// IncludeGenerated counts generated files in summary statistics
+	IncludeGenerated bool
+
+	// RetainAST keeps each parsed *ast.File on its ParseResult. This lets
+	// callers walk the tree without re-parsing, at the cost of memory.
+	RetainAST bool
+
+	// MeasureMemory records the allocation delta of each parse. It forces
+	// a GC before every file, which slows runs down and distorts parse
+	// times, and it is only accurate while nothing else allocates, so
+	// files must be parsed one at a time.
+	MeasureMemory bool
+
+	// Mode selects the parser flags ParseFile uses. ExtractFunctions and
+	// the other analyses always parse comments, whatever the mode.
+	Mode ParseMode
+
+	// Runs parses every file this many times after a discarded warmup and
+	// records the spread on ParseResult.Timing; zero or one parses once.
+	// Timings are steadiest with a single worker.
+	Runs int
+
+	// MaxTimingVariation is the relative standard deviation above which
+	// PrintSummary flags a repeated timing as unreliable; zero means
+	// DefaultMaxTimingVariation
+	MaxTimingVariation float64
+
+	// MaxFileSize and MaxLineLength, in bytes, skip larger files instead
+	// of parsing them; zero means DefaultMaxFileSize and
+	// DefaultMaxLineLength. Force parses every file regardless.
+	MaxFileSize   int64
+	MaxLineLength int
+	Force         bool
+
+	// RootDir is the directory that file paths in progress lines and
+	// exports are relative to, written with forward slashes on every OS so
+	// that output compares across machines and same-named files in
+	// different packages stay apart. Empty means the benchmarked directory;
+	// after benchmarking several directories, and for files outside the
+	// root, paths are written as walked.
+	RootDir string
+
+	// MinMapSwitchCases is the number of trivial cases from which a switch
+	// is recorded in ParseResult.MapifiableSwitches; zero means
+	// DefaultMinMapSwitchCases
+	MinMapSwitchCases int
+
+	// TypeCheck type-checks the package of every file functions are
+	// extracted from, filling in FunctionInfo.ResolvedParams and
+	// ResolvedResults. Imports are checked from source, which is slow the
+	// first time each is seen. Functions restored from a result cache keep
+	// the types they were cached with. BuildCallGraph also resolves calls
+	// with the type checker when set.
+	TypeCheck bool
+
+	// PackagesMode adds to what AnalyzeModule asks go/packages to load,
+	// such as packages.NeedDeps to parse and check dependencies too. The
+	// syntax of the module's packages is always loaded, and their types
+	// when TypeCheck is set.
+	PackagesMode packages.LoadMode
+
+	// FollowSymlinks makes StreamDirectory descend into symlinked
+	// directories. Either way each file is benchmarked once, under the
+	// first path found for it.
+	FollowSymlinks bool
+
+	// PublicAPI lists the package directories, relative to the analyzed
+	// directory and matched with path.Match, whose exports UnusedExports
+	// leaves alone because code outside the module uses them; empty means
+	// DefaultPublicAPI
+	PublicAPI []string
+
+	// Filter, when set, is asked about every Go file found under a
+	// directory, archive or fs.FS before it is parsed; files it rejects
+	// are left out of the run and of the passes over the directory. See
+	// ExcludeVendor and ExcludeGenerated.
+	Filter func(path string) bool
+
+	// KeepClone leaves the temporary clone of BenchmarkRemote on disk
+	KeepClone bool
+
+	// Handler receives every result of BenchmarkDirectory. Nil keeps
+	// them all on the analyzer; an AggregateHandler keeps totals only.
+	Handler ResultHandler
+
+	// Workers is the number of files parsed concurrently by
+	// StreamDirectory; zero means GOMAXPROCS. Results are still delivered
+	// in path order. MeasureMemory forces a single worker.
+	Workers int
+
+	// GraphRoot limits DOT output to the nodes reachable from this node.
+	// It matches a node ID, a function name or Type.Method.
+	GraphRoot string
+
+	// DiagramFocus limits Mermaid output to one type and its direct
+	// relations. DiagramMaxTypes caps the number of types rendered
+	// otherwise; zero means defaultDiagramMaxTypes.
+	DiagramFocus    string
+	DiagramMaxTypes int
+
+	// MermaidFence wraps Mermaid output in a ```mermaid block for Markdown
+	MermaidFence bool
+
+	// ItemRules are the struct tag rules World gives items affixes by; nil
+	// means game.DefaultAffixRules
+	ItemRules []game.AffixRule
+
+	// MaxComplexity is the cyclomatic complexity a function may reach
+	// before ExportJUnit fails it; zero means DefaultMaxComplexity
+	MaxComplexity int
+
+	// MinDocCoverage is the fraction of exported functions each package
+	// must document for ExportJUnit to pass it; zero disables the check
+	MinDocCoverage float64
+
+	// WatchInterval is how often Watch polls for changes; zero means
+	// DefaultWatchInterval
+	WatchInterval time.Duration
+
+	// Progress receives the header and per-file lines of BenchmarkDirectory
+	// and Watch; NewASTAnalyzer sets it to stdout. Quiet suppresses them,
+	// leaving only what PrintSummary prints. ASCII writes OK, FAIL and
+	// CACHED instead of the Unicode status symbols, for logs and terminals
+	// without UTF-8.
+	Progress io.Writer
+	Quiet    bool
+	ASCII    bool
+
+	fset *token.FileSet
+
+	// mu guards the recorded analysis state below
+	mu            sync.Mutex
+	results       []ParseResult
+	functions     map[string][]FunctionInfo // Extracted functions by file
+	lineCounts    map[string]int            // Lines of the files functions were extracted from
+	findings      []Finding
+	testStats     []PackageTestStats
+	callGraph     *CallGraph
+	reachability  *Reachability
+	callDepths    *CallDepths
+	importGraph   *ImportGraph
+	importCycles  []ImportCycle
+	unusedExports []PackageUnusedExports
+	types         []TypeInfo
+	apiSurface    *APIMetrics // Totals of APISurface over every directory
+	startTime     time.Time
+	rootDir       string
+	remotes       []RemoteCheckout // Repositories cloned for the run
+	nextRemotes   []RemoteCheckout // Recorded for the run about to start
+	warnings      []string
+
+	// typesResolved records whether TypeCheck resolved any function
+	typesResolved bool
+
+	// typesMu guards the type-checked packages, checked one at a time
+	typesMu      sync.Mutex
+	packageTypes map[string]*packageTypes // By directory and package name
+	typeImporter types.Importer
+
+	cache       *Cache // Parsed files shared by the analyses
+	metrics     *Metrics
+	resultCache map[string]cachedResult // Loaded by LoadCache, keyed by path
 }
 
 // NewASTAnalyzer creates a new analyzer
 func NewASTAnalyzer() *ASTAnalyzer {
+	fset := token.NewFileSet()
 	return &ASTAnalyzer{
-		fset:    token.NewFileSet(),
-		results: make([]ParseResult, 0),
+		Initialisms: append([]string(nil), commonInitialisms...),
+		fset:        fset,
+		results:     make([]ParseResult, 0),
+		functions:   make(map[string][]FunctionInfo),
+		lineCounts:  make(map[string]int),
+		cache:       NewCache(fset),
+		metrics:     NewMetrics(),
+		Progress:    os.Stdout,
 	}
 }
 
-// ParseFile parses a single Go file
+// SetCache makes the analyzer parse through c, sharing parsed files with
+// other analyzers using the same cache. Call it before analyzing anything:
+// the analyzer adopts the cache's FileSet so positions stay valid.
+func (a *ASTAnalyzer) SetCache(c *Cache) {
+	a.cache = c
+	a.fset = c.FileSet()
+}
+
+// ParseFile parses a single Go file. It always parses from disk, bypassing
+// the cache, since the parse itself is what is being measured. The parse
+// time includes reading the file, except for repeated runs.
 func (a *ASTAnalyzer) ParseFile(filePath string) ParseResult {
+	return a.parseSourceFile(osSource(filePath))
+}
+
+// parseSourceFile reads and parses one file for ParseFile and ParseFileFS
+func (a *ASTAnalyzer) parseSourceFile(file sourceFile) ParseResult {
+	// Check the size before reading, so huge files are never loaded
+	if info, err := fs.Stat(file.fsys, file.name); err == nil {
+		if err := a.checkFileSize(info.Size()); err != nil {
+			return a.skippedParse(file.path, info.Size(), err)
+		}
+	}
+
+	start := time.Now()
+	src, err := readGoSource(file)
+	if err != nil {
+		return a.failedParse(file.path, err, 0)
+	}
+	readTime := time.Since(start)
+
+	result := a.ParseSource(file.path, src)
+	if result.Success && result.Timing == nil {
+		result.ParseTime += readTime
+	}
+	return result
+}
+
+// ParseSource parses src as the content of filePath, which is used only
+// for positions and classification. Content that cannot be Go source,
+// such as binary data or invalid UTF-8, fails with an error wrapping
+// ErrNotGoSource instead of a parser error.
+func (a *ASTAnalyzer) ParseSource(filePath string, src []byte) ParseResult {
+	if err := a.checkFileSize(int64(len(src))); err != nil {
+		return a.skippedParse(filePath, int64(len(src)), err)
+	}
+	if err := a.checkLineLength(src); err != nil {
+		return a.skippedParse(filePath, int64(len(src)), err)
+	}
+	if err := checkGoSource(src); err != nil {
+		result := a.failedParse(filePath, fmt.Errorf("%s: %w", filePath, err), 0)
+		result.FileSizeBytes = int64(len(src))
+		return result
+	}
+
+	var before runtime.MemStats
+	if a.MeasureMemory {
+		runtime.GC()
+		runtime.ReadMemStats(&before)
+	}
+
+	// Unless the AST is kept, nothing needs its positions later, so a
+	// throwaway FileSet spares the shared one a line table per file
+	fset := a.fset
+	if !a.RetainAST {
+		fset = token.NewFileSet()
+	}
+
 	start := time.Now()
 
-	f, err := parser.ParseFile(a.fset, filePath, nil, parser.ParseComments)
+	f, err := parser.ParseFile(fset, filePath, src, a.Mode.parserMode())
+
+	var allocBytes uint64
+	if a.MeasureMemory {
+		var after runtime.MemStats
+		runtime.ReadMemStats(&after)
+		allocBytes = after.TotalAlloc - before.TotalAlloc
+	}
+
 	if err != nil {
-		return ParseResult{
-			FilePath: filePath,
-			Success:  false,
-			Error:    err,
-		}
+		result := a.failedParse(filePath, err, allocBytes)
+		result.FileSizeBytes = int64(len(src))
+		result.LineCount = lineCount(src)
+		return result
 	}
 
-	// Count elements
-	var numFunctions, numMethods, numInterfaces, numStructs int
+	result := a.astResult(fset, filePath, src, f)
+	result.ParseTime = time.Since(start)
+	result.AllocBytes = allocBytes
+
+	if a.RetainAST {
+		result.AST = f
+	}
+	if a.Runs > 1 {
+		timing := a.timeParses(filePath, src)
+		result.Timing = &timing
+		result.ParseTime = timing.Median
+	}
+
+	a.metrics.ObserveParse(result)
+	return result
+}
+
+// astResult describes a successfully parsed file: its counts, the sites
+// of note and how it is classified. Timing is left to the caller.
+func (a *ASTAnalyzer) astResult(fset *token.FileSet, filePath string, src []byte, f *ast.File) ParseResult {
+	var numFunctions, numMethods, numInterfaces, numStructs, numNodes, cgoCalls int
+	var mapifiable, panics, recovers, inits []token.Position
+	cgo := usesCgo(f)
+	minSwitchCases := a.minMapSwitchCases()
 
 	ast.Inspect(f, func(n ast.Node) bool {
+		if n != nil {
+			numNodes++
+		}
 		switch x := n.(type) {
 		case *ast.FuncDecl:
-			if x.Recv == nil {
+			if x.Recv == nil && x.Name.Name == "init" {
+				inits = append(inits, fset.Position(x.Pos()))
+			} else if x.Recv == nil {
 				numFunctions++
 			} else {
 				numMethods++
@@ -84,28 +465,141 @@ func (a *ASTAnalyzer) ParseFile(filePath string) ParseResult {
 			numInterfaces++
 		case *ast.StructType:
 			numStructs++
+		case *ast.CallExpr:
+			if cgo && isCgoCall(x) {
+				cgoCalls++
+			}
+			if isBuiltinCall(x, "panic") {
+				panics = append(panics, fset.Position(x.Pos()))
+			} else if isBuiltinCall(x, "recover") {
+				recovers = append(recovers, fset.Position(x.Pos()))
+			}
+		case *ast.SwitchStmt:
+			if isMapifiableSwitch(x, minSwitchCases) {
+				mapifiable = append(mapifiable, fset.Position(x.Pos()))
+			}
 		}
 		return true
 	})
 
-	return ParseResult{
+	result := ParseResult{
 		FilePath:      filePath,
-		ParseTime:     time.Since(start),
 		NumFunctions:  numFunctions,
 		NumMethods:    numMethods,
 		NumInterfaces: numInterfaces,
 		NumStructs:    numStructs,
+		Generated:     isGeneratedFile(filePath, f),
+		IsTest:        isTestName(filePath),
 		Success:       true,
+		FileSizeBytes: int64(len(src)),
+		LineCount:     lineCount(src),
+		NodeCount:     numNodes,
+		UsesCgo:       cgo,
+		CgoCalls:      cgoCalls,
+
+		MapifiableSwitches: mapifiable,
+		StringConcatInLoop: stringConcatsInLoops(fset, f),
+		PanicSites:         panics,
+		RecoverSites:       recovers,
+		InitFuncs:          inits,
+	}
+	if len(panics) > 0 {
+		result.UnrecoveredPanics = unrecoveredPanics(f)
+	}
+	return result
+}
+
+// lineCount returns the number of lines in src
+func lineCount(src []byte) int {
+	n := bytes.Count(src, []byte("\n"))
+	if len(src) > 0 && src[len(src)-1] != '\n' {
+		n++
 	}
+	return n
 }
 
-// ExtractFunctions extracts all function signatures from a file
+// failedParse records and returns the result of a file that could not be
+// parsed
+func (a *ASTAnalyzer) failedParse(filePath string, err error, allocBytes uint64) ParseResult {
+	result := ParseResult{
+		FilePath:     filePath,
+		Generated:    isGeneratedName(filePath),
+		IsTest:       isTestName(filePath),
+		Success:      false,
+		Error:        err,
+		ErrorMessage: err.Error(),
+		AllocBytes:   allocBytes,
+	}
+	a.metrics.ObserveParse(result)
+	return result
+}
+
+// Position translates a position from any file parsed by the analyzer into
+// a file, line and column
+func (a *ASTAnalyzer) Position(pos token.Pos) token.Position {
+	return a.fset.Position(pos)
+}
+
+// ExtractFunctions extracts all function signatures from a file. When the
+// file has syntax errors, the functions recovered from the partial AST are
+// returned together with the error.
 func (a *ASTAnalyzer) ExtractFunctions(filePath string) ([]FunctionInfo, error) {
-	f, err := parser.ParseFile(a.fset, filePath, nil, parser.ParseComments)
+	f, err := a.cache.Parse(filePath)
+	if f == nil {
+		return nil, err
+	}
+
+	// The parser returns a file without positions when it gives up before
+	// the package clause, leaving no token.File to count the lines of
+	lines := 0
+	if file := a.fset.File(f.Pos()); file != nil {
+		lines = file.LineCount()
+	} else if src, readErr := os.ReadFile(filePath); readErr == nil {
+		lines = lineCount(src)
+	}
+
+	functions := a.extractFunctions(f)
+	a.mu.Lock()
+	a.functions[filePath] = functions
+	a.lineCounts[filePath] = lines
+	a.mu.Unlock()
+	return functions, err
+}
+
+// ExtractFunctionsContext extracts the functions of every Go file under
+// dir, checking ctx between files. On cancellation it returns the
+// functions extracted so far together with ctx.Err().
+func (a *ASTAnalyzer) ExtractFunctionsContext(ctx context.Context, dir string) ([]FunctionInfo, error) {
+	files, err := a.goFiles(dir)
 	if err != nil {
 		return nil, err
 	}
 
+	var functions []FunctionInfo
+	for _, path := range files {
+		if err := ctx.Err(); err != nil {
+			return functions, err
+		}
+		fns, err := a.ExtractFunctions(path)
+		if err != nil && fns == nil {
+			return functions, err
+		}
+		functions = append(functions, fns...)
+	}
+	return functions, nil
+}
+
+// extractFunctions collects function metadata from an already parsed file
+func (a *ASTAnalyzer) extractFunctions(f *ast.File) []FunctionInfo {
+	functions := a.functionInfos(f)
+	if a.TypeCheck {
+		a.resolveTypes(f, functions)
+	}
+	return functions
+}
+
+// functionInfos is extractFunctions without type resolution
+func (a *ASTAnalyzer) functionInfos(f *ast.File) []FunctionInfo {
 	var functions []FunctionInfo
 
 	ast.Inspect(f, func(n ast.Node) bool {
@@ -115,15 +609,41 @@ func (a *ASTAnalyzer) ExtractFunctions(filePath string) ([]FunctionInfo, error)
 		}
 
 		info := FunctionInfo{
-			Name:       fn.Name.Name,
-			IsExported: fn.Name.IsExported(),
-			LineStart:  a.fset.Position(fn.Pos()).Line,
-			LineEnd:    a.fset.Position(fn.End()).Line,
+			Name:         fn.Name.Name,
+			IsExported:   fn.Name.IsExported(),
+			LineStart:    a.fset.Position(fn.Pos()).Line,
+			LineEnd:      a.fset.Position(fn.End()).Line,
+			Complexity:   cyclomaticComplexity(fn.Body),
+			IsStub:       isStub(fn.Body),
+			LabeledJumps: labeledJumps(fn.Body),
+			Halstead:     halstead(fn.Body),
+
+			MaxNestingDepth: nestingDepth(fn.Body),
+		}
+		// A declaration cut short by a syntax error can end nowhere
+		if info.LineEnd < info.LineStart {
+			info.LineEnd = info.LineStart
 		}
 
 		// Extract receiver (for methods)
 		if fn.Recv != nil && len(fn.Recv.List) > 0 {
 			info.Receiver = exprToString(fn.Recv.List[0].Type)
+			if names := fn.Recv.List[0].Names; len(names) > 0 {
+				info.ReceiverName = names[0].Name
+			}
+		}
+
+		// Extract type parameters (for generic functions)
+		if fn.Type.TypeParams != nil {
+			for _, field := range fn.Type.TypeParams.List {
+				constraint := exprToString(field.Type)
+				for _, name := range field.Names {
+					info.TypeParams = append(info.TypeParams, ParamInfo{
+						Name: name.Name,
+						Type: constraint,
+					})
+				}
+			}
 		}
 
 		// Extract parameters
@@ -162,84 +682,517 @@ func (a *ASTAnalyzer) ExtractFunctions(filePath string) ([]FunctionInfo, error)
 		functions = append(functions, info)
 		return true
 	})
+	return functions
+}
 
-	return functions, nil
+// BenchmarkDirectory benchmarks all Go files in a directory as a new run
+// and returns its results. They are also recorded for PrintSummary and
+// the exports, replacing those of the previous run. With an
+// AggregateHandler nothing is recorded and the results are nil.
+func (a *ASTAnalyzer) BenchmarkDirectory(dir string) ([]ParseResult, error) {
+	return a.BenchmarkDirectoryContext(context.Background(), dir)
 }
 
-// BenchmarkDirectory benchmarks all Go files in a directory
-func (a *ASTAnalyzer) BenchmarkDirectory(dir string) error {
-	fmt.Println(strings.Repeat("=", 70))
-	fmt.Printf("Benchmarking Go files in %s\n", dir)
-	fmt.Println(strings.Repeat("=", 70))
-	fmt.Println()
+// BenchmarkDirectoryContext is BenchmarkDirectory with cancellation. When
+// ctx is done it stops before the next file and returns ctx.Err(); the
+// files benchmarked so far are returned and stay recorded.
+func (a *ASTAnalyzer) BenchmarkDirectoryContext(ctx context.Context, dir string) ([]ParseResult, error) {
+	fsys, root, err := diskFS(dir)
+	if err != nil {
+		// Like a run that finds nothing, a missing directory still ends
+		// the previous run
+		a.newRun()
+		return nil, err
+	}
+	return a.benchmarkFS(ctx, fsys, root)
+}
 
-	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return err
-		}
+// benchmark starts a new run and benchmarks the files under root in it
+func (a *ASTAnalyzer) benchmark(root string, stream func(func(ParseResult) error) error) ([]ParseResult, error) {
+	a.newRun()
+	return a.benchmarkRoot(root, stream)
+}
 
-		if !info.IsDir() && filepath.Ext(path) == ".go" {
-			result := a.ParseFile(path)
-			a.results = append(a.results, result)
+// newRun drops the results, warnings and clones of the previous run, and
+// the totals of an AggregateHandler. The clones recorded since then
+// become those of the new run.
+func (a *ASTAnalyzer) newRun() {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	for _, r := range a.results {
+		a.metrics.Forget(r)
+	}
+	a.results = nil
+	a.warnings = nil
+	a.remotes, a.nextRemotes = a.nextRemotes, nil
+	if h, ok := a.Handler.(*AggregateHandler); ok {
+		h.reset()
+	}
+}
 
-			status := "✓"
-			if !result.Success {
-				status = "✗"
-			}
+// Reset returns the analyzer to its state after NewASTAnalyzer, keeping
+// its configuration, a cache loaded with LoadCache and the counters of
+// its metrics. Parsing restarts on a new FileSet with a new parse cache,
+// so positions and syntax trees from before are released; a cache shared
+// with SetCache is left to its other users.
+func (a *ASTAnalyzer) Reset() {
+	a.newRun()
 
-			fmt.Printf("%s %-40s Time: %6.2fms Funcs: %3d Methods: %3d\n",
-				status,
-				filepath.Base(path),
-				float64(result.ParseTime.Microseconds())/1000.0,
-				result.NumFunctions,
-				result.NumMethods)
+	// The importer holds files of the old FileSet
+	a.typesMu.Lock()
+	a.packageTypes = nil
+	a.typeImporter = nil
+	a.typesMu.Unlock()
 
-			if !result.Success {
-				fmt.Printf("  Error: %v\n", result.Error)
-			}
-		}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.fset = token.NewFileSet()
+	a.cache = NewCache(a.fset)
+	a.functions = make(map[string][]FunctionInfo)
+	a.lineCounts = make(map[string]int)
+	a.findings = nil
+	a.testStats = nil
+	a.callGraph = nil
+	a.reachability = nil
+	a.callDepths = nil
+	a.importGraph = nil
+	a.importCycles = nil
+	a.unusedExports = nil
+	a.types = nil
+	a.apiSurface = nil
+	a.startTime = time.Time{}
+	a.rootDir = ""
+	a.remotes = nil
+	a.nextRemotes = nil
+	a.typesResolved = false
+}
+
+// benchmarkRoot records and prints every result that stream delivers for
+// the files under root, adding them to the current run, and returns them
+func (a *ASTAnalyzer) benchmarkRoot(root string, stream func(func(ParseResult) error) error) ([]ParseResult, error) {
+	a.mu.Lock()
+	a.startTime = time.Now()
+	a.rootDir = root
+	first := len(a.results)
+	a.mu.Unlock()
+
+	if !a.Quiet {
+		fmt.Fprintln(a.Progress, strings.Repeat("=", 70))
+		fmt.Fprintf(a.Progress, "Benchmarking Go files in %s\n", root)
+		fmt.Fprintln(a.Progress, strings.Repeat("=", 70))
+		fmt.Fprintln(a.Progress)
+	}
 
+	handler := a.resultHandler()
+	err := stream(func(result ParseResult) error {
+		handler.Handle(result)
+		a.printResult(result)
 		return nil
 	})
 
-	return err
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if len(a.results) <= first {
+		return nil, err
+	}
+	return append([]ParseResult(nil), a.results[first:]...), err
 }
 
-// PrintSummary prints benchmark statistics
-func (a *ASTAnalyzer) PrintSummary() {
-	if len(a.results) == 0 {
-		fmt.Println("No results to summarize")
+// printResult writes one progress line for a parsed file
+func (a *ASTAnalyzer) printResult(result ParseResult) {
+	if a.Quiet {
 		return
 	}
 
-	var successful, failed int
-	var totalTime time.Duration
+	status := "✓"
+	if result.SkippedOversize {
+		status = "⊘"
+	} else if !result.Success {
+		status = "✗"
+	} else if result.FromCache {
+		status = "↺"
+	}
+	if a.ASCII {
+		// Padded so the columns still line up
+		status = fmt.Sprintf("%-6s", asciiStatus[status])
+	}
 
-	for _, r := range a.results {
-		if r.Success {
-			successful++
-			totalTime += r.ParseTime
-		} else {
-			failed++
+	fmt.Fprintf(a.Progress, "%s %-40s Time: %6.2fms Funcs: %3d Methods: %3d\n",
+		status,
+		outputPath(a.outputRoot(), result.FilePath),
+		float64(result.ParseTime.Microseconds())/1000.0,
+		result.NumFunctions,
+		result.NumMethods)
+
+	if result.SkippedOversize {
+		fmt.Fprintf(a.Progress, "  Skipped: %v\n", result.Error)
+	} else if !result.Success {
+		fmt.Fprintf(a.Progress, "  Error: %v\n", result.Error)
+	}
+}
+
+// asciiStatus maps the progress symbols to plain text
+var asciiStatus = map[string]string{
+	"✓": "OK",
+	"✗": "FAIL",
+	"↺": "CACHED",
+	"⊘": "SKIP",
+}
+
+// StreamDirectory parses every Go file under dir and hands each result to
+// fn, without recording it on the analyzer. Files are parsed by Workers
+// goroutines but fn is called from one goroutine at a time, in path order.
+// Unchanged files are taken from a cache loaded with LoadCache. The walk
+// stops at the first error returned by fn or when ctx is done.
+// Subdirectories that cannot be read are left out and recorded as
+// warnings.
+func (a *ASTAnalyzer) StreamDirectory(ctx context.Context, dir string, fn func(ParseResult) error) error {
+	fsys, root, err := diskFS(dir)
+	if err != nil {
+		return err
+	}
+	return a.StreamFS(ctx, fsys, root, fn)
+}
+
+// Warnings returns the problems that did not stop the benchmark, such as
+// unreadable subdirectories
+func (a *ASTAnalyzer) Warnings() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string(nil), a.warnings...)
+}
+
+// streamSources parses sources and hands each result to fn, tagged with
+// root. Files on disk may be taken from the result cache.
+func (a *ASTAnalyzer) streamSources(ctx context.Context, root string, sources []sourceFile, onDisk bool, fn func(ParseResult) error) error {
+	parse := func(file sourceFile) ParseResult {
+		if onDisk {
+			if result, ok := a.cachedParse(file.path); ok {
+				result.Root = root
+				return result
+			}
+		}
+		result := a.parseSourceFile(file)
+		result.Root = root
+		return result
+	}
+
+	if workers := a.workers(); workers > 1 {
+		return a.streamConcurrently(ctx, sources, workers, parse, fn)
+	}
+	for _, file := range sources {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(parse(file)); err != nil {
+			return err
 		}
 	}
+	return nil
+}
+
+// workers returns the number of parsing goroutines to use
+func (a *ASTAnalyzer) workers() int {
+	if a.MeasureMemory {
+		return 1
+	}
+	if a.Workers > 0 {
+		return a.Workers
+	}
+	return runtime.GOMAXPROCS(0)
+}
 
-	avgTime := time.Duration(0)
-	if successful > 0 {
-		avgTime = totalTime / time.Duration(successful)
+// streamConcurrently is streamSources with a pool of workers. File
+// indexes are fed to the workers, and results that complete out of order
+// are held back until every earlier file has been delivered. The shared
+// FileSet is safe for concurrent use, so positions stay valid.
+func (a *ASTAnalyzer) streamConcurrently(ctx context.Context, sources []sourceFile, workers int, parse func(sourceFile) ParseResult, fn func(ParseResult) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	type indexedResult struct {
+		index  int
+		result ParseResult
+	}
+	jobs := make(chan int)
+	results := make(chan indexedResult, workers)
+
+	go func() {
+		defer close(jobs)
+		for i := range sources {
+			select {
+			case jobs <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				result := parse(sources[i])
+				select {
+				case results <- indexedResult{i, result}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	pending := make(map[int]ParseResult)
+	next := 0
+	for r := range results {
+		pending[r.index] = r.result
+		for {
+			result, ok := pending[next]
+			if !ok {
+				break
+			}
+			// Like the serial walk, deliver nothing once ctx is done
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			delete(pending, next)
+			next++
+			if err := fn(result); err != nil {
+				return err
+			}
+		}
+	}
+	if next < len(sources) {
+		return ctx.Err()
+	}
+	return nil
+}
+
+// goFiles returns the paths of all Go files under dir, found like
+// StreamDirectory finds them, so that the passes over a tree see the files
+// the benchmark does; files over the size limits, which the benchmark
+// skips, are left out. The passes also leave out the files that do not
+// parse, so that one broken file, such as a fixture under testdata, does
+// not fail the whole tree.
+func (a *ASTAnalyzer) goFiles(dir string) ([]string, error) {
+	fsys, root, err := diskFS(dir)
+	if err != nil {
+		return nil, err
+	}
+	w := a.walker(fsys, "")
+	if err := w.walk(root); err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, file := range w.sources {
+		if !a.oversize(file.path) {
+			files = append(files, file.path)
+		}
+	}
+	return files, nil
+}
+
+// PrintSummary prints the statistics of the recorded run, or the totals
+// of an AggregateHandler
+func (a *ASTAnalyzer) PrintSummary() {
+	if h, ok := a.Handler.(*AggregateHandler); ok {
+		a.printSummary(h.Summary(), nil)
+		return
+	}
+	a.mu.Lock()
+	results := append([]ParseResult(nil), a.results...)
+	a.mu.Unlock()
+	a.PrintResultsSummary(results)
+}
+
+// PrintResultsSummary prints the statistics of results, such as those
+// returned by one BenchmarkDirectory call
+func (a *ASTAnalyzer) PrintResultsSummary(results []ParseResult) {
+	a.printSummary(summarizeResults(results, a.IncludeGenerated), results)
+}
+
+// printSummary prints the totals of a run and the sections drawn from its
+// results
+func (a *ASTAnalyzer) printSummary(summary RunSummary, results []ParseResult) {
+	if summary.TotalFiles == 0 {
+		fmt.Println("No results to summarize")
+		return
 	}
 
 	fmt.Println()
 	fmt.Println(strings.Repeat("=", 70))
 	fmt.Println("SUMMARY")
 	fmt.Println(strings.Repeat("=", 70))
-	fmt.Printf("Total files:        %d\n", len(a.results))
-	fmt.Printf("Successful:         %d\n", successful)
-	fmt.Printf("Failed:             %d\n", failed)
-	fmt.Printf("Total parse time:   %v\n", totalTime)
-	fmt.Printf("Average parse time: %.2fms\n", float64(avgTime.Microseconds())/1000.0)
+	fmt.Printf("Total files:        %d\n", summary.TotalFiles)
+	fmt.Printf("Successful:         %d\n", summary.Successful)
+	fmt.Printf("Failed:             %d\n", summary.Failed)
+	if summary.Skipped > 0 {
+		fmt.Printf("Skipped:            %d (%.2fMB over size limits, not in totals)\n",
+			summary.Skipped, float64(summary.SkippedBytes)/(1<<20))
+	}
+	fmt.Printf("Code files:         %d (%d functions)\n",
+		summary.TotalFiles-summary.TestFiles-summary.Skipped, summary.Functions-summary.TestFunctions)
+	fmt.Printf("Test files:         %d (%d functions)\n", summary.TestFiles, summary.TestFunctions)
+	if a.IncludeGenerated {
+		fmt.Printf("Generated:          %d (included)\n", summary.Generated)
+	} else {
+		fmt.Printf("Generated:          %d (excluded)\n", summary.Generated)
+	}
+	if summary.CgoFiles > 0 {
+		fmt.Printf("Cgo files:          %d (%d calls into C)\n", summary.CgoFiles, summary.CgoCalls)
+	}
+	if summary.PanicSites > 0 {
+		fmt.Printf("Panic sites:        %d (%d functions without recover)\n", summary.PanicSites, summary.UnrecoveredPanics)
+	}
+	if summary.InitFuncs > 0 {
+		fmt.Printf("Init functions:     %d\n", summary.InitFuncs)
+	}
+	if repository, commit := a.remoteSummary(); repository != "" {
+		fmt.Printf("Repository:         %s @ %s\n", repository, commit)
+	}
+	fmt.Printf("Parse mode:         %s\n", a.Mode)
+	fmt.Printf("Total parse time:   %v\n", summary.TotalParseTime)
+	fmt.Printf("Average parse time: %.2fms\n", float64(summary.AverageParseTime().Microseconds())/1000.0)
+	fmt.Printf("Total size:         %.2fMB (%d lines)\n", float64(summary.TotalBytes)/(1<<20), summary.TotalLines)
+	if seconds := summary.TotalParseTime.Seconds(); seconds > 0 {
+		fmt.Printf("Throughput:         %.2fMB/s (%.0f lines/s)\n",
+			float64(summary.TotalBytes)/(1<<20)/seconds, float64(summary.TotalLines)/seconds)
+	}
+	if a.MeasureMemory {
+		fmt.Printf("Total allocated:    %.2fMB\n", float64(summary.TotalAllocBytes)/(1<<20))
+	}
+	if warnings := a.Warnings(); len(warnings) > 0 {
+		fmt.Printf("Warnings:           %d\n", len(warnings))
+		for _, w := range warnings {
+			fmt.Printf("  %s\n", w)
+		}
+	}
 	fmt.Println(strings.Repeat("=", 70))
 	fmt.Println()
+
+	printRootSummary(results)
+	a.printInitFuncs(results)
+	a.printSlowest()
+	a.printTimingReliability(results)
+	a.printFunctionStats()
+	a.printTestPresence()
+	a.printAPISurface()
+	a.printReachability()
+	a.printCallDepths()
+	a.printUnusedExports()
+	a.printFindingsSummary()
+}
+
+// RunSummary holds the totals printed by PrintSummary. Generated files are
+// counted in Generated and, unless IncludeGenerated is set, nowhere else.
+type RunSummary struct {
+	TotalFiles      int
+	Successful      int
+	Failed          int
+	Generated       int
+	Skipped         int           // Over the size limits, counted nowhere else
+	SkippedBytes    int64         // Size of the skipped files
+	TotalParseTime  time.Duration // Successful parses only
+	TotalAllocBytes uint64
+
+	// TotalBytes and TotalLines size the successfully parsed sources, the
+	// same files TotalParseTime covers, so their ratio is the throughput
+	TotalBytes int64
+	TotalLines int
+
+	// Functions counts functions and methods of successful parses.
+	// TestFiles and TestFunctions are the part of the totals that comes
+	// from _test.go files.
+	Functions     int
+	TestFiles     int
+	TestFunctions int
+
+	// CgoFiles and CgoCalls count the successful parses importing C and
+	// their calls into it
+	CgoFiles int
+	CgoCalls int
+
+	// PanicSites counts the calls to panic and UnrecoveredPanics the
+	// functions making them without deferring a recover
+	PanicSites        int
+	UnrecoveredPanics int
+
+	// InitFuncs counts the init functions, which Functions leaves out
+	InitFuncs int
+}
+
+// AverageParseTime returns the mean parse time of successful parses
+func (s RunSummary) AverageParseTime() time.Duration {
+	if s.Successful == 0 {
+		return 0
+	}
+	return s.TotalParseTime / time.Duration(s.Successful)
+}
+
+// Summarize totals the results of the run, or returns the totals of the
+// AggregateHandler the results went to
+func (a *ASTAnalyzer) Summarize() RunSummary {
+	if h, ok := a.Handler.(*AggregateHandler); ok {
+		return h.Summary()
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return summarizeResults(a.results, a.IncludeGenerated)
+}
+
+// summarizeResults totals a set of results
+func summarizeResults(results []ParseResult, includeGenerated bool) RunSummary {
+	var s RunSummary
+	for _, r := range results {
+		s.add(r, includeGenerated)
+	}
+	return s
+}
+
+// add counts one result into the totals
+func (s *RunSummary) add(r ParseResult, includeGenerated bool) {
+	s.TotalFiles++
+	if r.SkippedOversize {
+		s.Skipped++
+		s.SkippedBytes += r.FileSizeBytes
+		return
+	}
+	if r.IsTest {
+		s.TestFiles++
+	}
+	if r.Generated {
+		s.Generated++
+		if !includeGenerated {
+			return
+		}
+	}
+	if !r.Success {
+		s.Failed++
+		return
+	}
+	s.Successful++
+	s.TotalParseTime += r.ParseTime
+	s.TotalAllocBytes += r.AllocBytes
+	s.TotalBytes += r.FileSizeBytes
+	s.TotalLines += r.LineCount
+	s.Functions += r.NumFunctions + r.NumMethods
+	if r.IsTest {
+		s.TestFunctions += r.NumFunctions + r.NumMethods
+	}
+	if r.UsesCgo {
+		s.CgoFiles++
+		s.CgoCalls += r.CgoCalls
+	}
+	s.PanicSites += len(r.PanicSites)
+	s.UnrecoveredPanics += len(r.UnrecoveredPanics)
+	s.InitFuncs += len(r.InitFuncs)
 }
 
 // exprToString converts an ast.Expr to a string representation
@@ -255,15 +1208,116 @@ func exprToString(expr ast.Expr) string {
 		return "[]" + exprToString(t.Elt)
 	case *ast.MapType:
 		return "map[" + exprToString(t.Key) + "]" + exprToString(t.Value)
+	case *ast.IndexExpr:
+		return exprToString(t.X) + "[" + exprToString(t.Index) + "]"
+	case *ast.IndexListExpr:
+		indices := make([]string, len(t.Indices))
+		for i, index := range t.Indices {
+			indices[i] = exprToString(index)
+		}
+		return exprToString(t.X) + "[" + strings.Join(indices, ", ") + "]"
+	case *ast.UnaryExpr:
+		// Approximation elements in constraints, e.g. ~int
+		if t.Op == token.TILDE {
+			return "~" + exprToString(t.X)
+		}
+		return "unknown"
+	case *ast.BinaryExpr:
+		// Union elements in constraints, e.g. ~int | ~string
+		if t.Op == token.OR {
+			return exprToString(t.X) + " | " + exprToString(t.Y)
+		}
+		return "unknown"
 	case *ast.InterfaceType:
-		return "interface{}"
+		return interfaceToString(t)
 	case *ast.StructType:
-		return "struct{}"
+		return structToString(t)
+	case *ast.FuncType:
+		return "func" + funcTypeToString(t)
+	case *ast.Ellipsis:
+		return "..." + exprToString(t.Elt)
 	default:
 		return "unknown"
 	}
 }
 
+// interfaceToString renders an interface type with its methods and
+// embedded elements, including the type set of constraint interfaces such
+// as interface{ ~int | ~string }
+func interfaceToString(t *ast.InterfaceType) string {
+	var elems []string
+	for _, field := range t.Methods.List {
+		if len(field.Names) == 0 {
+			elems = append(elems, exprToString(field.Type))
+			continue
+		}
+		signature := "unknown"
+		if fn, ok := field.Type.(*ast.FuncType); ok {
+			signature = funcTypeToString(fn)
+		}
+		for _, name := range field.Names {
+			elems = append(elems, name.Name+signature)
+		}
+	}
+
+	if len(elems) == 0 {
+		return "interface{}"
+	}
+	return "interface{ " + strings.Join(elems, "; ") + " }"
+}
+
+// structToString renders a struct type with one entry per field, e.g.
+// struct{ X int; Y int }, keeping embedded types and tags
+func structToString(t *ast.StructType) string {
+	var fields []string
+	for _, field := range t.Fields.List {
+		typeStr := exprToString(field.Type)
+		if field.Tag != nil {
+			typeStr += " " + field.Tag.Value
+		}
+		if len(field.Names) == 0 {
+			fields = append(fields, typeStr)
+			continue
+		}
+		for _, name := range field.Names {
+			fields = append(fields, name.Name+" "+typeStr)
+		}
+	}
+
+	if len(fields) == 0 {
+		return "struct{}"
+	}
+	return "struct{ " + strings.Join(fields, "; ") + " }"
+}
+
+// funcTypeToString renders the parameter and result types of a function
+// type, e.g. "([]byte) (int, error)". Names are left out.
+func funcTypeToString(t *ast.FuncType) string {
+	var params, results []string
+	for _, field := range t.Params.List {
+		for range max(len(field.Names), 1) {
+			params = append(params, exprToString(field.Type))
+		}
+	}
+	if t.Results != nil {
+		for _, field := range t.Results.List {
+			for range max(len(field.Names), 1) {
+				results = append(results, exprToString(field.Type))
+			}
+		}
+	}
+
+	s := "(" + strings.Join(params, ", ") + ")"
+	switch len(results) {
+	case 0:
+	case 1:
+		s += " " + results[0]
+	default:
+		s += " (" + strings.Join(results, ", ") + ")"
+	}
+	return s
+}
+
 // demoFunctionExtraction demonstrates function extraction
 func demoFunctionExtraction() {
 	// Create sample Go code
@@ -290,17 +1344,32 @@ func (c *Calculator) Add(a, b int) int {
 func Multiply(x, y int) (int, error) {
 	return x * y, nil
 }
-`
 
-	// Write to temp file
-	tmpFile := "sample_code.go"
-	if err := os.WriteFile(tmpFile, []byte(sampleCode), 0644); err != nil {
-		log.Fatal(err)
+// Number is a numeric constraint
+type Number interface {
+	~int | ~int64 | ~float64
+}
+
+// Sum adds up a slice of numbers
+func Sum[T Number](values []T) T {
+	var total T
+	for _, v := range values {
+		total += v
+	}
+	return total
+}
+
+// Max returns the larger of two ordered values
+func Max[T interface{ ~int | ~string }](x, y T) T {
+	if x > y {
+		return x
 	}
-	defer os.Remove(tmpFile)
+	return y
+}
+`
 
 	analyzer := NewASTAnalyzer()
-	functions, err := analyzer.ExtractFunctions(tmpFile)
+	functions, err := analyzer.ExtractFunctionsFromSource("sample_code.go", []byte(sampleCode))
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -312,9 +1381,13 @@ func Multiply(x, y int) (int, error) {
 
 	for _, fn := range functions {
 		fmt.Printf("Function: %s\n", fn.Name)
+		fmt.Printf("  Signature: %s\n", fn.Signature())
 		if fn.Receiver != "" {
 			fmt.Printf("  Receiver: %s\n", fn.Receiver)
 		}
+		if len(fn.TypeParams) > 0 {
+			fmt.Printf("  Type parameters: %+v\n", fn.TypeParams)
+		}
 		fmt.Printf("  Exported: %v\n", fn.IsExported)
 		fmt.Printf("  Parameters: %+v\n", fn.Params)
 		fmt.Printf("  Returns: %v\n", fn.Results)
@@ -327,19 +1400,73 @@ func Multiply(x, y int) (int, error) {
 }
 
 func main() {
-	// Demo function extraction
-	demoFunctionExtraction()
+	// Subcommands such as "enemies" come before any flag
+	if runCommand(os.Args[1:]) {
+		return
+	}
 
-	// Benchmark current directory
-	analyzer := NewASTAnalyzer()
-	if err := analyzer.BenchmarkDirectory("."); err != nil {
+	var opts cliOptions
+	flag.StringVar(&opts.format, "format", "text", "output format: text, json, jsonl, yaml, csv, html, markdown, mermaid, junit, sarif, proto, world or dungeon")
+	flag.StringVar(&opts.output, "o", "", "write structured output to this file instead of stdout")
+	flag.StringVar(&opts.functionsOutput, "functions", "", "with -format csv, also write one row per function to this file")
+	flag.BoolVar(&opts.measureMemory, "mem", false, "record heap allocations per parse (forces a GC per file)")
+	flag.StringVar(&opts.mode, "mode", "standard", "parser mode: fast (no comments or object resolution), standard or full")
+	flag.IntVar(&opts.runs, "runs", 1, "parse every file this many times after a warmup and report median timings")
+	flag.Int64Var(&opts.maxFileSize, "max-file-size", DefaultMaxFileSize, "skip files larger than this many bytes")
+	flag.IntVar(&opts.maxLineLength, "max-line-length", DefaultMaxLineLength, "skip files with a line longer than this many bytes")
+	flag.BoolVar(&opts.force, "force", false, "parse files over the size limits anyway")
+	flag.IntVar(&opts.workers, "workers", 0, "number of files to parse concurrently (default GOMAXPROCS)")
+	flag.StringVar(&opts.database, "db", "", "append the run to this SQLite database")
+	flag.StringVar(&opts.metricsAddr, "metrics", "", "with -format text, serve Prometheus metrics on this address after the run until interrupted")
+	flag.StringVar(&opts.cache, "cache", "", "reuse results of unchanged files from this cache file and update it after the run")
+	flag.StringVar(&opts.templateFile, "template", "", "render results with this text/template file (see templates/examples)")
+	flag.BoolVar(&opts.quiet, "quiet", false, "print only the summary, without per-file progress lines")
+	flag.BoolVar(&opts.ascii, "ascii", false, "write OK, FAIL and CACHED instead of Unicode symbols in progress lines")
+	flag.BoolVar(&opts.watch, "watch", false, "with -format text, keep re-analyzing changed files after the run until interrupted")
+	flag.BoolVar(&opts.jsonlFunctions, "jsonl-functions", false, "with -format jsonl, also stream one line per function")
+	flag.IntVar(&opts.maxComplexity, "max-complexity", DefaultMaxComplexity, "with -format junit, fail functions above this cyclomatic complexity")
+	flag.Float64Var(&opts.minDocCoverage, "min-doc-coverage", 0, "with -format junit, fail packages documenting fewer than this fraction of exported functions")
+	flag.StringVar(&opts.focus, "focus", "", "with -format mermaid, render only this type and its direct relations")
+	flag.BoolVar(&opts.fence, "fence", false, "with -format mermaid, wrap the diagram in a ```mermaid block")
+	flag.BoolVar(&opts.typeCheck, "types", false, "type-check packages to resolve the parameter and result types of functions and the targets of calls")
+	flag.StringVar(&opts.rootDir, "root", "", "write file paths relative to this directory (default the benchmarked directory)")
+	flag.BoolVar(&opts.followSymlinks, "follow-symlinks", false, "descend into symlinked directories, still counting each file once")
+	flag.BoolVar(&opts.skipGenerated, "skip-generated", false, "leave generated files out of the run instead of only out of the totals")
+	flag.StringVar(&opts.loader, "loader", ParserLoader, "how to find and parse files: parser walks the directories, packages loads one module with go/packages and its build constraints")
+	flag.StringVar(&opts.ref, "ref", "", "branch, tag or commit to clone for repository URL arguments (default the remote's HEAD)")
+	flag.BoolVar(&opts.keepClone, "keep-clone", false, "keep the temporary clones of repository URL arguments after the run")
+	flag.StringVar(&opts.serve, "serve", "", "serve the analyzer over HTTP on this address (POST /analyze, POST /summary) instead of benchmarking")
+	flag.StringVar(&opts.resume, "resume", "", "take unchanged files from the JSON results saved in this file and save the results there, even when interrupted")
+	flag.Parse()
+
+	if opts.serve != "" {
+		log.Fatal(StartServer(opts.serve))
+	}
+
+	opts.dirs = flag.Args()
+	if len(opts.dirs) == 0 {
+		opts.dirs = []string{"."}
+	}
+	if err := splitPatterns(&opts); err != nil {
 		log.Fatal(err)
 	}
-	analyzer.PrintSummary()
 
-	fmt.Println("💡 Next Steps:")
-	fmt.Println("1. Download Gin source code and benchmark")
-	fmt.Println("2. Test go/types for type inference")
-	fmt.Println("3. Test on larger codebases (5000+ LOC)")
-	fmt.Println("4. Build call graph using golang.org/x/tools/go/callgraph")
+	if opts.templateFile != "" {
+		opts.format = "template"
+	}
+
+	// Repository URLs are benchmarked from shallow clones
+	cleanup, err := cloneRemotes(&opts)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if opts.format != "text" {
+		err = writeStructured(opts)
+	} else {
+		err = writeText(opts)
+	}
+	cleanup()
+	if err != nil {
+		log.Fatal(err)
+	}
 }
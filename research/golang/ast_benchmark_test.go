@@ -0,0 +1,594 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"go/parser"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// update rewrites the golden files with the current output
+var update = flag.Bool("update", false, "rewrite the golden files under testdata")
+
+// checkGolden compares got with the golden file testdata/name, rewriting
+// the file instead with -update
+func checkGolden(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Errorf("output differs from %s; rerun with -update if the change is intended\ngot:\n%s", path, got)
+	}
+}
+
+// writeTree writes files, by slash-separated path, under a temporary
+// directory and returns the directory
+func writeTree(t testing.TB, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+// generatedTree writes n Go files spread over packages of ten files, with
+// one broken file per package, and returns the directory
+func generatedTree(t testing.TB, n int) string {
+	t.Helper()
+	files := make(map[string]string, n)
+	for i := 0; i < n; i++ {
+		name := fmt.Sprintf("p%02d/f%03d.go", i/10, i)
+		if i%10 == 9 {
+			files[name] = fmt.Sprintf("package p%02d\n\nfunc Broken%d( {\n", i/10, i)
+			continue
+		}
+		var b bytes.Buffer
+		fmt.Fprintf(&b, "package p%02d\n\ntype T%d struct{ n int }\n\ntype I%d interface{ M() }\n\n", i/10, i, i)
+		for j := 0; j <= i%7; j++ {
+			fmt.Fprintf(&b, "func F%d_%d(x int) int {\n\tif x > %d {\n\t\treturn x\n\t}\n\treturn %d\n}\n\n", i, j, j, j)
+		}
+		fmt.Fprintf(&b, "func (t *T%d) M() { t.n++ }\n", i)
+		files[name] = b.String()
+	}
+	return writeTree(t, files)
+}
+
+// resultCounts is a ParseResult without the fields that vary between
+// parses of the same file
+type resultCounts struct {
+	FilePath                                            string
+	Success                                             bool
+	NumFunctions, NumMethods, NumInterfaces, NumStructs int
+	LineCount, NodeCount                                int
+}
+
+// countsOf strips the timings from results
+func countsOf(results []ParseResult) []resultCounts {
+	counts := make([]resultCounts, len(results))
+	for i, r := range results {
+		counts[i] = resultCounts{
+			FilePath:      r.FilePath,
+			Success:       r.Success,
+			NumFunctions:  r.NumFunctions,
+			NumMethods:    r.NumMethods,
+			NumInterfaces: r.NumInterfaces,
+			NumStructs:    r.NumStructs,
+			LineCount:     r.LineCount,
+			NodeCount:     r.NodeCount,
+		}
+	}
+	return counts
+}
+
+// quietAnalyzer returns an analyzer that prints no progress
+func quietAnalyzer() *ASTAnalyzer {
+	a := NewASTAnalyzer()
+	a.Quiet = true
+	return a
+}
+
+func TestExtractFunctionsMalformed(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		functions int
+		lines     int
+		wantErr   bool
+	}{
+		{name: "valid", src: "package a\n\nfunc A() {}\nfunc B() {}\n", functions: 2, lines: 4},
+		{name: "empty", src: "", wantErr: true},
+		{name: "no package clause", src: "func A() {}\n", lines: 1, wantErr: true},
+		{name: "binary", src: "\xff\x00\x01", lines: 1, wantErr: true},
+		{name: "partial", src: "package a\n\nfunc A() {}\nfunc B( {\n", functions: 2, lines: 4, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeTree(t, map[string]string{"a.go": tt.src})
+			path := filepath.Join(dir, "a.go")
+			a := quietAnalyzer()
+			functions, err := a.ExtractFunctions(path)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExtractFunctions() error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(functions) != tt.functions {
+				t.Errorf("got %d functions, want %d", len(functions), tt.functions)
+			}
+			if got := a.lineCounts[path]; got != tt.lines {
+				t.Errorf("line count = %d, want %d", got, tt.lines)
+			}
+		})
+	}
+}
+
+func TestExprToStringConstraints(t *testing.T) {
+	tests := []struct {
+		expr string
+		want string
+	}{
+		{expr: "~int", want: "~int"},
+		{expr: "~int | ~string", want: "~int | ~string"},
+		{expr: "~int | ~int64 | ~float64", want: "~int | ~int64 | ~float64"},
+		{expr: "int | ~[]byte", want: "int | ~[]byte"},
+		{expr: "interface{ ~int | ~string }", want: "interface{ ~int | ~string }"},
+		{expr: "interface{ comparable; ~int | ~uint }", want: "interface{ comparable; ~int | ~uint }"},
+		{expr: "interface{ String() string }", want: "interface{ String() string }"},
+		{expr: "Pair[K, V]", want: "Pair[K, V]"},
+		{expr: "-x", want: "unknown"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, err := parser.ParseExpr(tt.expr)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := exprToString(expr); got != tt.want {
+				t.Errorf("exprToString(%s) = %q, want %q", tt.expr, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractFunctionsTypeParams(t *testing.T) {
+	const src = `package numbers
+
+// Number is a numeric constraint
+type Number interface {
+	~int | ~int64 | ~float64
+}
+
+func Sum[T Number](values []T) T { var total T; return total }
+
+func Max[T interface{ ~int | ~string }](x, y T) T { return x }
+
+func Map[K comparable, V any, R ~int | ~string](m map[K]V) []R { return nil }
+
+func Plain(x int) int { return x }
+`
+	functions, err := quietAnalyzer().ExtractFunctionsFromSource("numbers.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string][]ParamInfo{
+		"Sum": {{Name: "T", Type: "Number"}},
+		"Max": {{Name: "T", Type: "interface{ ~int | ~string }"}},
+		"Map": {
+			{Name: "K", Type: "comparable"},
+			{Name: "V", Type: "any"},
+			{Name: "R", Type: "~int | ~string"},
+		},
+		"Plain": nil,
+	}
+	if len(functions) != len(want) {
+		t.Fatalf("got %d functions, want %d", len(functions), len(want))
+	}
+	for _, fn := range functions {
+		if !reflect.DeepEqual(fn.TypeParams, want[fn.Name]) {
+			t.Errorf("%s type parameters = %+v, want %+v", fn.Name, fn.TypeParams, want[fn.Name])
+		}
+	}
+}
+
+func TestWorkers(t *testing.T) {
+	dir := generatedTree(t, 60)
+	serial := quietAnalyzer()
+	serial.Workers = 1
+	want, err := serial.BenchmarkDirectory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(want) != 60 {
+		t.Fatalf("serial run found %d files, want 60", len(want))
+	}
+
+	for _, workers := range []int{2, 4, 16, 100} {
+		t.Run(fmt.Sprint(workers), func(t *testing.T) {
+			a := quietAnalyzer()
+			a.Workers = workers
+			got, err := a.BenchmarkDirectory(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			// Results come back in path order, as from the serial run
+			if !reflect.DeepEqual(countsOf(got), countsOf(want)) {
+				t.Errorf("%d workers disagree with the serial run", workers)
+			}
+			if got, want := a.Summarize(), serial.Summarize(); got.Functions != want.Functions || got.Failed != want.Failed {
+				t.Errorf("summary %+v, want %+v", got, want)
+			}
+		})
+	}
+}
+
+// TestWorkersConcurrentUse reads the analyzer while a pooled benchmark
+// records into it; run it with -race
+func TestWorkersConcurrentUse(t *testing.T) {
+	dir := generatedTree(t, 40)
+	a := quietAnalyzer()
+	a.Workers = 8
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				a.Summarize()
+				a.BuildExport()
+				if _, err := a.ExtractTypes(dir); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+	_, err := a.BenchmarkDirectory(dir)
+	close(done)
+	wg.Wait()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if files := a.BuildExport().Files; len(files) != 40 {
+		t.Errorf("recorded %d files, want 40", len(files))
+	}
+}
+
+func BenchmarkWorkers(b *testing.B) {
+	dir := generatedTree(b, 400)
+	for _, workers := range []int{1, 2, 4, 8} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			a := quietAnalyzer()
+			a.Workers = workers
+			for i := 0; i < b.N; i++ {
+				if _, err := a.BenchmarkDirectory(dir); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkParseModes compares the parser modes on one worker, so the
+// difference is the parser's work alone
+func BenchmarkParseModes(b *testing.B) {
+	dir := generatedTree(b, 400)
+	for _, mode := range []ParseMode{FastMode, StandardMode, FullMode} {
+		b.Run(mode.String(), func(b *testing.B) {
+			a := quietAnalyzer()
+			a.Mode = mode
+			a.Workers = 1
+			for i := 0; i < b.N; i++ {
+				if _, err := a.BenchmarkDirectory(dir); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// TestParseFileConcurrent hammers ParseFile and ExtractFunctions from 32
+// goroutines; run it with -race
+func TestParseFileConcurrent(t *testing.T) {
+	const goroutines = 32
+	dir := generatedTree(t, 50)
+
+	tests := []struct {
+		name      string
+		retainAST bool
+	}{
+		{name: "throwaway file sets"},
+		{name: "shared file set", retainAST: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			serial := quietAnalyzer()
+			serial.RetainAST = tt.retainAST
+			paths, err := serial.goFiles(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := make([]ParseResult, len(paths))
+			functions := make([]int, len(paths))
+			for i, path := range paths {
+				want[i] = serial.ParseFile(path)
+				fns, _ := serial.ExtractFunctions(path)
+				functions[i] = len(fns)
+			}
+
+			a := quietAnalyzer()
+			a.RetainAST = tt.retainAST
+			got := make([][]ParseResult, goroutines)
+			var wg sync.WaitGroup
+			for g := 0; g < goroutines; g++ {
+				wg.Add(1)
+				go func(g int) {
+					defer wg.Done()
+					got[g] = make([]ParseResult, len(paths))
+					// Each goroutine starts at a different file
+					for k := range paths {
+						i := (g + k) % len(paths)
+						got[g][i] = a.ParseFile(paths[i])
+						fns, _ := a.ExtractFunctions(paths[i])
+						if len(fns) != functions[i] {
+							t.Errorf("goroutine %d extracted %d functions from %s, want %d", g, len(fns), paths[i], functions[i])
+						}
+					}
+				}(g)
+			}
+			wg.Wait()
+
+			for g := range got {
+				if !reflect.DeepEqual(countsOf(got[g]), countsOf(want)) {
+					t.Errorf("goroutine %d counts differ from the serial run", g)
+				}
+			}
+			if got, want := a.metrics.filesParsed.Load(), int64(goroutines*len(paths)); got != want {
+				t.Errorf("metrics counted %d parses, want %d", got, want)
+			}
+		})
+	}
+}
+
+// cancelHandler records results as the default handler does and cancels
+// its context after the first n
+type cancelHandler struct {
+	retainHandler
+	n      int
+	cancel context.CancelFunc
+}
+
+func (h *cancelHandler) Handle(result ParseResult) {
+	h.retainHandler.Handle(result)
+	if h.n--; h.n == 0 {
+		h.cancel()
+	}
+}
+
+func TestBenchmarkDirectoryContextCancel(t *testing.T) {
+	const numFiles = 40
+	dir := generatedTree(t, numFiles)
+
+	tests := []struct {
+		name        string
+		workers     int
+		cancelAfter int // Results handled before cancelling, zero to cancel up front
+	}{
+		{name: "serial", workers: 1, cancelAfter: 5},
+		{name: "pool", workers: 4, cancelAfter: 5},
+		{name: "before the walk", workers: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			a := quietAnalyzer()
+			a.Workers = tt.workers
+			a.Handler = &cancelHandler{retainHandler: retainHandler{a}, n: tt.cancelAfter, cancel: cancel}
+			if tt.cancelAfter == 0 {
+				cancel()
+			}
+
+			results, err := a.BenchmarkDirectoryContext(ctx, dir)
+			if !errors.Is(err, context.Canceled) {
+				t.Fatalf("BenchmarkDirectoryContext() error = %v, want context.Canceled", err)
+			}
+			if len(results) != tt.cancelAfter {
+				t.Errorf("got %d partial results, want %d", len(results), tt.cancelAfter)
+			}
+			// The partial results are recorded for the summary
+			if got := a.Summarize().TotalFiles; got != len(results) {
+				t.Errorf("summary counts %d files, want %d", got, len(results))
+			}
+			for i := 1; i < len(results); i++ {
+				if results[i-1].FilePath >= results[i].FilePath {
+					t.Errorf("partial results out of path order at %s", results[i].FilePath)
+				}
+			}
+		})
+	}
+}
+
+func TestExtractFunctionsContextCancel(t *testing.T) {
+	dir := generatedTree(t, 20)
+	a := quietAnalyzer()
+
+	all, err := a.ExtractFunctionsContext(context.Background(), dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) == 0 {
+		t.Fatal("no functions extracted")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	functions, err := a.ExtractFunctionsContext(ctx, dir)
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("ExtractFunctionsContext() error = %v, want context.Canceled", err)
+	}
+	if len(functions) != 0 {
+		t.Errorf("cancelled extraction returned %d functions", len(functions))
+	}
+}
+
+// captureStdout returns what fn prints
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	out := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		out <- string(data)
+	}()
+	fn()
+	w.Close()
+	return <-out
+}
+
+func TestBackToBackRuns(t *testing.T) {
+	first := writeTree(t, map[string]string{
+		"a.go": "package a\n\nfunc A() {}\n",
+		"b.go": "package a\n\nfunc B() {}\n",
+		"c.go": "package a\n\nfunc C() {}\n",
+	})
+	second := writeTree(t, map[string]string{
+		"d.go": "package d\n\nfunc D() {}\n",
+	})
+
+	tests := []struct {
+		name      string
+		aggregate bool
+		reset     bool
+	}{
+		{name: "recorded results"},
+		{name: "recorded results with reset", reset: true},
+		{name: "aggregate handler", aggregate: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := quietAnalyzer()
+			if tt.aggregate {
+				a.Handler = NewAggregateHandler(0)
+			}
+			if _, err := a.BenchmarkDirectory(first); err != nil {
+				t.Fatal(err)
+			}
+			if got := a.Summarize().TotalFiles; got != 3 {
+				t.Fatalf("first summary counts %d files, want 3", got)
+			}
+			if tt.reset {
+				a.Reset()
+			}
+
+			results, err := a.BenchmarkDirectory(second)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !tt.aggregate && (len(results) != 1 || filepath.Dir(results[0].FilePath) != second) {
+				t.Errorf("second run returned %d results", len(results))
+			}
+			summary := a.Summarize()
+			if summary.TotalFiles != 1 || summary.Functions != 1 {
+				t.Errorf("second summary counts %d files and %d functions, want 1 and 1", summary.TotalFiles, summary.Functions)
+			}
+			if out := captureStdout(t, a.PrintSummary); !strings.Contains(out, "Total files:        1\n") {
+				t.Errorf("PrintSummary printed\n%s", out)
+			}
+		})
+	}
+}
+
+func TestRemotesPerRun(t *testing.T) {
+	dir := writeTree(t, map[string]string{"a.go": "package a\n"})
+	a := quietAnalyzer()
+
+	a.RecordRemote(&RemoteCheckout{URL: "https://example.com/first", Commit: "111"})
+	if _, err := a.BenchmarkDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	if repository, commit := a.remoteSummary(); repository != "https://example.com/first" || commit != "111" {
+		t.Errorf("first run reports %q at %q", repository, commit)
+	}
+
+	a.RecordRemote(&RemoteCheckout{URL: "https://example.com/second", Commit: "222"})
+	if repository, _ := a.remoteSummary(); repository != "https://example.com/first" {
+		t.Errorf("recording a clone changed the finished run to %q", repository)
+	}
+	if _, err := a.BenchmarkDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	if repository, commit := a.remoteSummary(); repository != "https://example.com/second" || commit != "222" {
+		t.Errorf("second run reports %q at %q", repository, commit)
+	}
+
+	if _, err := a.BenchmarkDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	if repository, _ := a.remoteSummary(); repository != "" {
+		t.Errorf("run of a local directory reports %q", repository)
+	}
+}
+
+func TestResetFileSet(t *testing.T) {
+	dir := generatedTree(t, 10)
+	a := quietAnalyzer()
+	a.RetainAST = true
+	if _, err := a.BenchmarkDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := extractAllFunctions(a); err != nil {
+		t.Fatal(err)
+	}
+	if a.fset.Base() == 1 || len(a.functions) == 0 {
+		t.Fatal("the run recorded nothing")
+	}
+
+	a.Reset()
+	if a.fset.Base() != 1 {
+		t.Errorf("FileSet base %d after Reset, want a fresh FileSet", a.fset.Base())
+	}
+	if len(a.functions) != 0 || len(a.BuildExport().Files) != 0 || a.Summarize().TotalFiles != 0 {
+		t.Error("Reset kept results of the previous run")
+	}
+	if !a.RetainAST {
+		t.Error("Reset dropped the configuration")
+	}
+}
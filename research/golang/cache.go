@@ -0,0 +1,89 @@
+package main
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"sync"
+	"time"
+)
+
+// cacheParseMode is used for every cached parse. It keeps comments and
+// reports all errors so that any analysis can be served from the cache.
+const cacheParseMode = parser.ParseComments | parser.AllErrors
+
+// Cache parses each file once and shares the *ast.File between analyses.
+// Entries are keyed by path and invalidated when the file's modification
+// time or size changes. A Cache is safe for concurrent use; all of its
+// files are parsed into one FileSet.
+type Cache struct {
+	fset   *token.FileSet
+	mu     sync.Mutex
+	files  map[string]cacheEntry
+	hits   int
+	misses int
+}
+
+// cacheEntry is a parsed file and the file state it was parsed from
+type cacheEntry struct {
+	modTime time.Time
+	size    int64
+	file    *ast.File
+	err     error
+}
+
+// NewCache creates an empty cache that parses into fset
+func NewCache(fset *token.FileSet) *Cache {
+	return &Cache{
+		fset:  fset,
+		files: make(map[string]cacheEntry),
+	}
+}
+
+// FileSet returns the FileSet positions of cached files belong to
+func (c *Cache) FileSet() *token.FileSet {
+	return c.fset
+}
+
+// Parse returns the parsed file at path, parsing it only if it is not
+// cached or changed on disk since. Like parser.ParseFile, it may return a
+// partial file together with a syntax error; both are cached.
+func (c *Cache) Parse(path string) (*ast.File, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	entry, ok := c.files[path]
+	if ok && entry.modTime.Equal(info.ModTime()) && entry.size == info.Size() {
+		c.hits++
+		c.mu.Unlock()
+		return entry.file, entry.err
+	}
+	c.misses++
+	c.mu.Unlock()
+
+	f, err := parser.ParseFile(c.fset, path, nil, cacheParseMode)
+
+	c.mu.Lock()
+	c.files[path] = cacheEntry{modTime: info.ModTime(), size: info.Size(), file: f, err: err}
+	c.mu.Unlock()
+	return f, err
+}
+
+// Stats returns how many Parse calls were served from the cache and how
+// many parsed the file
+func (c *Cache) Stats() (hits, misses int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.hits, c.misses
+}
+
+// Forget drops path from the cache
+func (c *Cache) Forget(path string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.files, path)
+}
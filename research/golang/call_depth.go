@@ -0,0 +1,195 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// callChainsShown is how many of the deepest chains printCallDepths lists
+const callChainsShown = 5
+
+// CallDepths holds the call-chain depth of every declared function of a
+// call graph and its recursive groups
+type CallDepths struct {
+	// Depth maps each declared function to the number of calls on the
+	// longest chain of local calls it can start. A leaf has depth 0.
+	// Calls within a recursive group add nothing, so a chain going around
+	// a cycle stays finite.
+	Depth map[string]int
+
+	// Recursive holds the functions calling themselves, directly or
+	// through other functions of their group
+	Recursive map[string]bool
+
+	// Cycles are the recursive groups, as RecursionCycles returns them
+	Cycles [][]string
+
+	// Chains are the longest chains of the deepest functions, deepest
+	// first, each starting with the function it is for
+	Chains [][]string
+}
+
+// CallDepths builds the call graph of dir and computes the call-chain
+// depth of every function and whether it is recursive. Only calls to
+// functions declared under dir count; external and dynamic calls end a
+// chain. Functions already extracted get their CallDepth and Recursive
+// set, and the result is kept for PrintSummary.
+func (a *ASTAnalyzer) CallDepths(dir string) (*CallDepths, error) {
+	cg, err := a.BuildCallGraph(dir)
+	if err != nil {
+		return nil, err
+	}
+	d := cg.CallDepths()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.callDepths = d
+	for path, functions := range a.functions {
+		for i := range functions {
+			fn := &functions[i]
+			id := functionNodeID(filepath.Dir(path), receiverTypeName(fn.Receiver), fn.Name)
+			if depth, ok := d.Depth[id]; ok {
+				fn.CallDepth = depth
+				fn.Recursive = d.Recursive[id]
+			}
+		}
+	}
+	return d, nil
+}
+
+// CallDepths computes the call-chain depths of the graph over its
+// condensation: each strongly connected component is one node, so the
+// longest chain is a longest path in a DAG
+func (cg *CallGraph) CallDepths() *CallDepths {
+	d := &CallDepths{
+		Depth:     make(map[string]int),
+		Recursive: make(map[string]bool),
+		Cycles:    cg.RecursionCycles(),
+	}
+	for _, cycle := range d.Cycles {
+		for _, id := range cycle {
+			d.Recursive[id] = true
+		}
+	}
+
+	local := func(id string) bool {
+		node := cg.Nodes[id]
+		return node != nil && !node.External && !node.Dynamic
+	}
+	ids := cg.declaredIDs(local)
+	component := make(map[string]int)
+	components := stronglyConnectedComponents(ids, cg.localEdges(local))
+	for i, members := range components {
+		for _, id := range members {
+			component[id] = i
+		}
+	}
+
+	// Depths are memoized per component
+	depths := make(map[int]int)
+	var depthOf func(c int) int
+	depthOf = func(c int) int {
+		if depth, ok := depths[c]; ok {
+			return depth
+		}
+		depth := 0
+		for _, id := range components[c] {
+			for _, callee := range cg.Edges[id] {
+				if next, ok := component[callee]; ok && next != c {
+					depth = max(depth, depthOf(next)+1)
+				}
+			}
+		}
+		depths[c] = depth
+		return depth
+	}
+	for _, id := range ids {
+		d.Depth[id] = depthOf(component[id])
+	}
+
+	deepest := append([]string(nil), ids...)
+	sort.SliceStable(deepest, func(i, j int) bool { return d.Depth[deepest[i]] > d.Depth[deepest[j]] })
+	for _, id := range deepest {
+		if len(d.Chains) == callChainsShown || d.Depth[id] == 0 {
+			break
+		}
+		d.Chains = append(d.Chains, d.chain(cg, id, components, component))
+	}
+	return d
+}
+
+// localEdges returns the edges of the graph between nodes matching local
+func (cg *CallGraph) localEdges(local func(id string) bool) map[string][]string {
+	edges := make(map[string][]string)
+	for caller, callees := range cg.Edges {
+		for _, callee := range callees {
+			if local(caller) && local(callee) {
+				edges[caller] = append(edges[caller], callee)
+			}
+		}
+	}
+	return edges
+}
+
+// chain follows one longest chain from id. Where the next step is taken
+// by another member of the function's recursive group, that member is
+// listed before it.
+func (d *CallDepths) chain(cg *CallGraph, id string, components [][]string, component map[string]int) []string {
+	chain := []string{id}
+	for d.Depth[id] > 0 {
+		c := component[id]
+		next := ""
+		from := append([]string{id}, components[c]...)
+		for _, member := range from {
+			for _, callee := range cg.Edges[member] {
+				if to, ok := component[callee]; ok && to != c && d.Depth[callee] == d.Depth[id]-1 {
+					next = callee
+					break
+				}
+			}
+			if next != "" {
+				if member != id {
+					chain = append(chain, member)
+				}
+				break
+			}
+		}
+		if next == "" {
+			break
+		}
+		chain = append(chain, next)
+		id = next
+	}
+	return chain
+}
+
+// printCallDepths prints the deepest call chains and the recursive groups
+func (a *ASTAnalyzer) printCallDepths() {
+	d := a.callDepths
+	if d == nil {
+		return
+	}
+
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println("CALL DEPTH")
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Printf("Deepest chains:     %d shown\n", len(d.Chains))
+	for _, chain := range d.Chains {
+		marker := ""
+		for _, id := range chain {
+			if d.Recursive[id] {
+				marker = " (recursive)"
+				break
+			}
+		}
+		fmt.Printf("  %d: %s%s\n", d.Depth[chain[0]], strings.Join(chain, " -> "), marker)
+	}
+	fmt.Printf("Recursion cycles:   %d\n", len(d.Cycles))
+	for _, cycle := range d.Cycles {
+		fmt.Printf("  %s\n", strings.Join(cycle, ", "))
+	}
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println()
+}
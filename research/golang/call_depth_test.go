@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"testing"
+)
+
+func TestCallDepths(t *testing.T) {
+	tests := []struct {
+		name      string
+		files     map[string]string
+		depth     map[string]int
+		recursive []string
+	}{
+		{
+			name: "chain",
+			files: map[string]string{
+				"main.go": `package main
+
+func main() { a() }
+func a()    { b() }
+func b()    {}
+`,
+			},
+			depth: map[string]int{"main": 2, "a": 1, "b": 0},
+		},
+		{
+			name: "direct recursion",
+			files: map[string]string{
+				"main.go": `package main
+
+func main()      { fact(3) }
+func fact(n int) int {
+	if n == 0 {
+		return 1
+	}
+	return n * fact(n-1)
+}
+`,
+			},
+			depth:     map[string]int{"main": 1, "fact": 0},
+			recursive: []string{"fact"},
+		},
+		{
+			name: "mutual recursion adds nothing",
+			files: map[string]string{
+				"main.go": `package main
+
+func main()  { even(4) }
+func even(n int) bool { return n == 0 || odd(n-1) }
+func odd(n int) bool  { return n != 0 && even(n-1) && leaf() }
+func leaf() bool      { return true }
+`,
+			},
+			depth:     map[string]int{"main": 2, "even": 1, "odd": 1, "leaf": 0},
+			recursive: []string{"even", "odd"},
+		},
+		{
+			name: "three-function cycle",
+			files: map[string]string{
+				"main.go": `package main
+
+func main()      { a(3) }
+func a(n int)    { b(n) }
+func b(n int)    { c(n) }
+func c(n int) {
+	if n > 0 {
+		a(n - 1)
+	}
+	leaf()
+}
+func leaf() {}
+`,
+			},
+			depth:     map[string]int{"main": 2, "a": 1, "b": 1, "c": 1, "leaf": 0},
+			recursive: []string{"a", "b", "c"},
+		},
+		{
+			name: "file that does not parse",
+			files: map[string]string{
+				"main.go":                           "package main\n\nfunc main() { a() }\nfunc a() {}\n",
+				"broken.go":                         "package main\n\nfunc b( {\n",
+				"testdata/not_a_file.go/invalid.go": "This is not Go\n",
+			},
+			depth: map[string]int{"main": 1, "a": 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeTree(t, tt.files)
+			d, err := quietAnalyzer().CallDepths(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(d.Depth) != len(tt.depth) {
+				t.Errorf("got depths of %d functions, want %d: %v", len(d.Depth), len(tt.depth), d.Depth)
+			}
+			for name, want := range tt.depth {
+				if got := d.Depth[functionNodeID(dir, "", name)]; got != want {
+					t.Errorf("depth of %s = %d, want %d", name, got, want)
+				}
+			}
+			if len(d.Recursive) != len(tt.recursive) {
+				t.Errorf("got %d recursive functions, want %d: %v", len(d.Recursive), len(tt.recursive), d.Recursive)
+			}
+			for _, name := range tt.recursive {
+				if !d.Recursive[functionNodeID(dir, "", name)] {
+					t.Errorf("%s not reported recursive", name)
+				}
+			}
+		})
+	}
+}
+
+func TestCallDepthsSetsFunctionInfo(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"main.go": "package main\n\nfunc main() { loop() }\nfunc loop() { loop() }\n",
+	})
+	a := quietAnalyzer()
+	if _, err := a.ExtractFunctionsContext(context.Background(), dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.CallDepths(dir); err != nil {
+		t.Fatal(err)
+	}
+	for _, functions := range a.functions {
+		for _, fn := range functions {
+			switch fn.Name {
+			case "main":
+				if fn.CallDepth != 1 || fn.Recursive {
+					t.Errorf("main: depth %d, recursive %v; want 1, false", fn.CallDepth, fn.Recursive)
+				}
+			case "loop":
+				if fn.CallDepth != 0 || !fn.Recursive {
+					t.Errorf("loop: depth %d, recursive %v; want 0, true", fn.CallDepth, fn.Recursive)
+				}
+			}
+		}
+	}
+}
@@ -0,0 +1,604 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// CallGraphNode is a function or method in the call graph
+type CallGraphNode struct {
+	ID         string
+	Package    string // Package directory
+	Name       string
+	Receiver   string // Bare receiver type name, for methods
+	File       string
+	Line       int
+	Lines      int
+	Complexity int
+	External   bool // Callee that could not be resolved to a declaration
+	Dynamic    bool // The synthetic callee of calls through function values
+}
+
+// dynamicNodeID identifies the synthetic node that every call through a
+// function value leads to: variables, parameters and struct fields of
+// function type, interface methods, and functions returned by calls
+const dynamicNodeID = "dynamic"
+
+// CallGraph is a syntactic call graph of the functions under a directory.
+// Calls are resolved within a package by name and receiver type; calls
+// through function values lead to the dynamic node and anything else
+// becomes an external node. When the analyzer type-checks, calls are
+// resolved with go/types instead, across packages too, falling back to
+// syntax in files with type errors.
+type CallGraph struct {
+	Nodes map[string]*CallGraphNode
+	Edges map[string][]string // Caller ID to sorted, unique callee IDs
+}
+
+// builtinNames are predeclared identifiers that never become graph nodes
+var builtinNames = map[string]bool{
+	"append": true, "cap": true, "clear": true, "close": true, "complex": true,
+	"copy": true, "delete": true, "imag": true, "len": true, "make": true,
+	"max": true, "min": true, "new": true, "panic": true, "print": true,
+	"println": true, "real": true, "recover": true,
+	"bool": true, "byte": true, "complex64": true, "complex128": true,
+	"error": true, "float32": true, "float64": true, "int": true, "int8": true,
+	"int16": true, "int32": true, "int64": true, "rune": true, "string": true,
+	"uint": true, "uint8": true, "uint16": true, "uint32": true, "uint64": true,
+	"uintptr": true, "any": true,
+}
+
+// functionNodeID returns the call graph ID of a declared function. IDs are
+// qualified by package directory unless the package is the current one.
+func functionNodeID(pkgDir, receiver, name string) string {
+	id := name
+	if receiver != "" {
+		id = receiver + "." + name
+	}
+	if pkgDir == "." {
+		return id
+	}
+	return filepath.ToSlash(pkgDir) + "." + id
+}
+
+// BuildCallGraph builds the call graph of all non-test Go files under dir
+// that parse
+func (a *ASTAnalyzer) BuildCallGraph(dir string) (*CallGraph, error) {
+	files, err := a.goFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byPackage := make(map[string][]*ast.File)
+	paths := make(map[*ast.File]string)
+	for _, path := range files {
+		if isTestName(path) {
+			continue
+		}
+		f, err := a.cache.Parse(path)
+		if err != nil {
+			continue
+		}
+		pkgDir := filepath.Dir(path)
+		byPackage[pkgDir] = append(byPackage[pkgDir], f)
+		paths[f] = path
+	}
+
+	cg := &CallGraph{
+		Nodes: make(map[string]*CallGraphNode),
+		Edges: make(map[string][]string),
+	}
+
+	pkgDirs := make([]string, 0, len(byPackage))
+	for pkgDir := range byPackage {
+		pkgDirs = append(pkgDirs, pkgDir)
+	}
+	sort.Strings(pkgDirs)
+
+	// Type-checked callees are located by the absolute directory of their
+	// declaration
+	localDirs := make(map[string]string)
+	for _, pkgDir := range pkgDirs {
+		if abs, err := filepath.Abs(pkgDir); err == nil {
+			localDirs[abs] = pkgDir
+		}
+	}
+
+	// Every package is declared before any call is resolved, so that
+	// type-checked calls can reach functions of packages sorted later
+	scopes := make([]*packageScope, len(pkgDirs))
+	for i, pkgDir := range pkgDirs {
+		scopes[i] = a.declarePackage(cg, pkgDir, byPackage[pkgDir], paths)
+	}
+	for i, pkgDir := range pkgDirs {
+		a.addPackageCalls(cg, scopes[i], byPackage[pkgDir], paths, localDirs)
+	}
+
+	for caller, callees := range cg.Edges {
+		cg.Edges[caller] = sortedUnique(callees)
+	}
+	a.mu.Lock()
+	a.callGraph = cg
+	a.mu.Unlock()
+	return cg, nil
+}
+
+// packageScope indexes the declarations of one package for call resolution
+type packageScope struct {
+	dir         string
+	funcs       map[string]string            // Function name to node ID
+	methods     map[string]map[string]string // Type and method name to node ID
+	funcResults map[string]string            // Function name to its first result type
+	types       map[string]bool
+	interfaces  map[string]bool
+	funcFields  map[string]map[string]bool // Struct and field name of fields of function type
+	values      map[string]bool            // Package-level variables
+}
+
+// declarePackage adds the functions of one package and indexes its
+// declarations for resolving calls
+func (a *ASTAnalyzer) declarePackage(cg *CallGraph, pkgDir string, files []*ast.File, paths map[*ast.File]string) *packageScope {
+	scope := &packageScope{
+		dir:         pkgDir,
+		funcs:       make(map[string]string),
+		methods:     make(map[string]map[string]string),
+		funcResults: make(map[string]string),
+		types:       make(map[string]bool),
+		interfaces:  make(map[string]bool),
+		funcFields:  make(map[string]map[string]bool),
+		values:      make(map[string]bool),
+	}
+
+	for _, f := range files {
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				receiver := ""
+				if d.Recv != nil && len(d.Recv.List) > 0 {
+					receiver = receiverTypeName(exprToString(d.Recv.List[0].Type))
+				}
+				id := functionNodeID(pkgDir, receiver, d.Name.Name)
+				cg.Nodes[id] = &CallGraphNode{
+					ID:         id,
+					Package:    pkgDir,
+					Name:       d.Name.Name,
+					Receiver:   receiver,
+					File:       paths[f],
+					Line:       a.fset.Position(d.Pos()).Line,
+					Lines:      a.fset.Position(d.End()).Line - a.fset.Position(d.Pos()).Line + 1,
+					Complexity: cyclomaticComplexity(d.Body),
+				}
+				if receiver == "" {
+					scope.funcs[d.Name.Name] = id
+					if d.Type.Results != nil && len(d.Type.Results.List) > 0 {
+						scope.funcResults[d.Name.Name] = receiverTypeName(exprToString(d.Type.Results.List[0].Type))
+					}
+					continue
+				}
+				if scope.methods[receiver] == nil {
+					scope.methods[receiver] = make(map[string]string)
+				}
+				scope.methods[receiver][d.Name.Name] = id
+			case *ast.GenDecl:
+				for _, spec := range d.Specs {
+					switch s := spec.(type) {
+					case *ast.TypeSpec:
+						scope.declareType(s)
+					case *ast.ValueSpec:
+						if d.Tok == token.VAR {
+							for _, name := range s.Names {
+								scope.values[name.Name] = true
+							}
+						}
+					}
+				}
+			}
+		}
+	}
+
+	return scope
+}
+
+// addPackageCalls resolves the calls in every function body of a package
+func (a *ASTAnalyzer) addPackageCalls(cg *CallGraph, scope *packageScope, files []*ast.File, paths map[*ast.File]string, localDirs map[string]string) {
+	for _, f := range files {
+		imports := fileImports(f)
+		var uses map[*ast.Ident]types.Object
+		if a.TypeCheck {
+			if pt := a.checkPackage(paths[f], f); !pt.badFiles[paths[f]] {
+				uses = pt.uses
+			}
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil {
+				continue
+			}
+
+			receiver := ""
+			if fn.Recv != nil && len(fn.Recv.List) > 0 {
+				receiver = receiverTypeName(exprToString(fn.Recv.List[0].Type))
+			}
+			caller := functionNodeID(scope.dir, receiver, fn.Name.Name)
+			vars := scope.localTypes(fn)
+			locals := localNames(fn)
+
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				call, ok := n.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				callee, ok := a.typedCallee(cg, call.Fun, uses, localDirs)
+				if !ok {
+					callee = scope.resolve(cg, call.Fun, imports, vars, locals)
+				}
+				if callee != "" {
+					cg.Edges[caller] = append(cg.Edges[caller], callee)
+				}
+				return true
+			})
+		}
+	}
+}
+
+// declareType records a type declaration, along with what makes calls on
+// its values dynamic: being an interface or having fields of function type
+func (s *packageScope) declareType(ts *ast.TypeSpec) {
+	name := ts.Name.Name
+	s.types[name] = true
+	switch t := ts.Type.(type) {
+	case *ast.InterfaceType:
+		s.interfaces[name] = true
+	case *ast.StructType:
+		for _, field := range t.Fields.List {
+			if _, ok := ast.Unparen(field.Type).(*ast.FuncType); !ok {
+				continue
+			}
+			if s.funcFields[name] == nil {
+				s.funcFields[name] = make(map[string]bool)
+			}
+			for _, fieldName := range field.Names {
+				s.funcFields[name][fieldName.Name] = true
+			}
+		}
+	}
+}
+
+// localNames returns the names a function declares: its receiver,
+// parameters, results and local variables. Like localTypes it ignores
+// scopes.
+func localNames(fn *ast.FuncDecl) map[string]bool {
+	names := make(map[string]bool)
+	declare := func(exprs ...ast.Expr) {
+		for _, e := range exprs {
+			if ident, ok := e.(*ast.Ident); ok && ident.Name != "_" {
+				names[ident.Name] = true
+			}
+		}
+	}
+
+	ast.Inspect(fn, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.Field:
+			for _, name := range x.Names {
+				declare(name)
+			}
+		case *ast.ValueSpec:
+			for _, name := range x.Names {
+				declare(name)
+			}
+		case *ast.AssignStmt:
+			if x.Tok == token.DEFINE {
+				declare(x.Lhs...)
+			}
+		case *ast.RangeStmt:
+			if x.Tok == token.DEFINE {
+				declare(x.Key, x.Value)
+			}
+		}
+		return true
+	})
+	return names
+}
+
+// localTypes maps the receiver, parameters and local variables of a
+// function to their package-local type names, as far as they can be told
+// from syntax: declared types, composite literals and the results of
+// package functions. The mapping ignores scopes.
+func (s *packageScope) localTypes(fn *ast.FuncDecl) map[string]string {
+	vars := make(map[string]string)
+
+	declare := func(names []*ast.Ident, typ string) {
+		if !s.types[typ] {
+			return
+		}
+		for _, name := range names {
+			vars[name.Name] = typ
+		}
+	}
+
+	for _, list := range []*ast.FieldList{fn.Recv, fn.Type.Params} {
+		if list == nil {
+			continue
+		}
+		for _, field := range list.List {
+			declare(field.Names, receiverTypeName(exprToString(field.Type)))
+		}
+	}
+
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.ValueSpec:
+			if x.Type != nil {
+				declare(x.Names, receiverTypeName(exprToString(x.Type)))
+			}
+		case *ast.AssignStmt:
+			if x.Tok != token.DEFINE || len(x.Rhs) == 0 {
+				return true
+			}
+			if ident, ok := x.Lhs[0].(*ast.Ident); ok {
+				declare([]*ast.Ident{ident}, s.exprType(x.Rhs[0]))
+			}
+		}
+		return true
+	})
+
+	return vars
+}
+
+// exprType returns the local type name an expression evaluates to, or ""
+func (s *packageScope) exprType(expr ast.Expr) string {
+	switch x := expr.(type) {
+	case *ast.UnaryExpr:
+		if x.Op == token.AND {
+			return s.exprType(x.X)
+		}
+	case *ast.CompositeLit:
+		if x.Type != nil {
+			return receiverTypeName(exprToString(x.Type))
+		}
+	case *ast.CallExpr:
+		if ident, ok := x.Fun.(*ast.Ident); ok {
+			return s.funcResults[ident.Name]
+		}
+	}
+	return ""
+}
+
+// resolve returns the node ID of a call's target, adding an external node
+// when the target is not declared in the package and the dynamic node when
+// it is a function value. Builtins, type conversions and function literals
+// called in place resolve to "".
+func (s *packageScope) resolve(cg *CallGraph, fun ast.Expr, imports, vars map[string]string, locals map[string]bool) string {
+	switch x := fun.(type) {
+	case *ast.ParenExpr:
+		return s.resolve(cg, x.X, imports, vars, locals)
+	case *ast.IndexExpr:
+		return s.resolve(cg, x.X, imports, vars, locals)
+	case *ast.IndexListExpr:
+		return s.resolve(cg, x.X, imports, vars, locals)
+
+	case *ast.CallExpr:
+		return dynamicNode(cg)
+
+	case *ast.Ident:
+		if locals[x.Name] {
+			return dynamicNode(cg)
+		}
+		if id, ok := s.funcs[x.Name]; ok {
+			return id
+		}
+		if builtinNames[x.Name] || s.types[x.Name] {
+			return ""
+		}
+		if s.values[x.Name] {
+			return dynamicNode(cg)
+		}
+		return externalNode(cg, x.Name)
+
+	case *ast.SelectorExpr:
+		method := x.Sel.Name
+		if ident, ok := x.X.(*ast.Ident); ok {
+			if typ, ok := vars[ident.Name]; ok {
+				if id, ok := s.methods[typ][method]; ok {
+					return id
+				}
+				if s.interfaces[typ] || s.funcFields[typ][method] {
+					return dynamicNode(cg)
+				}
+			} else if path, ok := imports[ident.Name]; ok && !locals[ident.Name] {
+				return externalNode(cg, path+"."+method)
+			}
+		}
+		return externalNode(cg, method)
+	}
+
+	return ""
+}
+
+// typedCallee resolves a call's target from the identifiers the type
+// checker resolved, reporting false when it has nothing to go on. Local
+// functions, in this package or another under the graph's root, resolve
+// to their nodes; other functions become external nodes named by import
+// path, and interface methods and function values lead to the dynamic
+// node.
+func (a *ASTAnalyzer) typedCallee(cg *CallGraph, fun ast.Expr, uses map[*ast.Ident]types.Object, localDirs map[string]string) (string, bool) {
+	var ident *ast.Ident
+	switch x := fun.(type) {
+	case *ast.ParenExpr:
+		return a.typedCallee(cg, x.X, uses, localDirs)
+	case *ast.IndexExpr:
+		return a.typedCallee(cg, x.X, uses, localDirs)
+	case *ast.IndexListExpr:
+		return a.typedCallee(cg, x.X, uses, localDirs)
+	case *ast.Ident:
+		ident = x
+	case *ast.SelectorExpr:
+		ident = x.Sel
+	default:
+		return "", false
+	}
+
+	switch obj := uses[ident].(type) {
+	case *types.Builtin, *types.TypeName:
+		return "", true
+	case *types.Var:
+		return dynamicNode(cg), true
+	case *types.Func:
+		obj = obj.Origin()
+		receiver := ""
+		if recv := obj.Type().(*types.Signature).Recv(); recv != nil {
+			if types.IsInterface(recv.Type()) {
+				return dynamicNode(cg), true
+			}
+			t := recv.Type()
+			if ptr, ok := t.(*types.Pointer); ok {
+				t = ptr.Elem()
+			}
+			if named, ok := t.(*types.Named); ok {
+				receiver = named.Obj().Name()
+			}
+		}
+
+		if abs, err := filepath.Abs(filepath.Dir(a.fset.Position(obj.Pos()).Filename)); err == nil {
+			if pkgDir, ok := localDirs[abs]; ok {
+				if id := functionNodeID(pkgDir, receiver, obj.Name()); cg.Nodes[id] != nil {
+					return id, true
+				}
+			}
+		}
+		name := obj.Name()
+		if receiver != "" {
+			name = receiver + "." + name
+		}
+		if obj.Pkg() != nil {
+			name = obj.Pkg().Path() + "." + name
+		}
+		return externalNode(cg, name), true
+	}
+	return "", false
+}
+
+// dynamicNode returns the ID of the dynamic node, adding it on first use.
+// It counts as external, having no declaration to play.
+func dynamicNode(cg *CallGraph) string {
+	if _, ok := cg.Nodes[dynamicNodeID]; !ok {
+		cg.Nodes[dynamicNodeID] = &CallGraphNode{ID: dynamicNodeID, Name: dynamicNodeID, External: true, Dynamic: true}
+	}
+	return dynamicNodeID
+}
+
+// externalNode returns the ID of an unresolved callee, adding its node
+func externalNode(cg *CallGraph, name string) string {
+	id := "external:" + name
+	if _, ok := cg.Nodes[id]; !ok {
+		cg.Nodes[id] = &CallGraphNode{ID: id, Name: name, External: true}
+	}
+	return id
+}
+
+// fileImports maps the names a file uses for its imports to import paths
+func fileImports(f *ast.File) map[string]string {
+	imports := make(map[string]string)
+	for _, spec := range f.Imports {
+		path, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name := importName(path)
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		imports[name] = path
+	}
+	return imports
+}
+
+// importName guesses the package name of an import path, skipping major
+// version suffixes such as /v2 and .v3
+func importName(path string) string {
+	parts := strings.Split(path, "/")
+	name := parts[len(parts)-1]
+	if len(parts) > 1 && isMajorVersion(name) {
+		name = parts[len(parts)-2]
+	}
+	if i := strings.Index(name, ".v"); i > 0 && isMajorVersion(name[i+1:]) {
+		name = name[:i]
+	}
+	return strings.TrimPrefix(name, "go-")
+}
+
+// isMajorVersion reports whether s looks like v2, v3, ...
+func isMajorVersion(s string) bool {
+	if len(s) < 2 || s[0] != 'v' {
+		return false
+	}
+	_, err := strconv.Atoi(s[1:])
+	return err == nil
+}
+
+// sortedUnique sorts ids and removes duplicates
+func sortedUnique(ids []string) []string {
+	sort.Strings(ids)
+	out := ids[:0]
+	for i, id := range ids {
+		if i == 0 || id != ids[i-1] {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+// Callees returns the IDs of the functions id calls, in sorted order
+func (cg *CallGraph) Callees(id string) []string {
+	return cg.Edges[id]
+}
+
+// Callers returns the IDs of the functions calling id, in sorted order
+func (cg *CallGraph) Callers(id string) []string {
+	var callers []string
+	for caller, callees := range cg.Edges {
+		if slices.Contains(callees, id) {
+			callers = append(callers, caller)
+		}
+	}
+	sort.Strings(callers)
+	return callers
+}
+
+// Roots returns the declared functions nothing in the graph calls, such
+// as main, init and exported entry points, in sorted order
+func (cg *CallGraph) Roots() []string {
+	called := make(map[string]bool)
+	for _, callees := range cg.Edges {
+		for _, callee := range callees {
+			called[callee] = true
+		}
+	}
+	return cg.declaredIDs(func(id string) bool { return !called[id] })
+}
+
+// Leaves returns the declared functions that call nothing, in sorted
+// order
+func (cg *CallGraph) Leaves() []string {
+	return cg.declaredIDs(func(id string) bool { return len(cg.Edges[id]) == 0 })
+}
+
+// declaredIDs returns the sorted IDs of the non-external nodes matching
+// keep
+func (cg *CallGraph) declaredIDs(keep func(id string) bool) []string {
+	var ids []string
+	for id, node := range cg.Nodes {
+		if !node.External && keep(id) {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return ids
+}
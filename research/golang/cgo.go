@@ -0,0 +1,112 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// cgoImportPath is the pseudo-package through which cgo files call C
+const cgoImportPath = "C"
+
+// CgoImport is an import "C" declaration and the C preamble written in
+// the comment above it
+type CgoImport struct {
+	FilePath string
+	Line     int
+	Preamble string
+}
+
+// isCgoImport reports whether spec imports the C pseudo-package
+func isCgoImport(spec *ast.ImportSpec) bool {
+	path, err := strconv.Unquote(spec.Path.Value)
+	return err == nil && path == cgoImportPath
+}
+
+// usesCgo reports whether f imports the C pseudo-package
+func usesCgo(f *ast.File) bool {
+	for _, spec := range f.Imports {
+		if isCgoImport(spec) {
+			return true
+		}
+	}
+	return false
+}
+
+// cgoTypes are the numeric types cgo provides, whose conversions such as
+// C.int(n) look like calls
+var cgoTypes = map[string]bool{
+	"char": true, "schar": true, "uchar": true, "short": true, "ushort": true,
+	"int": true, "uint": true, "long": true, "ulong": true, "longlong": true,
+	"ulonglong": true, "float": true, "double": true, "complexfloat": true,
+	"complexdouble": true, "size_t": true, "uintptr_t": true,
+	"int8_t": true, "int16_t": true, "int32_t": true, "int64_t": true,
+	"uint8_t": true, "uint16_t": true, "uint32_t": true, "uint64_t": true,
+}
+
+// isCgoCall reports whether call calls into C, as in C.free(p).
+// Conversions to cgo's numeric types and to C.struct_, C.union_ and
+// C.enum_ types are not calls; typedefs cannot be told apart from
+// functions without the preamble and count as calls.
+func isCgoCall(call *ast.CallExpr) bool {
+	sel, ok := call.Fun.(*ast.SelectorExpr)
+	if !ok {
+		return false
+	}
+	ident, ok := sel.X.(*ast.Ident)
+	if !ok || ident.Name != cgoImportPath {
+		return false
+	}
+	name := sel.Sel.Name
+	for _, prefix := range []string{"struct_", "union_", "enum_"} {
+		if strings.HasPrefix(name, prefix) {
+			return false
+		}
+	}
+	return !cgoTypes[name]
+}
+
+// ExtractCgoImports lists the import "C" declarations of all Go files
+// under dir with their preambles. They are kept apart from the other
+// imports, where C would pass for a package that does not exist, and
+// BuildImportGraph leaves them out.
+func (a *ASTAnalyzer) ExtractCgoImports(dir string) ([]CgoImport, error) {
+	files, err := a.goFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var imports []CgoImport
+	for _, path := range files {
+		f, err := a.cache.Parse(path)
+		if err != nil {
+			continue
+		}
+
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.IMPORT {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				spec := spec.(*ast.ImportSpec)
+				if !isCgoImport(spec) {
+					continue
+				}
+				// The preamble of an unparenthesized import is the
+				// declaration's doc comment
+				doc := spec.Doc
+				if doc == nil && !gen.Lparen.IsValid() {
+					doc = gen.Doc
+				}
+				imports = append(imports, CgoImport{
+					FilePath: path,
+					Line:     a.fset.Position(spec.Pos()).Line,
+					Preamble: strings.TrimSpace(doc.Text()),
+				})
+			}
+		}
+	}
+	return imports, nil
+}
@@ -0,0 +1,667 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"text/template"
+	"time"
+)
+
+// cliOptions holds the command line flags of the main program
+type cliOptions struct {
+	dirs            []string
+	format          string
+	output          string
+	functionsOutput string
+	measureMemory   bool
+	database        string
+	focus           string
+	fence           bool
+	jsonlFunctions  bool
+	metricsAddr     string
+	cache           string
+	templateFile    string
+	watch           bool
+	maxComplexity   int
+	minDocCoverage  float64
+	workers         int
+	runs            int
+	quiet           bool
+	maxFileSize     int64
+	maxLineLength   int
+	force           bool
+	ascii           bool
+	mode            string
+	ref             string
+	keepClone       bool
+	resume          string
+	followSymlinks  bool
+	skipGenerated   bool
+	loader          string
+	patterns        []string // Arguments with ... wildcards, when any has one
+	serve           string
+	typeCheck       bool
+	rootDir         string
+	remotes         []*RemoteCheckout // Clones standing in for URL arguments
+}
+
+// runCommand runs the subcommand named by the first argument, if any, and
+// reports whether there was one
+func runCommand(args []string) bool {
+	if len(args) == 0 {
+		return false
+	}
+	var err error
+	switch args[0] {
+	case "enemies":
+		err = runEnemies(args[1:], os.Stdout)
+	case "bosses":
+		err = runBosses(args[1:], os.Stdout)
+	case "items":
+		err = runItems(args[1:], os.Stdout)
+	case "skills":
+		err = runSkills(args[1:], os.Stdout)
+	case "quests":
+		err = runQuests(args[1:], os.Stdout)
+	default:
+		return false
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+	return true
+}
+
+// writeStructured benchmarks the target directories and writes the results
+// in a machine readable format. Progress lines go to stderr so stdout
+// stays parseable.
+func writeStructured(opts cliOptions) error {
+	// Report template mistakes before spending time on the analysis
+	var tmpl *template.Template
+	if opts.format == "template" {
+		var err error
+		if tmpl, err = ParseReportTemplate(opts.templateFile); err != nil {
+			return err
+		}
+	}
+
+	analyzer := NewASTAnalyzer()
+	analyzer.Progress = os.Stderr
+	mode, err := ParseParseMode(opts.mode)
+	if err != nil {
+		return err
+	}
+	analyzer.Mode = mode
+	analyzer.MeasureMemory = opts.measureMemory
+	analyzer.Workers = opts.workers
+	analyzer.Runs = opts.runs
+	analyzer.Quiet = opts.quiet
+	analyzer.MaxFileSize = opts.maxFileSize
+	analyzer.MaxLineLength = opts.maxLineLength
+	analyzer.Force = opts.force
+	analyzer.ASCII = opts.ascii
+	analyzer.FollowSymlinks = opts.followSymlinks
+	if opts.skipGenerated {
+		analyzer.Filter = ExcludeGenerated()
+	}
+	analyzer.TypeCheck = opts.typeCheck
+	analyzer.RootDir = opts.rootDir
+	analyzer.DiagramFocus = opts.focus
+	analyzer.MermaidFence = opts.fence
+	analyzer.MaxComplexity = opts.maxComplexity
+	analyzer.MinDocCoverage = opts.minDocCoverage
+	for _, c := range opts.remotes {
+		analyzer.RecordRemote(c)
+	}
+
+	opts.cache = loadCache(analyzer, opts.cache)
+	loadResults(analyzer, opts.resume)
+	if opts.format == "jsonl" {
+		if opts.loader == PackagesLoader {
+			return fmt.Errorf("-loader %s does not stream; use another format", PackagesLoader)
+		}
+		if len(opts.patterns) > 0 {
+			return fmt.Errorf("-format jsonl streams directories, not patterns")
+		}
+		return streamJSONL(analyzer, opts)
+	}
+
+	if err := benchmarkDirectories(context.Background(), analyzer, opts); err != nil {
+		return err
+	}
+	// The packages loader extracts functions from the trees it parsed
+	if opts.loader != PackagesLoader {
+		if err := extractAllFunctions(analyzer); err != nil {
+			return err
+		}
+	}
+	if err := measureAPISurface(analyzer, opts.dirs); err != nil {
+		return err
+	}
+	if err := analyzeTestPresence(analyzer, opts.dirs); err != nil {
+		return err
+	}
+	if err := saveCache(analyzer, opts.cache); err != nil {
+		return err
+	}
+	if err := saveResults(analyzer, opts.resume); err != nil {
+		return err
+	}
+	if err := saveRun(analyzer, opts.database); err != nil {
+		return err
+	}
+
+	var w io.Writer = os.Stdout
+	if opts.output != "" {
+		f, err := os.Create(opts.output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	switch opts.format {
+	case "json":
+		if err := findImportCycles(analyzer, opts.dirs); err != nil {
+			return err
+		}
+		if err := measureCallDepths(analyzer, opts.dirs); err != nil {
+			return err
+		}
+		if err := extractAllTypes(analyzer, opts.dirs); err != nil {
+			return err
+		}
+		return analyzer.ExportJSON(w)
+	case "html":
+		return analyzer.ExportHTML(w)
+	case "markdown":
+		return analyzer.ExportMarkdown(w)
+	case "yaml":
+		if err := findImportCycles(analyzer, opts.dirs); err != nil {
+			return err
+		}
+		if err := measureCallDepths(analyzer, opts.dirs); err != nil {
+			return err
+		}
+		if err := extractAllTypes(analyzer, opts.dirs); err != nil {
+			return err
+		}
+		return analyzer.ExportYAML(w)
+	case "template":
+		return analyzer.ExportTemplate(w, tmpl)
+	case "world":
+		if err := extractAllTypes(analyzer, opts.dirs); err != nil {
+			return err
+		}
+		return analyzer.ExportWorld(w)
+	case "dungeon":
+		return analyzer.ExportDungeon(w, opts.dirs)
+	case "junit":
+		if err := runChecks(analyzer, opts.dirs); err != nil {
+			return err
+		}
+		return analyzer.ExportJUnit(w)
+	case "proto":
+		if err := extractAllTypes(analyzer, opts.dirs); err != nil {
+			return err
+		}
+		return analyzer.ExportProto(w)
+	case "sarif":
+		if err := runChecks(analyzer, opts.dirs); err != nil {
+			return err
+		}
+		return analyzer.ExportSARIF(w)
+	case "mermaid":
+		if err := extractAllTypes(analyzer, opts.dirs); err != nil {
+			return err
+		}
+		return analyzer.ExportMermaid(w)
+	case "csv":
+		if err := analyzer.WriteFilesCSV(w); err != nil {
+			return err
+		}
+		if opts.functionsOutput == "" {
+			return nil
+		}
+		f, err := os.Create(opts.functionsOutput)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		return analyzer.WriteFunctionsCSV(f)
+	default:
+		return fmt.Errorf("unknown format %q", opts.format)
+	}
+}
+
+// writeText benchmarks the target directories and prints the results as
+// text, with the demos and comparisons of non-quiet runs, then watches or
+// serves metrics as asked. Errors are returned rather than fatal so that
+// main can remove the clones of repository URLs first.
+func writeText(opts cliOptions) error {
+	// Demo function extraction; quiet runs print only the summary
+	if !opts.quiet {
+		demoFunctionExtraction()
+	}
+
+	// Benchmark the target directory
+	analyzer := NewASTAnalyzer()
+	analyzer.MeasureMemory = opts.measureMemory
+	mode, err := ParseParseMode(opts.mode)
+	if err != nil {
+		return err
+	}
+	analyzer.Mode = mode
+	analyzer.Workers = opts.workers
+	analyzer.Runs = opts.runs
+	analyzer.Quiet = opts.quiet
+	analyzer.MaxFileSize = opts.maxFileSize
+	analyzer.MaxLineLength = opts.maxLineLength
+	analyzer.Force = opts.force
+	analyzer.ASCII = opts.ascii
+	analyzer.FollowSymlinks = opts.followSymlinks
+	if opts.skipGenerated {
+		analyzer.Filter = ExcludeGenerated()
+	}
+	analyzer.TypeCheck = opts.typeCheck
+	analyzer.RootDir = opts.rootDir
+	for _, c := range opts.remotes {
+		analyzer.RecordRemote(c)
+	}
+	opts.cache = loadCache(analyzer, opts.cache)
+	loadResults(analyzer, opts.resume)
+
+	// Ctrl+C stops the walk but still summarizes the files done so far
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err = benchmarkDirectories(ctx, analyzer, opts)
+	stop()
+	if errors.Is(err, context.Canceled) {
+		fmt.Println()
+		fmt.Println("Interrupted, summarizing the files benchmarked so far")
+		analyzer.PrintSummary()
+		return saveResults(analyzer, opts.resume)
+	}
+	if err != nil {
+		return err
+	}
+	if err := measureAPISurface(analyzer, opts.dirs); err != nil {
+		return err
+	}
+	if err := analyzeTestPresence(analyzer, opts.dirs); err != nil {
+		return err
+	}
+	// Functions are extracted for the length distribution of the summary;
+	// the packages loader extracts them from the trees it parsed
+	if opts.loader != PackagesLoader {
+		if err := extractAllFunctions(analyzer); err != nil {
+			return err
+		}
+	}
+	if err := measureCallDepths(analyzer, opts.dirs); err != nil {
+		return err
+	}
+	analyzer.PrintSummary()
+	if err := saveCache(analyzer, opts.cache); err != nil {
+		return err
+	}
+	if err := saveResults(analyzer, opts.resume); err != nil {
+		return err
+	}
+
+	if opts.database != "" {
+		if err := saveRun(analyzer, opts.database); err != nil {
+			return err
+		}
+	}
+
+	if !opts.quiet {
+		// Compare the cost of comment parsing and object resolution, and
+		// lexing alone with building the tree, on the largest file
+		// benchmarked; each is skipped when the file can no longer be read
+		if sample, ok := sampleFile(analyzer); ok {
+			name := outputPath(analyzer.outputRoot(), sample)
+			if timings, err := BenchmarkParserModes(sample); err == nil {
+				PrintParserModes(name, timings)
+			}
+			counts, err := TokenStats(sample)
+			comparison, compareErr := CompareScanParse(sample)
+			if err == nil && compareErr == nil {
+				PrintTokenStats(name, counts, comparison)
+			}
+		}
+
+		fmt.Println("💡 Next Steps:")
+		fmt.Println("1. Benchmark Gin directly: ast_benchmark https://github.com/gin-gonic/gin")
+		fmt.Println("2. Resolve parameter types with go/types: ast_benchmark -types -format json")
+		fmt.Println("3. Test on larger codebases (5000+ LOC)")
+		fmt.Println("4. Build the skill tree from the call graph: BuildCallGraph, Roots and StronglyConnectedComponents")
+	}
+
+	if opts.watch {
+		// A metrics server that fails stops the watch
+		ctx, cancel := context.WithCancelCause(context.Background())
+		defer cancel(nil)
+		if opts.metricsAddr != "" {
+			go func() {
+				cancel(serveMetrics(analyzer, opts.metricsAddr))
+			}()
+		}
+		if err := watchDirectories(ctx, analyzer, opts.dirs); err != nil {
+			return err
+		}
+		return context.Cause(ctx)
+	}
+
+	if opts.metricsAddr != "" {
+		return serveMetrics(analyzer, opts.metricsAddr)
+	}
+	return nil
+}
+
+// streamJSONL writes results as JSON Lines while the walk runs. An
+// interrupt stops the walk but still produces the summary line.
+func streamJSONL(analyzer *ASTAnalyzer, opts cliOptions) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var w io.Writer = os.Stdout
+	if opts.output != "" {
+		f, err := os.Create(opts.output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return analyzer.StreamJSONL(ctx, w, opts.dirs, opts.jsonlFunctions)
+}
+
+// splitPatterns moves the arguments to opts.patterns when any of them is a
+// ... pattern, leaving in opts.dirs the directories they are walked from
+// for the passes that take directories
+func splitPatterns(opts *cliOptions) error {
+	if !slices.ContainsFunc(opts.dirs, isPattern) {
+		return nil
+	}
+	if slices.ContainsFunc(opts.dirs, isRemoteURL) {
+		return fmt.Errorf("repository URLs cannot be mixed with ... patterns")
+	}
+	opts.patterns = opts.dirs
+	opts.dirs = patternBases(opts.patterns)
+	return nil
+}
+
+// cloneRemotes replaces the repository URLs among opts.dirs with shallow
+// clones at opts.ref. The returned function removes the clones, or reports
+// where they are when opts.keepClone is set.
+func cloneRemotes(opts *cliOptions) (func(), error) {
+	cleanup := func() {
+		for _, c := range opts.remotes {
+			if opts.keepClone {
+				fmt.Fprintf(os.Stderr, "Kept clone of %s in %s\n", c.URL, c.Dir)
+			} else {
+				c.Remove()
+			}
+		}
+	}
+
+	for i, dir := range opts.dirs {
+		if !isRemoteURL(dir) {
+			continue
+		}
+		if !opts.quiet {
+			fmt.Fprintf(os.Stderr, "Cloning %s\n", dir)
+		}
+		c, err := CloneRemote(context.Background(), dir, opts.ref)
+		if err != nil {
+			cleanup()
+			return nil, err
+		}
+		opts.remotes = append(opts.remotes, c)
+		opts.dirs[i] = c.Dir
+	}
+	return cleanup, nil
+}
+
+// runChecks runs the passes that record findings over every directory
+func runChecks(analyzer *ASTAnalyzer, dirs []string) error {
+	for _, dir := range dirs {
+		if _, err := analyzer.CheckConventions(dir); err != nil {
+			return err
+		}
+		if _, err := analyzer.CheckSignatureComplexity(dir, DefaultMaxParams, DefaultMaxResults); err != nil {
+			return err
+		}
+		if _, err := analyzer.CheckReceiverConsistency(dir); err != nil {
+			return err
+		}
+		if _, err := analyzer.UnusedExports(dir); err != nil {
+			return err
+		}
+		if _, err := analyzer.Reachability(dir); err != nil && !errors.Is(err, ErrNoMain) {
+			return err
+		}
+	}
+	return findImportCycles(analyzer, dirs)
+}
+
+// findImportCycles records the import cycles of every directory
+func findImportCycles(analyzer *ASTAnalyzer, dirs []string) error {
+	for _, dir := range dirs {
+		if _, err := analyzer.FindImportCycles(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// measureCallDepths records the call-chain depths of every directory
+func measureCallDepths(analyzer *ASTAnalyzer, dirs []string) error {
+	for _, dir := range dirs {
+		if _, err := analyzer.CallDepths(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractAllTypes records the types of every directory
+func extractAllTypes(analyzer *ASTAnalyzer, dirs []string) error {
+	for _, dir := range dirs {
+		if _, err := analyzer.ExtractTypes(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// measureAPISurface totals the public API of every directory
+func measureAPISurface(analyzer *ASTAnalyzer, dirs []string) error {
+	for _, dir := range dirs {
+		if _, err := analyzer.APISurface(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// analyzeTestPresence records the test presence of every directory
+func analyzeTestPresence(analyzer *ASTAnalyzer, dirs []string) error {
+	for _, dir := range dirs {
+		if _, err := analyzer.AnalyzeTestPresence(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// extractAllFunctions runs function extraction on every parsed file whose
+// functions were not restored from the result cache
+func extractAllFunctions(analyzer *ASTAnalyzer) error {
+	// ExtractFunctions takes the lock itself, so the files are picked first
+	var files []string
+	analyzer.mu.Lock()
+	for _, r := range analyzer.results {
+		if !r.Success || r.FromCache && analyzer.functions[r.FilePath] != nil {
+			continue
+		}
+		files = append(files, r.FilePath)
+	}
+	analyzer.mu.Unlock()
+
+	for _, file := range files {
+		if _, err := analyzer.ExtractFunctions(file); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// benchmarkDirectories benchmarks the target directories with the loader
+// opts.loader names. The packages loader takes a single module directory.
+func benchmarkDirectories(ctx context.Context, analyzer *ASTAnalyzer, opts cliOptions) error {
+	switch opts.loader {
+	case "", ParserLoader:
+		if len(opts.patterns) > 0 {
+			return analyzer.BenchmarkPatternsContext(ctx, opts.patterns)
+		}
+		return analyzer.BenchmarkDirectoriesContext(ctx, opts.dirs)
+	case PackagesLoader:
+		if len(opts.patterns) > 0 {
+			return fmt.Errorf("-loader %s takes a module directory, not patterns", PackagesLoader)
+		}
+		if len(opts.dirs) != 1 {
+			return fmt.Errorf("-loader %s takes a single module directory, got %d", PackagesLoader, len(opts.dirs))
+		}
+		_, err := analyzer.AnalyzeModule(opts.dirs[0])
+		return err
+	default:
+		return fmt.Errorf("unknown loader %q (want %s or %s)", opts.loader, ParserLoader, PackagesLoader)
+	}
+}
+
+// sampleFile returns the largest file the analyzer parsed successfully,
+// the one the parser mode and scanner comparisons measure, and false when
+// there is none
+func sampleFile(analyzer *ASTAnalyzer) (string, bool) {
+	analyzer.mu.Lock()
+	defer analyzer.mu.Unlock()
+	var sample ParseResult
+	for _, r := range analyzer.results {
+		if r.Success && r.FileSizeBytes > sample.FileSizeBytes {
+			sample = r
+		}
+	}
+	return sample.FilePath, sample.FilePath != ""
+}
+
+// loadCache loads the result cache at path, if any. A cache that cannot
+// be used only costs a full parse, so problems are reported as warnings.
+// It returns the path to save the cache to, which is empty when path
+// holds something other than a cache.
+func loadCache(analyzer *ASTAnalyzer, path string) string {
+	if path == "" {
+		return ""
+	}
+	err := analyzer.LoadCache(path)
+	if err == nil {
+		return path
+	}
+	fmt.Fprintf(os.Stderr, "warning: ignoring cache: %v\n", err)
+	if errors.Is(err, ErrNotCache) {
+		return ""
+	}
+	return path
+}
+
+// saveCache extracts any missing functions and writes the result cache to
+// path, doing nothing when path is empty
+func saveCache(analyzer *ASTAnalyzer, path string) error {
+	if path == "" {
+		return nil
+	}
+	if err := extractAllFunctions(analyzer); err != nil {
+		return err
+	}
+	return analyzer.SaveCache(path)
+}
+
+// loadResults resumes from the results saved at path, warning and
+// starting over when they cannot be used
+func loadResults(analyzer *ASTAnalyzer, path string) {
+	if path == "" {
+		return
+	}
+	if err := analyzer.LoadResults(path); err != nil {
+		fmt.Fprintf(os.Stderr, "warning: not resuming: %v\n", err)
+	}
+}
+
+// saveResults extracts any missing functions and saves the results to
+// path for a later run to resume from, doing nothing when path is empty
+func saveResults(analyzer *ASTAnalyzer, path string) error {
+	if path == "" {
+		return nil
+	}
+	if err := extractAllFunctions(analyzer); err != nil {
+		return err
+	}
+	return analyzer.SaveResults(path)
+}
+
+// saveRun appends the analyzer's results to the SQLite database at path,
+// doing nothing when path is empty
+func saveRun(analyzer *ASTAnalyzer, path string) error {
+	if path == "" {
+		return nil
+	}
+
+	db, err := OpenResultsDB(path)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	runID, err := db.SaveRun(analyzer)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Saved run %d to %s\n", runID, path)
+	return nil
+}
+
+// serveMetrics serves the analyzer's Prometheus metrics on addr
+func serveMetrics(analyzer *ASTAnalyzer, addr string) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", analyzer.MetricsHandler())
+	fmt.Fprintf(os.Stderr, "Serving metrics on http://%s/metrics\n", addr)
+	return http.ListenAndServe(addr, mux)
+}
+
+// watchDirectories re-analyzes files under dirs as they change, printing a
+// progress line per file, until interrupted or ctx is done
+func watchDirectories(ctx context.Context, analyzer *ASTAnalyzer, dirs []string) error {
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt)
+	defer stop()
+
+	fmt.Fprintln(analyzer.Progress)
+	fmt.Fprintln(analyzer.Progress, "Watching for changes, press Ctrl+C to stop")
+	err := analyzer.WatchDirectories(ctx, dirs, func(changed []ParseResult) {
+		fmt.Fprintf(analyzer.Progress, "\n%s: %d file(s) changed\n", time.Now().Format("15:04:05"), len(changed))
+		for _, r := range changed {
+			analyzer.printResult(r)
+		}
+	})
+	if errors.Is(err, context.Canceled) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSampleFile(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []ParseResult
+		want    string
+	}{
+		{name: "no results"},
+		{name: "only failures", results: []ParseResult{{FilePath: "a.go", FileSizeBytes: 10}}},
+		{
+			name: "largest success",
+			results: []ParseResult{
+				{FilePath: "a.go", Success: true, FileSizeBytes: 10},
+				{FilePath: "b.go", Success: true, FileSizeBytes: 30},
+				{FilePath: "c.go", FileSizeBytes: 50},
+			},
+			want: "b.go",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := quietAnalyzer()
+			a.results = tt.results
+			got, ok := sampleFile(a)
+			if got != tt.want || ok != (tt.want != "") {
+				t.Errorf("sampleFile() = %q, %v; want %q", got, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteStructuredTestPresence(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"store/store.go":      "package store\n\nfunc Open() {}\n",
+		"store/cache.go":      "package store\n\nfunc Evict() {}\n",
+		"store/store_test.go": "package store\n\nimport \"testing\"\n\nfunc TestOpen(t *testing.T) { Open() }\n",
+	})
+	out := filepath.Join(t.TempDir(), "out.json")
+	opts := cliOptions{
+		dirs:          []string{dir},
+		format:        "json",
+		output:        out,
+		quiet:         true,
+		mode:          "standard",
+		maxFileSize:   DefaultMaxFileSize,
+		maxLineLength: DefaultMaxLineLength,
+	}
+	if err := writeStructured(opts); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var export Export
+	if err := json.Unmarshal(data, &export); err != nil {
+		t.Fatal(err)
+	}
+	if len(export.Packages) != 1 {
+		t.Fatalf("got %d packages, want 1", len(export.Packages))
+	}
+	pkg := export.Packages[0]
+	if pkg.TestFunctions != 1 || !reflect.DeepEqual(pkg.UntestedFiles, []string{"store/cache.go"}) {
+		t.Errorf("package has %d test functions and untested files %v, want 1 and [store/cache.go]", pkg.TestFunctions, pkg.UntestedFiles)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"strconv"
+	"strings"
+)
+
+// cyclomaticComplexity computes McCabe's cyclomatic complexity of a
+// function body: one plus the number of decision points
+func cyclomaticComplexity(body *ast.BlockStmt) int {
+	if body == nil {
+		return 1
+	}
+
+	complexity := 1
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.IfStmt, *ast.ForStmt, *ast.RangeStmt:
+			complexity++
+		case *ast.CaseClause:
+			if x.List != nil {
+				complexity++
+			}
+		case *ast.CommClause:
+			if x.Comm != nil {
+				complexity++
+			}
+		case *ast.BinaryExpr:
+			if x.Op == token.LAND || x.Op == token.LOR {
+				complexity++
+			}
+		}
+		return true
+	})
+	return complexity
+}
+
+// nestingDepth returns how deeply the control structures and function
+// literals of a body nest: an if inside a for inside an if has depth 3,
+// and a body without any has depth 0. An else if continues the chain of
+// its if rather than nesting in it.
+func nestingDepth(body *ast.BlockStmt) int {
+	if body == nil {
+		return 0
+	}
+
+	deepest, depth := 0, 0
+	var nested []bool // Whether each node being visited adds a level
+	elseIfs := make(map[ast.Node]bool)
+	ast.Inspect(body, func(n ast.Node) bool {
+		if n == nil {
+			if nested[len(nested)-1] {
+				depth--
+			}
+			nested = nested[:len(nested)-1]
+			return true
+		}
+		adds := false
+		switch x := n.(type) {
+		case *ast.IfStmt:
+			if elseIf, ok := x.Else.(*ast.IfStmt); ok {
+				elseIfs[elseIf] = true
+			}
+			adds = !elseIfs[x]
+		case *ast.ForStmt, *ast.RangeStmt, *ast.SwitchStmt, *ast.TypeSwitchStmt,
+			*ast.SelectStmt, *ast.FuncLit:
+			adds = true
+		}
+		nested = append(nested, adds)
+		if adds {
+			depth++
+			deepest = max(deepest, depth)
+		}
+		return true
+	})
+	return deepest
+}
+
+// labeledJumps counts break and continue statements that target a label.
+// They are rare and usually mean control flow worth a second look.
+func labeledJumps(body *ast.BlockStmt) int {
+	if body == nil {
+		return 0
+	}
+
+	jumps := 0
+	ast.Inspect(body, func(n ast.Node) bool {
+		if branch, ok := n.(*ast.BranchStmt); ok && branch.Label != nil &&
+			(branch.Tok == token.BREAK || branch.Tok == token.CONTINUE) {
+			jumps++
+		}
+		return true
+	})
+	return jumps
+}
+
+// stubMarkers are panic messages that mark a function as unimplemented
+var stubMarkers = []string{"todo", "not implemented", "unimplemented", "not yet implemented"}
+
+// isStub reports whether a function body is empty or only panics with a
+// TODO-style message
+func isStub(body *ast.BlockStmt) bool {
+	if body == nil {
+		// Declared without a body, e.g. implemented in assembly
+		return false
+	}
+	if len(body.List) == 0 {
+		return true
+	}
+	if len(body.List) != 1 {
+		return false
+	}
+
+	stmt, ok := body.List[0].(*ast.ExprStmt)
+	if !ok {
+		return false
+	}
+	call, ok := stmt.X.(*ast.CallExpr)
+	if !ok || len(call.Args) != 1 {
+		return false
+	}
+	if ident, ok := call.Fun.(*ast.Ident); !ok || ident.Name != "panic" {
+		return false
+	}
+	lit, ok := call.Args[0].(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return false
+	}
+
+	message, err := strconv.Unquote(lit.Value)
+	if err != nil {
+		return false
+	}
+	message = strings.ToLower(message)
+	for _, marker := range stubMarkers {
+		if strings.Contains(message, marker) {
+			return true
+		}
+	}
+	return false
+}
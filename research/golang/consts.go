@@ -0,0 +1,170 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"go/types"
+	"strconv"
+)
+
+// ConstGroup is one const declaration with its specs in source order. A
+// const block with iota usually defines an enum-like set, which only
+// makes sense read as a whole.
+type ConstGroup struct {
+	FilePath string
+	Line     int
+	Grouped  bool // Declared as a parenthesized block
+	UsesIota bool
+	Specs    []ConstSpec
+}
+
+// ConstSpec is one constant of a group. Specs that omit their value
+// repeat the previous expression, as Go does, so Value and Type show what
+// the constant is actually defined as.
+type ConstSpec struct {
+	Name     string
+	Type     string // Empty for untyped constants
+	Value    string // Expression, inherited when omitted
+	Implicit bool   // The expression was inherited
+	Line     int
+
+	// IntValue is the value of expressions built from integer literals,
+	// iota, parentheses and the operators + - * / % << >> & | ^, such as
+	// iota, iota + 1 and 1 << iota; HasInt reports whether it was inferred
+	IntValue int64
+	HasInt   bool
+}
+
+// ExtractConstGroups returns the const declarations of a file, keeping
+// each block's specs together
+func (a *ASTAnalyzer) ExtractConstGroups(filePath string) ([]ConstGroup, error) {
+	f, err := a.cache.Parse(filePath)
+	if f == nil {
+		return nil, err
+	}
+
+	var groups []ConstGroup
+	for _, decl := range f.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+
+		group := ConstGroup{
+			FilePath: filePath,
+			Line:     a.fset.Position(gen.Pos()).Line,
+			Grouped:  gen.Lparen.IsValid(),
+		}
+		var values []ast.Expr
+		var typ ast.Expr
+		for iota, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			implicit := len(vs.Values) == 0
+			if !implicit {
+				values, typ = vs.Values, vs.Type
+			}
+
+			for i, name := range vs.Names {
+				cs := ConstSpec{
+					Name:     name.Name,
+					Implicit: implicit,
+					Line:     a.fset.Position(name.Pos()).Line,
+				}
+				if typ != nil {
+					cs.Type = exprToString(typ)
+				}
+				if i < len(values) {
+					cs.Value = types.ExprString(values[i])
+					cs.IntValue, cs.HasInt = evalIntConst(values[i], int64(iota))
+					if usesIota(values[i]) {
+						group.UsesIota = true
+					}
+				}
+				group.Specs = append(group.Specs, cs)
+			}
+		}
+		groups = append(groups, group)
+	}
+	return groups, err
+}
+
+// usesIota reports whether expr refers to iota
+func usesIota(expr ast.Expr) bool {
+	found := false
+	ast.Inspect(expr, func(n ast.Node) bool {
+		if ident, ok := n.(*ast.Ident); ok && ident.Name == "iota" {
+			found = true
+		}
+		return !found
+	})
+	return found
+}
+
+// evalIntConst evaluates a simple integer constant expression with the
+// given value of iota. It gives up on anything else, including references
+// to other constants, and on overflowing or undefined operations.
+func evalIntConst(expr ast.Expr, iota int64) (int64, bool) {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		if e.Kind != token.INT {
+			return 0, false
+		}
+		v, err := strconv.ParseInt(e.Value, 0, 64)
+		return v, err == nil
+	case *ast.Ident:
+		return iota, e.Name == "iota"
+	case *ast.ParenExpr:
+		return evalIntConst(e.X, iota)
+	case *ast.UnaryExpr:
+		x, ok := evalIntConst(e.X, iota)
+		switch {
+		case !ok:
+			return 0, false
+		case e.Op == token.SUB:
+			return -x, true
+		case e.Op == token.ADD:
+			return x, true
+		case e.Op == token.XOR:
+			return ^x, true
+		}
+	case *ast.BinaryExpr:
+		x, okX := evalIntConst(e.X, iota)
+		y, okY := evalIntConst(e.Y, iota)
+		if !okX || !okY {
+			return 0, false
+		}
+		switch e.Op {
+		case token.ADD:
+			return x + y, true
+		case token.SUB:
+			return x - y, true
+		case token.MUL:
+			return x * y, true
+		case token.QUO, token.REM:
+			if y == 0 {
+				return 0, false
+			}
+			if e.Op == token.QUO {
+				return x / y, true
+			}
+			return x % y, true
+		case token.SHL, token.SHR:
+			if y < 0 || y > 62 {
+				return 0, false
+			}
+			if e.Op == token.SHL {
+				return x << y, true
+			}
+			return x >> y, true
+		case token.AND:
+			return x & y, true
+		case token.OR:
+			return x | y, true
+		case token.XOR:
+			return x ^ y, true
+		case token.AND_NOT:
+			return x &^ y, true
+		}
+	}
+	return 0, false
+}
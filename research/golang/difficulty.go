@@ -0,0 +1,118 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go/ast"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/study-game/research/game"
+)
+
+// enemiesShown is how many enemies the enemies command lists by default
+const enemiesShown = 10
+
+// DifficultyMetrics measures what game.DifficultyScorer levels for every
+// function and method declared under dir outside test files: cyclomatic
+// complexity, nesting depth, statement count and fan-in, the number of
+// other functions under dir calling it. Metrics are in ID order.
+func (a *ASTAnalyzer) DifficultyMetrics(dir string) ([]game.Metrics, error) {
+	cg, err := a.BuildCallGraph(dir)
+	if err != nil {
+		return nil, err
+	}
+	files, err := a.goFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	fanIn := make(map[string]int)
+	for caller, callees := range cg.Edges {
+		for _, callee := range callees {
+			if callee != caller {
+				fanIn[callee]++
+			}
+		}
+	}
+
+	bodies := make(map[string]*ast.BlockStmt)
+	for _, path := range files {
+		if isTestName(path) {
+			continue
+		}
+		f, err := a.cache.Parse(path)
+		if err != nil {
+			continue
+		}
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			receiver := ""
+			if fn.Recv != nil && len(fn.Recv.List) > 0 {
+				receiver = receiverTypeName(exprToString(fn.Recv.List[0].Type))
+			}
+			bodies[functionNodeID(filepath.Dir(path), receiver, fn.Name.Name)] = fn.Body
+		}
+	}
+
+	var metrics []game.Metrics
+	for _, id := range cg.declaredIDs(func(id string) bool { return !cg.Nodes[id].Dynamic }) {
+		body, ok := bodies[id]
+		if !ok {
+			continue
+		}
+		node := cg.Nodes[id]
+		metrics = append(metrics, game.Metrics{
+			ID:         id,
+			File:       node.File,
+			Line:       node.Line,
+			Complexity: node.Complexity,
+			Nesting:    nestingDepth(body),
+			Statements: countStatements(body),
+			FanIn:      fanIn[id],
+		})
+	}
+	return metrics, nil
+}
+
+// runEnemies runs the enemies command: it levels the functions of the
+// directories given in args and prints the highest-level ones
+func runEnemies(args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("enemies", flag.ExitOnError)
+	n := flags.Int("n", enemiesShown, "number of enemies to list")
+	flags.Parse(args)
+	dirs := flags.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+
+	analyzer := NewASTAnalyzer()
+	var metrics []game.Metrics
+	for _, dir := range dirs {
+		m, err := analyzer.DifficultyMetrics(dir)
+		if err != nil {
+			return err
+		}
+		metrics = append(metrics, m...)
+	}
+	scored := game.DifficultyScorer{}.Score(metrics)
+
+	fmt.Fprintln(w, strings.Repeat("=", 70))
+	fmt.Fprintf(w, "HIGHEST-LEVEL ENEMIES (%d functions)\n", len(scored))
+	fmt.Fprintln(w, strings.Repeat("=", 70))
+	fmt.Fprintf(w, "%5s  %-40s %5s %5s %5s %5s\n", "Level", "Function", "Cyclo", "Nest", "Stmts", "FanIn")
+	for i, d := range scored {
+		if i == *n {
+			break
+		}
+		fmt.Fprintf(w, "%5d  %-40s %5d %5d %5d %5d\n", d.Level, d.ID, d.Complexity, d.Nesting, d.Statements, d.FanIn)
+		c := d.Components
+		fmt.Fprintf(w, "       %s:%d (normalized %.2f %.2f %.2f %.2f)\n", d.File, d.Line, c.Complexity, c.Nesting, c.Statements, c.FanIn)
+	}
+	fmt.Fprintln(w, strings.Repeat("=", 70))
+	return nil
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"regexp"
+	"strings"
+)
+
+// DocMatch is a doc comment line that matched a SearchDocs pattern
+type DocMatch struct {
+	Symbol   string // Function name, Type.Method or type name
+	Kind     string // "func", "method" or "type"
+	Position token.Position
+	Line     string  // The matching doc comment line
+	Matches  [][]int // Byte ranges of the matches within Line
+}
+
+// Highlight returns the matching line with every match wrapped in open and
+// close, e.g. ANSI escapes or Markdown emphasis
+func (m DocMatch) Highlight(open, close string) string {
+	var b strings.Builder
+	last := 0
+	for _, loc := range m.Matches {
+		b.WriteString(m.Line[last:loc[0]])
+		b.WriteString(open)
+		b.WriteString(m.Line[loc[0]:loc[1]])
+		b.WriteString(close)
+		last = loc[1]
+	}
+	b.WriteString(m.Line[last:])
+	return b.String()
+}
+
+// SearchDocs returns the doc comment lines of functions and types under
+// dir that match pattern, one DocMatch per matching line
+func (a *ASTAnalyzer) SearchDocs(dir string, pattern string) ([]DocMatch, error) {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, err
+	}
+
+	files, err := a.goFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var matches []DocMatch
+	for _, path := range files {
+		f, err := a.cache.Parse(path)
+		if err != nil {
+			continue
+		}
+
+		for _, fn := range a.extractFunctions(f) {
+			kind, symbol := "func", fn.Name
+			if fn.Receiver != "" {
+				kind, symbol = "method", receiverTypeName(fn.Receiver)+"."+fn.Name
+			}
+			position := token.Position{Filename: path, Line: fn.LineStart}
+			matches = append(matches, matchDoc(re, symbol, kind, position, fn.DocComment)...)
+		}
+
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				ts := spec.(*ast.TypeSpec)
+				doc := ts.Doc
+				if doc == nil && len(gen.Specs) == 1 {
+					// A lone type's comment is attached to the declaration
+					doc = gen.Doc
+				}
+				matches = append(matches, matchDoc(re, ts.Name.Name, "type", a.fset.Position(ts.Pos()), doc.Text())...)
+			}
+		}
+	}
+
+	return matches, nil
+}
+
+// matchDoc returns a DocMatch for every line of doc that re matches
+func matchDoc(re *regexp.Regexp, symbol, kind string, position token.Position, doc string) []DocMatch {
+	if doc == "" {
+		return nil
+	}
+
+	var matches []DocMatch
+	for _, line := range strings.Split(strings.TrimRight(doc, "\n"), "\n") {
+		locs := re.FindAllStringIndex(line, -1)
+		if len(locs) == 0 {
+			continue
+		}
+		matches = append(matches, DocMatch{
+			Symbol:   symbol,
+			Kind:     kind,
+			Position: position,
+			Line:     line,
+			Matches:  locs,
+		})
+	}
+	return matches
+}
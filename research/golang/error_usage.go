@@ -0,0 +1,211 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ErrorUsage counts how the calls in one file treat returned errors
+type ErrorUsage struct {
+	FilePath string
+	// ErrorCalls is the number of calls whose results include an error
+	ErrorCalls int
+	// IgnoredErrors are calls whose error result is discarded, either by
+	// calling them as a statement or by assigning the error to _
+	IgnoredErrors []token.Position
+	// UnwrappedErrorReturns are errors returned unchanged: a local error
+	// variable named as a return value, without wrapping it with context
+	// as fmt.Errorf("...: %w", err) does
+	UnwrappedErrorReturns []token.Position
+}
+
+// ignoredErrorExclusions are prefixes of the full names of functions whose
+// errors are conventionally ignored, such as fmt.Println and
+// (*strings.Builder).WriteString
+var ignoredErrorExclusions = []string{
+	"fmt.Print",
+	"(*strings.Builder).Write",
+	"(*bytes.Buffer).Write",
+}
+
+// AnalyzeErrorHandling reports, per file under dir, the calls that discard
+// a returned error and the errors returned without context. Packages are type-checked with go/types so results are
+// matched against real signatures; imports are type-checked from source and
+// type errors are tolerated, so calls that cannot be resolved are skipped.
+func (a *ASTAnalyzer) AnalyzeErrorHandling(dir string) ([]ErrorUsage, error) {
+	files, err := a.goFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// A directory may hold a package and its external test package
+	byPackage := make(map[string][]*ast.File)
+	paths := make(map[*ast.File]string)
+	for _, path := range files {
+		f, err := a.cache.Parse(path)
+		if err != nil {
+			continue
+		}
+		key := filepath.Dir(path) + ":" + f.Name.Name
+		byPackage[key] = append(byPackage[key], f)
+		paths[f] = path
+	}
+
+	keys := make([]string, 0, len(byPackage))
+	for key := range byPackage {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	conf := types.Config{
+		Importer: importer.ForCompiler(a.fset, "source", nil),
+		Error:    func(error) {},
+	}
+
+	var usage []ErrorUsage
+	for _, key := range keys {
+		pkgFiles := byPackage[key]
+		info := &types.Info{
+			Types: make(map[ast.Expr]types.TypeAndValue),
+			Uses:  make(map[*ast.Ident]types.Object),
+		}
+		conf.Check(pkgFiles[0].Name.Name, a.fset, pkgFiles, info)
+
+		for _, f := range pkgFiles {
+			u := ErrorUsage{FilePath: paths[f]}
+			a.checkFileErrors(f, info, &u)
+			a.checkUnwrappedReturns(f, info, &u)
+			usage = append(usage, u)
+		}
+	}
+
+	sort.Slice(usage, func(i, j int) bool {
+		return usage[i].FilePath < usage[j].FilePath
+	})
+	return usage, nil
+}
+
+// checkFileErrors counts error-returning calls in f and records those whose
+// error is discarded
+func (a *ASTAnalyzer) checkFileErrors(f *ast.File, info *types.Info, u *ErrorUsage) {
+	// Calls whose results are used in an expression handle their error
+	// somewhere; only statements can discard one
+	discarded := make(map[*ast.CallExpr]bool)
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.ExprStmt:
+			if call, ok := x.X.(*ast.CallExpr); ok {
+				discarded[call] = errorIndex(info, call) >= 0
+			}
+		case *ast.GoStmt:
+			discarded[x.Call] = errorIndex(info, x.Call) >= 0
+		case *ast.DeferStmt:
+			discarded[x.Call] = errorIndex(info, x.Call) >= 0
+		case *ast.AssignStmt:
+			if len(x.Rhs) != 1 {
+				return true
+			}
+			call, ok := x.Rhs[0].(*ast.CallExpr)
+			if !ok {
+				return true
+			}
+			if i := errorIndex(info, call); i >= 0 && i < len(x.Lhs) {
+				ident, ok := x.Lhs[i].(*ast.Ident)
+				discarded[call] = ok && ident.Name == "_"
+			}
+		}
+		return true
+	})
+
+	ast.Inspect(f, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok || errorIndex(info, call) < 0 {
+			return true
+		}
+		u.ErrorCalls++
+		if discarded[call] && !isExcludedErrorCall(info, call) {
+			u.IgnoredErrors = append(u.IgnoredErrors, a.fset.Position(call.Pos()))
+		}
+		return true
+	})
+}
+
+// checkUnwrappedReturns records the return statements in f that hand back
+// a local error variable as it is. Package-level errors are sentinels
+// meant to be returned as they are, so only variables declared inside a
+// function count.
+func (a *ASTAnalyzer) checkUnwrappedReturns(f *ast.File, info *types.Info, u *ErrorUsage) {
+	errorType := types.Universe.Lookup("error").Type()
+	ast.Inspect(f, func(n ast.Node) bool {
+		ret, ok := n.(*ast.ReturnStmt)
+		if !ok {
+			return true
+		}
+		for _, result := range ret.Results {
+			ident, ok := ast.Unparen(result).(*ast.Ident)
+			if !ok {
+				continue
+			}
+			v, ok := info.Uses[ident].(*types.Var)
+			if !ok || v.Pkg() == nil || v.Parent() == v.Pkg().Scope() || !types.Identical(v.Type(), errorType) {
+				continue
+			}
+			u.UnwrappedErrorReturns = append(u.UnwrappedErrorReturns, a.fset.Position(ident.Pos()))
+		}
+		return true
+	})
+}
+
+// errorIndex returns the index of the error among a call's results, or -1
+// when the call returns no error or its type is unknown
+func errorIndex(info *types.Info, call *ast.CallExpr) int {
+	tv, ok := info.Types[call]
+	if !ok || tv.IsType() {
+		return -1
+	}
+
+	errorType := types.Universe.Lookup("error").Type()
+	switch t := tv.Type.(type) {
+	case *types.Tuple:
+		for i := t.Len() - 1; i >= 0; i-- {
+			if types.Identical(t.At(i).Type(), errorType) {
+				return i
+			}
+		}
+	default:
+		if t != nil && types.Identical(t, errorType) {
+			return 0
+		}
+	}
+	return -1
+}
+
+// isExcludedErrorCall reports whether call targets a function listed in
+// ignoredErrorExclusions
+func isExcludedErrorCall(info *types.Info, call *ast.CallExpr) bool {
+	var ident *ast.Ident
+	switch fun := call.Fun.(type) {
+	case *ast.Ident:
+		ident = fun
+	case *ast.SelectorExpr:
+		ident = fun.Sel
+	default:
+		return false
+	}
+
+	fn, ok := info.Uses[ident].(*types.Func)
+	if !ok {
+		return false
+	}
+	for _, excluded := range ignoredErrorExclusions {
+		if strings.HasPrefix(fn.FullName(), excluded) {
+			return true
+		}
+	}
+	return false
+}
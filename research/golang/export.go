@@ -0,0 +1,461 @@
+package main
+
+import (
+	"encoding/json"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AnalyzerVersion identifies the analyzer in exported run metadata
+const AnalyzerVersion = "0.1.0"
+
+// ExportSchemaVersion is bumped whenever the export schema changes in a
+// way that breaks consumers. Adding fields does not require a bump.
+const ExportSchemaVersion = 1
+
+// Export is the serialized form of an analysis run. Field names and
+// ordering are part of the schema and must stay stable.
+type Export struct {
+	Run       ExportRun        `json:"run"`
+	Files     []ExportFile     `json:"files"`
+	Functions []ExportFunction `json:"functions"`
+	Packages  []ExportPackage  `json:"packages"`
+	Findings  []ExportFinding  `json:"findings"`
+	API       *ExportAPI       `json:"api,omitempty"`
+
+	ImportCycles []ExportImportCycle `json:"import_cycles,omitempty"`
+	MethodSets   []ExportMethodSet   `json:"method_sets,omitempty"`
+}
+
+// ExportMethodSet is the serialized form of a MethodSet
+type ExportMethodSet struct {
+	Type      string            `json:"type"`
+	Package   string            `json:"package"`
+	Methods   []EffectiveMethod `json:"methods"`
+	Ambiguous []AmbiguousMethod `json:"ambiguous,omitempty"`
+}
+
+// ExportImportCycle is the serialized form of an ImportCycle
+type ExportImportCycle struct {
+	Kind string   `json:"kind"`
+	Path []string `json:"path"`
+	File string   `json:"file,omitempty"`
+	Line int      `json:"line,omitempty"`
+}
+
+// ExportAPI is the serialized form of APIMetrics with its score
+type ExportAPI struct {
+	APIMetrics
+	Score int `json:"score"`
+}
+
+// ExportRun holds metadata about the analysis run
+type ExportRun struct {
+	SchemaVersion   int       `json:"schema_version"`
+	AnalyzerVersion string    `json:"analyzer_version"`
+	StartTime       time.Time `json:"start_time"`
+	Directory       string    `json:"directory"`
+	Repository      string    `json:"repository,omitempty"`
+	Commit          string    `json:"commit,omitempty"`
+	TypesResolved   bool      `json:"types_resolved"`
+	ParseMode       string    `json:"parse_mode"`
+}
+
+// ExportDuration is a duration in both machine and human readable form
+type ExportDuration struct {
+	Nanoseconds int64  `json:"ns"`
+	Human       string `json:"human"`
+}
+
+// ExportFile is the serialized form of a ParseResult
+type ExportFile struct {
+	Path          string         `json:"path"`
+	Root          string         `json:"root,omitempty"`
+	ParseTime     ExportDuration `json:"parse_time"`
+	NumFunctions  int            `json:"num_functions"`
+	NumMethods    int            `json:"num_methods"`
+	NumInterfaces int            `json:"num_interfaces"`
+	NumStructs    int            `json:"num_structs"`
+	Generated     bool           `json:"generated"`
+	IsTest        bool           `json:"is_test"`
+	Success       bool           `json:"success"`
+	Skipped       bool           `json:"skipped_oversize,omitempty"`
+	Error         string         `json:"error,omitempty"`
+	SizeBytes     int64          `json:"size_bytes"`
+	LineCount     int            `json:"line_count"`
+	NodeCount     int            `json:"node_count"`
+	UsesCgo       bool           `json:"uses_cgo,omitempty"`
+	CgoCalls      int            `json:"cgo_calls,omitempty"`
+	AllocBytes    uint64         `json:"alloc_bytes,omitempty"`
+	FromCache     bool           `json:"from_cache,omitempty"`
+	Timing        *ExportTiming  `json:"timing,omitempty"`
+
+	// Lines of the switch statements that could be map lookups
+	MapifiableSwitches []int `json:"mapifiable_switches,omitempty"`
+
+	// Lines of the string concatenations in loops
+	StringConcatInLoop []int `json:"string_concat_in_loop,omitempty"`
+
+	// Lines of the calls to panic and recover, and the functions that
+	// panic without deferring a recover
+	PanicSites        []int    `json:"panic_sites,omitempty"`
+	RecoverSites      []int    `json:"recover_sites,omitempty"`
+	UnrecoveredPanics []string `json:"unrecovered_panics,omitempty"`
+
+	// Lines of the init functions
+	InitFuncs []int `json:"init_funcs,omitempty"`
+}
+
+// ExportTiming is the serialized form of a ParseTiming
+type ExportTiming struct {
+	Runs   int            `json:"runs"`
+	Min    ExportDuration `json:"min"`
+	Median ExportDuration `json:"median"`
+	P95    ExportDuration `json:"p95"`
+	StdDev ExportDuration `json:"stddev"`
+}
+
+// ExportFunction is the serialized form of a FunctionInfo
+type ExportFunction struct {
+	File         string          `json:"file"`
+	Name         string          `json:"name"`
+	Receiver     string          `json:"receiver,omitempty"`
+	Signature    string          `json:"signature"`
+	TypeParams   []ExportParam   `json:"type_params,omitempty"`
+	Params       []ExportParam   `json:"params"`
+	Results      []string        `json:"results"`
+	IsExported   bool            `json:"is_exported"`
+	LineStart    int             `json:"line_start"`
+	LineEnd      int             `json:"line_end"`
+	DocComment   string          `json:"doc_comment,omitempty"`
+	Complexity   int             `json:"complexity"`
+	IsStub       bool            `json:"is_stub"`
+	LabeledJumps int             `json:"labeled_jumps"`
+	Halstead     HalsteadMetrics `json:"halstead"`
+
+	MaxNestingDepth int `json:"max_nesting_depth"`
+
+	// Present only when the run resolved types
+	ResolvedParams  []string `json:"resolved_params,omitempty"`
+	ResolvedResults []string `json:"resolved_results,omitempty"`
+
+	// Present only when call-chain depths were computed
+	Depth     int  `json:"depth,omitempty"`
+	Recursive bool `json:"recursive,omitempty"`
+}
+
+// ExportParam is the serialized form of a ParamInfo
+type ExportParam struct {
+	Name string `json:"name"`
+	Type string `json:"type"`
+}
+
+// ExportPackage is the serialized form of a PackageSummary, joined with
+// the package's test statistics when they were computed
+type ExportPackage struct {
+	Path          string         `json:"path"`
+	Files         int            `json:"files"`
+	Failed        int            `json:"failed"`
+	Generated     int            `json:"generated"`
+	NumFunctions  int            `json:"num_functions"`
+	NumMethods    int            `json:"num_methods"`
+	NumInterfaces int            `json:"num_interfaces"`
+	NumStructs    int            `json:"num_structs"`
+	InitFuncs     int            `json:"init_funcs,omitempty"`
+	ParseTime     ExportDuration `json:"parse_time"`
+	TestFunctions int            `json:"test_functions"`
+	TestRatio     float64        `json:"test_ratio"`
+	UntestedFiles []string       `json:"untested_files,omitempty"`
+}
+
+// ExportFinding is the serialized form of a Finding
+type ExportFinding struct {
+	Category   string `json:"category"`
+	File       string `json:"file"`
+	Line       int    `json:"line"`
+	Column     int    `json:"column,omitempty"`
+	Identifier string `json:"identifier"`
+	Message    string `json:"message"`
+	Suggestion string `json:"suggestion,omitempty"`
+}
+
+// newExportDuration converts a duration for serialization
+func newExportDuration(d time.Duration) ExportDuration {
+	return ExportDuration{Nanoseconds: d.Nanoseconds(), Human: d.String()}
+}
+
+// outputRoot returns the directory output paths are relative to: RootDir,
+// or the benchmarked directory, or the directory of the benchmarked file
+func (a *ASTAnalyzer) outputRoot() string {
+	if a.RootDir != "" {
+		return a.RootDir
+	}
+	return dirOf(a.rootDir)
+}
+
+// dirOf returns path, or its directory when path names a file
+func dirOf(path string) string {
+	if info, err := os.Stat(path); err == nil && !info.IsDir() {
+		return filepath.Dir(path)
+	}
+	return path
+}
+
+// outputPath writes file, or a package directory, relative to root with
+// forward slashes, as every output does. Paths outside root are kept.
+func outputPath(root, file string) string {
+	if root == "" {
+		return filepath.ToSlash(file)
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	absFile, err := filepath.Abs(file)
+	if err != nil {
+		return filepath.ToSlash(file)
+	}
+	rel, err := filepath.Rel(absRoot, absFile)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return filepath.ToSlash(file)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// sourcePaths maps the output path of every result to the path it was
+// read from
+func (a *ASTAnalyzer) sourcePaths() map[string]string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	root := a.outputRoot()
+	paths := make(map[string]string, len(a.results))
+	for _, r := range a.results {
+		paths[outputPath(root, r.FilePath)] = r.FilePath
+	}
+	return paths
+}
+
+// BuildExport assembles the export model from the analyzer's state, with
+// every list in a deterministic order and file paths written by
+// outputPath
+func (a *ASTAnalyzer) BuildExport() Export {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	root := a.outputRoot()
+
+	repository, commit := a.remoteMetadata()
+	export := Export{
+		Run: ExportRun{
+			SchemaVersion:   ExportSchemaVersion,
+			AnalyzerVersion: AnalyzerVersion,
+			StartTime:       a.startTime,
+			Directory:       a.rootDir,
+			Repository:      repository,
+			Commit:          commit,
+			TypesResolved:   a.typesResolved,
+			ParseMode:       a.Mode.String(),
+		},
+		Files:     []ExportFile{},
+		Functions: []ExportFunction{},
+		Packages:  []ExportPackage{},
+		Findings:  []ExportFinding{},
+	}
+
+	for _, r := range a.results {
+		f := newExportFile(r)
+		f.Path = outputPath(root, r.FilePath)
+		export.Files = append(export.Files, f)
+	}
+	sort.Slice(export.Files, func(i, j int) bool {
+		return export.Files[i].Path < export.Files[j].Path
+	})
+
+	for file, functions := range a.functions {
+		for _, fn := range functions {
+			export.Functions = append(export.Functions, newExportFunction(outputPath(root, file), fn))
+		}
+	}
+	sort.Slice(export.Functions, func(i, j int) bool {
+		fi, fj := export.Functions[i], export.Functions[j]
+		if fi.File != fj.File {
+			return fi.File < fj.File
+		}
+		return fi.LineStart < fj.LineStart
+	})
+
+	testStats := make(map[string]PackageTestStats)
+	for _, s := range a.testStats {
+		testStats[s.Package] = s
+	}
+	for _, s := range summarizePackages(a.results) {
+		ts := testStats[s.Path]
+		var untested []string
+		for _, file := range ts.UntestedFiles {
+			untested = append(untested, outputPath(root, file))
+		}
+		export.Packages = append(export.Packages, ExportPackage{
+			Path:          outputPath(root, s.Path),
+			Files:         s.Files,
+			Failed:        s.Failed,
+			Generated:     s.Generated,
+			NumFunctions:  s.NumFunctions,
+			NumMethods:    s.NumMethods,
+			NumInterfaces: s.NumInterfaces,
+			NumStructs:    s.NumStructs,
+			InitFuncs:     s.InitFuncs,
+			ParseTime:     newExportDuration(s.ParseTime),
+			TestFunctions: ts.TestFunctions,
+			TestRatio:     ts.StatementRatio(),
+			UntestedFiles: untested,
+		})
+	}
+
+	for _, f := range a.findings {
+		export.Findings = append(export.Findings, ExportFinding{
+			Category:   f.Category,
+			File:       outputPath(root, f.FilePath),
+			Line:       f.Line,
+			Column:     f.Column,
+			Identifier: f.Identifier,
+			Message:    f.Message,
+			Suggestion: f.Suggestion,
+		})
+	}
+	sort.SliceStable(export.Findings, func(i, j int) bool {
+		fi, fj := export.Findings[i], export.Findings[j]
+		if fi.File != fj.File {
+			return fi.File < fj.File
+		}
+		return fi.Line < fj.Line
+	})
+
+	cycles := append([]ImportCycle(nil), a.importCycles...)
+	sortCycles(cycles)
+	for _, c := range cycles {
+		ec := ExportImportCycle{Kind: c.Kind, Path: c.Path, Line: c.Line}
+		if c.FilePath != "" {
+			ec.File = outputPath(root, c.FilePath)
+		}
+		export.ImportCycles = append(export.ImportCycles, ec)
+	}
+
+	for _, set := range a.methodSets() {
+		export.MethodSets = append(export.MethodSets, ExportMethodSet{
+			Type:      set.Type,
+			Package:   outputPath(root, set.Package),
+			Methods:   set.Methods,
+			Ambiguous: set.Ambiguous,
+		})
+	}
+	sort.SliceStable(export.MethodSets, func(i, j int) bool {
+		mi, mj := export.MethodSets[i], export.MethodSets[j]
+		if mi.Package != mj.Package {
+			return mi.Package < mj.Package
+		}
+		return mi.Type < mj.Type
+	})
+
+	if a.apiSurface != nil {
+		export.API = &ExportAPI{APIMetrics: *a.apiSurface, Score: a.apiSurface.Score()}
+	}
+
+	return export
+}
+
+// newExportFile converts a ParseResult for serialization
+func newExportFile(r ParseResult) ExportFile {
+	f := ExportFile{
+		Path:          r.FilePath,
+		Root:          r.Root,
+		ParseTime:     newExportDuration(r.ParseTime),
+		NumFunctions:  r.NumFunctions,
+		NumMethods:    r.NumMethods,
+		NumInterfaces: r.NumInterfaces,
+		NumStructs:    r.NumStructs,
+		Generated:     r.Generated,
+		IsTest:        r.IsTest,
+		Success:       r.Success,
+		Skipped:       r.SkippedOversize,
+		Error:         r.ErrorMessage,
+		SizeBytes:     r.FileSizeBytes,
+		LineCount:     r.LineCount,
+		NodeCount:     r.NodeCount,
+		UsesCgo:       r.UsesCgo,
+		CgoCalls:      r.CgoCalls,
+		AllocBytes:    r.AllocBytes,
+		FromCache:     r.FromCache,
+	}
+	f.MapifiableSwitches = positionLines(r.MapifiableSwitches)
+	f.StringConcatInLoop = positionLines(r.StringConcatInLoop)
+	f.PanicSites = positionLines(r.PanicSites)
+	f.RecoverSites = positionLines(r.RecoverSites)
+	f.UnrecoveredPanics = r.UnrecoveredPanics
+	f.InitFuncs = positionLines(r.InitFuncs)
+	if t := r.Timing; t != nil {
+		f.Timing = &ExportTiming{
+			Runs:   t.Runs,
+			Min:    newExportDuration(t.Min),
+			Median: newExportDuration(t.Median),
+			P95:    newExportDuration(t.P95),
+			StdDev: newExportDuration(t.StdDev),
+		}
+	}
+	return f
+}
+
+// positionLines returns the lines of positions, nil for none
+func positionLines(positions []token.Position) []int {
+	var lines []int
+	for _, pos := range positions {
+		lines = append(lines, pos.Line)
+	}
+	return lines
+}
+
+// newExportFunction converts a FunctionInfo for serialization
+func newExportFunction(file string, fn FunctionInfo) ExportFunction {
+	ef := ExportFunction{
+		File:         file,
+		Name:         fn.Name,
+		Receiver:     fn.Receiver,
+		Signature:    fn.Signature(),
+		Params:       []ExportParam{},
+		Results:      []string{},
+		IsExported:   fn.IsExported,
+		LineStart:    fn.LineStart,
+		LineEnd:      fn.LineEnd,
+		DocComment:   fn.DocComment,
+		Complexity:   fn.Complexity,
+		IsStub:       fn.IsStub,
+		LabeledJumps: fn.LabeledJumps,
+		Halstead:     fn.Halstead,
+
+		MaxNestingDepth: fn.MaxNestingDepth,
+
+		ResolvedParams:  fn.ResolvedParams,
+		ResolvedResults: fn.ResolvedResults,
+
+		Depth:     fn.CallDepth,
+		Recursive: fn.Recursive,
+	}
+	for _, p := range fn.TypeParams {
+		ef.TypeParams = append(ef.TypeParams, ExportParam(p))
+	}
+	for _, p := range fn.Params {
+		ef.Params = append(ef.Params, ExportParam(p))
+	}
+	ef.Results = append(ef.Results, fn.Results...)
+	return ef
+}
+
+// ExportJSON writes all analysis results as indented JSON
+func (a *ASTAnalyzer) ExportJSON(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(a.BuildExport())
+}
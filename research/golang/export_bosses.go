@@ -0,0 +1,128 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/study-game/research/game"
+)
+
+// bossesShown is how many bosses the bosses command generates by default
+const bossesShown = 5
+
+// Hotspots levels the functions under the directories together, as the
+// enemies command does, and gathers for each what game.GenerateBosses
+// builds a boss from: its callees under the directory or outside it, and
+// the constants of its file. Packages, files and IDs are relative to the
+// directory the function was found under, behind that directory when
+// there are several, so that the same function in two trees gives two
+// hotspots; a function under two of the directories is only taken once.
+func (a *ASTAnalyzer) Hotspots(dirs []string) ([]game.Hotspot, error) {
+	hotspots := make(map[string]game.Hotspot) // By call graph ID
+	var metrics []game.Metrics
+	for _, dir := range dirs {
+		m, err := a.DifficultyMetrics(dir)
+		if err != nil {
+			return nil, err
+		}
+		cg, err := a.BuildCallGraph(dir)
+		if err != nil {
+			return nil, err
+		}
+
+		constants := make(map[string][]string) // By file
+		for _, fm := range m {
+			node := cg.Nodes[fm.ID]
+			if _, ok := constants[node.File]; !ok {
+				groups, err := a.ExtractConstGroups(node.File)
+				if err != nil {
+					return nil, err
+				}
+				names := []string{}
+				for _, group := range groups {
+					for _, spec := range group.Specs {
+						names = append(names, spec.Name)
+					}
+				}
+				constants[node.File] = names
+			}
+
+			var callees []game.Callee
+			for _, id := range cg.Callees(fm.ID) {
+				callee := cg.Nodes[id]
+				if callee == nil || callee.Dynamic || id == fm.ID {
+					continue
+				}
+				name := callee.Name
+				if callee.External {
+					// External names keep their package, as in fmt.Println
+					name = name[strings.LastIndex(name, ".")+1:]
+				} else {
+					id = functionNodeID(rootPath(dirs, dir, callee.Package), callee.Receiver, callee.Name)
+				}
+				callees = append(callees, game.Callee{ID: id, Name: name})
+			}
+			fm.ID = functionNodeID(rootPath(dirs, dir, node.Package), node.Receiver, node.Name)
+			fm.File = rootPath(dirs, dir, node.File)
+			if _, ok := hotspots[fm.ID]; ok {
+				// Found before under an overlapping directory
+				continue
+			}
+			hotspots[fm.ID] = game.Hotspot{
+				Name:      node.Name,
+				Receiver:  node.Receiver,
+				Package:   rootPath(dirs, dir, node.Package),
+				LineEnd:   node.Line + node.Lines - 1,
+				Callees:   callees,
+				Constants: constants[node.File],
+			}
+			metrics = append(metrics, fm)
+		}
+	}
+
+	var result []game.Hotspot
+	for _, d := range (game.DifficultyScorer{}).Score(metrics) {
+		h := hotspots[d.ID]
+		h.Difficulty = d
+		result = append(result, h)
+	}
+	return result, nil
+}
+
+// rootPath writes file, or a package directory, relative to dir, one of
+// the input directories dirs. With several inputs the path starts with
+// dir, so that files at the same place in two trees stay apart.
+func rootPath(dirs []string, dir, file string) string {
+	rel := outputPath(dir, file)
+	if len(dirs) == 1 {
+		return rel
+	}
+	return path.Join(filepath.ToSlash(filepath.Clean(dir)), rel)
+}
+
+// ExportBosses writes the n highest-level bosses of the directories as
+// indented JSON
+func (a *ASTAnalyzer) ExportBosses(w io.Writer, dirs []string, n int) error {
+	hotspots, err := a.Hotspots(dirs)
+	if err != nil {
+		return err
+	}
+	bosses := &game.Bosses{Bosses: game.GenerateBosses(n, hotspots)}
+	return bosses.WriteJSON(w)
+}
+
+// runBosses runs the bosses command: it writes the bosses of the
+// directories given in args as JSON
+func runBosses(args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("bosses", flag.ExitOnError)
+	n := flags.Int("n", bossesShown, "number of bosses to generate")
+	flags.Parse(args)
+	dirs := flags.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+	return NewASTAnalyzer().ExportBosses(w, dirs, *n)
+}
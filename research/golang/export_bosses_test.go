@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/study-game/research/game"
+)
+
+func TestExportBossesGolden(t *testing.T) {
+	var out bytes.Buffer
+	if err := quietAnalyzer().ExportBosses(&out, []string{"testdata/bosses"}, 10); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "bosses.golden.json", out.Bytes())
+}
+
+func TestHotspotsSeveralDirectories(t *testing.T) {
+	src := "package main\n\nfunc main() {\n\tif true {\n\t\tprintln()\n\t}\n}\n"
+	dirA := writeTree(t, map[string]string{"main.go": src})
+	dirB := writeTree(t, map[string]string{"main.go": src})
+
+	tests := []struct {
+		name  string
+		dirs  []string
+		files []string
+	}{
+		{name: "one directory", dirs: []string{dirA}, files: []string{"main.go"}},
+		{name: "two directories", dirs: []string{dirA, dirB}, files: []string{
+			outputPath("", dirA) + "/main.go",
+			outputPath("", dirB) + "/main.go",
+		}},
+		{name: "same directory twice", dirs: []string{dirA, dirA}, files: []string{
+			outputPath("", dirA) + "/main.go",
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := quietAnalyzer().ExportBosses(&out, tt.dirs, 10); err != nil {
+				t.Fatal(err)
+			}
+			var bosses game.Bosses
+			if err := json.Unmarshal(out.Bytes(), &bosses); err != nil {
+				t.Fatal(err)
+			}
+			if len(bosses.Bosses) != len(tt.files) {
+				t.Fatalf("got %d bosses, want %d", len(bosses.Bosses), len(tt.files))
+			}
+			ids := make(map[string]bool)
+			files := make(map[string]bool)
+			for _, b := range bosses.Bosses {
+				ids[b.ID] = true
+				files[b.File] = true
+				if !strings.HasSuffix(b.ID, "main") {
+					t.Errorf("boss %s is not main's", b.ID)
+				}
+			}
+			if len(ids) != len(tt.files) {
+				t.Errorf("boss IDs collide: %v", ids)
+			}
+			for _, file := range tt.files {
+				if !files[file] {
+					t.Errorf("no boss in %s, got %v", file, files)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// GraphKind selects the graph written by ExportDOT
+type GraphKind int
+
+const (
+	// CallGraphKind renders functions and the calls between them
+	CallGraphKind GraphKind = iota
+	// PackageGraphKind renders packages and the imports between them
+	PackageGraphKind
+)
+
+// ExportDOT writes a Graphviz description of the call graph or package
+// graph built earlier by BuildCallGraph or BuildImportGraph. Node color
+// encodes complexity, node size encodes length, and unresolved nodes are
+// dashed. When GraphRoot is set, only nodes reachable from it are written.
+func (a *ASTAnalyzer) ExportDOT(w io.Writer, kind GraphKind) error {
+	switch kind {
+	case CallGraphKind:
+		if a.callGraph == nil {
+			return errors.New("call graph has not been built")
+		}
+		return a.writeCallGraphDOT(w)
+	case PackageGraphKind:
+		if a.importGraph == nil {
+			return errors.New("import graph has not been built")
+		}
+		return a.writePackageGraphDOT(w)
+	default:
+		return fmt.Errorf("unknown graph kind %d", kind)
+	}
+}
+
+// writeCallGraphDOT renders the call graph with one cluster per file
+func (a *ASTAnalyzer) writeCallGraphDOT(w io.Writer) error {
+	cg := a.callGraph
+	include, err := reachableFrom(cg.Edges, a.GraphRoot, func(root string) []string {
+		var ids []string
+		for id, node := range cg.Nodes {
+			if id == root || node.Name == root || node.Receiver+"."+node.Name == root {
+				ids = append(ids, id)
+			}
+		}
+		return ids
+	})
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph calls {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=filled, fontname=\"Helvetica\"];\n")
+
+	byFile := make(map[string][]*CallGraphNode)
+	var external []*CallGraphNode
+	for id, node := range cg.Nodes {
+		if !include(id) {
+			continue
+		}
+		if node.External {
+			external = append(external, node)
+			continue
+		}
+		byFile[node.File] = append(byFile[node.File], node)
+	}
+
+	files := make([]string, 0, len(byFile))
+	for file := range byFile {
+		files = append(files, file)
+	}
+	sort.Strings(files)
+
+	for i, file := range files {
+		nodes := byFile[file]
+		sort.Slice(nodes, func(i, j int) bool { return nodes[i].ID < nodes[j].ID })
+
+		fmt.Fprintf(&b, "  subgraph %s {\n", dotQuote(fmt.Sprintf("cluster_%d", i)))
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote(file))
+		for _, node := range nodes {
+			label := node.Name
+			if node.Receiver != "" {
+				label = node.Receiver + "." + node.Name
+			}
+			fmt.Fprintf(&b, "    %s [label=%s, fillcolor=%s, width=%.2f, height=%.2f];\n",
+				dotQuote(node.ID),
+				dotQuote(label),
+				dotQuote(complexityColor(node.Complexity)),
+				sizeDimension(node.Lines, 0.75, 100),
+				sizeDimension(node.Lines, 0.5, 200))
+		}
+		b.WriteString("  }\n")
+	}
+
+	sort.Slice(external, func(i, j int) bool { return external[i].ID < external[j].ID })
+	for _, node := range external {
+		fmt.Fprintf(&b, "  %s [label=%s, style=dashed, fillcolor=none];\n",
+			dotQuote(node.ID), dotQuote(node.Name))
+	}
+
+	writeDOTEdges(&b, cg.Edges, include)
+	b.WriteString("}\n")
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// writePackageGraphDOT renders the import graph with local and external
+// packages in separate clusters
+func (a *ASTAnalyzer) writePackageGraphDOT(w io.Writer) error {
+	g := a.importGraph
+	include, err := reachableFrom(g.Edges, a.GraphRoot, func(root string) []string {
+		if _, ok := g.Nodes[root]; ok {
+			return []string{root}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	b.WriteString("digraph packages {\n")
+	b.WriteString("  rankdir=LR;\n")
+	b.WriteString("  node [shape=box, style=filled, fontname=\"Helvetica\"];\n")
+
+	clusters := []struct {
+		name     string
+		label    string
+		external bool
+	}{
+		{"cluster_local", "local", false},
+		{"cluster_external", "external", true},
+	}
+	for _, cluster := range clusters {
+		fmt.Fprintf(&b, "  subgraph %s {\n", dotQuote(cluster.name))
+		fmt.Fprintf(&b, "    label=%s;\n", dotQuote(cluster.label))
+		for _, node := range g.SortedNodes() {
+			if node.External != cluster.external || !include(node.ImportPath) {
+				continue
+			}
+			if node.External {
+				fmt.Fprintf(&b, "    %s [style=dashed, fillcolor=none];\n", dotQuote(node.ImportPath))
+				continue
+			}
+			fmt.Fprintf(&b, "    %s [fillcolor=%s, width=%.2f, height=%.2f];\n",
+				dotQuote(node.ImportPath),
+				dotQuote(complexityColor(node.Functions/5+1)),
+				sizeDimension(node.Files, 0.75, 5),
+				sizeDimension(node.Files, 0.5, 10))
+		}
+		b.WriteString("  }\n")
+	}
+
+	writeDOTEdges(&b, g.Edges, include)
+	b.WriteString("}\n")
+
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// writeDOTEdges writes the edges between included nodes in sorted order
+func writeDOTEdges(b *strings.Builder, edges map[string][]string, include func(string) bool) {
+	from := make([]string, 0, len(edges))
+	for id := range edges {
+		from = append(from, id)
+	}
+	sort.Strings(from)
+
+	for _, src := range from {
+		if !include(src) {
+			continue
+		}
+		for _, dst := range edges[src] {
+			if include(dst) {
+				fmt.Fprintf(b, "  %s -> %s;\n", dotQuote(src), dotQuote(dst))
+			}
+		}
+	}
+}
+
+// reachableFrom returns a predicate selecting the nodes reachable from the
+// nodes matching root, or every node when root is empty
+func reachableFrom(edges map[string][]string, root string, match func(string) []string) (func(string) bool, error) {
+	if root == "" {
+		return func(string) bool { return true }, nil
+	}
+
+	queue := match(root)
+	if len(queue) == 0 {
+		return nil, fmt.Errorf("graph root %q not found", root)
+	}
+
+	seen := make(map[string]bool)
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		queue = append(queue, edges[id]...)
+	}
+	return func(id string) bool { return seen[id] }, nil
+}
+
+// complexityColor maps complexity onto a green to red HSV color
+func complexityColor(complexity int) string {
+	const maxComplexity = 20
+	if complexity > maxComplexity {
+		complexity = maxComplexity
+	}
+	hue := 0.33 * float64(maxComplexity-complexity) / float64(maxComplexity-1)
+	return fmt.Sprintf("%.3f 0.45 1.000", hue)
+}
+
+// sizeDimension scales a node dimension with value, capped at three times
+// the base size
+func sizeDimension(value int, base float64, unit int) float64 {
+	size := base * (1 + float64(value)/float64(unit))
+	if size > 3*base {
+		size = 3 * base
+	}
+	return size
+}
+
+// dotQuote quotes a DOT identifier so names with dots, slashes and quotes
+// stay valid
+func dotQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
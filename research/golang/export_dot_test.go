@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// dotFixture is a module whose package and function names need quoting
+var dotFixture = map[string]string{
+	"go.mod": "module example.com/game\n\ngo 1.21\n",
+	"main.go": `package main
+
+import (
+	"fmt"
+
+	"example.com/game/store"
+)
+
+func main() {
+	s := store.New()
+	fmt.Println(s.Get("key"))
+	helper()
+}
+
+func helper() {}
+`,
+	"store/store.go": `package store
+
+import "strings"
+
+type Store struct{ m map[string]string }
+
+func New() *Store { return &Store{m: map[string]string{}} }
+
+func (s *Store) Get(key string) string {
+	if v, ok := s.m[strings.ToLower(key)]; ok {
+		return v
+	}
+	return ""
+}
+
+func Unused() {}
+`,
+}
+
+// bareDOTID matches the DOT identifiers that need no quotes
+var bareDOTID = regexp.MustCompile(`^([A-Za-z_][A-Za-z_0-9]*|-?[0-9]*\.?[0-9]+)$`)
+
+// checkDOT validates the parts of the DOT grammar ExportDOT relies on:
+// braces balance outside quoted strings, identifiers with dots or
+// slashes are quoted, and every edge joins declared nodes. It returns the
+// declared node names.
+func checkDOT(t *testing.T, dot string) map[string]bool {
+	t.Helper()
+	var tokens []string
+	depth := 0
+	for i := 0; i < len(dot); i++ {
+		c := dot[i]
+		switch {
+		case c == '"':
+			j := i + 1
+			for ; j < len(dot) && dot[j] != '"'; j++ {
+				if dot[j] == '\\' {
+					j++
+				}
+			}
+			if j >= len(dot) {
+				t.Fatalf("unterminated string at offset %d", i)
+			}
+			tokens = append(tokens, dot[i:j+1])
+			i = j
+		case c == '{':
+			depth++
+			tokens = append(tokens, "{")
+		case c == '}':
+			depth--
+			if depth < 0 {
+				t.Fatalf("unbalanced } at offset %d", i)
+			}
+			tokens = append(tokens, "}")
+		case strings.ContainsRune("[]=,;", rune(c)):
+			tokens = append(tokens, string(c))
+		case c == '-' && i+1 < len(dot) && dot[i+1] == '>':
+			tokens = append(tokens, "->")
+			i++
+		case c == ' ' || c == '\n' || c == '\t':
+		default:
+			j := i
+			for j < len(dot) && !strings.ContainsRune(" \n\t\"{}[]=,;", rune(dot[j])) {
+				j++
+			}
+			if id := dot[i:j]; !bareDOTID.MatchString(id) {
+				t.Errorf("unquoted identifier %q", id)
+			}
+			tokens = append(tokens, dot[i:j])
+			i = j - 1
+		}
+	}
+	if depth != 0 {
+		t.Fatalf("%d unclosed braces", depth)
+	}
+
+	// Node statements are an ID followed by an attribute list
+	nodes := make(map[string]bool)
+	for i := 0; i+1 < len(tokens); i++ {
+		if strings.HasPrefix(tokens[i], `"`) && tokens[i+1] == "[" && (i == 0 || tokens[i-1] != "=") {
+			nodes[tokens[i]] = true
+		}
+	}
+	for i := 1; i+1 < len(tokens); i++ {
+		if tokens[i] != "->" {
+			continue
+		}
+		for _, end := range []string{tokens[i-1], tokens[i+1]} {
+			if !nodes[end] {
+				t.Errorf("edge endpoint %s is not a declared node", end)
+			}
+		}
+	}
+	return nodes
+}
+
+func TestExportDOT(t *testing.T) {
+	dir := writeTree(t, dotFixture)
+	a := quietAnalyzer()
+	if _, err := a.BuildCallGraph(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.BuildImportGraph(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		kind    GraphKind
+		root    string
+		want    []string // Quoted node names, with DIR for the fixture directory
+		notWant []string
+		wantErr bool
+	}{
+		{
+			name: "calls",
+			kind: CallGraphKind,
+			want: []string{`"DIR.main"`, `"DIR/store.Store.Get"`, `"DIR/store.Unused"`, `"external:fmt.Println"`},
+		},
+		{
+			name:    "calls from root",
+			kind:    CallGraphKind,
+			root:    "main",
+			want:    []string{`"DIR.main"`, `"DIR.helper"`, `"external:fmt.Println"`},
+			notWant: []string{`"DIR/store.Unused"`},
+		},
+		{
+			name: "packages",
+			kind: PackageGraphKind,
+			want: []string{`"example.com/game"`, `"example.com/game/store"`, `"fmt"`, `"strings"`},
+		},
+		{
+			name:    "packages from root",
+			kind:    PackageGraphKind,
+			root:    "example.com/game/store",
+			want:    []string{`"example.com/game/store"`, `"strings"`},
+			notWant: []string{`"example.com/game"`, `"fmt"`},
+		},
+		{name: "unknown root", kind: PackageGraphKind, root: "nowhere", wantErr: true},
+		{name: "unknown kind", kind: GraphKind(7), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a.GraphRoot = tt.root
+			var out bytes.Buffer
+			err := a.ExportDOT(&out, tt.kind)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExportDOT() error = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			nodes := checkDOT(t, out.String())
+			for _, name := range tt.want {
+				name = strings.Replace(name, "DIR", dir, 1)
+				if !nodes[name] {
+					t.Errorf("node %s missing from\n%s", name, out.String())
+				}
+			}
+			for _, name := range tt.notWant {
+				name = strings.Replace(name, "DIR", dir, 1)
+				if nodes[name] {
+					t.Errorf("node %s should not be reachable from %s", name, tt.root)
+				}
+			}
+			if tt.kind == CallGraphKind && !strings.Contains(out.String(), `"external:fmt.Println" [label="fmt.Println", style=dashed`) {
+				t.Error("external callee is not dashed")
+			}
+		})
+	}
+}
+
+func TestExportDOTNotBuilt(t *testing.T) {
+	for _, kind := range []GraphKind{CallGraphKind, PackageGraphKind} {
+		if err := quietAnalyzer().ExportDOT(&bytes.Buffer{}, kind); err == nil {
+			t.Errorf("ExportDOT(%d) without a graph succeeded", kind)
+		}
+	}
+}
+
+func TestDOTQuote(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{in: "main", want: `"main"`},
+		{in: "example.com/game/store.(*Store).Get", want: `"example.com/game/store.(*Store).Get"`},
+		{in: `say "hi"`, want: `"say \"hi\""`},
+		{in: `C:\src`, want: `"C:\\src"`},
+	}
+	for _, tt := range tests {
+		if got := dotQuote(tt.in); got != tt.want {
+			t.Errorf("dotQuote(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
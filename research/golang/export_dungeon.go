@@ -0,0 +1,109 @@
+package main
+
+import (
+	"go/ast"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/study-game/research/game"
+)
+
+// Dungeon lays out one floor per package under dir with
+// game.GenerateDungeon. Rooms are the package's files outside tests and
+// corridors follow the call graph between them. Packages are named
+// relative to dir and floors are in package order.
+func (a *ASTAnalyzer) Dungeon(dir string) (*game.Dungeon, error) {
+	cg, err := a.BuildCallGraph(dir)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := a.goFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]map[string]*game.DungeonFile) // By package directory and path
+	for _, path := range paths {
+		if isTestName(path) {
+			continue
+		}
+		pkgDir := filepath.Dir(path)
+		if files[pkgDir] == nil {
+			files[pkgDir] = make(map[string]*game.DungeonFile)
+		}
+		files[pkgDir][path] = &game.DungeonFile{Path: filepath.ToSlash(filepath.Base(path))}
+	}
+	for _, id := range cg.declaredIDs(func(id string) bool { return !cg.Nodes[id].Dynamic }) {
+		node := cg.Nodes[id]
+		file, ok := files[filepath.Dir(node.File)][node.File]
+		if !ok {
+			continue
+		}
+		file.Functions++
+		if ast.IsExported(node.Name) {
+			file.Exported++
+		}
+	}
+
+	calls := make(map[string]map[[2]string]int) // By package directory
+	for caller, callees := range cg.Edges {
+		from := cg.Nodes[caller]
+		for _, callee := range callees {
+			to := cg.Nodes[callee]
+			if to == nil || to.External || to.Dynamic || from.File == to.File {
+				continue
+			}
+			pkgDir := filepath.Dir(from.File)
+			if files[pkgDir][from.File] == nil || files[pkgDir][to.File] == nil {
+				continue
+			}
+			if calls[pkgDir] == nil {
+				calls[pkgDir] = make(map[[2]string]int)
+			}
+			calls[pkgDir][[2]string{from.File, to.File}]++
+		}
+	}
+
+	pkgDirs := make([]string, 0, len(files))
+	for pkgDir := range files {
+		pkgDirs = append(pkgDirs, pkgDir)
+	}
+	sort.Strings(pkgDirs)
+
+	dungeon := &game.Dungeon{Floors: []game.Floor{}}
+	for _, pkgDir := range pkgDirs {
+		var pkgFiles []game.DungeonFile
+		for _, file := range files[pkgDir] {
+			pkgFiles = append(pkgFiles, *file)
+		}
+		var pkgCalls []game.FileCall
+		for pair, n := range calls[pkgDir] {
+			pkgCalls = append(pkgCalls, game.FileCall{
+				From:  files[pkgDir][pair[0]].Path,
+				To:    files[pkgDir][pair[1]].Path,
+				Calls: n,
+			})
+		}
+		floor, err := game.GenerateDungeon(outputPath(dir, pkgDir), pkgFiles, pkgCalls)
+		if err != nil {
+			return nil, err
+		}
+		dungeon.Floors = append(dungeon.Floors, *floor)
+	}
+	return dungeon, nil
+}
+
+// ExportDungeon writes the dungeon of every directory as indented JSON,
+// their floors together
+func (a *ASTAnalyzer) ExportDungeon(w io.Writer, dirs []string) error {
+	all := &game.Dungeon{Floors: []game.Floor{}}
+	for _, dir := range dirs {
+		d, err := a.Dungeon(dir)
+		if err != nil {
+			return err
+		}
+		all.Floors = append(all.Floors, d.Floors...)
+	}
+	return all.WriteJSON(w)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"time"
+)
+
+//go:embed templates/report.html.tmpl
+var reportTemplateText string
+
+var reportTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"ms": func(ns int64) string {
+		return fmt.Sprintf("%.3f", float64(ns)/1e6)
+	},
+	"lines": func(fn ExportFunction) int {
+		return fn.LineEnd - fn.LineStart + 1
+	},
+}).Parse(reportTemplateText))
+
+// htmlTopFunctions is how many functions the HTML report lists
+const htmlTopFunctions = 20
+
+// Bar chart layout in pixels
+const (
+	chartLabelWidth = 320
+	chartBarWidth   = 400
+	chartBarHeight  = 14
+	chartRowHeight  = 20
+)
+
+// htmlReport is the data passed to the HTML template
+type htmlReport struct {
+	Export
+	TotalFiles     int
+	TotalFunctions int
+	Failures       int
+	TotalParseTime time.Duration
+	TopFunctions   []ExportFunction
+	Chart          htmlChart
+}
+
+// htmlChart is an inline SVG bar chart of parse time per file
+type htmlChart struct {
+	Width      int
+	Height     int
+	LabelWidth int
+	BarHeight  int
+	Bars       []htmlBar
+}
+
+// htmlBar is a single bar of the chart
+type htmlBar struct {
+	Label  string
+	Value  string
+	Y      int
+	TextY  int
+	Width  int
+	ValueX int
+}
+
+// ExportHTML writes a self-contained HTML report with summary cards,
+// sortable tables and a parse time chart. It needs no external resources.
+func (a *ASTAnalyzer) ExportHTML(w io.Writer) error {
+	export := a.BuildExport()
+	report := htmlReport{
+		Export:     export,
+		TotalFiles: len(export.Files),
+		Chart: htmlChart{
+			Width:      chartLabelWidth + chartBarWidth + 80,
+			Height:     len(export.Files) * chartRowHeight,
+			LabelWidth: chartLabelWidth,
+			BarHeight:  chartBarHeight,
+		},
+	}
+
+	var maxTime int64
+	for _, f := range export.Files {
+		if f.Skipped {
+			continue
+		}
+		if !f.Success {
+			report.Failures++
+			continue
+		}
+		report.TotalFunctions += f.NumFunctions + f.NumMethods
+		report.TotalParseTime += time.Duration(f.ParseTime.Nanoseconds)
+		if f.ParseTime.Nanoseconds > maxTime {
+			maxTime = f.ParseTime.Nanoseconds
+		}
+	}
+
+	for i, f := range export.Files {
+		width := 0
+		if maxTime > 0 {
+			width = int(f.ParseTime.Nanoseconds * chartBarWidth / maxTime)
+		}
+		y := i * chartRowHeight
+		report.Chart.Bars = append(report.Chart.Bars, htmlBar{
+			Label:  f.Path,
+			Value:  fmt.Sprintf("%.3fms", float64(f.ParseTime.Nanoseconds)/1e6),
+			Y:      y,
+			TextY:  y + chartBarHeight - 2,
+			Width:  width,
+			ValueX: chartLabelWidth + width + 6,
+		})
+	}
+
+	top := append([]ExportFunction(nil), export.Functions...)
+	sort.SliceStable(top, func(i, j int) bool {
+		if top[i].Complexity != top[j].Complexity {
+			return top[i].Complexity > top[j].Complexity
+		}
+		return top[i].LineEnd-top[i].LineStart > top[j].LineEnd-top[j].LineStart
+	})
+	if len(top) > htmlTopFunctions {
+		top = top[:htmlTopFunctions]
+	}
+	report.TopFunctions = top
+
+	return reportTemplate.Execute(w, report)
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestExportHTML(t *testing.T) {
+	var out bytes.Buffer
+	if err := goldenAnalyzer().ExportHTML(&out); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "report.golden.html", out.Bytes())
+	html := out.String()
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "files card", want: `<div class="value">2</div><div class="label">Files</div>`},
+		{name: "functions card", want: `<div class="value">2</div><div class="label">Functions</div>`},
+		{name: "failures card", want: `<div class="value">1</div><div class="label">Failures</div>`},
+		{name: "parse time card", want: `<div class="value">1.5ms</div><div class="label">Total parse time</div>`},
+		{name: "file row", want: `<td class="path">a.go</td><td class="num">1.500</td>`},
+		{name: "failed row", want: `<tr class="failed"><td class="path">b_test.go</td>`},
+		{name: "escaped error", want: `expected &#39;package&#39;, found &#39;EOF&#39;`},
+		{name: "function row", want: `<tr><td>Sum</td><td class="path">a.go:3</td><td class="num">1</td><td class="num">3</td></tr>`},
+		{name: "chart bar", want: `width="400" height="14" fill="#0969da"><title>a.go: 1.500ms</title>`},
+		{name: "sortable tables", want: `<table class="sortable">`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(html, tt.want) {
+				t.Errorf("report lacks %s", tt.want)
+			}
+		})
+	}
+
+	// The report must work offline
+	for _, external := range []string{"<script src", "<link", "http://", "https://", "<a "} {
+		if strings.Contains(html, external) {
+			t.Errorf("report refers to an external resource: %s", external)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"go/parser"
+	"io"
+	"time"
+)
+
+// JSON Lines record types, stored in each line's "type" field
+const (
+	JSONLFile     = "file"
+	JSONLFunction = "function"
+	JSONLSummary  = "summary"
+)
+
+// jsonlFile is a file line: an ExportFile tagged with its record type
+type jsonlFile struct {
+	Type string `json:"type"`
+	ExportFile
+}
+
+// jsonlFunction is a function line: an ExportFunction tagged with its
+// record type
+type jsonlFunction struct {
+	Type string `json:"type"`
+	ExportFunction
+}
+
+// JSONLSummaryRecord is the last line of a stream. Complete is false when
+// the walk stopped early, in which case Error says why.
+type JSONLSummaryRecord struct {
+	Type            string         `json:"type"`
+	SchemaVersion   int            `json:"schema_version"`
+	AnalyzerVersion string         `json:"analyzer_version"`
+	StartTime       time.Time      `json:"start_time"`
+	Directories     []string       `json:"directories"`
+	Repository      string         `json:"repository,omitempty"`
+	Commit          string         `json:"commit,omitempty"`
+	ParseMode       string         `json:"parse_mode"`
+	Files           int            `json:"files"`
+	Failed          int            `json:"failed"`
+	Skipped         int            `json:"skipped,omitempty"`
+	Functions       int            `json:"functions"`
+	Methods         int            `json:"methods"`
+	ParseTime       ExportDuration `json:"parse_time"`
+	Complete        bool           `json:"complete"`
+	Error           string         `json:"error,omitempty"`
+}
+
+// StreamJSONL benchmarks dirs and writes one JSON object per line as each
+// file is parsed, followed by a summary line. With functions set, every
+// function of a parsed file follows its file line; set RetainAST to avoid
+// parsing those files twice. Nothing is accumulated on the analyzer, so
+// memory stays flat on very large trees. The summary is written even when
+// the walk fails or ctx is cancelled.
+func (a *ASTAnalyzer) StreamJSONL(ctx context.Context, w io.Writer, dirs []string, functions bool) error {
+	enc := json.NewEncoder(w)
+	summary := JSONLSummaryRecord{
+		Type:            JSONLSummary,
+		SchemaVersion:   ExportSchemaVersion,
+		AnalyzerVersion: AnalyzerVersion,
+		StartTime:       time.Now(),
+		Directories:     dirs,
+		ParseMode:       a.Mode.String(),
+	}
+	summary.Repository, summary.Commit = a.remoteSummary()
+
+	// Paths are written as BuildExport writes them
+	root := a.RootDir
+	if root == "" && len(dirs) == 1 {
+		root = dirOf(dirs[0])
+	}
+
+	var parseTime time.Duration
+	var walkErr error
+	for _, dir := range dirs {
+		walkErr = a.StreamDirectory(ctx, dir, func(result ParseResult) error {
+			summary.Files++
+			if result.SkippedOversize {
+				summary.Skipped++
+			} else if !result.Success {
+				summary.Failed++
+			} else {
+				summary.Functions += result.NumFunctions
+				summary.Methods += result.NumMethods
+				parseTime += result.ParseTime
+			}
+
+			file := newExportFile(result)
+			file.Path = outputPath(root, result.FilePath)
+			if err := enc.Encode(jsonlFile{Type: JSONLFile, ExportFile: file}); err != nil {
+				return err
+			}
+			if !functions || !result.Success {
+				return nil
+			}
+
+			f := result.AST
+			if f == nil {
+				var err error
+				if f, err = parser.ParseFile(a.fset, result.FilePath, nil, parser.ParseComments); err != nil {
+					return err
+				}
+			}
+			for _, fn := range a.extractFunctions(f) {
+				record := jsonlFunction{Type: JSONLFunction, ExportFunction: newExportFunction(file.Path, fn)}
+				if err := enc.Encode(record); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if walkErr != nil {
+			break
+		}
+	}
+
+	summary.ParseTime = newExportDuration(parseTime)
+	summary.Complete = walkErr == nil
+	if walkErr != nil {
+		summary.Error = walkErr.Error()
+	}
+	if err := enc.Encode(summary); err != nil {
+		return err
+	}
+	return walkErr
+}
+
+// StreamResultsJSONL streams the file lines and summary of a single
+// directory to w, without function lines. It is StreamJSONL for callers
+// that need neither cancellation nor several roots.
+func (a *ASTAnalyzer) StreamResultsJSONL(dir string, w io.Writer) error {
+	return a.StreamJSONL(context.Background(), w, []string{dir}, false)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+// cancelWriter cancels a context once lines lines have been written
+type cancelWriter struct {
+	bytes.Buffer
+	lines  int
+	cancel context.CancelFunc
+}
+
+func (w *cancelWriter) Write(p []byte) (int, error) {
+	if w.lines--; w.lines == 0 {
+		w.cancel()
+	}
+	return w.Buffer.Write(p)
+}
+
+// jsonlRecords decodes every line of a stream, counting records by type
+func jsonlRecords(t *testing.T, stream []byte) (map[string]int, JSONLSummaryRecord) {
+	t.Helper()
+	counts := make(map[string]int)
+	var summary JSONLSummaryRecord
+	scanner := bufio.NewScanner(bytes.NewReader(stream))
+	for scanner.Scan() {
+		var record struct {
+			Type string `json:"type"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			t.Fatalf("line %q is not JSON: %v", scanner.Text(), err)
+		}
+		if counts[JSONLSummary] > 0 {
+			t.Fatalf("%s line after the summary", record.Type)
+		}
+		counts[record.Type]++
+		if record.Type == JSONLSummary {
+			if err := json.Unmarshal(scanner.Bytes(), &summary); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if counts[JSONLSummary] != 1 {
+		t.Fatalf("stream has %d summary lines, want 1", counts[JSONLSummary])
+	}
+	return counts, summary
+}
+
+func TestStreamJSONL(t *testing.T) {
+	const numFiles = 20
+	files := make(map[string]string)
+	for i := 0; i < numFiles; i++ {
+		files[fmt.Sprintf("f%02d.go", i)] = fmt.Sprintf("package a\n\nfunc F%d() {}\n\nfunc G%d() {}\n", i, i)
+	}
+	dir := writeTree(t, files)
+
+	tests := []struct {
+		name        string
+		functions   bool
+		cancelAfter int // Lines written before cancelling, zero for none
+		complete    bool
+	}{
+		{name: "files", complete: true},
+		{name: "functions", functions: true, complete: true},
+		{name: "cancelled", cancelAfter: 3},
+		{name: "cancelled with functions", functions: true, cancelAfter: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx, cancel := context.WithCancel(context.Background())
+			defer cancel()
+			w := &cancelWriter{lines: tt.cancelAfter, cancel: cancel}
+
+			err := quietAnalyzer().StreamJSONL(ctx, w, []string{dir}, tt.functions)
+			counts, summary := jsonlRecords(t, w.Bytes())
+
+			if tt.complete {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if counts[JSONLFile] != numFiles || summary.Files != numFiles || !summary.Complete {
+					t.Errorf("complete stream has %d file lines, summary %+v", counts[JSONLFile], summary)
+				}
+			} else {
+				if !errors.Is(err, context.Canceled) {
+					t.Fatalf("StreamJSONL() error = %v, want context.Canceled", err)
+				}
+				if counts[JSONLFile] == 0 || counts[JSONLFile] >= numFiles {
+					t.Errorf("cancelled stream has %d file lines, want some but not all", counts[JSONLFile])
+				}
+				if summary.Complete || summary.Error != context.Canceled.Error() || summary.Files != counts[JSONLFile] {
+					t.Errorf("summary %+v after %d file lines", summary, counts[JSONLFile])
+				}
+			}
+
+			if !tt.functions && counts[JSONLFunction] != 0 {
+				t.Errorf("%d function lines without functions", counts[JSONLFunction])
+			}
+			if tt.functions && counts[JSONLFunction] != 2*counts[JSONLFile] {
+				t.Errorf("%d function lines for %d files", counts[JSONLFunction], counts[JSONLFile])
+			}
+		})
+	}
+}
@@ -0,0 +1,217 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"time"
+)
+
+// DefaultMaxComplexity is the cyclomatic complexity above which ExportJUnit
+// fails a function when MaxComplexity is zero
+const DefaultMaxComplexity = 10
+
+// junitTestSuites is the root element of a JUnit XML report
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Name     string           `xml:"name,attr"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+// junitTestSuite holds the test cases of one package
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Timestamp string          `xml:"timestamp,attr,omitempty"`
+	Cases     []junitTestCase `xml:"testcase"`
+}
+
+// junitTestCase is a clean file or a single violation
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *junitSkipped `xml:"skipped,omitempty"`
+}
+
+// junitSkipped marks a file that was not analyzed
+type junitSkipped struct {
+	Message string `xml:"message,attr"`
+}
+
+// junitFailure describes why a test case failed
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// ExportJUnit writes the results as a JUnit XML report with one test suite
+// per package, so CI servers can display violations and fail the build.
+// Every parse failure, finding and function whose complexity exceeds
+// MaxComplexity becomes a failed test case, and files without any become
+// passing ones; files skipped for their size are reported as skipped.
+// When MinDocCoverage is set, each package also gets a test
+// case that fails if fewer of its exported functions are documented.
+func (a *ASTAnalyzer) ExportJUnit(w io.Writer) error {
+	export := a.BuildExport()
+	maxComplexity := a.MaxComplexity
+	if maxComplexity <= 0 {
+		maxComplexity = DefaultMaxComplexity
+	}
+
+	// Collect the violations of each file
+	violations := make(map[string][]junitTestCase)
+	for _, f := range export.Files {
+		if f.Skipped {
+			violations[f.Path] = []junitTestCase{{
+				Name:      f.Path,
+				ClassName: path.Dir(f.Path),
+				Time:      junitSeconds(0),
+				Skipped:   &junitSkipped{Message: f.Error},
+			}}
+		} else if !f.Success {
+			violations[f.Path] = append(violations[f.Path], a.junitViolation(f.Path, "parse", "parse error", f.Error))
+		}
+	}
+	for _, fn := range export.Functions {
+		if fn.Complexity <= maxComplexity {
+			continue
+		}
+		name := fn.Name
+		if fn.Receiver != "" {
+			name = fn.Receiver + "." + fn.Name
+		}
+		message := fmt.Sprintf("%s:%d: %s has cyclomatic complexity %d (max %d)",
+			fn.File, fn.LineStart, name, fn.Complexity, maxComplexity)
+		violations[fn.File] = append(violations[fn.File], a.junitViolation(fn.File, "complexity", "complexity: "+name, message))
+	}
+	for _, f := range export.Findings {
+		message := fmt.Sprintf("%s:%d: %s", f.File, f.Line, f.Message)
+		if f.Suggestion != "" {
+			message += " (" + f.Suggestion + ")"
+		}
+		violations[f.File] = append(violations[f.File], a.junitViolation(f.File, f.Category, f.Category+": "+f.Identifier, message))
+	}
+
+	suites := make(map[string]*junitTestSuite)
+	var order []string
+	suiteFor := func(file string) *junitTestSuite {
+		pkg := path.Dir(file)
+		s, ok := suites[pkg]
+		if !ok {
+			s = &junitTestSuite{Name: pkg}
+			suites[pkg] = s
+			order = append(order, pkg)
+		}
+		return s
+	}
+
+	var total time.Duration
+	suiteTimes := make(map[*junitTestSuite]time.Duration)
+	for _, f := range export.Files {
+		s := suiteFor(f.Path)
+		d := time.Duration(f.ParseTime.Nanoseconds)
+		suiteTimes[s] += d
+		total += d
+
+		cases := violations[f.Path]
+		if len(cases) == 0 {
+			cases = []junitTestCase{{
+				Name:      f.Path,
+				ClassName: s.Name,
+				Time:      junitSeconds(d),
+			}}
+		}
+		s.Cases = append(s.Cases, cases...)
+	}
+
+	if a.MinDocCoverage > 0 {
+		documented := make(map[*junitTestSuite]int)
+		exported := make(map[*junitTestSuite]int)
+		for _, fn := range export.Functions {
+			if !fn.IsExported {
+				continue
+			}
+			s := suiteFor(fn.File)
+			exported[s]++
+			if fn.DocComment != "" {
+				documented[s]++
+			}
+		}
+		for _, pkg := range order {
+			s := suites[pkg]
+			tc := junitTestCase{Name: "doc coverage", ClassName: s.Name, Time: junitSeconds(0)}
+			if exported[s] > 0 {
+				coverage := float64(documented[s]) / float64(exported[s])
+				if coverage < a.MinDocCoverage {
+					message := fmt.Sprintf("%s: %d of %d exported functions documented (%.1f%%, target %.1f%%)",
+						s.Name, documented[s], exported[s], 100*coverage, 100*a.MinDocCoverage)
+					tc.Failure = &junitFailure{Message: message, Type: "doc-coverage", Text: message}
+				}
+			}
+			s.Cases = append(s.Cases, tc)
+		}
+	}
+
+	sort.Strings(order)
+	report := junitTestSuites{Name: "ast-benchmark", Time: junitSeconds(total)}
+	timestamp := ""
+	if !a.startTime.IsZero() {
+		timestamp = a.startTime.UTC().Format("2006-01-02T15:04:05")
+	}
+	for _, pkg := range order {
+		s := suites[pkg]
+		s.Tests = len(s.Cases)
+		for _, tc := range s.Cases {
+			if tc.Failure != nil {
+				s.Failures++
+			}
+			if tc.Skipped != nil {
+				s.Skipped++
+			}
+		}
+		s.Time = junitSeconds(suiteTimes[s])
+		s.Timestamp = timestamp
+		report.Tests += s.Tests
+		report.Failures += s.Failures
+		report.Suites = append(report.Suites, *s)
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(report); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// junitViolation builds a failed test case for a violation in file
+func (a *ASTAnalyzer) junitViolation(file, kind, name, message string) junitTestCase {
+	return junitTestCase{
+		Name:      name,
+		ClassName: path.Dir(file),
+		Time:      junitSeconds(0),
+		Failure:   &junitFailure{Message: message, Type: kind, Text: message},
+	}
+}
+
+// junitSeconds formats a duration the way JUnit time attributes expect:
+// decimal seconds without a unit
+func junitSeconds(d time.Duration) string {
+	return fmt.Sprintf("%.6f", d.Seconds())
+}
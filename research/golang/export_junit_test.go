@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestExportJUnit(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"clean.go": "package a\n\n// Clean is documented\nfunc Clean() {}\n",
+		"complex.go": `package a
+
+func Complex(x int) int {
+	if x > 0 {
+		return 1
+	}
+	if x < -1 && x > -10 {
+		return 2
+	}
+	return 0
+}
+`,
+		"broken.go":   "package a\n\nfunc Broken( {\n",
+		"store/db.go": "package store\n\nfunc Open() {}\n\n// Close is documented\nfunc Close() {}\n",
+	})
+	a := quietAnalyzer()
+	a.MaxComplexity = 2
+	a.MinDocCoverage = 0.75
+	if _, err := a.BenchmarkDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := extractAllFunctions(a); err != nil {
+		t.Fatal(err)
+	}
+	a.addFindings(Finding{
+		Category:   CategorySingleLetter,
+		FilePath:   dir + "/store/db.go",
+		Line:       3,
+		Identifier: "X",
+		Message:    `name <X> & "Y" aren't escaped`,
+	})
+
+	var out bytes.Buffer
+	if err := a.ExportJUnit(&out); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out.String(), xml.Header) {
+		t.Error("report lacks the XML header")
+	}
+	var report junitTestSuites
+	if err := xml.Unmarshal(out.Bytes(), &report); err != nil {
+		t.Fatalf("report is not XML: %v\n%s", err, out.String())
+	}
+
+	type caseKey struct{ suite, name string }
+	cases := make(map[caseKey]junitTestCase)
+	tests, failures := 0, 0
+	for _, s := range report.Suites {
+		suiteFailures := 0
+		for _, tc := range s.Cases {
+			cases[caseKey{s.Name, tc.Name}] = tc
+			if tc.Failure != nil {
+				suiteFailures++
+			}
+			if _, err := strconv.ParseFloat(tc.Time, 64); err != nil {
+				t.Errorf("case %s time %q: %v", tc.Name, tc.Time, err)
+			}
+		}
+		if s.Tests != len(s.Cases) || s.Failures != suiteFailures {
+			t.Errorf("suite %s counts %d tests, %d failures; has %d, %d", s.Name, s.Tests, s.Failures, len(s.Cases), suiteFailures)
+		}
+		tests += s.Tests
+		failures += s.Failures
+	}
+	if report.Tests != tests || report.Failures != failures {
+		t.Errorf("report counts %d tests, %d failures; suites have %d, %d", report.Tests, report.Failures, tests, failures)
+	}
+	if len(report.Suites) != 2 || report.Suites[0].Name != "." || report.Suites[1].Name != "store" {
+		t.Errorf("suites %+v, want . and store", report.Suites)
+	}
+
+	want := []struct {
+		suite, name string
+		failure     string // Failure type, empty for a passing case
+		message     string // Expected in the failure message
+	}{
+		{suite: ".", name: "clean.go"},
+		{suite: ".", name: "parse error", failure: "parse", message: "broken.go"},
+		{suite: ".", name: "complexity: Complex", failure: "complexity", message: "complex.go:3: Complex has cyclomatic complexity 4 (max 2)"},
+		{suite: ".", name: "doc coverage", failure: "doc-coverage", message: "1 of 2 exported functions documented"},
+		{suite: "store", name: "single-letter: X", failure: CategorySingleLetter, message: `store/db.go:3: name <X> & "Y" aren't escaped`},
+		{suite: "store", name: "doc coverage", failure: "doc-coverage", message: "1 of 2"},
+	}
+	for _, w := range want {
+		t.Run(w.suite+"/"+w.name, func(t *testing.T) {
+			tc, ok := cases[caseKey{w.suite, w.name}]
+			if !ok {
+				t.Fatalf("no test case %s in suite %s", w.name, w.suite)
+			}
+			if w.failure == "" {
+				if tc.Failure != nil {
+					t.Errorf("case fails with %+v", tc.Failure)
+				}
+				return
+			}
+			if tc.Failure == nil {
+				t.Fatal("case passes")
+			}
+			if tc.Failure.Type != w.failure || !strings.Contains(tc.Failure.Message, w.message) || tc.Failure.Text != tc.Failure.Message {
+				t.Errorf("failure %+v, want type %s with %q", tc.Failure, w.failure, w.message)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+// markdownTopN is how many rows the Markdown tables list
+const markdownTopN = 10
+
+// ExportMarkdown writes a compact summary suitable for a pull request
+// comment. Paths are shown relative to RootDir and the output
+// contains no timestamps, so identical runs produce identical text apart
+// from the measured times.
+func (a *ASTAnalyzer) ExportMarkdown(w io.Writer) error {
+	export := a.BuildExport()
+
+	var functions, failed int
+	var totalTime time.Duration
+	var failures []ExportFile
+	for _, f := range export.Files {
+		if f.Skipped {
+			continue
+		}
+		if !f.Success {
+			failed++
+			failures = append(failures, f)
+			continue
+		}
+		functions += f.NumFunctions + f.NumMethods
+		totalTime += time.Duration(f.ParseTime.Nanoseconds)
+	}
+
+	var b strings.Builder
+	b.WriteString("## AST benchmark\n\n")
+	fmt.Fprintf(&b, "**%d** files · **%d** functions · **%d** failed · **%s** total parse time\n\n",
+		len(export.Files), functions, failed, totalTime.Round(time.Microsecond))
+
+	slowest := append([]ExportFile(nil), export.Files...)
+	sort.SliceStable(slowest, func(i, j int) bool {
+		return slowest[i].ParseTime.Nanoseconds > slowest[j].ParseTime.Nanoseconds
+	})
+	if len(slowest) > markdownTopN {
+		slowest = slowest[:markdownTopN]
+	}
+	if len(slowest) > 0 {
+		b.WriteString("### Slowest files\n\n")
+		b.WriteString("| File | Parse time | Functions | Methods |\n")
+		b.WriteString("|------|-----------:|----------:|--------:|\n")
+		for _, f := range slowest {
+			fmt.Fprintf(&b, "| `%s` | %.3fms | %d | %d |\n",
+				f.Path,
+				float64(f.ParseTime.Nanoseconds)/1e6,
+				f.NumFunctions,
+				f.NumMethods)
+		}
+		b.WriteString("\n")
+	}
+
+	mostComplex := append([]ExportFunction(nil), export.Functions...)
+	sort.SliceStable(mostComplex, func(i, j int) bool {
+		return mostComplex[i].Complexity > mostComplex[j].Complexity
+	})
+	if len(mostComplex) > markdownTopN {
+		mostComplex = mostComplex[:markdownTopN]
+	}
+	if len(mostComplex) > 0 {
+		b.WriteString("### Most complex functions\n\n")
+		b.WriteString("| Function | Location | Complexity |\n")
+		b.WriteString("|----------|----------|-----------:|\n")
+		for _, fn := range mostComplex {
+			name := fn.Name
+			if fn.Receiver != "" {
+				name = "(" + fn.Receiver + ") " + fn.Name
+			}
+			fmt.Fprintf(&b, "| `%s` | `%s:%d` | %d |\n",
+				name,
+				fn.File,
+				fn.LineStart,
+				fn.Complexity)
+		}
+		b.WriteString("\n")
+	}
+
+	if len(failures) > 0 {
+		fmt.Fprintf(&b, "<details>\n<summary>%d failed files</summary>\n\n", len(failures))
+		for _, f := range failures {
+			fmt.Fprintf(&b, "- `%s`: %s\n", f.Path, markdownEscape(f.Error))
+		}
+		b.WriteString("\n</details>\n")
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// markdownEscape keeps error messages from breaking the surrounding list
+func markdownEscape(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	return strings.ReplaceAll(s, "|", "\\|")
+}
@@ -0,0 +1,342 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+	"io"
+	"sort"
+	"strings"
+)
+
+// defaultDiagramMaxTypes caps Mermaid diagrams when DiagramMaxTypes is zero
+const defaultDiagramMaxTypes = 40
+
+// ExportMermaid writes the types recorded by ExtractTypes as a Mermaid
+// classDiagram. Fields and methods become members, embedding becomes an
+// inheritance arrow and a struct whose method set covers an interface gets
+// a realization arrow. Class names are sanitized to Mermaid identifiers and
+// a legend comment maps them back to Go names.
+func (a *ASTAnalyzer) ExportMermaid(w io.Writer) error {
+	if len(a.types) == 0 {
+		return errors.New("no types have been extracted")
+	}
+
+	d := newMermaidDiagram(a.types, a.outputRoot())
+	included, omitted, err := d.selectTypes(a.DiagramFocus, a.DiagramMaxTypes)
+	if err != nil {
+		return err
+	}
+
+	var b strings.Builder
+	if a.MermaidFence {
+		b.WriteString("```mermaid\n")
+	}
+	b.WriteString("classDiagram\n")
+
+	var legend []string
+	for _, t := range included {
+		if id, name := d.ids[t], d.names[t]; id != name {
+			legend = append(legend, fmt.Sprintf("%%%%   %s = %s\n", id, name))
+		}
+	}
+	if len(legend) > 0 {
+		b.WriteString("%% Legend: Mermaid class = Go type\n")
+		for _, line := range legend {
+			b.WriteString(line)
+		}
+	}
+	if omitted > 0 {
+		fmt.Fprintf(&b, "%%%% %d more types omitted; set a focus type to see them\n", omitted)
+	}
+
+	for _, t := range included {
+		d.writeClass(&b, t)
+	}
+
+	inDiagram := make(map[*TypeInfo]bool, len(included))
+	for _, t := range included {
+		inDiagram[t] = true
+	}
+	for _, rel := range d.relations {
+		if inDiagram[rel.from] && inDiagram[rel.to] {
+			fmt.Fprintf(&b, "  %s %s %s\n", d.ids[rel.to], rel.arrow, d.ids[rel.from])
+		}
+	}
+
+	if a.MermaidFence {
+		b.WriteString("```\n")
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+// mermaidRelation is an arrow from a type to the type it embeds or
+// implements
+type mermaidRelation struct {
+	from, to *TypeInfo
+	arrow    string
+}
+
+// mermaidDiagram holds the names and relations of the types to render
+type mermaidDiagram struct {
+	types     []*TypeInfo
+	names     map[*TypeInfo]string // Go name, qualified by package directory outside the root
+	ids       map[*TypeInfo]string // Mermaid class name
+	byKey     map[string]*TypeInfo // Package directory and type name
+	relations []mermaidRelation
+}
+
+func newMermaidDiagram(types []TypeInfo, root string) *mermaidDiagram {
+	d := &mermaidDiagram{
+		names: make(map[*TypeInfo]string),
+		ids:   make(map[*TypeInfo]string),
+		byKey: make(map[string]*TypeInfo),
+	}
+
+	for i := range types {
+		t := &types[i]
+		d.types = append(d.types, t)
+		d.byKey[t.Package+"."+t.Name] = t
+	}
+
+	used := make(map[string]bool)
+	for _, t := range d.types {
+		name := t.Name
+		if len(t.TypeParams) > 0 {
+			params := make([]string, len(t.TypeParams))
+			for i, p := range t.TypeParams {
+				params[i] = p.Name
+			}
+			name += "[" + strings.Join(params, ", ") + "]"
+		}
+		if pkg := outputPath(root, t.Package); pkg != "." {
+			name = pkg + "." + name
+		}
+		d.names[t] = name
+
+		id := mermaidIdentifier(name)
+		for n := 2; used[id]; n++ {
+			id = fmt.Sprintf("%s_%d", mermaidIdentifier(name), n)
+		}
+		used[id] = true
+		d.ids[t] = id
+	}
+
+	d.addRelations()
+	return d
+}
+
+// addRelations records embedding and interface satisfaction between the
+// diagram's types
+func (d *mermaidDiagram) addRelations() {
+	for _, t := range d.types {
+		for _, embed := range t.Embeds {
+			if parent := d.lookup(t.Package, embed); parent != nil {
+				d.relations = append(d.relations, mermaidRelation{from: t, to: parent, arrow: "<|--"})
+			}
+		}
+	}
+
+	for _, iface := range d.types {
+		if iface.Kind != InterfaceKind {
+			continue
+		}
+		required := d.methodSet(iface, make(map[*TypeInfo]bool))
+		if len(required) == 0 {
+			// Empty interfaces and constraints say nothing useful
+			continue
+		}
+		for _, t := range d.types {
+			if t.Kind != StructKind {
+				continue
+			}
+			provided := d.methodSet(t, make(map[*TypeInfo]bool))
+			if containsAll(provided, required) {
+				d.relations = append(d.relations, mermaidRelation{from: t, to: iface, arrow: "<|.."})
+			}
+		}
+	}
+}
+
+// lookup resolves a type expression to a type declared in pkgDir
+func (d *mermaidDiagram) lookup(pkgDir, expr string) *TypeInfo {
+	name := receiverTypeName(expr)
+	if strings.ContainsAny(name, ". |~") {
+		return nil
+	}
+	return d.byKey[pkgDir+"."+name]
+}
+
+// methodSet returns the signature keys of a type's methods, including
+// those promoted from embedded types declared in the same package
+func (d *mermaidDiagram) methodSet(t *TypeInfo, seen map[*TypeInfo]bool) map[string]bool {
+	set := make(map[string]bool)
+	if seen[t] {
+		return set
+	}
+	seen[t] = true
+
+	for _, m := range t.Methods {
+		set[m.signatureKey()] = true
+	}
+	for _, embed := range t.Embeds {
+		if parent := d.lookup(t.Package, embed); parent != nil {
+			for key := range d.methodSet(parent, seen) {
+				set[key] = true
+			}
+		}
+	}
+	return set
+}
+
+// containsAll reports whether every key of subset is in set
+func containsAll(set, subset map[string]bool) bool {
+	for key := range subset {
+		if !set[key] {
+			return false
+		}
+	}
+	return true
+}
+
+// selectTypes picks the types to render: the focus type and its direct
+// relations, or the first maxTypes types. It also returns how many types
+// were left out.
+func (d *mermaidDiagram) selectTypes(focus string, maxTypes int) ([]*TypeInfo, int, error) {
+	if maxTypes <= 0 {
+		maxTypes = defaultDiagramMaxTypes
+	}
+
+	if focus == "" {
+		if len(d.types) <= maxTypes {
+			return d.types, 0, nil
+		}
+		return d.types[:maxTypes], len(d.types) - maxTypes, nil
+	}
+
+	var center *TypeInfo
+	for _, t := range d.types {
+		if t.Name == focus || d.names[t] == focus || d.ids[t] == focus {
+			center = t
+			break
+		}
+	}
+	if center == nil {
+		return nil, 0, fmt.Errorf("focus type %q not found", focus)
+	}
+
+	related := map[*TypeInfo]bool{center: true}
+	for _, rel := range d.relations {
+		if rel.from == center {
+			related[rel.to] = true
+		}
+		if rel.to == center {
+			related[rel.from] = true
+		}
+	}
+
+	var included []*TypeInfo
+	for _, t := range d.types {
+		if related[t] {
+			included = append(included, t)
+		}
+	}
+	sort.SliceStable(included, func(i, j int) bool {
+		return included[i] == center && included[j] != center
+	})
+	if len(included) > maxTypes {
+		return included[:maxTypes], len(included) - maxTypes, nil
+	}
+	return included, 0, nil
+}
+
+// writeClass renders one type and its members
+func (d *mermaidDiagram) writeClass(b *strings.Builder, t *TypeInfo) {
+	fmt.Fprintf(b, "  class %s {\n", d.ids[t])
+	if t.Kind == InterfaceKind {
+		b.WriteString("    <<interface>>\n")
+	}
+	for _, field := range t.Fields {
+		fmt.Fprintf(b, "    %s%s %s\n", mermaidVisibility(field.Name), field.Name, mermaidType(field.Type))
+	}
+	for _, m := range t.Methods {
+		params := make([]string, len(m.Params))
+		for i, p := range m.Params {
+			params[i] = strings.TrimSpace(p.Name + " " + mermaidType(p.Type))
+		}
+		results := make([]string, len(m.Results))
+		for i, r := range m.Results {
+			results[i] = mermaidType(r)
+		}
+		result := strings.Join(results, ", ")
+		if len(results) > 1 {
+			result = "(" + result + ")"
+		}
+		line := fmt.Sprintf("    %s%s(%s) %s", mermaidVisibility(m.Name), m.Name, strings.Join(params, ", "), result)
+		b.WriteString(strings.TrimRight(line, " ") + "\n")
+	}
+	b.WriteString("  }\n")
+}
+
+// mermaidVisibility returns the Mermaid visibility marker of a Go name
+func mermaidVisibility(name string) string {
+	if ast.IsExported(name) {
+		return "+"
+	}
+	return "-"
+}
+
+// mermaidIdentifier turns a Go type name into a Mermaid class name by
+// replacing everything but letters, digits and underscores
+func mermaidIdentifier(name string) string {
+	var b strings.Builder
+	for _, r := range name {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return strings.TrimRight(b.String(), "_")
+}
+
+// mermaidType rewrites a Go type for a member line. Type arguments use
+// Mermaid's ~T~ generic notation and braces, which would end the class
+// body, are dropped.
+func mermaidType(t string) string {
+	var b strings.Builder
+	var generic []bool
+	for i := 0; i < len(t); i++ {
+		c := t[i]
+		switch c {
+		case '[':
+			isGeneric := i > 0 && isIdentByte(t[i-1]) && !strings.HasSuffix(t[:i], "map")
+			generic = append(generic, isGeneric)
+			if isGeneric {
+				b.WriteByte('~')
+				continue
+			}
+		case ']':
+			if len(generic) > 0 {
+				isGeneric := generic[len(generic)-1]
+				generic = generic[:len(generic)-1]
+				if isGeneric {
+					b.WriteByte('~')
+					continue
+				}
+			}
+		case '{', '}':
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return b.String()
+}
+
+// isIdentByte reports whether c can appear in a Go identifier
+func isIdentByte(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9'
+}
@@ -0,0 +1,742 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+// The encoding below follows proto/analysis.proto by hand so that no
+// protoc step or protobuf module is needed. Field numbers must match the
+// schema, which documents them as stable.
+
+// maxProtoMessageSize bounds the length prefix accepted by DecodeProto
+const maxProtoMessageSize = 64 << 20
+
+// Protobuf wire types
+const (
+	protoVarint  = 0
+	protoFixed64 = 1
+	protoBytes   = 2
+	protoFixed32 = 5
+)
+
+// FileAnalysis is the decoded form of a FileAnalysis message: a parsed
+// file with the functions and types extracted from it
+type FileAnalysis struct {
+	File      ExportFile
+	Functions []FunctionInfo
+	Types     []TypeInfo
+}
+
+// AnalysisStream is the decoded content of an ExportProto stream
+type AnalysisStream struct {
+	Run     ExportRun
+	Files   []FileAnalysis
+	Summary RunSummary
+}
+
+// ExportProto writes the results as a stream of length-delimited protobuf
+// messages, one FileAnalysis per file in path order and then a Summary.
+// Functions and types are included when they have been extracted.
+func (a *ASTAnalyzer) ExportProto(w io.Writer) error {
+	export := a.BuildExport()
+
+	// Keyed by output path, like the exported files
+	a.mu.Lock()
+	root := a.outputRoot()
+	functions := make(map[string][]FunctionInfo)
+	for file, fns := range a.functions {
+		functions[outputPath(root, file)] = fns
+	}
+	types := make(map[string][]TypeInfo)
+	for _, t := range a.types {
+		file := outputPath(root, t.FilePath)
+		types[file] = append(types[file], t)
+	}
+	a.mu.Unlock()
+
+	bw := bufio.NewWriter(w)
+	for _, f := range export.Files {
+		fa := FileAnalysis{File: f, Functions: functions[f.Path], Types: types[f.Path]}
+		if err := writeDelimited(bw, encodeFileAnalysis(fa)); err != nil {
+			return err
+		}
+	}
+	if err := writeDelimited(bw, encodeSummary(export.Run, a.Summarize())); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// DecodeProto reads a stream written by ExportProto
+func DecodeProto(r io.Reader) (*AnalysisStream, error) {
+	var messages [][]byte
+	br := bufio.NewReader(r)
+	for {
+		size, err := binary.ReadUvarint(br)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("reading message size: %w", err)
+		}
+		if size > maxProtoMessageSize {
+			return nil, fmt.Errorf("message of %d bytes exceeds the limit", size)
+		}
+		msg := make([]byte, size)
+		if _, err := io.ReadFull(br, msg); err != nil {
+			return nil, fmt.Errorf("reading message: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+	if len(messages) == 0 {
+		return nil, errors.New("stream has no summary message")
+	}
+
+	stream := &AnalysisStream{}
+	for _, msg := range messages[:len(messages)-1] {
+		fa, err := decodeFileAnalysis(msg)
+		if err != nil {
+			return nil, err
+		}
+		stream.Files = append(stream.Files, fa)
+	}
+	if err := decodeSummary(messages[len(messages)-1], stream); err != nil {
+		return nil, err
+	}
+	return stream, nil
+}
+
+// writeDelimited writes msg preceded by its length
+func writeDelimited(w io.Writer, msg []byte) error {
+	if _, err := w.Write(binary.AppendUvarint(nil, uint64(len(msg)))); err != nil {
+		return err
+	}
+	_, err := w.Write(msg)
+	return err
+}
+
+// protoEncoder appends protobuf fields to a message. Scalar fields are
+// omitted when they hold the zero value, as in proto3.
+type protoEncoder struct {
+	b []byte
+}
+
+// tag writes a field key
+func (e *protoEncoder) tag(field, wireType int) {
+	e.b = binary.AppendUvarint(e.b, uint64(field)<<3|uint64(wireType))
+}
+
+// uint writes a varint field
+func (e *protoEncoder) uint(field int, v uint64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, protoVarint)
+	e.b = binary.AppendUvarint(e.b, v)
+}
+
+// int encodes a signed value as int32 and int64 fields do, negative
+// values taking ten bytes
+func (e *protoEncoder) int(field int, v int64) {
+	e.uint(field, uint64(v))
+}
+
+// bool writes a bool field
+func (e *protoEncoder) bool(field int, v bool) {
+	if v {
+		e.uint(field, 1)
+	}
+}
+
+// string writes a string field
+func (e *protoEncoder) string(field int, s string) {
+	if s != "" {
+		e.bytes(field, []byte(s))
+	}
+}
+
+// bytes writes a length-delimited field even when it is empty, which
+// repeated fields and nested messages need
+func (e *protoEncoder) bytes(field int, b []byte) {
+	e.tag(field, protoBytes)
+	e.b = binary.AppendUvarint(e.b, uint64(len(b)))
+	e.b = append(e.b, b...)
+}
+
+// strings writes a repeated string field
+func (e *protoEncoder) strings(field int, values []string) {
+	for _, s := range values {
+		e.bytes(field, []byte(s))
+	}
+}
+
+// ints writes a packed repeated int32 field
+func (e *protoEncoder) ints(field int, values []int) {
+	if len(values) == 0 {
+		return
+	}
+	var packed []byte
+	for _, v := range values {
+		packed = binary.AppendUvarint(packed, uint64(int64(v)))
+	}
+	e.bytes(field, packed)
+}
+
+// params writes a repeated Param field
+func (e *protoEncoder) params(field int, params []ParamInfo) {
+	for _, p := range params {
+		var m protoEncoder
+		m.string(1, p.Name)
+		m.string(2, p.Type)
+		e.bytes(field, m.b)
+	}
+}
+
+// encodeFileAnalysis encodes a FileAnalysis message
+func encodeFileAnalysis(fa FileAnalysis) []byte {
+	var e protoEncoder
+	f := fa.File
+	e.string(1, f.Path)
+	e.string(2, f.Root)
+	e.int(3, f.ParseTime.Nanoseconds)
+	e.bool(4, f.Success)
+	e.bool(5, f.Generated)
+	e.string(6, f.Error)
+	e.int(7, int64(f.NumFunctions))
+	e.int(8, int64(f.NumMethods))
+	e.int(9, int64(f.NumInterfaces))
+	e.int(10, int64(f.NumStructs))
+	e.uint(11, f.AllocBytes)
+	e.bool(12, f.FromCache)
+	e.bool(13, f.IsTest)
+	e.int(14, f.SizeBytes)
+	e.int(15, int64(f.LineCount))
+	e.int(16, int64(f.NodeCount))
+	e.bool(17, f.Skipped)
+	e.bool(18, f.UsesCgo)
+	e.int(19, int64(f.CgoCalls))
+	for _, fn := range fa.Functions {
+		e.bytes(20, encodeFunction(fn))
+	}
+	for _, t := range fa.Types {
+		e.bytes(21, encodeTypeDecl(t))
+	}
+	if t := f.Timing; t != nil {
+		var m protoEncoder
+		m.int(1, int64(t.Runs))
+		m.int(2, t.Min.Nanoseconds)
+		m.int(3, t.Median.Nanoseconds)
+		m.int(4, t.P95.Nanoseconds)
+		m.int(5, t.StdDev.Nanoseconds)
+		e.bytes(22, m.b)
+	}
+	e.ints(100, f.MapifiableSwitches)
+	e.ints(101, f.PanicSites)
+	e.ints(102, f.RecoverSites)
+	e.strings(103, f.UnrecoveredPanics)
+	e.ints(104, f.InitFuncs)
+	e.ints(105, f.StringConcatInLoop)
+	return e.b
+}
+
+// encodeFunction encodes a Function message
+func encodeFunction(fn FunctionInfo) []byte {
+	var e protoEncoder
+	e.string(1, fn.Name)
+	e.string(2, fn.Receiver)
+	e.string(3, fn.ReceiverName)
+	e.bool(4, fn.IsExported)
+	e.int(5, int64(fn.LineStart))
+	e.int(6, int64(fn.LineEnd))
+	e.string(7, fn.DocComment)
+	e.int(8, int64(fn.Complexity))
+	e.bool(9, fn.IsStub)
+	e.int(10, int64(fn.LabeledJumps))
+	e.int(11, int64(fn.MaxNestingDepth))
+	e.params(20, fn.TypeParams)
+	e.params(21, fn.Params)
+	e.bytes(22, encodeHalstead(fn.Halstead))
+	e.strings(100, fn.Results)
+	e.strings(101, fn.ResolvedParams)
+	e.strings(102, fn.ResolvedResults)
+	return e.b
+}
+
+// encodeHalstead encodes a Halstead message
+func encodeHalstead(m HalsteadMetrics) []byte {
+	var e protoEncoder
+	e.int(1, int64(m.DistinctOperators))
+	e.int(2, int64(m.DistinctOperands))
+	e.int(3, int64(m.TotalOperators))
+	e.int(4, int64(m.TotalOperands))
+	return e.b
+}
+
+// encodeTypeDecl encodes a TypeDecl message and its Methods
+func encodeTypeDecl(t TypeInfo) []byte {
+	var e protoEncoder
+	e.string(1, t.Name)
+	e.string(2, t.Package)
+	e.string(3, t.Kind)
+	e.int(4, int64(t.Line))
+	e.params(20, t.TypeParams)
+	e.params(21, t.Fields)
+	for _, m := range t.Methods {
+		var me protoEncoder
+		me.string(1, m.Name)
+		me.params(20, m.Params)
+		me.strings(100, m.Results)
+		e.bytes(22, me.b)
+	}
+	e.strings(100, t.Embeds)
+	e.strings(101, t.PromotedMethods)
+	return e.b
+}
+
+// encodeSummary encodes a Summary message
+func encodeSummary(run ExportRun, s RunSummary) []byte {
+	var e protoEncoder
+	e.uint(1, uint64(run.SchemaVersion))
+	e.string(2, run.AnalyzerVersion)
+	if !run.StartTime.IsZero() {
+		e.int(3, run.StartTime.UnixNano())
+	}
+	e.string(4, run.Directory)
+	e.int(5, int64(s.TotalFiles))
+	e.int(6, int64(s.Successful))
+	e.int(7, int64(s.Failed))
+	e.int(8, int64(s.Generated))
+	e.int(9, int64(s.TotalParseTime))
+	e.uint(10, s.TotalAllocBytes)
+	e.int(11, int64(s.Functions))
+	e.int(12, int64(s.TestFiles))
+	e.int(13, int64(s.TestFunctions))
+	e.string(14, run.ParseMode)
+	e.int(15, s.TotalBytes)
+	e.int(16, int64(s.TotalLines))
+	e.int(17, int64(s.Skipped))
+	e.int(18, s.SkippedBytes)
+	e.string(19, run.Repository)
+	e.string(100, run.Commit)
+	e.int(101, int64(s.CgoFiles))
+	e.int(102, int64(s.CgoCalls))
+	e.bool(103, run.TypesResolved)
+	e.int(104, int64(s.PanicSites))
+	e.int(105, int64(s.UnrecoveredPanics))
+	e.int(106, int64(s.InitFuncs))
+	return e.b
+}
+
+// protoField is one decoded field: v holds varints, data holds
+// length-delimited content
+type protoField struct {
+	num  int
+	v    uint64
+	data []byte
+}
+
+// decodeFields splits a message into its fields, skipping fixed-width
+// fields that this version of the schema does not use
+func decodeFields(msg []byte, fn func(protoField) error) error {
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return errors.New("proto: malformed field key")
+		}
+		msg = msg[n:]
+		f := protoField{num: int(key >> 3)}
+
+		switch key & 7 {
+		case protoVarint:
+			f.v, n = binary.Uvarint(msg)
+			if n <= 0 {
+				return errors.New("proto: malformed varint")
+			}
+			msg = msg[n:]
+		case protoBytes:
+			size, n := binary.Uvarint(msg)
+			if n <= 0 || size > uint64(len(msg)-n) {
+				return errors.New("proto: malformed length")
+			}
+			f.data = msg[n : n+int(size)]
+			msg = msg[n+int(size):]
+		case protoFixed64, protoFixed32:
+			width := 8
+			if key&7 == protoFixed32 {
+				width = 4
+			}
+			if len(msg) < width {
+				return errors.New("proto: truncated fixed-width field")
+			}
+			msg = msg[width:]
+			continue
+		default:
+			return fmt.Errorf("proto: unsupported wire type %d", key&7)
+		}
+
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeInts decodes a repeated int32 field, whether packed or not
+func decodeInts(f protoField) ([]int, error) {
+	if f.data == nil {
+		return []int{int(int32(f.v))}, nil
+	}
+	var values []int
+	for msg := f.data; len(msg) > 0; {
+		v, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return nil, errors.New("proto: malformed packed varint")
+		}
+		values = append(values, int(int32(v)))
+		msg = msg[n:]
+	}
+	return values, nil
+}
+
+// decodeParam decodes a Param message
+func decodeParam(msg []byte) (ParamInfo, error) {
+	var p ParamInfo
+	err := decodeFields(msg, func(f protoField) error {
+		switch f.num {
+		case 1:
+			p.Name = string(f.data)
+		case 2:
+			p.Type = string(f.data)
+		}
+		return nil
+	})
+	return p, err
+}
+
+// decodeHalstead decodes a Halstead message and derives the remaining
+// metrics from its counts
+func decodeHalstead(msg []byte) (HalsteadMetrics, error) {
+	var m HalsteadMetrics
+	err := decodeFields(msg, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.DistinctOperators = int(int32(f.v))
+		case 2:
+			m.DistinctOperands = int(int32(f.v))
+		case 3:
+			m.TotalOperators = int(int32(f.v))
+		case 4:
+			m.TotalOperands = int(int32(f.v))
+		}
+		return nil
+	})
+	m.derive()
+	return m, err
+}
+
+// decodeFileAnalysis decodes a FileAnalysis message
+func decodeFileAnalysis(msg []byte) (FileAnalysis, error) {
+	var fa FileAnalysis
+	f := &fa.File
+	err := decodeFields(msg, func(pf protoField) error {
+		switch pf.num {
+		case 1:
+			f.Path = string(pf.data)
+		case 2:
+			f.Root = string(pf.data)
+		case 3:
+			f.ParseTime = newExportDuration(time.Duration(int64(pf.v)))
+		case 4:
+			f.Success = pf.v != 0
+		case 5:
+			f.Generated = pf.v != 0
+		case 6:
+			f.Error = string(pf.data)
+		case 7:
+			f.NumFunctions = int(int32(pf.v))
+		case 8:
+			f.NumMethods = int(int32(pf.v))
+		case 9:
+			f.NumInterfaces = int(int32(pf.v))
+		case 10:
+			f.NumStructs = int(int32(pf.v))
+		case 11:
+			f.AllocBytes = pf.v
+		case 12:
+			f.FromCache = pf.v != 0
+		case 13:
+			f.IsTest = pf.v != 0
+		case 14:
+			f.SizeBytes = int64(pf.v)
+		case 15:
+			f.LineCount = int(int32(pf.v))
+		case 16:
+			f.NodeCount = int(int32(pf.v))
+		case 17:
+			f.Skipped = pf.v != 0
+		case 18:
+			f.UsesCgo = pf.v != 0
+		case 19:
+			f.CgoCalls = int(int32(pf.v))
+		case 20:
+			fn, err := decodeFunction(pf.data)
+			if err != nil {
+				return err
+			}
+			fa.Functions = append(fa.Functions, fn)
+		case 21:
+			t, err := decodeTypeDecl(pf.data)
+			if err != nil {
+				return err
+			}
+			t.FilePath = f.Path
+			fa.Types = append(fa.Types, t)
+		case 22:
+			t, err := decodeTiming(pf.data)
+			if err != nil {
+				return err
+			}
+			f.Timing = t
+		case 100:
+			lines, err := decodeInts(pf)
+			if err != nil {
+				return err
+			}
+			f.MapifiableSwitches = append(f.MapifiableSwitches, lines...)
+		case 101:
+			lines, err := decodeInts(pf)
+			if err != nil {
+				return err
+			}
+			f.PanicSites = append(f.PanicSites, lines...)
+		case 102:
+			lines, err := decodeInts(pf)
+			if err != nil {
+				return err
+			}
+			f.RecoverSites = append(f.RecoverSites, lines...)
+		case 103:
+			f.UnrecoveredPanics = append(f.UnrecoveredPanics, string(pf.data))
+		case 104:
+			lines, err := decodeInts(pf)
+			if err != nil {
+				return err
+			}
+			f.InitFuncs = append(f.InitFuncs, lines...)
+		case 105:
+			lines, err := decodeInts(pf)
+			if err != nil {
+				return err
+			}
+			f.StringConcatInLoop = append(f.StringConcatInLoop, lines...)
+		}
+		return nil
+	})
+	if f.ParseTime.Human == "" {
+		f.ParseTime = newExportDuration(0)
+	}
+	return fa, err
+}
+
+// decodeFunction decodes a Function message
+func decodeFunction(msg []byte) (FunctionInfo, error) {
+	var fn FunctionInfo
+	err := decodeFields(msg, func(f protoField) error {
+		switch f.num {
+		case 1:
+			fn.Name = string(f.data)
+		case 2:
+			fn.Receiver = string(f.data)
+		case 3:
+			fn.ReceiverName = string(f.data)
+		case 4:
+			fn.IsExported = f.v != 0
+		case 5:
+			fn.LineStart = int(int32(f.v))
+		case 6:
+			fn.LineEnd = int(int32(f.v))
+		case 7:
+			fn.DocComment = string(f.data)
+		case 8:
+			fn.Complexity = int(int32(f.v))
+		case 9:
+			fn.IsStub = f.v != 0
+		case 10:
+			fn.LabeledJumps = int(int32(f.v))
+		case 11:
+			fn.MaxNestingDepth = int(int32(f.v))
+		case 20, 21:
+			p, err := decodeParam(f.data)
+			if err != nil {
+				return err
+			}
+			if f.num == 20 {
+				fn.TypeParams = append(fn.TypeParams, p)
+			} else {
+				fn.Params = append(fn.Params, p)
+			}
+		case 22:
+			h, err := decodeHalstead(f.data)
+			if err != nil {
+				return err
+			}
+			fn.Halstead = h
+		case 100:
+			fn.Results = append(fn.Results, string(f.data))
+		case 101:
+			fn.ResolvedParams = append(fn.ResolvedParams, string(f.data))
+		case 102:
+			fn.ResolvedResults = append(fn.ResolvedResults, string(f.data))
+		}
+		return nil
+	})
+	return fn, err
+}
+
+// decodeTypeDecl decodes a TypeDecl message
+func decodeTypeDecl(msg []byte) (TypeInfo, error) {
+	var t TypeInfo
+	err := decodeFields(msg, func(f protoField) error {
+		switch f.num {
+		case 1:
+			t.Name = string(f.data)
+		case 2:
+			t.Package = string(f.data)
+		case 3:
+			t.Kind = string(f.data)
+		case 4:
+			t.Line = int(int32(f.v))
+		case 20, 21:
+			p, err := decodeParam(f.data)
+			if err != nil {
+				return err
+			}
+			if f.num == 20 {
+				t.TypeParams = append(t.TypeParams, p)
+			} else {
+				t.Fields = append(t.Fields, p)
+			}
+		case 22:
+			m, err := decodeMethod(f.data)
+			if err != nil {
+				return err
+			}
+			t.Methods = append(t.Methods, m)
+		case 100:
+			t.Embeds = append(t.Embeds, string(f.data))
+		case 101:
+			t.PromotedMethods = append(t.PromotedMethods, string(f.data))
+		}
+		return nil
+	})
+	return t, err
+}
+
+// decodeMethod decodes a Method message
+func decodeMethod(msg []byte) (MethodInfo, error) {
+	var m MethodInfo
+	err := decodeFields(msg, func(f protoField) error {
+		switch f.num {
+		case 1:
+			m.Name = string(f.data)
+		case 20:
+			p, err := decodeParam(f.data)
+			if err != nil {
+				return err
+			}
+			m.Params = append(m.Params, p)
+		case 100:
+			m.Results = append(m.Results, string(f.data))
+		}
+		return nil
+	})
+	return m, err
+}
+
+// decodeTiming decodes a Timing message
+func decodeTiming(msg []byte) (*ExportTiming, error) {
+	var t ExportTiming
+	err := decodeFields(msg, func(f protoField) error {
+		switch f.num {
+		case 1:
+			t.Runs = int(int32(f.v))
+		case 2:
+			t.Min = newExportDuration(time.Duration(int64(f.v)))
+		case 3:
+			t.Median = newExportDuration(time.Duration(int64(f.v)))
+		case 4:
+			t.P95 = newExportDuration(time.Duration(int64(f.v)))
+		case 5:
+			t.StdDev = newExportDuration(time.Duration(int64(f.v)))
+		}
+		return nil
+	})
+	return &t, err
+}
+
+// decodeSummary decodes the Summary message into stream
+func decodeSummary(msg []byte, stream *AnalysisStream) error {
+	run, s := &stream.Run, &stream.Summary
+	return decodeFields(msg, func(f protoField) error {
+		switch f.num {
+		case 1:
+			run.SchemaVersion = int(f.v)
+		case 2:
+			run.AnalyzerVersion = string(f.data)
+		case 3:
+			run.StartTime = time.Unix(0, int64(f.v))
+		case 4:
+			run.Directory = string(f.data)
+		case 5:
+			s.TotalFiles = int(int32(f.v))
+		case 6:
+			s.Successful = int(int32(f.v))
+		case 7:
+			s.Failed = int(int32(f.v))
+		case 8:
+			s.Generated = int(int32(f.v))
+		case 9:
+			s.TotalParseTime = time.Duration(int64(f.v))
+		case 10:
+			s.TotalAllocBytes = f.v
+		case 11:
+			s.Functions = int(int32(f.v))
+		case 12:
+			s.TestFiles = int(int32(f.v))
+		case 13:
+			s.TestFunctions = int(int32(f.v))
+		case 14:
+			run.ParseMode = string(f.data)
+		case 15:
+			s.TotalBytes = int64(f.v)
+		case 16:
+			s.TotalLines = int(int32(f.v))
+		case 17:
+			s.Skipped = int(int32(f.v))
+		case 18:
+			s.SkippedBytes = int64(f.v)
+		case 19:
+			run.Repository = string(f.data)
+		case 100:
+			run.Commit = string(f.data)
+		case 101:
+			s.CgoFiles = int(int32(f.v))
+		case 102:
+			s.CgoCalls = int(int32(f.v))
+		case 103:
+			run.TypesResolved = f.v != 0
+		case 104:
+			s.PanicSites = int(int32(f.v))
+		case 105:
+			s.UnrecoveredPanics = int(int32(f.v))
+		case 106:
+			s.InitFuncs = int(int32(f.v))
+		}
+		return nil
+	})
+}
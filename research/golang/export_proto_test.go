@@ -0,0 +1,243 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// protoFixture is a package using every part of the schema
+var protoFixture = map[string]string{
+	"shapes.go": `package shapes
+
+import "fmt"
+
+func init() {}
+
+// Shape is anything with an area
+type Shape interface {
+	Area() float64
+}
+
+type base struct{ ID int }
+
+// Square is a shape
+type Square[T any] struct {
+	base
+	Side  float64 ` + "`json:\"side\"`" + `
+	Label T
+}
+
+// Area returns the area of the square
+func (s *Square[T]) Area() float64 { return s.Side * s.Side }
+
+// Name names a number
+func Name(n int) (string, error) {
+	switch n {
+	case 1:
+		return "one", nil
+	case 2:
+		return "two", nil
+	case 3:
+		return "three", nil
+	case 4:
+		return "four", nil
+	case 5:
+		return "five", nil
+	}
+	s := ""
+	for i := 0; i < n; i++ {
+		s += "x"
+	}
+	if n < 0 {
+		panic(fmt.Sprint("negative: ", n))
+	}
+	return s, nil
+}
+`,
+	"broken.go": "package shapes\n\nfunc Broken( {\n",
+}
+
+func TestExportProtoRoundTrip(t *testing.T) {
+	dir := writeTree(t, protoFixture)
+	a := quietAnalyzer()
+	if _, err := a.BenchmarkDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := extractAllFunctions(a); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.ExtractTypes(dir); err != nil {
+		t.Fatal(err)
+	}
+
+	var out bytes.Buffer
+	if err := a.ExportProto(&out); err != nil {
+		t.Fatal(err)
+	}
+	stream, err := DecodeProto(&out)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	export := a.BuildExport()
+	if len(stream.Files) != len(export.Files) {
+		t.Fatalf("decoded %d files, want %d", len(stream.Files), len(export.Files))
+	}
+	for i, fa := range stream.Files {
+		if !reflect.DeepEqual(fa.File, export.Files[i]) {
+			t.Errorf("file %d:\ngot  %+v\nwant %+v", i, fa.File, export.Files[i])
+		}
+	}
+	if got, want := stream.Summary, a.Summarize(); !reflect.DeepEqual(got, want) {
+		t.Errorf("summary:\ngot  %+v\nwant %+v", got, want)
+	}
+	run := export.Run
+	if !stream.Run.StartTime.Equal(run.StartTime) {
+		t.Errorf("start time %v, want %v", stream.Run.StartTime, run.StartTime)
+	}
+	stream.Run.StartTime = run.StartTime
+	if stream.Run != run {
+		t.Errorf("run:\ngot  %+v\nwant %+v", stream.Run, run)
+	}
+
+	var shapes FileAnalysis
+	for _, fa := range stream.Files {
+		if fa.File.Path == "shapes.go" {
+			shapes = fa
+		}
+	}
+	functions := a.functions[dir+"/shapes.go"]
+	if len(shapes.Functions) != len(functions) || len(functions) == 0 {
+		t.Fatalf("decoded %d functions, want %d", len(shapes.Functions), len(functions))
+	}
+	for i, got := range shapes.Functions {
+		want := functions[i]
+		if got.Name != want.Name || got.Receiver != want.Receiver || got.LineStart != want.LineStart ||
+			got.LineEnd != want.LineEnd || got.Complexity != want.Complexity || got.DocComment != want.DocComment ||
+			!reflect.DeepEqual(got.Params, want.Params) || !reflect.DeepEqual(got.Results, want.Results) ||
+			!reflect.DeepEqual(got.TypeParams, want.TypeParams) ||
+			got.Halstead.DistinctOperators != want.Halstead.DistinctOperators ||
+			got.Halstead.TotalOperands != want.Halstead.TotalOperands {
+			t.Errorf("function %d:\ngot  %+v\nwant %+v", i, got, want)
+		}
+	}
+
+	types := make(map[string]TypeInfo)
+	for _, typ := range shapes.Types {
+		types[typ.Name] = typ
+	}
+	for _, want := range a.types {
+		got, ok := types[want.Name]
+		if !ok {
+			t.Errorf("type %s not decoded", want.Name)
+			continue
+		}
+		if got.Kind != want.Kind || got.Line != want.Line || !reflect.DeepEqual(got.Fields, want.Fields) ||
+			!reflect.DeepEqual(got.Embeds, want.Embeds) || !reflect.DeepEqual(got.TypeParams, want.TypeParams) ||
+			len(got.Methods) != len(want.Methods) {
+			t.Errorf("type %s:\ngot  %+v\nwant %+v", want.Name, got, want)
+		}
+	}
+}
+
+func TestExportProtoGolden(t *testing.T) {
+	a := goldenAnalyzer()
+	var out bytes.Buffer
+	if err := a.ExportProto(&out); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "analysis.golden.pb", out.Bytes())
+
+	stream, err := DecodeProto(bytes.NewReader(out.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stream.Files) != 2 || stream.Files[0].Functions[0].Name != "Sum" || stream.Files[0].Types[0].Embeds[0] != "io.Reader" {
+		t.Errorf("golden stream decodes to %+v", stream.Files)
+	}
+}
+
+// TestProtoWireFormat checks encoded messages against bytes written out by
+// hand from the protobuf encoding rules, so the encoder cannot drift from
+// what protoc-generated code reads
+func TestProtoWireFormat(t *testing.T) {
+	tests := []struct {
+		name string
+		got  []byte
+		want string
+	}{
+		{
+			name: "halstead",
+			got:  encodeHalstead(HalsteadMetrics{DistinctOperators: 3, TotalOperands: 150}),
+			// 1: varint 3; 4: varint 150
+			want: "0803 209601",
+		},
+		{
+			name: "method",
+			got:  encodeMethodFixture(),
+			// 1: "Get"; 20: Param{1: "k", 2: "int"}; 100: "error"
+			want: "0a03476574 a20108 0a016b 1203696e74 a206056572726f72",
+		},
+		{
+			name: "summary",
+			got: encodeSummary(
+				ExportRun{SchemaVersion: 1, Commit: "ab"},
+				RunSummary{TotalFiles: 2, InitFuncs: 1},
+			),
+			// 1: varint 1; 5: varint 2; 100: "ab"; 106: varint 1
+			want: "0801 2802 a2060261 62 d00601",
+		},
+		{
+			name: "file lines",
+			got: encodeFileAnalysis(FileAnalysis{File: ExportFile{
+				Path:               "a.go",
+				MapifiableSwitches: []int{3, 300},
+			}}),
+			// 1: "a.go"; 100: packed [3, 300]
+			want: "0a04612e676f a2060303ac02",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got, want := hex.EncodeToString(tt.got), strings.ReplaceAll(tt.want, " ", ""); got != want {
+				t.Errorf("encoded %s, want %s", got, want)
+			}
+		})
+	}
+}
+
+// encodeMethodFixture encodes a type with one method and returns the
+// Method message alone
+func encodeMethodFixture() []byte {
+	var msg []byte
+	t := TypeInfo{Methods: []MethodInfo{{Name: "Get", Params: []ParamInfo{{Name: "k", Type: "int"}}, Results: []string{"error"}}}}
+	decodeFields(encodeTypeDecl(t), func(f protoField) error {
+		if f.num == 22 {
+			msg = f.data
+		}
+		return nil
+	})
+	return msg
+}
+
+func TestDecodeProtoErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input []byte
+	}{
+		{name: "empty", input: nil},
+		{name: "truncated size", input: []byte{0x80}},
+		{name: "truncated message", input: []byte{0x05, 0x08}},
+		{name: "malformed field", input: []byte{0x02, 0x0a, 0x05}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := DecodeProto(bytes.NewReader(tt.input)); err == nil {
+				t.Error("DecodeProto() succeeded, want error")
+			}
+		})
+	}
+}
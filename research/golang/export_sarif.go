@@ -0,0 +1,204 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/url"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// sarifSchema and sarifVersion identify the SARIF format written by
+// ExportSARIF
+const (
+	sarifSchema  = "https://json.schemastore.org/sarif-2.1.0.json"
+	sarifVersion = "2.1.0"
+)
+
+// sarifRootBaseID names the analyzed root that result URIs are relative to
+const sarifRootBaseID = "SRCROOT"
+
+// sarifRule describes a finding category for code scanning tools
+type sarifRule struct {
+	Name        string
+	Description string
+	Level       string // "error", "warning" or "note"
+}
+
+// sarifRules holds the rule metadata of every finding category. Categories
+// missing here are still exported with a generic description.
+var sarifRules = map[string]sarifRule{
+	CategoryUnderscore:      {"Underscores", "Identifiers should use mixedCaps instead of underscores", "warning"},
+	CategoryStutter:         {"Stutter", "Exported names should not repeat the package name", "note"},
+	CategoryInitialism:      {"Initialisms", "Initialisms should be written in a consistent case", "warning"},
+	CategorySingleLetter:    {"SingleLetterExport", "Exported names should be more than a single letter", "note"},
+	CategorySignature:       {"LongSignature", "Functions should not take or return too many values", "warning"},
+	CategoryImportCycle:     {"ImportCycle", "Packages must not import each other in a cycle", "error"},
+	CategoryTestImportCycle: {"TestImportCycle", "Test files should not close an import cycle", "warning"},
+	CategoryNearImportCycle: {"NearImportCycle", "Packages should not depend on their parent package", "note"},
+	CategoryUnusedExport:    {"UnusedExport", "Exported names should be used outside their package", "note"},
+	CategoryTestOnlyExport:  {"TestOnlyExport", "Exported names should not exist only for tests", "note"},
+	CategoryUnreachable:     {"Unreachable", "Functions should be reachable from main or init", "note"},
+	CategoryReceiver:        {"ReceiverNames", "Methods of a type should name their receiver consistently", "note"},
+}
+
+// sarifLog is the root object of a SARIF file
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+// sarifRun holds the results of one analysis run
+type sarifRun struct {
+	Tool               sarifTool                   `json:"tool"`
+	OriginalURIBaseIDs map[string]sarifArtifactURI `json:"originalUriBaseIds,omitempty"`
+	Results            []sarifResult               `json:"results"`
+}
+
+// sarifTool describes the analyzer
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+// sarifDriver names the analyzer and the rules it checks
+type sarifDriver struct {
+	Name    string                `json:"name"`
+	Version string                `json:"version"`
+	Rules   []sarifRuleDescriptor `json:"rules"`
+}
+
+// sarifRuleDescriptor is the serialized form of a sarifRule
+type sarifRuleDescriptor struct {
+	ID                   string             `json:"id"`
+	Name                 string             `json:"name"`
+	ShortDescription     sarifMessage       `json:"shortDescription"`
+	DefaultConfiguration sarifConfiguration `json:"defaultConfiguration"`
+}
+
+// sarifConfiguration holds the default level of a rule
+type sarifConfiguration struct {
+	Level string `json:"level"`
+}
+
+// sarifMessage is a plain text message
+type sarifMessage struct {
+	Text string `json:"text"`
+}
+
+// sarifResult is one finding
+type sarifResult struct {
+	RuleID    string          `json:"ruleId"`
+	RuleIndex int             `json:"ruleIndex"`
+	Level     string          `json:"level"`
+	Message   sarifMessage    `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+// sarifLocation wraps the file position of a result
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+// sarifPhysicalLocation is a region of a file
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifactURI `json:"artifactLocation"`
+	Region           sarifRegion      `json:"region"`
+}
+
+// sarifArtifactURI is a file URI, relative to uriBaseId when it is set
+type sarifArtifactURI struct {
+	URI       string `json:"uri"`
+	URIBaseID string `json:"uriBaseId,omitempty"`
+}
+
+// sarifRegion is a position within a file
+type sarifRegion struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn,omitempty"`
+}
+
+// ExportSARIF writes the recorded findings as a SARIF 2.1.0 log for code
+// scanning tools such as GitHub's. Each finding category becomes a rule
+// and file URIs are relative to the analyzed root.
+func (a *ASTAnalyzer) ExportSARIF(w io.Writer) error {
+	export := a.BuildExport()
+
+	// Rules are listed once per category, in name order
+	var categories []string
+	seen := make(map[string]bool)
+	for _, f := range export.Findings {
+		if !seen[f.Category] {
+			seen[f.Category] = true
+			categories = append(categories, f.Category)
+		}
+	}
+	sort.Strings(categories)
+
+	ruleIndex := make(map[string]int, len(categories))
+	rules := make([]sarifRuleDescriptor, len(categories))
+	for i, category := range categories {
+		rule := sarifRuleFor(category)
+		ruleIndex[category] = i
+		rules[i] = sarifRuleDescriptor{
+			ID:                   category,
+			Name:                 rule.Name,
+			ShortDescription:     sarifMessage{Text: rule.Description},
+			DefaultConfiguration: sarifConfiguration{Level: rule.Level},
+		}
+	}
+
+	run := sarifRun{
+		Tool: sarifTool{Driver: sarifDriver{
+			Name:    "ast-benchmark",
+			Version: AnalyzerVersion,
+			Rules:   rules,
+		}},
+		Results: []sarifResult{},
+	}
+	if root, err := filepath.Abs(a.outputRoot()); err == nil && a.outputRoot() != "" {
+		run.OriginalURIBaseIDs = map[string]sarifArtifactURI{
+			sarifRootBaseID: {URI: (&url.URL{Scheme: "file", Path: filepath.ToSlash(root) + "/"}).String()},
+		}
+	}
+
+	for _, f := range export.Findings {
+		message := f.Message
+		if f.Suggestion != "" {
+			message += " (suggestion: " + f.Suggestion + ")"
+		}
+		run.Results = append(run.Results, sarifResult{
+			RuleID:    f.Category,
+			RuleIndex: ruleIndex[f.Category],
+			Level:     sarifRuleFor(f.Category).Level,
+			Message:   sarifMessage{Text: message},
+			Locations: []sarifLocation{{PhysicalLocation: sarifPhysicalLocation{
+				ArtifactLocation: a.sarifArtifact(f.File),
+				Region:           sarifRegion{StartLine: f.Line, StartColumn: f.Column},
+			}}},
+		})
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{Schema: sarifSchema, Version: sarifVersion, Runs: []sarifRun{run}})
+}
+
+// sarifRuleFor returns the rule metadata of a category
+func sarifRuleFor(category string) sarifRule {
+	if rule, ok := sarifRules[category]; ok {
+		return rule
+	}
+	return sarifRule{Name: category, Description: "Finding reported by the " + category + " check", Level: "warning"}
+}
+
+// sarifArtifact locates an exported path, which is relative to the output
+// root unless the file lies outside it
+func (a *ASTAnalyzer) sarifArtifact(file string) sarifArtifactURI {
+	uri := (&url.URL{Path: file}).String()
+	if a.outputRoot() == "" || filepath.IsAbs(filepath.FromSlash(file)) || strings.HasPrefix(file, "../") {
+		return sarifArtifactURI{URI: uri}
+	}
+	return sarifArtifactURI{URI: uri, URIBaseID: sarifRootBaseID}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// sarifFixture runs every pass that records findings over testdata/sarif,
+// a module with at least one finding of each category
+func sarifFixture(t *testing.T) *ASTAnalyzer {
+	t.Helper()
+	dir := filepath.Join("testdata", "sarif")
+	a := quietAnalyzer()
+	a.RootDir = dir
+	if _, err := a.CheckConventions(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.CheckSignatureComplexity(dir, DefaultMaxParams, DefaultMaxResults); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.CheckReceiverConsistency(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.FindImportCycles(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.UnusedExports(dir); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := a.Reachability(dir); err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+func TestExportSARIF(t *testing.T) {
+	a := sarifFixture(t)
+	var out bytes.Buffer
+	if err := a.ExportSARIF(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	// The root's file URI depends on where the repository is checked out
+	root, err := filepath.Abs(filepath.Join("testdata", "sarif"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	golden := strings.ReplaceAll(out.String(), "file://"+filepath.ToSlash(root)+"/", "file:///SRCROOT/")
+	checkGolden(t, "sarif.golden.json", []byte(golden))
+
+	var log sarifLog
+	if err := json.Unmarshal(out.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != sarifVersion || log.Schema != sarifSchema || len(log.Runs) != 1 {
+		t.Fatalf("log header %s %s with %d runs", log.Schema, log.Version, len(log.Runs))
+	}
+	run := log.Runs[0]
+	if base, ok := run.OriginalURIBaseIDs[sarifRootBaseID]; !ok || !strings.HasPrefix(base.URI, "file://") || !strings.HasSuffix(base.URI, "/") {
+		t.Errorf("root base %+v", run.OriginalURIBaseIDs)
+	}
+
+	categories := make([]string, 0, len(sarifRules))
+	for category := range sarifRules {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	for i, rule := range run.Tool.Driver.Rules {
+		if i > 0 && run.Tool.Driver.Rules[i-1].ID >= rule.ID {
+			t.Errorf("rule %s out of order", rule.ID)
+		}
+	}
+
+	for _, category := range categories {
+		t.Run(category, func(t *testing.T) {
+			index := -1
+			for i, rule := range run.Tool.Driver.Rules {
+				if rule.ID == category {
+					index = i
+				}
+			}
+			if index < 0 {
+				t.Fatalf("no rule for %s", category)
+			}
+			rule := run.Tool.Driver.Rules[index]
+			if want := sarifRules[category]; rule.Name != want.Name || rule.ShortDescription.Text != want.Description || rule.DefaultConfiguration.Level != want.Level {
+				t.Errorf("rule %+v, want %+v", rule, want)
+			}
+
+			results := 0
+			for _, r := range run.Results {
+				if r.RuleID != category {
+					continue
+				}
+				results++
+				if r.RuleIndex != index || r.Level != rule.DefaultConfiguration.Level || r.Message.Text == "" {
+					t.Errorf("result %+v", r)
+				}
+				if len(r.Locations) != 1 {
+					t.Fatalf("result has %d locations", len(r.Locations))
+				}
+				loc := r.Locations[0].PhysicalLocation
+				if loc.ArtifactLocation.URIBaseID != sarifRootBaseID || strings.HasPrefix(loc.ArtifactLocation.URI, "/") ||
+					!strings.HasSuffix(loc.ArtifactLocation.URI, ".go") || loc.Region.StartLine < 1 {
+					t.Errorf("location %+v", loc)
+				}
+			}
+			if results == 0 {
+				t.Errorf("no %s results", category)
+			}
+		})
+	}
+}
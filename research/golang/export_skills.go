@@ -0,0 +1,84 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/study-game/research/game"
+)
+
+// SkillTree builds the skill tree of the call graph under dir from the
+// function root, matched by ID, name or Type.Method as GraphRoot is. Skills
+// cost the difficulty level of their function and IDs are relative to
+// dir. maxDepth limits the tree as game.SkillTreeBuilder.MaxDepth does.
+func (a *ASTAnalyzer) SkillTree(dir, root string, maxDepth int) (*game.SkillTree, error) {
+	cg, err := a.BuildCallGraph(dir)
+	if err != nil {
+		return nil, err
+	}
+	metrics, err := a.DifficultyMetrics(dir)
+	if err != nil {
+		return nil, err
+	}
+	levels := make(map[string]int)
+	for _, d := range (game.DifficultyScorer{}).Score(metrics) {
+		levels[d.ID] = d.Level
+	}
+
+	declared := cg.declaredIDs(func(id string) bool { return !cg.Nodes[id].Dynamic })
+	relative := make(map[string]string, len(declared))
+	graph := &game.CallGraph{Edges: make(map[string][]string)}
+	var matches []string
+	for _, id := range declared {
+		node := cg.Nodes[id]
+		relative[id] = functionNodeID(outputPath(dir, node.Package), node.Receiver, node.Name)
+		graph.Nodes = append(graph.Nodes, game.CallNode{ID: relative[id], Name: node.Name, Cost: levels[id]})
+		if root == relative[id] || root == node.Name || root == node.Receiver+"."+node.Name {
+			matches = append(matches, relative[id])
+		}
+	}
+	for _, id := range declared {
+		for _, callee := range cg.Edges[id] {
+			if to, ok := relative[callee]; ok {
+				graph.Edges[relative[id]] = append(graph.Edges[relative[id]], to)
+			}
+		}
+	}
+
+	switch len(matches) {
+	case 0:
+		return nil, fmt.Errorf("skill tree root %q not found", root)
+	case 1:
+		return game.SkillTreeBuilder{MaxDepth: maxDepth}.Build(graph, matches[0])
+	default:
+		sort.Strings(matches)
+		return nil, fmt.Errorf("skill tree root %q is ambiguous: %s", root, strings.Join(matches, ", "))
+	}
+}
+
+// runSkills runs the skills command: it writes the skill tree of the
+// directory given in args as JSON
+func runSkills(args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("skills", flag.ExitOnError)
+	root := flags.String("root", "main", "function the tree starts from, by ID, name or Type.Method")
+	depth := flags.Int("depth", game.DefaultSkillDepth, "prune skills deeper than this")
+	flags.Parse(args)
+	dir := "."
+	switch flags.NArg() {
+	case 0:
+	case 1:
+		dir = flags.Arg(0)
+	default:
+		return errors.New("skills takes one directory")
+	}
+
+	tree, err := NewASTAnalyzer().SkillTree(dir, *root, *depth)
+	if err != nil {
+		return err
+	}
+	return tree.WriteJSON(w)
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// sqliteMigrations are applied in order on open. The schema version is the
+// number of migrations applied; never edit a released migration, append a
+// new one instead.
+var sqliteMigrations = []string{
+	`CREATE TABLE runs (
+		id               INTEGER PRIMARY KEY AUTOINCREMENT,
+		started_at       TEXT NOT NULL,
+		directory        TEXT NOT NULL,
+		analyzer_version TEXT NOT NULL
+	);
+	CREATE TABLE files (
+		run_id         INTEGER NOT NULL REFERENCES runs(id),
+		path           TEXT NOT NULL,
+		content_hash   TEXT NOT NULL,
+		parse_time_ns  INTEGER NOT NULL,
+		success        INTEGER NOT NULL,
+		generated      INTEGER NOT NULL,
+		num_functions  INTEGER NOT NULL,
+		num_methods    INTEGER NOT NULL,
+		num_interfaces INTEGER NOT NULL,
+		num_structs    INTEGER NOT NULL,
+		error          TEXT NOT NULL,
+		PRIMARY KEY (run_id, path)
+	);
+	CREATE INDEX files_content_hash ON files(content_hash);
+	CREATE TABLE functions (
+		run_id     INTEGER NOT NULL REFERENCES runs(id),
+		file       TEXT NOT NULL,
+		name       TEXT NOT NULL,
+		receiver   TEXT NOT NULL,
+		exported   INTEGER NOT NULL,
+		line_start INTEGER NOT NULL,
+		line_end   INTEGER NOT NULL,
+		complexity INTEGER NOT NULL
+	);
+	CREATE INDEX functions_run ON functions(run_id, file, receiver, name);
+	CREATE TABLE findings (
+		run_id     INTEGER NOT NULL REFERENCES runs(id),
+		category   TEXT NOT NULL,
+		file       TEXT NOT NULL,
+		line       INTEGER NOT NULL,
+		identifier TEXT NOT NULL,
+		message    TEXT NOT NULL,
+		suggestion TEXT NOT NULL
+	);`,
+	`ALTER TABLE runs ADD COLUMN parse_mode TEXT NOT NULL DEFAULT 'standard';`,
+	`ALTER TABLE runs ADD COLUMN repository TEXT NOT NULL DEFAULT '';
+	ALTER TABLE runs ADD COLUMN commit_sha TEXT NOT NULL DEFAULT '';`,
+}
+
+// ResultsDB stores analysis runs in a SQLite database so that several
+// runs can be compared with SQL
+type ResultsDB struct {
+	db *sql.DB
+}
+
+// SlowFile is a row of the slowest-files query
+type SlowFile struct {
+	RunID     int64
+	Path      string
+	ParseTime time.Duration
+}
+
+// ComplexityChange is a function whose complexity differs between runs
+type ComplexityChange struct {
+	File          string
+	Receiver      string
+	Name          string
+	OldComplexity int
+	NewComplexity int
+}
+
+// OpenResultsDB opens or creates the database at path and migrates its
+// schema to the current version
+func OpenResultsDB(path string) (*ResultsDB, error) {
+	db, err := sql.Open("sqlite", path)
+	if err != nil {
+		return nil, err
+	}
+
+	rdb := &ResultsDB{db: db}
+	if err := rdb.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return rdb, nil
+}
+
+// Close closes the database
+func (d *ResultsDB) Close() error {
+	return d.db.Close()
+}
+
+// migrate applies the migrations the database has not seen yet
+func (d *ResultsDB) migrate() error {
+	if _, err := d.db.Exec(`CREATE TABLE IF NOT EXISTS schema_version (version INTEGER NOT NULL)`); err != nil {
+		return err
+	}
+
+	var version int
+	err := d.db.QueryRow(`SELECT version FROM schema_version`).Scan(&version)
+	switch {
+	case err == sql.ErrNoRows:
+		if _, err := d.db.Exec(`INSERT INTO schema_version (version) VALUES (0)`); err != nil {
+			return err
+		}
+	case err != nil:
+		return err
+	}
+
+	if version > len(sqliteMigrations) {
+		return fmt.Errorf("database schema version %d is newer than supported version %d",
+			version, len(sqliteMigrations))
+	}
+
+	for i := version; i < len(sqliteMigrations); i++ {
+		tx, err := d.db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(sqliteMigrations[i]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("migration %d: %w", i+1, err)
+		}
+		if _, err := tx.Exec(`UPDATE schema_version SET version = ?`, i+1); err != nil {
+			tx.Rollback()
+			return err
+		}
+		if err := tx.Commit(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SaveRun stores the analyzer's results as a new run and returns its ID
+func (d *ResultsDB) SaveRun(a *ASTAnalyzer) (int64, error) {
+	export := a.BuildExport()
+	sources := a.sourcePaths()
+
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`INSERT INTO runs (started_at, directory, analyzer_version, parse_mode, repository, commit_sha) VALUES (?, ?, ?, ?, ?, ?)`,
+		export.Run.StartTime.UTC().Format(time.RFC3339Nano),
+		export.Run.Directory,
+		export.Run.AnalyzerVersion,
+		export.Run.ParseMode,
+		export.Run.Repository,
+		export.Run.Commit)
+	if err != nil {
+		return 0, err
+	}
+	runID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+
+	for _, f := range export.Files {
+		_, err := tx.Exec(`INSERT INTO files VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			runID, f.Path, contentHash(sources[f.Path]), f.ParseTime.Nanoseconds, f.Success, f.Generated,
+			f.NumFunctions, f.NumMethods, f.NumInterfaces, f.NumStructs, f.Error)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for _, fn := range export.Functions {
+		_, err := tx.Exec(`INSERT INTO functions VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
+			runID, fn.File, fn.Name, fn.Receiver, fn.IsExported, fn.LineStart, fn.LineEnd, fn.Complexity)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	for _, f := range export.Findings {
+		_, err := tx.Exec(`INSERT INTO findings VALUES (?, ?, ?, ?, ?, ?, ?)`,
+			runID, f.Category, f.File, f.Line, f.Identifier, f.Message, f.Suggestion)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return runID, tx.Commit()
+}
+
+// SlowestFiles returns the slowest successfully parsed files across all
+// runs
+func (d *ResultsDB) SlowestFiles(limit int) ([]SlowFile, error) {
+	rows, err := d.db.Query(`
+		SELECT run_id, path, parse_time_ns FROM files
+		WHERE success = 1
+		ORDER BY parse_time_ns DESC, run_id, path
+		LIMIT ?`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var files []SlowFile
+	for rows.Next() {
+		var f SlowFile
+		var ns int64
+		if err := rows.Scan(&f.RunID, &f.Path, &ns); err != nil {
+			return nil, err
+		}
+		f.ParseTime = time.Duration(ns)
+		files = append(files, f)
+	}
+	return files, rows.Err()
+}
+
+// ComplexityIncreases returns functions present in both runs whose
+// cyclomatic complexity grew from oldRun to newRun
+func (d *ResultsDB) ComplexityIncreases(oldRun, newRun int64) ([]ComplexityChange, error) {
+	rows, err := d.db.Query(`
+		SELECT n.file, n.receiver, n.name, o.complexity, n.complexity
+		FROM functions n
+		JOIN functions o
+		  ON o.file = n.file AND o.receiver = n.receiver AND o.name = n.name
+		WHERE o.run_id = ? AND n.run_id = ? AND n.complexity > o.complexity
+		ORDER BY n.complexity - o.complexity DESC, n.file, n.name`, oldRun, newRun)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var changes []ComplexityChange
+	for rows.Next() {
+		var c ComplexityChange
+		if err := rows.Scan(&c.File, &c.Receiver, &c.Name, &c.OldComplexity, &c.NewComplexity); err != nil {
+			return nil, err
+		}
+		changes = append(changes, c)
+	}
+	return changes, rows.Err()
+}
+
+// contentHash returns the hex SHA-256 of a file, or an empty string when
+// it cannot be read
+func contentHash(path string) string {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// sqliteRun analyzes a fixture tree and saves it as a run
+func sqliteRun(t *testing.T, db *ResultsDB, files map[string]string) int64 {
+	t.Helper()
+	a := quietAnalyzer()
+	if _, err := a.BenchmarkDirectory(writeTree(t, files)); err != nil {
+		t.Fatal(err)
+	}
+	if err := extractAllFunctions(a); err != nil {
+		t.Fatal(err)
+	}
+	id, err := db.SaveRun(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestResultsDBComplexityIncreases(t *testing.T) {
+	const unchanged = "package a\n\nfunc Same() {}\n"
+	old := map[string]string{
+		"same.go": unchanged,
+		"a.go": `package a
+
+func Grows(x int) int { return x }
+
+func Steady(x int) int {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+
+func Shrinks(x int) int {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+
+func Removed() {}
+
+func (s *S) Method(x int) {}
+
+type S struct{}
+`,
+	}
+	updated := map[string]string{
+		"same.go": unchanged,
+		"a.go": `package a
+
+func Grows(x int) int {
+	if x > 0 {
+		return 1
+	}
+	if x < -10 || x == 5 {
+		return 2
+	}
+	return x
+}
+
+func Steady(x int) int {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+
+func Shrinks(x int) int { return x }
+
+func Added(x int) int {
+	if x > 0 {
+		return 1
+	}
+	return 0
+}
+
+func (s *S) Method(x int) {
+	for i := 0; i < x; i++ {
+	}
+}
+
+type S struct{}
+`,
+	}
+
+	db, err := OpenResultsDB(filepath.Join(t.TempDir(), "results.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	oldRun := sqliteRun(t, db, old)
+	newRun := sqliteRun(t, db, updated)
+	if oldRun == newRun {
+		t.Fatalf("both runs got ID %d", oldRun)
+	}
+
+	tests := []struct {
+		name     string
+		from, to int64
+		want     []ComplexityChange
+	}{
+		{
+			name: "old to new",
+			from: oldRun, to: newRun,
+			want: []ComplexityChange{
+				{File: "a.go", Name: "Grows", OldComplexity: 1, NewComplexity: 4},
+				{File: "a.go", Receiver: "*S", Name: "Method", OldComplexity: 1, NewComplexity: 2},
+			},
+		},
+		{
+			name: "new to old",
+			from: newRun, to: oldRun,
+			want: []ComplexityChange{
+				{File: "a.go", Name: "Shrinks", OldComplexity: 1, NewComplexity: 2},
+			},
+		},
+		{name: "same run", from: newRun, to: newRun},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := db.ComplexityIncreases(tt.from, tt.to)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ComplexityIncreases() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// Unchanged files join across runs on their content hash
+	var joined int
+	err = db.db.QueryRow(`
+		SELECT count(*) FROM files o JOIN files n ON o.content_hash = n.content_hash
+		WHERE o.run_id = ? AND n.run_id = ? AND o.content_hash != ''`, oldRun, newRun).Scan(&joined)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if joined != 1 {
+		t.Errorf("%d files joined on content hash, want 1", joined)
+	}
+
+	slowest, err := db.SlowestFiles(10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(slowest) != 4 {
+		t.Errorf("SlowestFiles() returned %d files, want 4 across both runs", len(slowest))
+	}
+}
+
+func TestResultsDBReopen(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "results.db")
+	db, err := OpenResultsDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := sqliteRun(t, db, map[string]string{"a.go": "package a\n"})
+	db.Close()
+
+	db, err = OpenResultsDB(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	var version int
+	if err := db.db.QueryRow(`SELECT version FROM schema_version`).Scan(&version); err != nil {
+		t.Fatal(err)
+	}
+	if version != len(sqliteMigrations) {
+		t.Errorf("schema version %d, want %d", version, len(sqliteMigrations))
+	}
+	if second := sqliteRun(t, db, map[string]string{"a.go": "package a\n"}); second <= first {
+		t.Errorf("second run ID %d, want more than %d", second, first)
+	}
+}
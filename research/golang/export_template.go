@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// templateFuncs are the helpers available to user templates:
+//
+//	duration    formats an ExportDuration, time.Duration or nanoseconds
+//	percent     formats part/total as a percentage, e.g. "12.5%"
+//	sortBy      sorts a slice of structs by a field, "-Field" for descending;
+//	            fields are named as in Go or by their json tag
+//	top         returns the first n elements of a slice
+//	rel         shortens a path relative to a root directory
+//	join        joins strings with a separator
+var templateFuncs = template.FuncMap{
+	"duration": templateDuration,
+	"percent":  templatePercent,
+	"sortBy":   templateSortBy,
+	"top":      templateTop,
+	"rel":      outputPath,
+	"join":     func(sep string, s []string) string { return strings.Join(s, sep) },
+}
+
+// ParseReportTemplate parses a user-supplied text/template. It is meant to
+// run before any analysis so that syntax errors, which carry line numbers,
+// are reported without waiting for a run. The template is executed against
+// the Export model: .Run, .Files, .Functions, .Packages and .Findings.
+func ParseReportTemplate(path string) (*template.Template, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return template.New(filepath.Base(path)).Funcs(templateFuncs).Parse(string(text))
+}
+
+// ExportTemplate executes a template parsed by ParseReportTemplate against
+// the analyzer's results
+func (a *ASTAnalyzer) ExportTemplate(w io.Writer, tmpl *template.Template) error {
+	return tmpl.Execute(w, a.BuildExport())
+}
+
+// templateDuration formats a duration rounded to the microsecond
+func templateDuration(v any) (string, error) {
+	var d time.Duration
+	switch x := v.(type) {
+	case ExportDuration:
+		d = time.Duration(x.Nanoseconds)
+	case time.Duration:
+		d = x
+	case int64:
+		d = time.Duration(x)
+	case int:
+		d = time.Duration(x)
+	default:
+		return "", fmt.Errorf("duration: unsupported type %T", v)
+	}
+	return d.Round(time.Microsecond).String(), nil
+}
+
+// templatePercent formats part as a percentage of total
+func templatePercent(part, total any) (string, error) {
+	p, ok := templateNumber(part)
+	if !ok {
+		return "", fmt.Errorf("percent: unsupported type %T", part)
+	}
+	t, ok := templateNumber(total)
+	if !ok {
+		return "", fmt.Errorf("percent: unsupported type %T", total)
+	}
+	if t == 0 {
+		return "0.0%", nil
+	}
+	return fmt.Sprintf("%.1f%%", 100*p/t), nil
+}
+
+// templateSortBy returns a sorted copy of a slice of structs
+func templateSortBy(field string, slice any) (any, error) {
+	v := reflect.ValueOf(slice)
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("sortBy: %T is not a slice", slice)
+	}
+	descending := strings.HasPrefix(field, "-")
+	field = strings.TrimPrefix(field, "-")
+
+	sorted := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+	reflect.Copy(sorted, v)
+	if v.Len() == 0 {
+		return sorted.Interface(), nil
+	}
+
+	index, ok := templateFieldIndex(v.Type().Elem(), field)
+	if !ok {
+		return nil, fmt.Errorf("sortBy: %s has no field %q", v.Type().Elem(), field)
+	}
+
+	var sortErr error
+	sort.SliceStable(sorted.Interface(), func(i, j int) bool {
+		x := sorted.Index(i).FieldByIndex(index).Interface()
+		y := sorted.Index(j).FieldByIndex(index).Interface()
+		if descending {
+			x, y = y, x
+		}
+		less, err := templateLess(x, y)
+		if err != nil {
+			sortErr = err
+		}
+		return less
+	})
+	return sorted.Interface(), sortErr
+}
+
+// templateFieldIndex finds a struct field by Go name or json tag name
+func templateFieldIndex(t reflect.Type, name string) ([]int, bool) {
+	if t.Kind() != reflect.Struct {
+		return nil, false
+	}
+	if sf, ok := t.FieldByName(name); ok {
+		return sf.Index, true
+	}
+	for i := 0; i < t.NumField(); i++ {
+		tag, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if tag == name {
+			return t.Field(i).Index, true
+		}
+	}
+	return nil, false
+}
+
+// templateLess orders two field values of the same type
+func templateLess(x, y any) (bool, error) {
+	if xs, ok := x.(string); ok {
+		return xs < y.(string), nil
+	}
+	if xb, ok := x.(bool); ok {
+		return !xb && y.(bool), nil
+	}
+	xn, ok := templateNumber(x)
+	if !ok {
+		return false, fmt.Errorf("sortBy: cannot sort by %T", x)
+	}
+	yn, _ := templateNumber(y)
+	return xn < yn, nil
+}
+
+// templateNumber converts numeric values, including durations, to float64
+func templateNumber(v any) (float64, bool) {
+	switch x := v.(type) {
+	case ExportDuration:
+		return float64(x.Nanoseconds), true
+	case time.Duration:
+		return float64(x), true
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint()), true
+	case reflect.Float32, reflect.Float64:
+		return rv.Float(), true
+	}
+	return 0, false
+}
+
+// templateTop returns at most the first n elements of a slice
+func templateTop(n int, slice any) (any, error) {
+	v := reflect.ValueOf(slice)
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("top: %T is not a slice", slice)
+	}
+	if n < v.Len() {
+		v = v.Slice(0, n)
+	}
+	return v.Interface(), nil
+}
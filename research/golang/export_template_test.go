@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExampleTemplates(t *testing.T) {
+	paths, err := filepath.Glob(filepath.Join("templates", "examples", "*.tmpl"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no example templates")
+	}
+
+	analyzed := quietAnalyzer()
+	dir := writeTree(t, map[string]string{
+		"a.go":           "package a\n\nfunc A(x int) int {\n\tif x > 0 {\n\t\treturn 1\n\t}\n\treturn 0\n}\n",
+		"store/store.go": "package store\n\ntype S struct{}\n\nfunc (s *S) Get() {}\n",
+		"store/bad.go":   "package store\n\nfunc Bad( {\n",
+	})
+	if _, err := analyzed.BenchmarkDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := extractAllFunctions(analyzed); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, path := range paths {
+		name := filepath.Base(path)
+		t.Run(name, func(t *testing.T) {
+			tmpl, err := ParseReportTemplate(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var out bytes.Buffer
+			if err := goldenAnalyzer().ExportTemplate(&out, tmpl); err != nil {
+				t.Fatal(err)
+			}
+			checkGolden(t, strings.TrimSuffix(name, ".tmpl")+".golden", out.Bytes())
+
+			out.Reset()
+			if err := analyzed.ExportTemplate(&out, tmpl); err != nil {
+				t.Fatal(err)
+			}
+			for _, want := range []string{"a.go", "store/bad.go"} {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output for the analyzed tree lacks %s:\n%s", want, out.String())
+				}
+			}
+		})
+	}
+}
+
+func TestParseReportTemplateError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "bad.tmpl")
+	if err := os.WriteFile(path, []byte("{{.Run.Directory}}\n{{range .Files}}\n{{.Path}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := ParseReportTemplate(path)
+	if err == nil || !strings.Contains(err.Error(), "bad.tmpl:3:") {
+		t.Errorf("ParseReportTemplate() error = %v, want one at line 3", err)
+	}
+}
+
+func TestTemplateFuncs(t *testing.T) {
+	files := []ExportFile{
+		{Path: "b.go", ParseTime: newExportDuration(2 * time.Millisecond), NumFunctions: 1},
+		{Path: "a.go", ParseTime: newExportDuration(3 * time.Millisecond), NumFunctions: 1},
+		{Path: "c.go", ParseTime: newExportDuration(time.Millisecond), NumFunctions: 4},
+	}
+	paths := func(v any) string {
+		var names []string
+		for _, f := range v.([]ExportFile) {
+			names = append(names, f.Path)
+		}
+		return strings.Join(names, " ")
+	}
+
+	tests := []struct {
+		name    string
+		call    func() (string, error)
+		want    string
+		wantErr bool
+	}{
+		{name: "duration", call: func() (string, error) { return templateDuration(newExportDuration(1234567)) }, want: "1.235ms"},
+		{name: "duration int", call: func() (string, error) { return templateDuration(1500) }, want: "2µs"},
+		{name: "duration string", call: func() (string, error) { return templateDuration("1s") }, wantErr: true},
+		{name: "percent", call: func() (string, error) { return templatePercent(1, 8) }, want: "12.5%"},
+		{name: "percent of zero", call: func() (string, error) { return templatePercent(3, 0) }, want: "0.0%"},
+		{name: "percent of string", call: func() (string, error) { return templatePercent("1", 2) }, wantErr: true},
+		{name: "sort by path", call: func() (string, error) {
+			v, err := templateSortBy("Path", files)
+			return paths(v), err
+		}, want: "a.go b.go c.go"},
+		{name: "sort by duration descending", call: func() (string, error) {
+			v, err := templateSortBy("-ParseTime", files)
+			return paths(v), err
+		}, want: "a.go b.go c.go"},
+		{name: "sort by json name, stable", call: func() (string, error) {
+			v, err := templateSortBy("num_functions", files)
+			return paths(v), err
+		}, want: "b.go a.go c.go"},
+		{name: "sort by unknown field", call: func() (string, error) {
+			_, err := templateSortBy("Nope", files)
+			return "", err
+		}, wantErr: true},
+		{name: "top", call: func() (string, error) {
+			v, err := templateTop(2, files)
+			return paths(v), err
+		}, want: "b.go a.go"},
+		{name: "top of more than there are", call: func() (string, error) {
+			v, err := templateTop(5, files)
+			return paths(v), err
+		}, want: "b.go a.go c.go"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.call()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, want error %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	// sortBy must not reorder its argument
+	if got := paths(files); got != "b.go a.go c.go" {
+		t.Errorf("sortBy reordered its input to %s", got)
+	}
+}
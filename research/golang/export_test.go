@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+// goldenAnalyzer returns an analyzer holding a fixed, hand-built run, so
+// the exporters produce the same bytes on every machine
+func goldenAnalyzer() *ASTAnalyzer {
+	parseErr := errors.New("b_test.go:1:1: expected 'package', found 'EOF'")
+	a := quietAnalyzer()
+	a.startTime = time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	a.rootDir = "/src"
+	a.results = []ParseResult{
+		{
+			FilePath: "/src/a.go", Root: "/src", Success: true, ParseTime: 1500 * time.Microsecond,
+			NumFunctions: 2, NumStructs: 1, FileSizeBytes: 120, LineCount: 9, NodeCount: 40,
+		},
+		{
+			FilePath: "/src/b_test.go", Root: "/src", IsTest: true,
+			Error: parseErr, ErrorMessage: parseErr.Error(),
+		},
+	}
+	a.functions["/src/a.go"] = []FunctionInfo{{
+		Name: "Sum", IsExported: true, LineStart: 3, LineEnd: 5, Complexity: 1,
+		Params:  []ParamInfo{{Name: "xs", Type: "[]int"}},
+		Results: []string{"int"},
+	}}
+	a.types = []TypeInfo{{
+		Name: "T", Package: "/src", Kind: StructKind, FilePath: "/src/a.go", Line: 7,
+		Fields: []ParamInfo{{Name: "N", Type: "int"}},
+		Embeds: []string{"io.Reader"},
+	}}
+	a.findings = []Finding{{
+		Category: CategoryInitialism, FilePath: "/src/a.go", Line: 7, Column: 6,
+		Identifier: "Url", Message: "initialism should be written in a consistent case", Suggestion: "URL",
+	}}
+	return a
+}
+
+func TestExportJSONGolden(t *testing.T) {
+	var out bytes.Buffer
+	if err := goldenAnalyzer().ExportJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "export.golden.json", out.Bytes())
+}
+
+func TestExportJSONFields(t *testing.T) {
+	var out bytes.Buffer
+	if err := goldenAnalyzer().ExportJSON(&out); err != nil {
+		t.Fatal(err)
+	}
+	var export map[string]any
+	if err := json.Unmarshal(out.Bytes(), &export); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		path []any // Keys and indices into the decoded document
+		want any
+	}{
+		{name: "schema version", path: []any{"run", "schema_version"}, want: float64(ExportSchemaVersion)},
+		{name: "analyzer version", path: []any{"run", "analyzer_version"}, want: AnalyzerVersion},
+		{name: "start time", path: []any{"run", "start_time"}, want: "2024-05-01T12:00:00Z"},
+		{name: "directory", path: []any{"run", "directory"}, want: "/src"},
+		{name: "relative path", path: []any{"files", 0, "path"}, want: "a.go"},
+		{name: "duration nanoseconds", path: []any{"files", 0, "parse_time", "ns"}, want: float64(1500000)},
+		{name: "duration string", path: []any{"files", 0, "parse_time", "human"}, want: "1.5ms"},
+		{name: "error message", path: []any{"files", 1, "error"}, want: "b_test.go:1:1: expected 'package', found 'EOF'"},
+		{name: "function", path: []any{"functions", 0, "name"}, want: "Sum"},
+		{name: "function file", path: []any{"functions", 0, "file"}, want: "a.go"},
+		{name: "package", path: []any{"packages", 0, "path"}, want: "."},
+		{name: "finding", path: []any{"findings", 0, "suggestion"}, want: "URL"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var v any = export
+			for _, key := range tt.path {
+				switch key := key.(type) {
+				case string:
+					m, ok := v.(map[string]any)
+					if !ok {
+						t.Fatalf("no object at %v", tt.path)
+					}
+					v = m[key]
+				case int:
+					l, ok := v.([]any)
+					if !ok || key >= len(l) {
+						t.Fatalf("no element at %v", tt.path)
+					}
+					v = l[key]
+				}
+			}
+			if v != tt.want {
+				t.Errorf("%v = %v, want %v", tt.path, v, tt.want)
+			}
+		})
+	}
+}
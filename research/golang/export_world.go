@@ -0,0 +1,137 @@
+package main
+
+import (
+	"flag"
+	"go/ast"
+	"io"
+	"path/filepath"
+	"sort"
+
+	"github.com/study-game/research/game"
+)
+
+// World builds the game world of the run from the parsed files, the
+// functions extracted from them and the structs extracted with
+// ExtractTypes, which become items by ItemRules. Package paths and file
+// names are written relative to the output root, so the IDs do not depend
+// on where the code was checked out.
+func (a *ASTAnalyzer) World() (*game.World, error) {
+	root := a.outputRoot()
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	byDir := make(map[string]*game.Package)
+	var packages []*game.Package
+	for _, s := range summarizePackages(a.results) {
+		pkg := &game.Package{Path: outputPath(root, s.Path), Files: s.Files}
+		byDir[s.Path] = pkg
+		packages = append(packages, pkg)
+	}
+	for file, functions := range a.functions {
+		pkg, ok := byDir[filepath.Dir(file)]
+		if !ok {
+			continue
+		}
+		for _, fn := range functions {
+			pkg.Functions = append(pkg.Functions, game.Function{
+				Name:      fn.Name,
+				Receiver:  receiverTypeName(fn.Receiver),
+				File:      outputPath(root, file),
+				Exported:  fn.IsExported,
+				Params:    len(fn.Params),
+				Results:   len(fn.Results),
+				LineStart: fn.LineStart,
+				LineEnd:   fn.LineEnd,
+			})
+		}
+	}
+
+	structs := gameStructs(root, a.types)
+	for dir, pkg := range byDir {
+		pkg.Items = game.BuildItems(pkg.Path, structs[dir], a.ItemRules)
+	}
+
+	input := make([]game.Package, len(packages))
+	for i, pkg := range packages {
+		input[i] = *pkg
+	}
+	return game.BuildWorld(input)
+}
+
+// StructsToItems turns every struct under dir into a craftable item with
+// game.CraftItems: its fields are its components, base materials or the
+// items of other structs of the package, crafted in turn. Packages and
+// files are named relative to dir, and items are in package order.
+func (a *ASTAnalyzer) StructsToItems(dir string) ([]game.Item, error) {
+	types, err := a.extractTypes(dir)
+	if err != nil {
+		return nil, err
+	}
+	structs := gameStructs(dir, types)
+	pkgDirs := make([]string, 0, len(structs))
+	for pkgDir := range structs {
+		pkgDirs = append(pkgDirs, pkgDir)
+	}
+	sort.Strings(pkgDirs)
+
+	items := []game.Item{}
+	for _, pkgDir := range pkgDirs {
+		items = append(items, game.CraftItems(outputPath(dir, pkgDir), structs[pkgDir], a.ItemRules)...)
+	}
+	return items, nil
+}
+
+// gameStructs converts the structs among types for the game package, by
+// package directory, with files named relative to root
+func gameStructs(root string, types []TypeInfo) map[string][]game.Struct {
+	structs := make(map[string][]game.Struct)
+	for _, t := range types {
+		if t.Kind != StructKind {
+			continue
+		}
+		s := game.Struct{
+			Name:     t.Name,
+			File:     outputPath(root, t.FilePath),
+			Line:     t.Line,
+			Exported: ast.IsExported(t.Name),
+			Embeds:   t.Embeds,
+		}
+		for _, field := range t.Fields {
+			s.Fields = append(s.Fields, game.Field{Name: field.Name, Type: field.Type, Tag: t.Tags[field.Name]})
+		}
+		structs[t.Package] = append(structs[t.Package], s)
+	}
+	return structs
+}
+
+// runItems runs the items command: it writes the items crafted from the
+// structs under the directories given in args as JSON
+func runItems(args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("items", flag.ExitOnError)
+	flags.Parse(args)
+	dirs := flags.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+
+	analyzer := NewASTAnalyzer()
+	inventory := &game.Inventory{Items: []game.Item{}}
+	for _, dir := range dirs {
+		items, err := analyzer.StructsToItems(dir)
+		if err != nil {
+			return err
+		}
+		inventory.Items = append(inventory.Items, items...)
+	}
+	return inventory.WriteJSON(w)
+}
+
+// ExportWorld writes the game world of the run as indented JSON
+func (a *ASTAnalyzer) ExportWorld(w io.Writer) error {
+	world, err := a.World()
+	if err != nil {
+		return err
+	}
+	return world.WriteJSON(w)
+}
@@ -0,0 +1,112 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// worldFixture has several packages, exported and unexported functions,
+// methods, same-named init functions and structs that become items
+var worldFixture = map[string]string{
+	"main.go": `package main
+
+func init() {}
+
+func init() {}
+
+func main() { run(nil, 0) }
+
+func run(args []string, n int) (int, error) {
+	return n, nil
+}
+`,
+	"store/store.go": `package store
+
+// Store holds records
+type Store struct {
+	Records []Record
+	Index   map[string]int
+}
+
+// Record is one stored value
+type Record struct {
+	Key   string
+	Value []byte
+}
+
+// Open opens a store
+func Open(path string) (*Store, error) { return &Store{}, nil }
+
+func (s *Store) Get(key string) (Record, bool) {
+	i, ok := s.Index[key]
+	if !ok {
+		return Record{}, false
+	}
+	return s.Records[i], true
+}
+
+func (s *Store) grow() {}
+`,
+	"store/broken.go": "package store\n\nfunc Broken( {\n",
+}
+
+// worldJSON analyzes dir the way -format world does and returns the world
+func worldJSON(t *testing.T, dir string, workers int) []byte {
+	t.Helper()
+	a := quietAnalyzer()
+	a.Workers = workers
+	if _, err := a.BenchmarkDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := extractAllFunctions(a); err != nil {
+		t.Fatal(err)
+	}
+	if err := extractAllTypes(a, []string{dir}); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	if err := a.ExportWorld(&out); err != nil {
+		t.Fatal(err)
+	}
+	return out.Bytes()
+}
+
+func TestExportWorldDeterministic(t *testing.T) {
+	dir := writeTree(t, worldFixture)
+	want := worldJSON(t, dir, 1)
+
+	var world struct {
+		Zones []struct {
+			ID      string            `json:"id"`
+			NPCs    []json.RawMessage `json:"npcs"`
+			Enemies []json.RawMessage `json:"enemies"`
+		} `json:"zones"`
+	}
+	if err := json.Unmarshal(want, &world); err != nil {
+		t.Fatal(err)
+	}
+	if len(world.Zones) != 2 || world.Zones[0].ID != "zone:." || world.Zones[1].ID != "zone:store" {
+		t.Fatalf("zones %+v", world.Zones)
+	}
+	if len(world.Zones[1].NPCs) != 2 || len(world.Zones[1].Enemies) != 1 {
+		t.Errorf("store zone has %d NPCs and %d enemies, want 2 and 1", len(world.Zones[1].NPCs), len(world.Zones[1].Enemies))
+	}
+
+	tests := []struct {
+		name    string
+		dir     string
+		workers int
+	}{
+		{name: "same tree again", dir: dir, workers: 1},
+		{name: "another checkout", dir: writeTree(t, worldFixture), workers: 1},
+		{name: "worker pool", dir: dir, workers: 8},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := worldJSON(t, tt.dir, tt.workers); !bytes.Equal(got, want) {
+				t.Errorf("world differs from the first run:\n%s\nwant\n%s", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ExportYAML writes all analysis results as YAML. The document mirrors the
+// JSON export: it is produced from the same Export model and field names
+// come from the same json struct tags, so the two formats cannot drift.
+// Durations are written as strings such as "12.4ms" and multi-line strings
+// such as doc comments as block scalars.
+func (a *ASTAnalyzer) ExportYAML(w io.Writer) error {
+	var y yamlWriter
+	y.writeMapping(yamlFields(reflect.ValueOf(a.BuildExport())), 0, "")
+	_, err := io.WriteString(w, y.b.String())
+	return err
+}
+
+// yamlWriter emits block-style YAML for the export model
+type yamlWriter struct {
+	b strings.Builder
+}
+
+// yamlField is a mapping key and its value
+type yamlField struct {
+	key   string
+	value reflect.Value
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(ExportDuration{})
+)
+
+// writeMapping writes fields one per line at indent. The first line starts
+// with firstPrefix instead, which lets a mapping open a sequence item.
+func (y *yamlWriter) writeMapping(fields []yamlField, indent int, firstPrefix string) {
+	for i, f := range fields {
+		if i == 0 {
+			y.b.WriteString(firstPrefix)
+		} else {
+			y.b.WriteString(strings.Repeat(" ", indent))
+		}
+		y.b.WriteString(yamlString(f.key) + ":")
+		y.writeValue(f.value, indent+2)
+	}
+}
+
+// writeValue writes what follows a "key:" or "-", nesting collections at
+// indent
+func (y *yamlWriter) writeValue(v reflect.Value, indent int) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			y.b.WriteString(" null\n")
+			return
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Type() == timeType:
+		y.b.WriteString(" " + yamlString(v.Interface().(time.Time).Format(time.RFC3339Nano)) + "\n")
+		return
+	case v.Type() == durationType:
+		y.b.WriteString(" " + yamlString(v.Interface().(ExportDuration).Human) + "\n")
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Struct, reflect.Map:
+		fields := yamlFields(v)
+		if len(fields) == 0 {
+			y.b.WriteString(" {}\n")
+			return
+		}
+		y.b.WriteString("\n")
+		y.writeMapping(fields, indent, strings.Repeat(" ", indent))
+
+	case reflect.Slice, reflect.Array:
+		if v.Len() == 0 {
+			y.b.WriteString(" []\n")
+			return
+		}
+		y.b.WriteString("\n")
+		for i := 0; i < v.Len(); i++ {
+			y.writeItem(v.Index(i), indent)
+		}
+
+	case reflect.String:
+		s := v.String()
+		if isBlockScalar(s) {
+			y.writeBlockScalar(s, indent)
+			return
+		}
+		y.b.WriteString(" " + yamlString(s) + "\n")
+
+	case reflect.Bool:
+		y.b.WriteString(" " + strconv.FormatBool(v.Bool()) + "\n")
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		y.b.WriteString(" " + strconv.FormatInt(v.Int(), 10) + "\n")
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		y.b.WriteString(" " + strconv.FormatUint(v.Uint(), 10) + "\n")
+	case reflect.Float32, reflect.Float64:
+		y.b.WriteString(" " + strconv.FormatFloat(v.Float(), 'g', -1, 64) + "\n")
+	default:
+		y.b.WriteString(" " + yamlString(fmt.Sprint(v.Interface())) + "\n")
+	}
+}
+
+// writeItem writes one sequence item. Mappings start on the dash line.
+func (y *yamlWriter) writeItem(v reflect.Value, indent int) {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			break
+		}
+		v = v.Elem()
+	}
+
+	prefix := strings.Repeat(" ", indent) + "-"
+	if (v.Kind() == reflect.Struct || v.Kind() == reflect.Map) && v.Type() != timeType && v.Type() != durationType {
+		if fields := yamlFields(v); len(fields) > 0 {
+			y.writeMapping(fields, indent+2, prefix+" ")
+			return
+		}
+	}
+	y.b.WriteString(prefix)
+	y.writeValue(v, indent+2)
+}
+
+// writeBlockScalar writes a multi-line string as a literal block scalar,
+// choosing the chomping indicator that preserves trailing newlines
+func (y *yamlWriter) writeBlockScalar(s string, indent int) {
+	header := " |"
+	if strings.HasPrefix(s, " ") {
+		header += "2"
+	}
+	body := strings.TrimRight(s, "\n")
+	switch trailing := len(s) - len(body); {
+	case trailing == 0:
+		header += "-"
+	case trailing > 1:
+		header += "+"
+	}
+	y.b.WriteString(header + "\n")
+
+	for _, line := range strings.Split(strings.TrimSuffix(s, "\n"), "\n") {
+		if line == "" {
+			y.b.WriteString("\n")
+			continue
+		}
+		y.b.WriteString(strings.Repeat(" ", indent) + line + "\n")
+	}
+}
+
+// yamlFields lists the fields of a struct under their json tag names,
+// honoring "-" and omitempty and flattening embedded structs, or the
+// entries of a map in key order
+func yamlFields(v reflect.Value) []yamlField {
+	var fields []yamlField
+
+	if v.Kind() == reflect.Map {
+		keys := v.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, k := range keys {
+			fields = append(fields, yamlField{key: fmt.Sprint(k.Interface()), value: v.MapIndex(k)})
+		}
+		return fields
+	}
+
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		if !sf.IsExported() {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		fv := v.Field(i)
+		if sf.Anonymous && name == "" && fv.Kind() == reflect.Struct {
+			fields = append(fields, yamlFields(fv)...)
+			continue
+		}
+		if name == "" {
+			name = sf.Name
+		}
+		if strings.Contains(opts, "omitempty") && isEmptyValue(fv) {
+			continue
+		}
+		fields = append(fields, yamlField{key: name, value: fv})
+	}
+	return fields
+}
+
+// isEmptyValue reports whether v is empty in the sense of json's omitempty
+func isEmptyValue(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array, reflect.String:
+		return v.Len() == 0
+	case reflect.Pointer, reflect.Interface:
+		return v.IsNil()
+	case reflect.Struct:
+		return false
+	}
+	return v.IsZero()
+}
+
+// yamlPlain matches strings that can be written unquoted
+var yamlPlain = regexp.MustCompile(`^[A-Za-z_/.][A-Za-z0-9_/.() *\[\]-]*$`)
+
+// yamlReserved are plain scalars YAML would read as something other than
+// a string
+var yamlReserved = map[string]bool{
+	"true": true, "false": true, "yes": true, "no": true, "on": true, "off": true,
+	"null": true, "y": true, "n": true, ".inf": true, ".nan": true,
+}
+
+// yamlString writes s plain when that is unambiguous and double-quoted
+// otherwise
+func yamlString(s string) string {
+	if yamlPlain.MatchString(s) && !yamlReserved[strings.ToLower(s)] && !strings.HasSuffix(s, " ") {
+		return s
+	}
+	return strconv.Quote(s)
+}
+
+// isBlockScalar reports whether s should be written as a block scalar:
+// it spans lines and has no characters a block scalar cannot hold
+func isBlockScalar(s string) bool {
+	if !strings.Contains(strings.TrimRight(s, "\n"), "\n") {
+		return false
+	}
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimRight(line, " \t") != line || strings.ContainsAny(line, "\r\t") {
+			return false
+		}
+	}
+	return true
+}
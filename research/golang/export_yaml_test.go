@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+// normalizeJSON round-trips v through encoding/json, so numbers compare
+// as float64 whichever decoder produced them
+func normalizeJSON(t *testing.T, v any) any {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// humanDurations replaces the duration objects of a decoded JSON export
+// with their human strings, as the YAML export writes them
+func humanDurations(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		if human, ok := v["human"]; ok && len(v) == 2 {
+			if _, ok := v["ns"]; ok {
+				return human
+			}
+		}
+		for k, e := range v {
+			v[k] = humanDurations(e)
+		}
+	case []any:
+		for i, e := range v {
+			v[i] = humanDurations(e)
+		}
+	}
+	return v
+}
+
+func TestExportYAMLRoundTrip(t *testing.T) {
+	analyzed := quietAnalyzer()
+	dir := writeTree(t, map[string]string{
+		"a.go": `package a
+
+// Parse reads a "quoted" value.
+//
+// It returns: the value, or an error
+// when the input is # malformed.
+func Parse(s string) (string, error) { return s, nil }
+
+// Yes is a YAML 1.1 boolean
+func Yes() {}
+`,
+		"broken.go": "package a\n\nfunc B( {\n",
+	})
+	if _, err := analyzed.BenchmarkDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := extractAllFunctions(analyzed); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		a    *ASTAnalyzer
+	}{
+		{name: "fixed run", a: goldenAnalyzer()},
+		{name: "analyzed tree", a: analyzed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out bytes.Buffer
+			if err := tt.a.ExportYAML(&out); err != nil {
+				t.Fatal(err)
+			}
+			var fromYAML any
+			if err := yaml.Unmarshal(out.Bytes(), &fromYAML); err != nil {
+				t.Fatalf("output is not YAML: %v\n%s", err, out.String())
+			}
+
+			var js bytes.Buffer
+			if err := tt.a.ExportJSON(&js); err != nil {
+				t.Fatal(err)
+			}
+			var fromJSON any
+			if err := json.Unmarshal(js.Bytes(), &fromJSON); err != nil {
+				t.Fatal(err)
+			}
+
+			got, want := normalizeJSON(t, fromYAML), humanDurations(fromJSON)
+			if !reflect.DeepEqual(got, want) {
+				gotJSON, _ := json.MarshalIndent(got, "", "  ")
+				wantJSON, _ := json.MarshalIndent(want, "", "  ")
+				t.Errorf("YAML decodes to\n%s\nwant\n%s", gotJSON, wantJSON)
+			}
+		})
+	}
+}
+
+func TestExportYAMLScalars(t *testing.T) {
+	a := goldenAnalyzer()
+	a.functions["/src/a.go"][0].DocComment = "Sum adds up xs.\n\nIt returns zero for no values.\n"
+
+	var out bytes.Buffer
+	if err := a.ExportYAML(&out); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		want string
+	}{
+		{name: "block scalar", want: "    doc_comment: |\n      Sum adds up xs.\n\n      It returns zero for no values.\n"},
+		{name: "duration", want: `    parse_time: "1.5ms"` + "\n"},
+		{name: "time", want: `  start_time: "2024-05-01T12:00:00Z"` + "\n"},
+		{name: "quoted flow indicator", want: `        type: "[]int"` + "\n"},
+		{name: "plain string", want: "    name: Sum\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !strings.Contains(out.String(), tt.want) {
+				t.Errorf("output lacks %q:\n%s", tt.want, out.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ExcludeVendor returns a Filter rejecting files inside a vendor
+// directory. The walk already skips vendor directories below the
+// benchmarked root; this also covers roots that lie inside one.
+func ExcludeVendor() func(path string) bool {
+	return func(path string) bool {
+		for _, elem := range strings.Split(filepath.ToSlash(path), "/") {
+			if elem == "vendor" {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// ExcludeGenerated returns a Filter rejecting generated files, recognized
+// by name or by a "// Code generated ... DO NOT EDIT." line before the
+// package clause. Only the header is read. Files that cannot be read,
+// such as those of an fs.FS, are kept unless their name gives them away.
+func ExcludeGenerated() func(path string) bool {
+	return func(path string) bool {
+		return !isGeneratedName(path) && !hasGeneratedHeader(path)
+	}
+}
+
+// AllFilters returns a Filter accepting the files every filter accepts
+func AllFilters(filters ...func(path string) bool) func(path string) bool {
+	return func(path string) bool {
+		for _, filter := range filters {
+			if !filter(path) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// hasGeneratedHeader reports whether the file at path has the generated
+// code comment before its package clause
+func hasGeneratedHeader(path string) bool {
+	f, err := os.Open(path)
+	if err != nil {
+		return false
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if strings.HasPrefix(line, "package ") {
+			return false
+		}
+		if generatedHeader.MatchString(line) {
+			return true
+		}
+	}
+	return false
+}
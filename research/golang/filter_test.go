@@ -0,0 +1,72 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFilters(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"a.go":          "package a\n",
+		"a_string.go":   "// Code generated by stringer; DO NOT EDIT.\n\npackage a\n",
+		"api.pb.go":     "package a\n",
+		"late.go":       "package a\n\n// Code generated by hand; DO NOT EDIT.\n",
+		"vendor/v/v.go": "package v\n",
+	})
+	tests := []struct {
+		name   string
+		filter func(string) bool
+		path   string
+		want   bool
+	}{
+		{"vendor keeps others", ExcludeVendor(), "a.go", true},
+		{"vendor rejects vendored", ExcludeVendor(), "vendor/v/v.go", false},
+		{"generated keeps plain", ExcludeGenerated(), "a.go", true},
+		{"generated rejects header", ExcludeGenerated(), "a_string.go", false},
+		{"generated rejects name", ExcludeGenerated(), "api.pb.go", false},
+		{"generated header after package clause", ExcludeGenerated(), "late.go", true},
+		{"all filters", AllFilters(ExcludeVendor(), ExcludeGenerated()), "vendor/v/v.go", false},
+		{"all filters keep", AllFilters(ExcludeVendor(), ExcludeGenerated()), "a.go", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.filter(filepath.Join(dir, filepath.FromSlash(tt.path))); got != tt.want {
+				t.Errorf("filter(%s) = %v, want %v", tt.path, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterAppliesToPasses(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"a.go":      "package a\n\nfunc A() {}\n",
+		"skip_x.go": "package a\n\nfunc B() {}\nfunc C() {}\n",
+	})
+	a := quietAnalyzer()
+	a.Filter = func(path string) bool { return !strings.HasPrefix(filepath.Base(path), "skip_") }
+
+	files, err := a.goFiles(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || filepath.Base(files[0]) != "a.go" {
+		t.Errorf("goFiles() = %v, want a.go alone", files)
+	}
+
+	m, err := a.APISurface(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Functions != 1 {
+		t.Errorf("APISurface counts %d functions, want 1", m.Functions)
+	}
+
+	results, err := a.BenchmarkDirectory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Errorf("benchmarked %d files, want 1", len(results))
+	}
+}
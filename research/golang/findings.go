@@ -0,0 +1,187 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Finding categories reported by the convention pass
+const (
+	CategoryUnderscore   = "underscore"
+	CategoryStutter      = "stutter"
+	CategoryInitialism   = "initialism"
+	CategorySingleLetter = "single-letter"
+)
+
+// Finding is a single issue reported by an analysis pass
+type Finding struct {
+	Category   string
+	FilePath   string
+	Line       int
+	Column     int // Zero when only the line is known
+	Identifier string
+	Message    string
+	Suggestion string
+}
+
+// CheckConventions runs the naming-convention pass over all Go files under
+// dir and records the findings for PrintSummary
+func (a *ASTAnalyzer) CheckConventions(dir string) ([]Finding, error) {
+	files, err := a.goFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var findings []Finding
+	for _, path := range files {
+		f, err := a.cache.Parse(path)
+		if err != nil {
+			continue
+		}
+		findings = append(findings, a.checkFileConventions(path, f)...)
+	}
+
+	a.addFindings(findings...)
+	return findings, nil
+}
+
+// addFindings records findings for PrintSummary and the exports
+func (a *ASTAnalyzer) addFindings(findings ...Finding) {
+	a.mu.Lock()
+	a.findings = append(a.findings, findings...)
+	a.mu.Unlock()
+}
+
+// checkFileConventions applies the convention rules to every declared
+// identifier in a parsed file
+func (a *ASTAnalyzer) checkFileConventions(path string, f *ast.File) []Finding {
+	var findings []Finding
+	pkgName := f.Name.Name
+	allowUnderscores := isTestName(path) || isGeneratedFile(path, f)
+
+	report := func(name *ast.Ident, category, message, suggestion string) {
+		findings = append(findings, Finding{
+			Category:   category,
+			FilePath:   path,
+			Line:       a.fset.Position(name.Pos()).Line,
+			Column:     a.fset.Position(name.Pos()).Column,
+			Identifier: name.Name,
+			Message:    message,
+			Suggestion: suggestion,
+		})
+	}
+
+	check := func(name *ast.Ident, topLevel bool) {
+		if name.Name == "_" {
+			return
+		}
+
+		if !allowUnderscores && strings.Contains(strings.Trim(name.Name, "_"), "_") {
+			report(name, CategoryUnderscore, "identifier contains underscores", removeUnderscores(name.Name))
+		}
+
+		if topLevel && name.IsExported() && pkgName != "main" {
+			if trimmed, ok := stutterFix(pkgName, name.Name); ok {
+				report(name, CategoryStutter,
+					fmt.Sprintf("%s.%s repeats the package name", pkgName, name.Name), trimmed)
+			}
+		}
+
+		if fixed := a.fixInitialisms(name.Name); fixed != name.Name {
+			report(name, CategoryInitialism, "initialism should be written in a consistent case", fixed)
+		}
+
+		if name.IsExported() && len([]rune(name.Name)) == 1 {
+			report(name, CategorySingleLetter, "exported name is a single letter", strings.ToLower(name.Name))
+		}
+	}
+
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			check(d.Name, d.Recv == nil)
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					check(s.Name, true)
+					if st, ok := s.Type.(*ast.StructType); ok {
+						for _, field := range st.Fields.List {
+							for _, name := range field.Names {
+								check(name, false)
+							}
+						}
+					}
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						check(name, true)
+					}
+				}
+			}
+		}
+	}
+
+	return findings
+}
+
+// stutterFix returns name without the package-name prefix when a qualified
+// reference like user.UserID would repeat it
+func stutterFix(pkgName, name string) (string, bool) {
+	if len(name) <= len(pkgName) || !strings.EqualFold(name[:len(pkgName)], pkgName) {
+		return "", false
+	}
+	rest := name[len(pkgName):]
+	if !unicode.IsUpper([]rune(rest)[0]) {
+		return "", false
+	}
+	return rest, true
+}
+
+// removeUnderscores converts snake_case words into mixedCaps
+func removeUnderscores(name string) string {
+	parts := strings.Split(name, "_")
+	var b strings.Builder
+	for i, part := range parts {
+		if part == "" {
+			continue
+		}
+		if i > 0 && b.Len() > 0 {
+			part = upperFirst(part)
+		}
+		b.WriteString(part)
+	}
+	return b.String()
+}
+
+// printFindingsSummary prints finding counts grouped by category
+func (a *ASTAnalyzer) printFindingsSummary() {
+	a.mu.Lock()
+	total := len(a.findings)
+	counts := make(map[string]int)
+	for _, f := range a.findings {
+		counts[f.Category]++
+	}
+	a.mu.Unlock()
+	if total == 0 {
+		return
+	}
+
+	categories := make([]string, 0, len(counts))
+	for category := range counts {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println("FINDINGS")
+	fmt.Println(strings.Repeat("=", 70))
+	for _, category := range categories {
+		fmt.Printf("%-20s %d\n", category+":", counts[category])
+	}
+	fmt.Printf("%-20s %d\n", "Total:", total)
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println()
+}
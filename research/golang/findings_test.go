@@ -0,0 +1,126 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+func TestCheckConventions(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want map[string]string // Suggestion by category
+	}{
+		{
+			name: "underscore",
+			src:  "package store\n\nvar max_size = 1\n",
+			want: map[string]string{CategoryUnderscore: "maxSize"},
+		},
+		{
+			name: "stutter",
+			src:  "package store\n\ntype StoreItem struct{}\n",
+			want: map[string]string{CategoryStutter: "Item"},
+		},
+		{
+			name: "initialism",
+			src:  "package store\n\nfunc ParseUrl() {}\n",
+			want: map[string]string{CategoryInitialism: "ParseURL"},
+		},
+		{
+			name: "single letter",
+			src:  "package store\n\nconst N = 1\n",
+			want: map[string]string{CategorySingleLetter: "n"},
+		},
+		{
+			name: "clean",
+			src:  "package store\n\nfunc ParseURL(userID int) {}\n",
+			want: map[string]string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeTree(t, map[string]string{"store/store.go": tt.src})
+			findings, err := quietAnalyzer().CheckConventions(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]string)
+			for _, f := range findings {
+				got[f.Category] = f.Suggestion
+			}
+			if len(got) != len(tt.want) || len(findings) != len(tt.want) {
+				t.Fatalf("findings %+v, want %v", findings, tt.want)
+			}
+			for category, suggestion := range tt.want {
+				if got[category] != suggestion {
+					t.Errorf("%s suggestion %q, want %q", category, got[category], suggestion)
+				}
+			}
+		})
+	}
+}
+
+func TestInitialismsInBothPasses(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"store/store.go": "package store\n\ntype Config struct {\n\tName string\n}\n\nfunc ParseUrl() {}\n\nfunc (c Config) GetName() string { return \"\" }\n",
+	})
+	a := quietAnalyzer()
+	findings, err := a.CheckConventions(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	issues, err := a.CheckNaming(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var initialisms []string
+	for _, f := range findings {
+		if f.Category == CategoryInitialism {
+			initialisms = append(initialisms, f.Suggestion)
+		}
+	}
+	if len(initialisms) != 1 || initialisms[0] != "ParseURL" {
+		t.Errorf("CheckConventions suggested %v, want [ParseURL]", initialisms)
+	}
+
+	kinds := make(map[string]string)
+	for _, issue := range issues {
+		kinds[issue.Kind] = issue.Suggestion
+	}
+	want := map[string]string{"initialism": "ParseURL", "getter": "Name"}
+	if !reflect.DeepEqual(kinds, want) || len(issues) != len(want) {
+		t.Errorf("CheckNaming reported %+v, want suggestions %v", issues, want)
+	}
+}
+
+func TestPrintFindingsSummaryConcurrent(t *testing.T) {
+	stdout := os.Stdout
+	devNull, err := os.OpenFile(os.DevNull, os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer devNull.Close()
+	os.Stdout = devNull
+	defer func() { os.Stdout = stdout }()
+
+	a := quietAnalyzer()
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			a.addFindings(Finding{Category: CategoryStutter})
+		}()
+		go func() {
+			defer wg.Done()
+			a.printFindingsSummary()
+		}()
+	}
+	wg.Wait()
+	if n := len(a.BuildExport().Findings); n != 8 {
+		t.Errorf("recorded %d findings, want 8", n)
+	}
+}
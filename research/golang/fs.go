@@ -0,0 +1,274 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// sourceFile locates a file to parse: its name within fsys, and the path
+// it is reported under in results and positions
+type sourceFile struct {
+	fsys fs.FS
+	name string
+	path string
+}
+
+// osSource locates a file on disk
+func osSource(filePath string) sourceFile {
+	return sourceFile{fsys: os.DirFS(filepath.Dir(filePath)), name: filepath.Base(filePath), path: filePath}
+}
+
+// dirFS is os.DirFS for a directory on disk whose files are reported under
+// their paths on disk rather than their names within it. The result cache
+// applies to its files, and symlinks within it are resolved so that a
+// file reached through several of them is found once.
+type dirFS struct {
+	fs.FS
+	dir string
+}
+
+// diskFS returns the dirFS and the root within it to walk for dir, which
+// may also name a single file
+func diskFS(dir string) (dirFS, string, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return dirFS{}, "", err
+	}
+	if !info.IsDir() {
+		parent := filepath.Dir(dir)
+		return dirFS{FS: os.DirFS(parent), dir: parent}, filepath.Base(dir), nil
+	}
+	return dirFS{FS: os.DirFS(dir), dir: dir}, ".", nil
+}
+
+// path returns the path on disk of name, and the directory as given for "."
+func (d dirFS) path(name string) string {
+	if name == "." {
+		return d.dir
+	}
+	return filepath.Join(d.dir, filepath.FromSlash(name))
+}
+
+// isSkippedDir reports whether a directory below the walked root is left
+// out: vendored dependencies and hidden directories such as .git
+func isSkippedDir(name string) bool {
+	return name == "vendor" || strings.HasPrefix(name, ".") && name != "." && name != ".."
+}
+
+// fsWalker finds the Go files under a root in a file system. Every file is
+// reported once, by the first name that reaches it, however many symlinks
+// lead to it; following symlinked directories cannot loop because each
+// real directory is entered once. Subdirectories that cannot be read are
+// recorded as warnings and left out instead of failing the walk.
+type fsWalker struct {
+	fsys           fs.FS
+	prefix         string // Joined to names to report them, outside a dirFS
+	followSymlinks bool
+	filter         func(path string) bool // Files to keep, by reported path; nil keeps all
+	realDirs       map[string]string      // Real paths of entered directories, by name
+	visited        map[string]bool        // Real paths of entered directories and found files
+	sources        []sourceFile
+	warnings       []string
+}
+
+// walker returns an fsWalker over fsys set up like the analyzer: following
+// symlinked directories when FollowSymlinks is set and keeping the files
+// Filter accepts
+func (a *ASTAnalyzer) walker(fsys fs.FS, prefix string) *fsWalker {
+	return &fsWalker{
+		fsys:           fsys,
+		prefix:         prefix,
+		followSymlinks: a.FollowSymlinks,
+		filter:         a.Filter,
+		realDirs:       make(map[string]string),
+		visited:        make(map[string]bool),
+	}
+}
+
+// path returns the path name is reported under
+func (w *fsWalker) path(name string) string {
+	if d, ok := w.fsys.(dirFS); ok {
+		return d.path(name)
+	}
+	return path.Join(w.prefix, name)
+}
+
+// realPath returns what identifies the file or directory at name: its
+// real path on disk in a dirFS, where symlinks can lead to it by other
+// names, and name itself elsewhere
+func (w *fsWalker) realPath(name string) string {
+	d, ok := w.fsys.(dirFS)
+	if !ok {
+		return name
+	}
+	real, err := filepath.EvalSymlinks(d.path(name))
+	if err != nil {
+		return d.path(name)
+	}
+	return real
+}
+
+// walk adds the Go files under root, or root itself when it names a file
+func (w *fsWalker) walk(root string) error {
+	info, err := fs.Stat(w.fsys, root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		if path.Ext(root) == ".go" {
+			w.addFile(root)
+		}
+		return nil
+	}
+	return w.walkTree(root)
+}
+
+// walkTree walks the directory at root. Entries met during the walk are
+// never symlinks themselves, so only directories need their real paths
+// resolved: a file's is found from its directory's.
+func (w *fsWalker) walkTree(root string) error {
+	return fs.WalkDir(w.fsys, root, func(name string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if name == root || !errors.Is(err, fs.ErrPermission) {
+				return err
+			}
+			w.warnings = append(w.warnings, fmt.Sprintf("skipped %s: permission denied", w.path(name)))
+			return nil
+		}
+
+		switch {
+		case d.IsDir():
+			if name != root && isSkippedDir(d.Name()) {
+				return fs.SkipDir
+			}
+			real := w.realPath(name)
+			if w.visited[real] {
+				return fs.SkipDir
+			}
+			w.visited[real] = true
+			w.realDirs[name] = real
+		case d.Type()&fs.ModeSymlink != 0:
+			return w.walkSymlink(name)
+		case path.Ext(name) == ".go":
+			real := name
+			if dir, ok := w.realDirs[path.Dir(name)]; ok {
+				real = dir + "/" + d.Name()
+			}
+			if !w.visited[real] {
+				w.visited[real] = true
+				w.add(name)
+			}
+		}
+		return nil
+	})
+}
+
+// walkSymlink handles a symlink met during the walk: a linked Go file is
+// added unless already found, a linked directory is walked when following
+// symlinks, and a dangling link is ignored
+func (w *fsWalker) walkSymlink(name string) error {
+	info, err := fs.Stat(w.fsys, name)
+	if err != nil {
+		return nil
+	}
+	if info.IsDir() {
+		if !w.followSymlinks || isSkippedDir(path.Base(name)) {
+			return nil
+		}
+		return w.walkTree(name)
+	}
+	if path.Ext(name) == ".go" {
+		w.addFile(name)
+	}
+	return nil
+}
+
+// addFile adds a file found outside a directory walk, such as through a
+// symlink, unless its real path was already found
+func (w *fsWalker) addFile(name string) {
+	real := w.realPath(name)
+	if !w.visited[real] {
+		w.visited[real] = true
+		w.add(name)
+	}
+}
+
+// add adds the file at name unless the filter rejects it
+func (w *fsWalker) add(name string) {
+	file := sourceFile{fsys: w.fsys, name: name, path: w.path(name)}
+	if w.filter == nil || w.filter(file.path) {
+		w.sources = append(w.sources, file)
+	}
+}
+
+// fsSources locates the Go files under root in fsys that Filter accepts,
+// recording the subdirectories it could not read as warnings. Files are
+// reported under their paths on disk in a dirFS, and under their names in
+// fsys joined to prefix elsewhere.
+func (a *ASTAnalyzer) fsSources(fsys fs.FS, root, prefix string) ([]sourceFile, error) {
+	w := a.walker(fsys, prefix)
+	if err := w.walk(root); err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	a.warnings = append(a.warnings, w.warnings...)
+	a.mu.Unlock()
+	return w.sources, nil
+}
+
+// BenchmarkFS benchmarks all Go files under root in fsys, such as an
+// embed.FS or a zip archive opened with zip.NewReader. Results and
+// positions use the names within fsys as file paths. The result cache
+// only applies to files on disk and is not consulted.
+func (a *ASTAnalyzer) BenchmarkFS(fsys fs.FS, root string) error {
+	_, err := a.benchmarkFS(context.Background(), fsys, root)
+	return err
+}
+
+// benchmarkFS benchmarks the Go files under root in fsys as a new run for
+// BenchmarkFS and BenchmarkDirectory
+func (a *ASTAnalyzer) benchmarkFS(ctx context.Context, fsys fs.FS, root string) ([]ParseResult, error) {
+	reported := root
+	if d, ok := fsys.(dirFS); ok {
+		reported = d.path(root)
+	}
+	return a.benchmark(reported, func(fn func(ParseResult) error) error {
+		return a.StreamFS(ctx, fsys, root, fn)
+	})
+}
+
+// StreamFS is StreamDirectory for the Go files under root in fsys
+func (a *ASTAnalyzer) StreamFS(ctx context.Context, fsys fs.FS, root string, fn func(ParseResult) error) error {
+	sources, err := a.fsSources(fsys, root, "")
+	if err != nil {
+		return err
+	}
+	d, onDisk := fsys.(dirFS)
+	if onDisk {
+		root = d.path(root)
+	}
+	return a.streamSources(ctx, root, sources, onDisk, fn)
+}
+
+// ParseFileFS parses a single Go file read from fsys, like ParseFile
+func (a *ASTAnalyzer) ParseFileFS(fsys fs.FS, name string) ParseResult {
+	return a.parseSourceFile(sourceFile{fsys: fsys, name: name, path: name})
+}
+
+// ExtractFunctionsFS extracts function metadata from a Go file read from
+// fsys and records it under name, like ExtractFunctions. The file is
+// parsed with ExtractFunctionsFromSource rather than through the shared
+// cache, which only holds files on disk.
+func (a *ASTAnalyzer) ExtractFunctionsFS(fsys fs.FS, name string) ([]FunctionInfo, error) {
+	src, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return nil, err
+	}
+	return a.ExtractFunctionsFromSource(name, src)
+}
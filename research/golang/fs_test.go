@@ -0,0 +1,186 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// symlinkTree writes a tree reaching its files through symlinked
+// directories, a symlinked file, a symlink cycle and a dangling link, next
+// to a directory outside it that a link points into. It returns the root.
+func symlinkTree(t *testing.T) string {
+	t.Helper()
+	dir := writeTree(t, map[string]string{
+		"root/a/x.go":       "package a\n",
+		"root/a/y.go":       "package a\n",
+		"root/loop/z.go":    "package loop\n",
+		"root/.git/hook.go": "package hook\n",
+		"outside/w.go":      "package outside\n",
+	})
+	root := filepath.Join(dir, "root")
+	links := map[string]string{
+		"root/link_a":       "a",
+		"root/file_link.go": "a/x.go",
+		"root/loop/self":    "..",
+		"root/dangling.go":  "missing.go",
+		"root/ext":          "../outside",
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			t.Skipf("symlinks unsupported: %v", err)
+		}
+	}
+	return root
+}
+
+func TestWalkSymlinks(t *testing.T) {
+	root := symlinkTree(t)
+
+	tests := []struct {
+		name   string
+		dir    string
+		follow bool
+		want   []string // Reported paths relative to root
+	}{
+		{
+			name: "skip symlinked directories",
+			dir:  root,
+			want: []string{"a/x.go", "a/y.go", "loop/z.go"},
+		},
+		{
+			name:   "follow symlinked directories",
+			dir:    root,
+			follow: true,
+			want:   []string{"a/x.go", "a/y.go", "ext/w.go", "loop/z.go"},
+		},
+		{
+			name: "root through a symlink",
+			dir:  filepath.Join(root, "link_a"),
+			want: []string{"link_a/x.go", "link_a/y.go"},
+		},
+		{
+			name: "symlinked file",
+			dir:  filepath.Join(root, "file_link.go"),
+			want: []string{"file_link.go"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := quietAnalyzer()
+			a.FollowSymlinks = tt.follow
+			results, err := a.BenchmarkDirectory(tt.dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			real := make(map[string]bool)
+			for _, r := range results {
+				rel, err := filepath.Rel(root, r.FilePath)
+				if err != nil || strings.HasPrefix(rel, "..") {
+					t.Errorf("file %s reported outside the root", r.FilePath)
+				}
+				got = append(got, filepath.ToSlash(rel))
+
+				p, err := filepath.EvalSymlinks(r.FilePath)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if real[p] {
+					t.Errorf("%s counted twice", p)
+				}
+				real[p] = true
+			}
+			sort.Strings(got)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("found %v, want %v", got, tt.want)
+			}
+			if s := a.Summarize(); s.TotalFiles != len(tt.want) {
+				t.Errorf("summary counts %d files, want %d", s.TotalFiles, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestWalkPermissionDenied(t *testing.T) {
+	if os.Geteuid() == 0 {
+		t.Skip("permissions do not apply to root")
+	}
+	dir := writeTree(t, map[string]string{
+		"ok/a.go":     "package ok\n",
+		"locked/b.go": "package locked\n",
+	})
+	locked := filepath.Join(dir, "locked")
+	if err := os.Chmod(locked, 0); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(locked, 0o755)
+
+	a := quietAnalyzer()
+	results, err := a.BenchmarkDirectory(dir)
+	if err != nil {
+		t.Fatalf("BenchmarkDirectory() error = %v, want a warning instead", err)
+	}
+	if len(results) != 1 {
+		t.Errorf("got %d results, want 1", len(results))
+	}
+	if warnings := a.Warnings(); len(warnings) != 1 || !strings.Contains(warnings[0], "permission denied") {
+		t.Errorf("warnings %v", warnings)
+	}
+}
+
+// TestBenchmarkDirectoryMatchesFS checks that a directory benchmarks like
+// the same tree read through os.DirFS, except for the result cache,
+// which only applies on disk
+func TestBenchmarkDirectoryMatchesFS(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"main.go":            "package main\n\nfunc main() {}\n",
+		"pkg/lib.go":         "package pkg\n\nfunc F() {}\n\nfunc G() {}\n",
+		"pkg/broken.go":      "package pkg\n\nfunc {\n",
+		"vendor/dep/dep.go":  "package dep\n",
+		".hidden/skipped.go": "package hidden\n",
+	})
+	cache := filepath.Join(t.TempDir(), "cache.json")
+	first := quietAnalyzer()
+	if _, err := first.BenchmarkDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := first.SaveCache(cache); err != nil {
+		t.Fatal(err)
+	}
+
+	a := quietAnalyzer()
+	if err := a.LoadCache(cache); err != nil {
+		t.Fatal(err)
+	}
+	onDisk, err := a.BenchmarkDirectory(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := a.BenchmarkFS(os.DirFS(dir), "."); err != nil {
+		t.Fatal(err)
+	}
+	inFS := a.results
+
+	if len(onDisk) != 3 || len(inFS) != len(onDisk) {
+		t.Fatalf("got %d files on disk and %d in the FS, want 3 each", len(onDisk), len(inFS))
+	}
+	for i, r := range onDisk {
+		rel, _ := filepath.Rel(dir, r.FilePath)
+		got := inFS[i]
+		if filepath.ToSlash(rel) != got.FilePath || r.Success != got.Success || r.NumFunctions != got.NumFunctions {
+			t.Errorf("file %d: %s (%v, %d functions) on disk, %s (%v, %d functions) in the FS",
+				i, rel, r.Success, r.NumFunctions, got.FilePath, got.Success, got.NumFunctions)
+		}
+		if got.FromCache {
+			t.Errorf("%s taken from the result cache through the FS", got.FilePath)
+		}
+		if r.Success && !r.FromCache {
+			t.Errorf("%s not taken from the result cache on disk", r.FilePath)
+		}
+	}
+}
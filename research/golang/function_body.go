@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go/ast"
+)
+
+// ErrFunctionNotFound is returned by GetFunctionBody when the file
+// declares no matching function
+var ErrFunctionNotFound = errors.New("function not found")
+
+// GetFunctionBody returns the body of the function funcName declared in
+// filePath, for callers that want to inspect the statements behind a
+// FunctionInfo. Methods are selected by receiver type, as "T" or "*T";
+// with an empty receiver the package-level function is returned, or the
+// method of that name when exactly one type declares it. The body shares
+// the analyzer's file set, so Position locates its nodes. When the file
+// has syntax errors, a body recovered from the partial AST is returned
+// together with the error.
+func (a *ASTAnalyzer) GetFunctionBody(filePath, funcName, receiver string) (*ast.BlockStmt, error) {
+	f, err := a.cache.Parse(filePath)
+	if f == nil {
+		return nil, err
+	}
+
+	var methods []*ast.FuncDecl
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Name.Name != funcName {
+			continue
+		}
+		declared := ""
+		if fn.Recv != nil && len(fn.Recv.List) > 0 {
+			declared = receiverTypeName(exprToString(fn.Recv.List[0].Type))
+		}
+		switch {
+		case declared == receiverTypeName(receiver):
+			return functionBody(fn, err)
+		case receiver == "":
+			methods = append(methods, fn)
+		}
+	}
+
+	name := funcName
+	if receiver != "" {
+		name = receiver + "." + funcName
+	}
+	switch len(methods) {
+	case 0:
+		if err != nil {
+			return nil, err
+		}
+		return nil, fmt.Errorf("%s in %s: %w", name, filePath, ErrFunctionNotFound)
+	case 1:
+		return functionBody(methods[0], err)
+	default:
+		return nil, fmt.Errorf("%s in %s is a method of %d types; pass a receiver", name, filePath, len(methods))
+	}
+}
+
+// functionBody returns the body of fn along with the file's parse error,
+// failing for functions declared without one, such as assembly stubs
+func functionBody(fn *ast.FuncDecl, parseErr error) (*ast.BlockStmt, error) {
+	if fn.Body == nil {
+		return nil, fmt.Errorf("%s has no body", fn.Name.Name)
+	}
+	return fn.Body, parseErr
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// functionLengthBounds are the upper line counts of the length buckets of
+// FunctionStats; a last bucket holds the longer functions
+var functionLengthBounds = []int{10, 25, 50}
+
+// FunctionStats describes the sizes of a set of functions
+type FunctionStats struct {
+	Functions int     // Functions and methods counted
+	Mean      float64 // Mean length in lines
+	Median    float64
+	P90       int // Nearest-rank 90th percentile
+	Buckets   []LengthBucket
+}
+
+// LengthBucket counts the functions whose length is within a range
+type LengthBucket struct {
+	Min   int
+	Max   int // 0 for the last bucket, which has no upper bound
+	Count int
+}
+
+// Label names the range of the bucket, such as "11-25" or "51+"
+func (b LengthBucket) Label() string {
+	if b.Max == 0 {
+		return fmt.Sprintf("%d+", b.Min)
+	}
+	return fmt.Sprintf("%d-%d", b.Min, b.Max)
+}
+
+// FunctionStats aggregates the lengths, from the first line of the
+// declaration to the closing brace, of the functions and methods of the Go
+// files under dir. Files not passed to ExtractFunctions yet are extracted
+// with it first, so the statistics never depend on what ran before.
+func (a *ASTAnalyzer) FunctionStats(dir string) (FunctionStats, error) {
+	files, err := a.goFiles(dir)
+	if err != nil {
+		return FunctionStats{}, err
+	}
+	under := make(map[string]bool, len(files))
+	for _, path := range files {
+		under[path] = true
+		a.mu.Lock()
+		_, extracted := a.functions[path]
+		a.mu.Unlock()
+		if !extracted {
+			// A file that does not parse counts the functions it has left
+			a.ExtractFunctions(path)
+		}
+	}
+	return a.recordedFunctionStats(func(path string) bool { return under[path] }), nil
+}
+
+// recordedFunctionStats aggregates the lengths of the functions extracted
+// from the files keep accepts
+func (a *ASTAnalyzer) recordedFunctionStats(keep func(path string) bool) FunctionStats {
+	a.mu.Lock()
+	var lengths []int
+	for path, functions := range a.functions {
+		if !keep(path) {
+			continue
+		}
+		for _, fn := range functions {
+			lengths = append(lengths, fn.LineEnd-fn.LineStart+1)
+		}
+	}
+	a.mu.Unlock()
+	return newFunctionStats(lengths)
+}
+
+// newFunctionStats summarizes a set of function lengths
+func newFunctionStats(lengths []int) FunctionStats {
+	s := FunctionStats{Functions: len(lengths)}
+	low := 1
+	for _, bound := range functionLengthBounds {
+		s.Buckets = append(s.Buckets, LengthBucket{Min: low, Max: bound})
+		low = bound + 1
+	}
+	s.Buckets = append(s.Buckets, LengthBucket{Min: low})
+	if len(lengths) == 0 {
+		return s
+	}
+
+	sorted := append([]int(nil), lengths...)
+	sort.Ints(sorted)
+	n := len(sorted)
+	total := 0
+	for _, length := range sorted {
+		total += length
+		i := sort.SearchInts(functionLengthBounds, length)
+		s.Buckets[i].Count++
+	}
+	s.Mean = float64(total) / float64(n)
+	if n%2 == 1 {
+		s.Median = float64(sorted[n/2])
+	} else {
+		s.Median = float64(sorted[n/2-1]+sorted[n/2]) / 2
+	}
+	s.P90 = sorted[int(math.Ceil(0.9*float64(n)))-1]
+	return s
+}
+
+// printFunctionStats prints the length distribution of every extracted
+// function
+func (a *ASTAnalyzer) printFunctionStats() {
+	s := a.recordedFunctionStats(func(string) bool { return true })
+	if s.Functions == 0 {
+		return
+	}
+
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println("FUNCTION LENGTH")
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Printf("Functions:          %d\n", s.Functions)
+	fmt.Printf("Mean:               %.1f lines\n", s.Mean)
+	fmt.Printf("Median:             %.1f lines\n", s.Median)
+	fmt.Printf("90th percentile:    %d lines\n", s.P90)
+	for _, b := range s.Buckets {
+		fmt.Printf("  %-8s %6d (%.1f%%)\n", b.Label(), b.Count, 100*float64(b.Count)/float64(s.Functions))
+	}
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println()
+}
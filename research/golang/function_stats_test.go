@@ -0,0 +1,41 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestFunctionStatsFreshAnalyzer(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"a/a.go": "package a\n\nfunc One() {}\n\nfunc Three() {\n\t_ = 1\n}\n",
+		"b/b.go": "package b\n\nfunc Two() {\n}\n",
+	})
+
+	tests := []struct {
+		name      string
+		dir       string
+		functions int
+		mean      float64
+	}{
+		{name: "whole tree", dir: dir, functions: 3, mean: 2},
+		{name: "one package", dir: filepath.Join(dir, "a"), functions: 2, mean: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := quietAnalyzer().FunctionStats(tt.dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if s.Functions != tt.functions || s.Mean != tt.mean {
+				t.Errorf("got %d functions of mean length %.2f, want %d of %.2f", s.Functions, s.Mean, tt.functions, tt.mean)
+			}
+			if s.Buckets[0].Count != tt.functions {
+				t.Errorf("bucket %s counts %d functions, want %d", s.Buckets[0].Label(), s.Buckets[0].Count, tt.functions)
+			}
+		})
+	}
+
+	if _, err := quietAnalyzer().FunctionStats(filepath.Join(dir, "missing")); err == nil {
+		t.Error("FunctionStats of a missing directory returned no error")
+	}
+}
@@ -0,0 +1,170 @@
+package game
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Hotspot is a leveled function together with what its boss is built from
+type Hotspot struct {
+	Difficulty
+	Name      string
+	Receiver  string // Bare receiver type name, for methods
+	Package   string // Slash-separated, such as "." or "internal/store"
+	LineEnd   int
+	Callees   []Callee
+	Constants []string // Constants declared in the function's file
+}
+
+// Callee is a function a hotspot calls
+type Callee struct {
+	ID   string // Stable identifier, unique among the callees
+	Name string // Bare function name
+}
+
+// Bosses is the serializable list of bosses of a codebase
+type Bosses struct {
+	Bosses []Boss `json:"bosses"`
+}
+
+// Boss is the encounter standing for one hotspot function. Defeating it
+// means refactoring lines LineStart to LineEnd of File.
+type Boss struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Function  string   `json:"function"` // Receiver and name, such as "DB.Close"
+	Package   string   `json:"package"`
+	File      string   `json:"file"`
+	LineStart int      `json:"line_start"`
+	LineEnd   int      `json:"line_end"`
+	Level     int      `json:"level"`
+	Phases    int      `json:"phases"` // Deepest nesting of the body
+	Moves     []Move   `json:"moves"`
+	Loot      []string `json:"loot"`
+}
+
+// Move is an attack pattern of a boss, one per function it calls
+type Move struct {
+	Name   string `json:"name"`
+	Callee string `json:"callee"`
+}
+
+// GenerateBosses promotes the n highest-level hotspots into bosses. A
+// boss is named after its function, humanized from camel case, fights in
+// as many phases as the function nests deep, has a move for each
+// distinct callee and drops the constants of the function's file as
+// loot. IDs derive from the hotspot IDs, which qualify functions by
+// package, so functions sharing a name in different packages make
+// distinct bosses. Hotspots are ranked as DifficultyScorer ranks them,
+// and moves and loot are sorted, so the same hotspots give the same
+// bosses.
+func GenerateBosses(n int, hotspots []Hotspot) []Boss {
+	ranked := append([]Hotspot(nil), hotspots...)
+	sort.Slice(ranked, func(i, j int) bool {
+		if ranked[i].Level != ranked[j].Level {
+			return ranked[i].Level > ranked[j].Level
+		}
+		if ranked[i].Score != ranked[j].Score {
+			return ranked[i].Score > ranked[j].Score
+		}
+		return ranked[i].ID < ranked[j].ID
+	})
+
+	bosses := []Boss{}
+	for i, h := range ranked {
+		if i == n {
+			break
+		}
+		function := h.Name
+		if h.Receiver != "" {
+			function = h.Receiver + "." + h.Name
+		}
+		bosses = append(bosses, Boss{
+			ID:        "boss:" + h.ID,
+			Name:      humanize(h.Name),
+			Function:  function,
+			Package:   h.Package,
+			File:      h.File,
+			LineStart: h.Line,
+			LineEnd:   max(h.Line, h.LineEnd),
+			Level:     h.Level,
+			Phases:    h.Nesting,
+			Moves:     moves(h.Callees),
+			Loot:      loot(h.Constants),
+		})
+	}
+	return bosses
+}
+
+// moves returns a move for each distinct callee, sorted by name then ID
+func moves(callees []Callee) []Move {
+	seen := make(map[string]bool)
+	moves := []Move{}
+	for _, c := range callees {
+		if seen[c.ID] {
+			continue
+		}
+		seen[c.ID] = true
+		moves = append(moves, Move{Name: humanize(c.Name), Callee: c.ID})
+	}
+	sort.Slice(moves, func(i, j int) bool {
+		if moves[i].Name != moves[j].Name {
+			return moves[i].Name < moves[j].Name
+		}
+		return moves[i].Callee < moves[j].Callee
+	})
+	return moves
+}
+
+// loot returns the distinct constant names, sorted, leaving out blanks
+func loot(constants []string) []string {
+	seen := make(map[string]bool)
+	loot := []string{}
+	for _, name := range constants {
+		if name == "_" || seen[name] {
+			continue
+		}
+		seen[name] = true
+		loot = append(loot, name)
+	}
+	sort.Strings(loot)
+	return loot
+}
+
+// humanize turns an identifier into capitalized words: "parseHTTPRequest"
+// becomes "Parse HTTP Request" and "max_depth" "Max Depth"
+func humanize(name string) string {
+	var words []string
+	var word []rune
+	runes := []rune(name)
+	flush := func() {
+		if len(word) > 0 {
+			word[0] = unicode.ToUpper(word[0])
+			words = append(words, string(word))
+			word = nil
+		}
+	}
+	for i, r := range runes {
+		if r == '_' {
+			flush()
+			continue
+		}
+		if i > 0 && unicode.IsUpper(r) {
+			prev := runes[i-1]
+			// Split "HTTPRequest" into "HTTP" and "Request"
+			if !unicode.IsUpper(prev) || i+1 < len(runes) && unicode.IsLower(runes[i+1]) {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+	return strings.Join(words, " ")
+}
+
+// WriteJSON writes the bosses as indented JSON
+func (b *Bosses) WriteJSON(out io.Writer) error {
+	return writeJSON(out, b)
+}
@@ -0,0 +1,162 @@
+package game
+
+import (
+	"io"
+	"sort"
+	"strings"
+)
+
+// Inventory is a serializable list of items
+type Inventory struct {
+	Items []Item `json:"items"`
+}
+
+// Component is a part an item is crafted from, standing for one field of
+// its struct: a base material, or the item of another struct of the
+// package, referenced by ID
+type Component struct {
+	Name     string `json:"name"` // Field name, or type name when embedded
+	Type     string `json:"type"` // As written
+	Embedded bool   `json:"embedded,omitempty"`
+
+	// Material marks base materials: fields whose type, looking through
+	// pointers, slices, arrays and map values, is no struct of the package
+	Material bool `json:"material,omitempty"`
+
+	// Item is the ID of the item crafted for the field otherwise, listed
+	// with the other items of the package. Cycle marks components whose
+	// item is crafted, directly or in turn, from the item they are part of.
+	Item  string `json:"item,omitempty"`
+	Cycle bool   `json:"cycle,omitempty"`
+}
+
+// CraftItems turns every struct of package pkg, exported or not, into an
+// item as BuildItems does, with the components it is crafted from:
+// embedded structs first, then fields in declaration order. Every item is
+// listed once, and components refer to the items of other structs by ID,
+// so following them walks the crafting tree. Items are sorted by ID.
+func CraftItems(pkg string, structs []Struct, rules []AffixRule) []Item {
+	if rules == nil {
+		rules = DefaultAffixRules
+	}
+	byName := make(map[string]Struct, len(structs))
+	for _, s := range structs {
+		byName[s.Name] = s
+	}
+
+	items := make([]Item, 0, len(structs))
+	for _, s := range structs {
+		item := newItem(pkg, s, byName, rules)
+		item.Components = components(pkg, s, byName)
+		items = append(items, item)
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items
+}
+
+// components returns the components of struct s
+func components(pkg string, s Struct, byName map[string]Struct) []Component {
+	var parts []Component
+	for _, field := range craftedFields(s) {
+		c := Component{Name: field.Name, Type: field.Type, Embedded: field.embedded}
+		inner, ok := byName[elementType(field.Type)]
+		if !ok {
+			c.Material = true
+		} else {
+			c.Item = itemID(pkg, inner.Name)
+			c.Cycle = craftedFrom(inner.Name, s.Name, byName, make(map[string]bool))
+		}
+		parts = append(parts, c)
+	}
+	return parts
+}
+
+// craftedField is a field of a struct, or one of its embedded types named
+// by its type name
+type craftedField struct {
+	Field
+	embedded bool
+}
+
+// craftedFields returns the embedded types of s, then its fields
+func craftedFields(s Struct) []craftedField {
+	var fields []craftedField
+	for _, embed := range s.Embeds {
+		name := embeddedName(embed)
+		if name == "" {
+			name = embed
+		}
+		fields = append(fields, craftedField{Field{Name: name, Type: embed}, true})
+	}
+	for _, field := range s.Fields {
+		fields = append(fields, craftedField{field, false})
+	}
+	return fields
+}
+
+// craftedFrom reports whether the item of struct name is crafted, directly
+// or in turn, from the item of struct target. seen holds the structs
+// already looked through.
+func craftedFrom(name, target string, byName map[string]Struct, seen map[string]bool) bool {
+	if name == target {
+		return true
+	}
+	if seen[name] {
+		return false
+	}
+	seen[name] = true
+	for _, field := range craftedFields(byName[name]) {
+		inner, ok := byName[elementType(field.Type)]
+		if ok && craftedFrom(inner.Name, target, byName, seen) {
+			return true
+		}
+	}
+	return false
+}
+
+// elementType returns the type a field type holds, looking through
+// pointers, slices, arrays and map values, such as Part for map[string]*Part
+func elementType(typ string) string {
+	for {
+		switch {
+		case strings.HasPrefix(typ, "*"):
+			typ = typ[1:]
+		case strings.HasPrefix(typ, "map["):
+			end := closingBracket(typ, len("map"))
+			if end < 0 {
+				return typ
+			}
+			typ = typ[end+1:]
+		case strings.HasPrefix(typ, "["):
+			end := strings.Index(typ, "]")
+			if end < 0 {
+				return typ
+			}
+			typ = typ[end+1:]
+		default:
+			return typ
+		}
+	}
+}
+
+// closingBracket returns the index of the bracket closing the one at open
+// in typ, or -1
+func closingBracket(typ string, open int) int {
+	depth := 0
+	for i := open; i < len(typ); i++ {
+		switch typ[i] {
+		case '[':
+			depth++
+		case ']':
+			if depth--; depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// WriteJSON writes the inventory as indented JSON
+func (inv *Inventory) WriteJSON(out io.Writer) error {
+	return writeJSON(out, inv)
+}
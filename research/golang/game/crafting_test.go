@@ -0,0 +1,102 @@
+package game
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestCraftItems(t *testing.T) {
+	tests := []struct {
+		name    string
+		structs []Struct
+		want    map[string][]Component // By item ID
+	}{
+		{
+			name: "materials and items",
+			structs: []Struct{
+				{Name: "Sword", Fields: []Field{{Name: "Blade", Type: "*Blade"}, {Name: "Name", Type: "string"}}},
+				{Name: "Blade", Fields: []Field{{Name: "Edge", Type: "int"}}},
+			},
+			want: map[string][]Component{
+				"item:Blade": {{Name: "Edge", Type: "int", Material: true}},
+				"item:Sword": {
+					{Name: "Blade", Type: "*Blade", Item: "item:Blade"},
+					{Name: "Name", Type: "string", Material: true},
+				},
+			},
+		},
+		{
+			name: "shared component referenced by ID",
+			structs: []Struct{
+				{Name: "Left", Fields: []Field{{Name: "Gem", Type: "Gem"}}},
+				{Name: "Right", Fields: []Field{{Name: "Gems", Type: "[]Gem"}}},
+				{Name: "Gem", Fields: []Field{{Name: "Cut", Type: "float64"}}},
+			},
+			want: map[string][]Component{
+				"item:Gem":   {{Name: "Cut", Type: "float64", Material: true}},
+				"item:Left":  {{Name: "Gem", Type: "Gem", Item: "item:Gem"}},
+				"item:Right": {{Name: "Gems", Type: "[]Gem", Item: "item:Gem"}},
+			},
+		},
+		{
+			name: "embedded first and cycles",
+			structs: []Struct{
+				{Name: "Node", Embeds: []string{"*Base"}, Fields: []Field{{Name: "Next", Type: "*Node"}, {Name: "Tree", Type: "map[string]*Tree"}}},
+				{Name: "Tree", Fields: []Field{{Name: "Root", Type: "*Node"}}},
+				{Name: "Base", Fields: []Field{{Name: "ID", Type: "int"}}},
+			},
+			want: map[string][]Component{
+				"item:Base": {{Name: "ID", Type: "int", Material: true}},
+				"item:Node": {
+					{Name: "Base", Type: "*Base", Embedded: true, Item: "item:Base"},
+					{Name: "Next", Type: "*Node", Item: "item:Node", Cycle: true},
+					{Name: "Tree", Type: "map[string]*Tree", Item: "item:Tree", Cycle: true},
+				},
+				"item:Tree": {{Name: "Root", Type: "*Node", Item: "item:Node", Cycle: true}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			items := CraftItems("", tt.structs, nil)
+			if len(items) != len(tt.structs) {
+				t.Fatalf("got %d items, want one per struct (%d)", len(items), len(tt.structs))
+			}
+			got := make(map[string][]Component, len(items))
+			for _, item := range items {
+				got[item.ID] = item.Components
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("components = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestCraftItemsSharedChain checks that the inventory grows with the
+// number of structs, not with the number of paths through them: every
+// struct of the chain holds the next one twice
+func TestCraftItemsSharedChain(t *testing.T) {
+	size := func(n int) int {
+		structs := make([]Struct, n)
+		for i := range structs {
+			structs[i].Name = fmt.Sprintf("Level%02d", i)
+			if i+1 < n {
+				next := fmt.Sprintf("Level%02d", i+1)
+				structs[i].Fields = []Field{{Name: "A", Type: next}, {Name: "B", Type: next}}
+			}
+		}
+		var buf bytes.Buffer
+		inv := &Inventory{Items: CraftItems("", structs, nil)}
+		if err := inv.WriteJSON(&buf); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Len()
+	}
+	small, large := size(10), size(20)
+	if large > 3*small {
+		t.Errorf("20 structs take %d bytes, 10 take %d: output grows faster than the structs", large, small)
+	}
+}
@@ -0,0 +1,126 @@
+package game
+
+import (
+	"math"
+	"sort"
+)
+
+// MaxLevel is the level of the hardest function of a codebase
+const MaxLevel = 100
+
+// Metrics are the measurements of one function a level is derived from
+type Metrics struct {
+	ID         string // Stable identifier, used to order ties
+	File       string
+	Line       int
+	Complexity int // Cyclomatic complexity
+	Nesting    int // Deepest nesting of blocks in the body
+	Statements int
+	FanIn      int // Distinct functions calling it
+}
+
+// Weights weigh the normalized metrics into a score
+type Weights struct {
+	Complexity float64
+	Nesting    float64
+	Statements float64
+	FanIn      float64
+}
+
+// DefaultWeights are used by a DifficultyScorer with zero Weights
+var DefaultWeights = Weights{Complexity: 0.4, Nesting: 0.2, Statements: 0.25, FanIn: 0.15}
+
+// Components are the metrics of a function normalized against the
+// codebase: 0 for the lowest value found and 1 for the highest
+type Components struct {
+	Complexity float64 `json:"complexity"`
+	Nesting    float64 `json:"nesting"`
+	Statements float64 `json:"statements"`
+	FanIn      float64 `json:"fan_in"`
+}
+
+// Difficulty is the scored difficulty of one function
+type Difficulty struct {
+	Metrics
+	Components Components
+	Score      float64 // Weighted sum of the components
+	Level      int     // 1 to MaxLevel
+}
+
+// DifficultyScorer turns function metrics into enemy levels relative to
+// the codebase they come from
+type DifficultyScorer struct {
+	// Weights weigh the components; zero means DefaultWeights
+	Weights Weights
+}
+
+// weights returns the configured weights
+func (s DifficultyScorer) weights() Weights {
+	if s.Weights == (Weights{}) {
+		return DefaultWeights
+	}
+	return s.Weights
+}
+
+// Score levels every function. Each metric is normalized between the
+// lowest and highest value among metrics, the components are weighed
+// into a score and scores are scaled so the highest is MaxLevel and a
+// score of 0 is level 1. The result depends on nothing but metrics, so
+// scoring an unchanged codebase again gives the same levels. It is sorted
+// by level, highest first, then by ID.
+func (s DifficultyScorer) Score(metrics []Metrics) []Difficulty {
+	if len(metrics) == 0 {
+		return nil
+	}
+
+	normalize := func(value func(Metrics) int) func(Metrics) float64 {
+		low, high := value(metrics[0]), value(metrics[0])
+		for _, m := range metrics {
+			low = min(low, value(m))
+			high = max(high, value(m))
+		}
+		return func(m Metrics) float64 {
+			if high == low {
+				return 0
+			}
+			return float64(value(m)-low) / float64(high-low)
+		}
+	}
+	complexity := normalize(func(m Metrics) int { return m.Complexity })
+	nesting := normalize(func(m Metrics) int { return m.Nesting })
+	statements := normalize(func(m Metrics) int { return m.Statements })
+	fanIn := normalize(func(m Metrics) int { return m.FanIn })
+
+	w := s.weights()
+	scored := make([]Difficulty, len(metrics))
+	highest := 0.0
+	for i, m := range metrics {
+		c := Components{
+			Complexity: complexity(m),
+			Nesting:    nesting(m),
+			Statements: statements(m),
+			FanIn:      fanIn(m),
+		}
+		d := Difficulty{Metrics: m, Components: c}
+		d.Score = w.Complexity*c.Complexity + w.Nesting*c.Nesting + w.Statements*c.Statements + w.FanIn*c.FanIn
+		highest = max(highest, d.Score)
+		scored[i] = d
+	}
+
+	for i := range scored {
+		scored[i].Level = 1
+		if highest > 0 {
+			scored[i].Level += int(math.Round(float64(MaxLevel-1) * scored[i].Score / highest))
+		}
+	}
+	sort.Slice(scored, func(i, j int) bool {
+		if scored[i].Level != scored[j].Level {
+			return scored[i].Level > scored[j].Level
+		}
+		if scored[i].Score != scored[j].Score {
+			return scored[i].Score > scored[j].Score
+		}
+		return scored[i].ID < scored[j].ID
+	})
+	return scored
+}
@@ -0,0 +1,241 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+)
+
+// Room sizes are in grid cells. A room with no functions is the smallest
+// room; rooms grow with the square root of their function count, so their
+// area grows with the count itself.
+const (
+	minRoomSize = 3
+	roomGap     = 2 // Cells between neighboring grid slots
+)
+
+// DungeonFile is the part of a file of a package a room is built from
+type DungeonFile struct {
+	Path      string
+	Functions int // Functions and methods declared in the file
+	Exported  int // Those of them that are exported
+}
+
+// FileCall counts the calls from the functions of one file of a package
+// to those of another
+type FileCall struct {
+	From  string
+	To    string
+	Calls int
+}
+
+// Dungeon is the serializable dungeon of a codebase, one floor per package
+type Dungeon struct {
+	Floors []Floor `json:"floors"`
+}
+
+// Floor is the layout of one package: rooms placed on a grid and the
+// corridors between them
+type Floor struct {
+	Package   string     `json:"package"`
+	Entry     string     `json:"entry"` // ID of the room players enter by
+	Width     int        `json:"width"`
+	Height    int        `json:"height"`
+	Rooms     []Room     `json:"rooms"`
+	Corridors []Corridor `json:"corridors"`
+}
+
+// Room is one file, placed with its top-left corner at X, Y
+type Room struct {
+	ID        string `json:"id"`
+	File      string `json:"file"`
+	X         int    `json:"x"`
+	Y         int    `json:"y"`
+	Width     int    `json:"width"`
+	Height    int    `json:"height"`
+	Functions int    `json:"functions"`
+	Exported  int    `json:"exported"`
+}
+
+// Corridor joins two rooms, in either direction
+type Corridor struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Calls int    `json:"calls"` // Calls between the files both ways
+
+	// Fallback marks corridors added only to connect rooms no calls lead
+	// between
+	Fallback bool `json:"fallback,omitempty"`
+}
+
+// GenerateDungeon lays out the floor of package pkg. Each file becomes a
+// room sized by its function count, and files whose functions call each
+// other are joined by a corridor. Groups of rooms that calls leave apart
+// are chained by fallback corridors, so every room can be reached. The
+// entry is the room with the most exported functions. Rooms are placed
+// row by row on a square grid in breadth-first order from the entry, so
+// rooms that call each other tend to be close. The layout depends only on
+// the inputs: IDs derive from the package and file paths, and ties are
+// broken by path. It fails when files is empty or names a file twice.
+func GenerateDungeon(pkg string, files []DungeonFile, calls []FileCall) (*Floor, error) {
+	if len(files) == 0 {
+		return nil, errors.New("package has no files")
+	}
+	files = append([]DungeonFile(nil), files...)
+	sort.Slice(files, func(i, j int) bool { return files[i].Path < files[j].Path })
+	index := make(map[string]int, len(files))
+	for i, file := range files {
+		if _, ok := index[file.Path]; ok {
+			return nil, fmt.Errorf("file %q given twice", file.Path)
+		}
+		index[file.Path] = i
+	}
+
+	floor := &Floor{Package: pkg, Corridors: []Corridor{}}
+	for _, file := range files {
+		size := minRoomSize + int(math.Ceil(math.Sqrt(float64(file.Functions))))
+		floor.Rooms = append(floor.Rooms, Room{
+			ID:        "room:" + pkg + "/" + file.Path,
+			File:      file.Path,
+			Width:     size,
+			Height:    size,
+			Functions: file.Functions,
+			Exported:  file.Exported,
+		})
+	}
+
+	// Calls both ways between two files make one corridor
+	weights := make(map[[2]int]int)
+	for _, call := range calls {
+		from, okFrom := index[call.From]
+		to, okTo := index[call.To]
+		if !okFrom || !okTo || from == to || call.Calls <= 0 {
+			continue
+		}
+		weights[[2]int{min(from, to), max(from, to)}] += call.Calls
+	}
+	var pairs [][2]int
+	for pair := range weights {
+		pairs = append(pairs, pair)
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i][0] != pairs[j][0] {
+			return pairs[i][0] < pairs[j][0]
+		}
+		return pairs[i][1] < pairs[j][1]
+	})
+
+	groups := newUnionFind(len(files))
+	neighbors := make([][]int, len(files))
+	for _, pair := range pairs {
+		groups.union(pair[0], pair[1])
+		neighbors[pair[0]] = append(neighbors[pair[0]], pair[1])
+		neighbors[pair[1]] = append(neighbors[pair[1]], pair[0])
+		floor.Corridors = append(floor.Corridors, Corridor{
+			From:  floor.Rooms[pair[0]].ID,
+			To:    floor.Rooms[pair[1]].ID,
+			Calls: weights[pair],
+		})
+	}
+
+	// The first room of each group is chained to the first of the next,
+	// which keeps the spanning path short and deterministic
+	last := -1
+	for i := range files {
+		if groups.find(i) != i {
+			continue
+		}
+		if last >= 0 {
+			groups.union(last, i)
+			neighbors[last] = append(neighbors[last], i)
+			neighbors[i] = append(neighbors[i], last)
+			floor.Corridors = append(floor.Corridors, Corridor{
+				From:     floor.Rooms[last].ID,
+				To:       floor.Rooms[i].ID,
+				Fallback: true,
+			})
+		}
+		last = i
+	}
+
+	entry := 0
+	for i, file := range files {
+		if file.Exported > files[entry].Exported {
+			entry = i
+		}
+	}
+	floor.Entry = floor.Rooms[entry].ID
+	floor.place(breadthFirst(entry, neighbors))
+	return floor, nil
+}
+
+// place puts the rooms, in order, on a square grid whose slots fit the
+// largest room
+func (f *Floor) place(order []int) {
+	slot := 0
+	for _, room := range f.Rooms {
+		slot = max(slot, room.Width)
+	}
+	slot += roomGap
+	columns := int(math.Ceil(math.Sqrt(float64(len(order)))))
+
+	for i, room := range order {
+		f.Rooms[room].X = (i % columns) * slot
+		f.Rooms[room].Y = (i / columns) * slot
+		f.Width = max(f.Width, f.Rooms[room].X+f.Rooms[room].Width)
+		f.Height = max(f.Height, f.Rooms[room].Y+f.Rooms[room].Height)
+	}
+}
+
+// breadthFirst returns the nodes reachable from start, in breadth-first
+// order with neighbors visited by index
+func breadthFirst(start int, neighbors [][]int) []int {
+	seen := map[int]bool{start: true}
+	order := []int{start}
+	for i := 0; i < len(order); i++ {
+		next := append([]int(nil), neighbors[order[i]]...)
+		sort.Ints(next)
+		for _, n := range next {
+			if !seen[n] {
+				seen[n] = true
+				order = append(order, n)
+			}
+		}
+	}
+	return order
+}
+
+// unionFind tracks which rooms corridors already connect
+type unionFind []int
+
+// newUnionFind puts each of n elements in a set of its own
+func newUnionFind(n int) unionFind {
+	u := make(unionFind, n)
+	for i := range u {
+		u[i] = i
+	}
+	return u
+}
+
+// find returns the representative of the set of i, the smallest element
+// of the set
+func (u unionFind) find(i int) int {
+	for u[i] != i {
+		u[i] = u[u[i]]
+		i = u[i]
+	}
+	return i
+}
+
+// union merges the sets of i and j
+func (u unionFind) union(i, j int) {
+	ri, rj := u.find(i), u.find(j)
+	u[max(ri, rj)] = min(ri, rj)
+}
+
+// WriteJSON writes the dungeon as indented JSON
+func (d *Dungeon) WriteJSON(out io.Writer) error {
+	return writeJSON(out, d)
+}
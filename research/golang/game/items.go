@@ -0,0 +1,233 @@
+package game
+
+import (
+	"hash/fnv"
+	"path"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// maxCapacity bounds the capacity of container properties
+const maxCapacity = 16
+
+// Struct is the part of an extracted struct type an item is built from
+type Struct struct {
+	Name     string
+	File     string
+	Line     int
+	Exported bool
+	Fields   []Field  // Named fields
+	Embeds   []string // Embedded types, as written
+}
+
+// Field is a named struct field
+type Field struct {
+	Name string
+	Type string // As written, such as "int" or "[]string"
+	Tag  string // Raw struct tag, without quotes
+}
+
+// Item is the piece of equipment standing for an exported struct
+type Item struct {
+	ID         string      `json:"id"`
+	Name       string      `json:"name"`
+	File       string      `json:"file"`
+	Line       int         `json:"line"`
+	Stats      []Stat      `json:"stats"`
+	Flavor     []string    `json:"flavor"` // Text slots, one per string field
+	Containers []Container `json:"containers"`
+	Affixes    []string    `json:"affixes"`
+	Inherits   []string    `json:"inherits,omitempty"` // IDs of the embedded items
+
+	// Components are what the item is crafted from, set by CraftItems
+	Components []Component `json:"components,omitempty"`
+}
+
+// Stat is a stat line of an item, from a numeric field. Its value is the
+// bit size of the field's type, with int and uint counted as 64.
+type Stat struct {
+	Name  string `json:"name"`
+	Value int    `json:"value"`
+}
+
+// Container is a property of an item holding other things, from a slice
+// or map field. Its capacity is derived from a hash of the field's name
+// and type, so it means nothing but never changes.
+type Container struct {
+	Name     string `json:"name"`
+	Kind     string `json:"kind"` // "slice" or "map"
+	Capacity int    `json:"capacity"`
+}
+
+// AffixRule gives an item an affix when one of its fields has a struct tag
+// with Key
+type AffixRule struct {
+	Key   string
+	Affix string
+
+	// PerOption gives an affix for every comma-separated option of the tag
+	// value instead, written as Affix:option, such as "cursed:required"
+	PerOption bool
+}
+
+// DefaultAffixRules are used by BuildItems when given no rules: a json tag
+// makes an item tradeable and every validate rule curses it
+var DefaultAffixRules = []AffixRule{
+	{Key: "json", Affix: "tradeable"},
+	{Key: "validate", Affix: "cursed", PerOption: true},
+}
+
+// statBits are the bit sizes of the numeric types that become stats
+var statBits = map[string]int{
+	"int": 64, "int8": 8, "int16": 16, "int32": 32, "int64": 64,
+	"uint": 64, "uint8": 8, "uint16": 16, "uint32": 32, "uint64": 64,
+	"uintptr": 64, "byte": 8, "rune": 32,
+	"float32": 32, "float64": 64, "complex64": 64, "complex128": 128,
+}
+
+// BuildItems turns the exported structs of package pkg into items, keyed
+// by the file that defines them. Numeric fields become stats, string
+// fields flavor slots and slice and map fields containers; pointers are
+// looked through. Struct tags add affixes by rules, or DefaultAffixRules
+// when rules is nil. An item inherits the stats, flavor and containers
+// of the structs of pkg it embeds, exported or not, unless a field of its
+// own has the same name, as Go promotes fields. Items are sorted by ID
+// and their properties by name.
+func BuildItems(pkg string, structs []Struct, rules []AffixRule) map[string][]Item {
+	if rules == nil {
+		rules = DefaultAffixRules
+	}
+	byName := make(map[string]Struct, len(structs))
+	for _, s := range structs {
+		byName[s.Name] = s
+	}
+
+	items := make(map[string][]Item)
+	for _, s := range structs {
+		if s.Exported {
+			items[s.File] = append(items[s.File], newItem(pkg, s, byName, rules))
+		}
+	}
+	for file := range items {
+		sort.Slice(items[file], func(i, j int) bool { return items[file][i].ID < items[file][j].ID })
+	}
+	return items
+}
+
+// newItem builds the item of struct s of package pkg, whose structs are
+// byName
+func newItem(pkg string, s Struct, byName map[string]Struct, rules []AffixRule) Item {
+	item := Item{
+		ID:         itemID(pkg, s.Name),
+		Name:       s.Name,
+		File:       s.File,
+		Line:       s.Line,
+		Stats:      []Stat{},
+		Flavor:     []string{},
+		Containers: []Container{},
+		Affixes:    affixes(s.Fields, rules),
+	}
+	for _, embed := range s.Embeds {
+		if inner, ok := byName[embeddedName(embed)]; ok && inner.Exported {
+			item.Inherits = append(item.Inherits, itemID(pkg, inner.Name))
+		}
+	}
+	item.addFields(s, byName, make(map[string]bool))
+	sort.Slice(item.Stats, func(i, j int) bool { return item.Stats[i].Name < item.Stats[j].Name })
+	sort.Strings(item.Flavor)
+	sort.Slice(item.Containers, func(i, j int) bool { return item.Containers[i].Name < item.Containers[j].Name })
+	return item
+}
+
+// itemID returns the ID of the item of struct name in package pkg
+func itemID(pkg, name string) string {
+	return "item:" + path.Join(pkg, name)
+}
+
+// addFields adds the properties of the fields of s and, below them, of
+// the structs it embeds. Names already taken are skipped, as are structs
+// already visited, which stops embedding cycles.
+func (item *Item) addFields(s Struct, byName map[string]Struct, visited map[string]bool) {
+	visited[s.Name] = true
+	taken := make(map[string]bool)
+	for _, st := range item.Stats {
+		taken[st.Name] = true
+	}
+	for _, f := range item.Flavor {
+		taken[f] = true
+	}
+	for _, c := range item.Containers {
+		taken[c.Name] = true
+	}
+
+	for _, field := range s.Fields {
+		if taken[field.Name] {
+			continue
+		}
+		typ := strings.TrimLeft(field.Type, "*")
+		switch {
+		case statBits[typ] > 0:
+			item.Stats = append(item.Stats, Stat{Name: field.Name, Value: statBits[typ]})
+		case typ == "string":
+			item.Flavor = append(item.Flavor, field.Name)
+		case strings.HasPrefix(typ, "[]"):
+			item.Containers = append(item.Containers, newContainer(field, "slice"))
+		case strings.HasPrefix(typ, "map["):
+			item.Containers = append(item.Containers, newContainer(field, "map"))
+		}
+	}
+	for _, embed := range s.Embeds {
+		if inner, ok := byName[embeddedName(embed)]; ok && !visited[inner.Name] {
+			item.addFields(inner, byName, visited)
+		}
+	}
+}
+
+// newContainer makes the container property of a slice or map field
+func newContainer(field Field, kind string) Container {
+	h := fnv.New32a()
+	h.Write([]byte(field.Name + " " + field.Type))
+	return Container{Name: field.Name, Kind: kind, Capacity: 1 + int(h.Sum32()%maxCapacity)}
+}
+
+// affixes returns the sorted, distinct affixes the rules give fields
+func affixes(fields []Field, rules []AffixRule) []string {
+	seen := make(map[string]bool)
+	affixes := []string{}
+	add := func(affix string) {
+		if !seen[affix] {
+			seen[affix] = true
+			affixes = append(affixes, affix)
+		}
+	}
+	for _, field := range fields {
+		for _, rule := range rules {
+			value, ok := reflect.StructTag(field.Tag).Lookup(rule.Key)
+			if !ok {
+				continue
+			}
+			if !rule.PerOption {
+				add(rule.Affix)
+				continue
+			}
+			for _, option := range strings.Split(value, ",") {
+				if option = strings.TrimSpace(option); option != "" {
+					add(rule.Affix + ":" + option)
+				}
+			}
+		}
+	}
+	sort.Strings(affixes)
+	return affixes
+}
+
+// embeddedName returns the type name of an embedded field of the same
+// package, such as Base for *Base, or "" for qualified and generic types
+func embeddedName(embed string) string {
+	name := strings.TrimPrefix(embed, "*")
+	if strings.ContainsAny(name, ".[") {
+		return ""
+	}
+	return name
+}
@@ -0,0 +1,192 @@
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Quest rewards grow with the length of the function a task sits in, the
+// age proxy of the task: long functions have usually had their TODOs for
+// a long time. Urgent quests pay double.
+const (
+	baseReward     = 10
+	rewardPerLines = 10 // Lines of the enclosing function per extra point
+	urgentMultiple = 2
+)
+
+// Quest priorities
+const (
+	urgentPriority  = "urgent"
+	defaultPriority = "normal"
+)
+
+// maxTitleLength is the length in runes past which titles are cut
+const maxTitleLength = 72
+
+// urgentMarkers are the task markers that make urgent quests
+var urgentMarkers = map[string]bool{"FIXME": true, "BUG": true}
+
+// TaskComment is a task left in a comment, such as
+// "// TODO(ann): handle retries"
+type TaskComment struct {
+	Marker    string // TODO, FIXME or BUG
+	Owner     string // From TODO(owner), if any
+	Text      string // The comment after the marker, continuation lines included
+	Package   string // Slash-separated, such as "." or "internal/store"
+	File      string // Slash-separated path, in Package
+	Line      int
+	Enclosing string // Function, Type.Method or type the comment is in, if any
+
+	// EnclosingLines is the length of the enclosing declaration in lines
+	EnclosingLines int
+}
+
+// QuestLog is the serializable list of the open quests of a codebase and
+// of the quests completed since an earlier run
+type QuestLog struct {
+	Quests    []Quest `json:"quests"`
+	Completed []Quest `json:"completed"`
+}
+
+// Quest is a task to be done in the code
+type Quest struct {
+	ID       string   `json:"id"`
+	Title    string   `json:"title"`
+	Giver    string   `json:"giver"`
+	Marker   string   `json:"marker"`
+	Priority string   `json:"priority"` // "urgent" for FIXME and BUG, else "normal"
+	Location Location `json:"location"`
+	File     string   `json:"file"`
+	Line     int      `json:"line"`
+	Reward   int      `json:"reward"`
+}
+
+// Location points at the zone and dungeon room of a file, by the IDs
+// BuildWorld and GenerateDungeon give them
+type Location struct {
+	Zone string `json:"zone"`
+	Room string `json:"room,omitempty"` // Empty for test files, which have no room
+}
+
+// GenerateQuests turns tasks into quests. The title is the first sentence
+// of the text, cleaned up; the giver is the task's owner, else the
+// declaration it is in, else its package. IDs hash the file with the
+// marker and normalized text, not the line, so quests keep their IDs
+// while the code around them moves; identical tasks of one file are told
+// apart by a suffix in line order. Quests are sorted by file and line.
+func GenerateQuests(tasks []TaskComment) []Quest {
+	tasks = append([]TaskComment(nil), tasks...)
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].File != tasks[j].File {
+			return tasks[i].File < tasks[j].File
+		}
+		return tasks[i].Line < tasks[j].Line
+	})
+
+	quests := []Quest{}
+	uses := make(map[string]int)
+	for _, t := range tasks {
+		id := questID(t)
+		if uses[id]++; uses[id] > 1 {
+			id = fmt.Sprintf("%s#%d", id, uses[id])
+		}
+
+		giver := t.Owner
+		if giver == "" {
+			giver = t.Enclosing
+		}
+		if giver == "" {
+			giver = t.Package
+		}
+		// Dungeons leave test files out, so their quests are in no room
+		location := Location{Zone: "zone:" + t.Package}
+		if !strings.HasSuffix(t.File, "_test.go") {
+			location.Room = "room:" + t.Package + "/" + path.Base(t.File)
+		}
+		q := Quest{
+			ID:       id,
+			Title:    questTitle(t),
+			Giver:    giver,
+			Marker:   t.Marker,
+			Priority: defaultPriority,
+			Location: location,
+			File:     t.File,
+			Line:     t.Line,
+			Reward:   baseReward + t.EnclosingLines/rewardPerLines,
+		}
+		if urgentMarkers[t.Marker] {
+			q.Priority = urgentPriority
+			q.Reward *= urgentMultiple
+		}
+		quests = append(quests, q)
+	}
+	return quests
+}
+
+// CompletedQuests returns the quests of previous missing from current,
+// those whose tasks were resolved in between, in their previous order
+func CompletedQuests(previous, current []Quest) []Quest {
+	open := make(map[string]bool, len(current))
+	for _, q := range current {
+		open[q.ID] = true
+	}
+	completed := []Quest{}
+	for _, q := range previous {
+		if !open[q.ID] {
+			completed = append(completed, q)
+		}
+	}
+	return completed
+}
+
+// questID derives the ID of a task from its file, marker and normalized
+// text
+func questID(t TaskComment) string {
+	h := fnv.New64a()
+	h.Write([]byte(t.File + "\x00" + t.Marker + "\x00" + strings.ToLower(strings.Join(strings.Fields(t.Text), " "))))
+	return fmt.Sprintf("quest:%016x", h.Sum64())
+}
+
+// questTitle returns the first sentence of a task's text with whitespace
+// collapsed, its first letter capitalized and without a final period,
+// cut at a word when long. Tasks without text are named after the marker.
+func questTitle(t TaskComment) string {
+	text := strings.Join(strings.Fields(t.Text), " ")
+	if end := strings.Index(text, ". "); end >= 0 {
+		text = text[:end]
+	}
+	text = strings.TrimRight(text, ".:;, ")
+	if text == "" {
+		return t.Marker
+	}
+	if runes := []rune(text); len(runes) > maxTitleLength {
+		text = string(runes[:maxTitleLength])
+		if space := strings.LastIndex(text, " "); space > 0 {
+			text = text[:space]
+		}
+		text += "…"
+	}
+	runes := []rune(text)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+// ReadQuestLog reads a quest log written by WriteJSON
+func ReadQuestLog(r io.Reader) (*QuestLog, error) {
+	var log QuestLog
+	if err := json.NewDecoder(r).Decode(&log); err != nil {
+		return nil, err
+	}
+	return &log, nil
+}
+
+// WriteJSON writes the quest log as indented JSON
+func (l *QuestLog) WriteJSON(out io.Writer) error {
+	return writeJSON(out, l)
+}
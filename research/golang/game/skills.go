@@ -0,0 +1,271 @@
+package game
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// DefaultSkillDepth is the depth past which a SkillTreeBuilder with zero
+// MaxDepth prunes skills
+const DefaultSkillDepth = 8
+
+// CallGraph is the part of a call graph a skill tree is built from
+type CallGraph struct {
+	Nodes []CallNode
+	Edges map[string][]string // Caller ID to callee IDs
+}
+
+// CallNode is a function of a CallGraph
+type CallNode struct {
+	ID   string
+	Name string
+	Cost int // Price of unlocking its skill, such as its difficulty level
+}
+
+// SkillTree is the serializable skill tree of a call graph. Root and its
+// children form a strict tree; every other call between skills of the
+// tree is a synergy.
+type SkillTree struct {
+	Root      Skill     `json:"root"`
+	MaxDepth  int       `json:"max_depth"`
+	Synergies []Synergy `json:"synergies"`
+
+	// Pruned lists the IDs of the functions reachable from the root that
+	// were cut for lying deeper than MaxDepth
+	Pruned []string `json:"pruned"`
+}
+
+// Skill is one function, or one recursion cycle collapsed into a mastery
+// skill, unlocked by its parent
+type Skill struct {
+	ID       string   `json:"id"`
+	Name     string   `json:"name"`
+	Cost     int      `json:"cost"`
+	Depth    int      `json:"depth"`
+	Mastery  bool     `json:"mastery,omitempty"`
+	Members  []string `json:"members,omitempty"` // Function IDs of a mastery
+	Children []Skill  `json:"children"`
+}
+
+// Synergy is a call between two skills of a tree that the tree does not
+// follow
+type Synergy struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// SkillTreeBuilder builds skill trees out of call graphs
+type SkillTreeBuilder struct {
+	// MaxDepth is the deepest level kept below the root; zero means
+	// DefaultSkillDepth
+	MaxDepth int
+}
+
+// maxDepth returns the configured depth limit
+func (b SkillTreeBuilder) maxDepth() int {
+	if b.MaxDepth <= 0 {
+		return DefaultSkillDepth
+	}
+	return b.MaxDepth
+}
+
+// BuildSkillTree builds the skill tree of cg from root with a
+// SkillTreeBuilder of default depth
+func BuildSkillTree(cg *CallGraph, root string) (*SkillTree, error) {
+	return SkillTreeBuilder{}.Build(cg, root)
+}
+
+// Build builds the skill tree of cg rooted at the function with ID root.
+// Every function calling each other in a cycle, or calling itself, is
+// collapsed into one mastery skill costing what its members cost
+// together. The graph is then walked breadth first from the root, callees
+// in ID order, so each skill is unlocked by the first parent found on a
+// shortest path to it; the other calls between skills become synergies.
+// Skills deeper than the limit are pruned and listed. Children, synergies
+// and pruned IDs are sorted, so the same graph gives the same tree. It
+// fails when root is not a node of cg.
+func (b SkillTreeBuilder) Build(cg *CallGraph, root string) (*SkillTree, error) {
+	nodes := make(map[string]CallNode, len(cg.Nodes))
+	ids := make([]string, 0, len(cg.Nodes))
+	for _, n := range cg.Nodes {
+		nodes[n.ID] = n
+		ids = append(ids, n.ID)
+	}
+	if _, ok := nodes[root]; !ok {
+		return nil, fmt.Errorf("skill tree root %q not found", root)
+	}
+	sort.Strings(ids)
+
+	// Skills of the condensation, by the ID of each member function
+	component := make(map[string]*Skill)
+	for _, members := range cycles(ids, cg.Edges, nodes) {
+		skill := &Skill{Children: []Skill{}}
+		for _, id := range members {
+			component[id] = skill
+			skill.Cost += nodes[id].Cost
+		}
+		if len(members) > 1 || selfCalls(cg.Edges, members[0]) {
+			skill.ID = "mastery:" + strings.Join(members, "+")
+			skill.Name = nodes[members[0]].Name + " mastery"
+			skill.Mastery = true
+			skill.Members = members
+		} else {
+			skill.ID = members[0]
+			skill.Name = nodes[members[0]].Name
+		}
+	}
+
+	// Calls between skills, deduplicated and sorted
+	calls := make(map[*Skill][]*Skill)
+	for _, caller := range ids {
+		from := component[caller]
+		for _, callee := range cg.Edges[caller] {
+			to, ok := component[callee]
+			if ok && to != from {
+				calls[from] = append(calls[from], to)
+			}
+		}
+	}
+	for from, to := range calls {
+		sort.Slice(to, func(i, j int) bool { return to[i].ID < to[j].ID })
+		calls[from] = uniqueSkills(to)
+	}
+
+	limit := b.maxDepth()
+	tree := &SkillTree{MaxDepth: limit, Synergies: []Synergy{}, Pruned: []string{}}
+	start := component[root]
+	parent := map[*Skill]*Skill{start: nil}
+	order := []*Skill{start}
+	for i := 0; i < len(order); i++ {
+		for _, next := range calls[order[i]] {
+			if _, seen := parent[next]; !seen {
+				parent[next] = order[i]
+				next.Depth = order[i].Depth + 1
+				order = append(order, next)
+			}
+		}
+	}
+
+	kept := make(map[*Skill]bool)
+	for _, skill := range order {
+		if skill.Depth > limit {
+			if skill.Mastery {
+				tree.Pruned = append(tree.Pruned, skill.Members...)
+			} else {
+				tree.Pruned = append(tree.Pruned, skill.ID)
+			}
+			continue
+		}
+		kept[skill] = true
+	}
+	for _, from := range order {
+		if !kept[from] {
+			continue
+		}
+		for _, to := range calls[from] {
+			if kept[to] && parent[to] != from {
+				tree.Synergies = append(tree.Synergies, Synergy{From: from.ID, To: to.ID})
+			}
+		}
+	}
+	sort.Slice(tree.Synergies, func(i, j int) bool {
+		if tree.Synergies[i].From != tree.Synergies[j].From {
+			return tree.Synergies[i].From < tree.Synergies[j].From
+		}
+		return tree.Synergies[i].To < tree.Synergies[j].To
+	})
+	sort.Strings(tree.Pruned)
+
+	// Children are attached deepest first, so each is complete when its
+	// parent copies it
+	for n := len(order) - 1; n >= 0; n-- {
+		skill := order[n]
+		if !kept[skill] {
+			continue
+		}
+		sort.Slice(skill.Children, func(i, j int) bool { return skill.Children[i].ID < skill.Children[j].ID })
+		if p := parent[skill]; p != nil {
+			p.Children = append(p.Children, *skill)
+		}
+	}
+	tree.Root = *start
+	return tree, nil
+}
+
+// cycles returns the strongly connected components of the graph, each
+// with its members sorted, with Tarjan's algorithm
+func cycles(ids []string, edges map[string][]string, nodes map[string]CallNode) [][]string {
+	index := make(map[string]int)
+	low := make(map[string]int)
+	onStack := make(map[string]bool)
+	var stack []string
+	var components [][]string
+
+	var visit func(id string)
+	visit = func(id string) {
+		index[id] = len(index)
+		low[id] = index[id]
+		stack = append(stack, id)
+		onStack[id] = true
+		for _, next := range edges[id] {
+			if _, ok := nodes[next]; !ok {
+				continue
+			}
+			if _, seen := index[next]; !seen {
+				visit(next)
+				low[id] = min(low[id], low[next])
+			} else if onStack[next] {
+				low[id] = min(low[id], index[next])
+			}
+		}
+		if low[id] != index[id] {
+			return
+		}
+		var members []string
+		for {
+			top := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			onStack[top] = false
+			members = append(members, top)
+			if top == id {
+				break
+			}
+		}
+		sort.Strings(members)
+		components = append(components, members)
+	}
+	for _, id := range ids {
+		if _, seen := index[id]; !seen {
+			visit(id)
+		}
+	}
+	return components
+}
+
+// selfCalls reports whether function id calls itself
+func selfCalls(edges map[string][]string, id string) bool {
+	for _, callee := range edges[id] {
+		if callee == id {
+			return true
+		}
+	}
+	return false
+}
+
+// uniqueSkills removes adjacent duplicates from sorted skills
+func uniqueSkills(skills []*Skill) []*Skill {
+	var unique []*Skill
+	for i, s := range skills {
+		if i == 0 || s != skills[i-1] {
+			unique = append(unique, s)
+		}
+	}
+	return unique
+}
+
+// WriteJSON writes the skill tree as indented JSON
+func (t *SkillTree) WriteJSON(out io.Writer) error {
+	return writeJSON(out, t)
+}
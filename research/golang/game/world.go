@@ -0,0 +1,157 @@
+// Package game maps the results of the AST benchmark onto a game world:
+// packages become zones, their functions the characters living there and
+// their exported structs the items found there.
+// It takes its own plain inputs so it does not depend on the analyzer.
+package game
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+)
+
+// Package is the part of an analyzed package a world is built from
+type Package struct {
+	Path      string // Slash-separated, such as "." or "internal/store"
+	Files     int
+	Functions []Function
+
+	// Items are the package's items by defining file, as BuildItems
+	// builds them
+	Items map[string][]Item
+}
+
+// Function is the part of an extracted function a character is built from
+type Function struct {
+	Name      string
+	Receiver  string // Bare receiver type name, for methods
+	File      string
+	Exported  bool
+	Params    int
+	Results   int
+	LineStart int
+	LineEnd   int
+}
+
+// World is the serializable game world of an analyzed codebase
+type World struct {
+	Zones []Zone `json:"zones"`
+}
+
+// Zone is the area of one package
+type Zone struct {
+	ID      string   `json:"id"`
+	Name    string   `json:"name"`
+	Files   int      `json:"files"`
+	NPCs    []Entity `json:"npcs"`    // Exported functions
+	Enemies []Entity `json:"enemies"` // Unexported functions
+
+	// Inventories hold the items of the package by defining file
+	Inventories map[string][]Item `json:"inventories"`
+}
+
+// Entity is a character standing for one function. Its attributes grow
+// with the function: parameters give attack, results defense and its
+// length in lines hit points.
+type Entity struct {
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	Receiver string `json:"receiver,omitempty"`
+	File     string `json:"file"`
+	Line     int    `json:"line"`
+	Attack   int    `json:"attack"`
+	Defense  int    `json:"defense"`
+	HP       int    `json:"hp"`
+}
+
+// BuildWorld builds one zone per package and one character per function:
+// an NPC for each exported function and an enemy for each unexported one.
+// IDs join the package path with the function's receiver and name, such
+// as "npc:store/DB.Close", so building the same packages again gives the
+// same world. Functions sharing a name, such as several init functions,
+// are told apart by a suffix in file and line order. Zones are sorted by
+// path and characters by ID. The items of each package are put in its
+// zone's inventories. It fails when two packages share a path.
+func BuildWorld(packages []Package) (*World, error) {
+	world := &World{Zones: []Zone{}}
+	seen := make(map[string]bool)
+	for _, pkg := range packages {
+		if seen[pkg.Path] {
+			return nil, fmt.Errorf("package %q given twice", pkg.Path)
+		}
+		seen[pkg.Path] = true
+		world.Zones = append(world.Zones, newZone(pkg))
+	}
+	sort.Slice(world.Zones, func(i, j int) bool { return world.Zones[i].ID < world.Zones[j].ID })
+	return world, nil
+}
+
+// newZone builds the zone of a package
+func newZone(pkg Package) Zone {
+	zone := Zone{
+		ID:      "zone:" + pkg.Path,
+		Name:    pkg.Path,
+		Files:   pkg.Files,
+		NPCs:    []Entity{},
+		Enemies: []Entity{},
+
+		Inventories: make(map[string][]Item),
+	}
+	for file, items := range pkg.Items {
+		zone.Inventories[file] = items
+	}
+
+	functions := append([]Function(nil), pkg.Functions...)
+	sort.SliceStable(functions, func(i, j int) bool {
+		if functions[i].File != functions[j].File {
+			return functions[i].File < functions[j].File
+		}
+		return functions[i].LineStart < functions[j].LineStart
+	})
+
+	uses := make(map[string]int)
+	for _, fn := range functions {
+		name := fn.Name
+		if fn.Receiver != "" {
+			name = fn.Receiver + "." + fn.Name
+		}
+		id := path.Join(pkg.Path, name)
+		if uses[id]++; uses[id] > 1 {
+			id = fmt.Sprintf("%s#%d", id, uses[id])
+		}
+
+		e := Entity{
+			Name:     fn.Name,
+			Receiver: fn.Receiver,
+			File:     fn.File,
+			Line:     fn.LineStart,
+			Attack:   fn.Params,
+			Defense:  fn.Results,
+			HP:       fn.LineEnd - fn.LineStart + 1,
+		}
+		if fn.Exported {
+			e.ID = "npc:" + id
+			zone.NPCs = append(zone.NPCs, e)
+		} else {
+			e.ID = "enemy:" + id
+			zone.Enemies = append(zone.Enemies, e)
+		}
+	}
+	sort.Slice(zone.NPCs, func(i, j int) bool { return zone.NPCs[i].ID < zone.NPCs[j].ID })
+	sort.Slice(zone.Enemies, func(i, j int) bool { return zone.Enemies[i].ID < zone.Enemies[j].ID })
+	return zone
+}
+
+// WriteJSON writes the world as indented JSON
+func (w *World) WriteJSON(out io.Writer) error {
+	return writeJSON(out, w)
+}
+
+// writeJSON writes v as indented JSON
+func writeJSON(out io.Writer, v any) error {
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
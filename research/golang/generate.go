@@ -0,0 +1,52 @@
+package main
+
+import "strings"
+
+// generatePrefix starts every directive run by go generate
+const generatePrefix = "//go:generate"
+
+// GenerateDirective is a //go:generate line and the command it runs
+type GenerateDirective struct {
+	FilePath string
+	Line     int
+	Command  string // Everything after the prefix, trimmed
+}
+
+// ExtractGenerateDirectives lists the //go:generate directives of all Go
+// files under dir. Like go generate, it only accepts directives that
+// start at the beginning of a line. Files with syntax errors contribute
+// the directives found before the error.
+func (a *ASTAnalyzer) ExtractGenerateDirectives(dir string) ([]GenerateDirective, error) {
+	files, err := a.goFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var directives []GenerateDirective
+	for _, path := range files {
+		f, _ := a.cache.Parse(path)
+		if f == nil {
+			continue
+		}
+
+		for _, group := range f.Comments {
+			for _, c := range group.List {
+				command, ok := strings.CutPrefix(c.Text, generatePrefix)
+				if !ok || command != "" && command[0] != ' ' && command[0] != '\t' {
+					continue
+				}
+				position := a.fset.Position(c.Pos())
+				if position.Column != 1 {
+					continue
+				}
+				directives = append(directives, GenerateDirective{
+					FilePath: path,
+					Line:     position.Line,
+					Command:  strings.TrimSpace(command),
+				})
+			}
+		}
+	}
+
+	return directives, nil
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"go/ast"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// generatedHeader matches the canonical generated-code comment, see
+// https://go.dev/s/generatedcode
+var generatedHeader = regexp.MustCompile(`^// Code generated .* DO NOT EDIT\.$`)
+
+// generatedNameMarkers are file name fragments used by common generators
+var generatedNameMarkers = []string{".pb.go", "_string.go", "zz_generated"}
+
+// isGeneratedFile reports whether a file was produced by a code generator,
+// either by its name or by the header comment. Only comments before the
+// package clause are considered, per the Go convention.
+func isGeneratedFile(path string, f *ast.File) bool {
+	if isGeneratedName(path) {
+		return true
+	}
+	if f == nil {
+		return false
+	}
+
+	for _, group := range f.Comments {
+		if group.Pos() >= f.Package {
+			break
+		}
+		for _, comment := range group.List {
+			if generatedHeader.MatchString(comment.Text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isGeneratedName reports whether a file name carries a generator marker
+func isGeneratedName(path string) bool {
+	base := filepath.Base(path)
+	for _, marker := range generatedNameMarkers {
+		if strings.Contains(base, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// isTestName reports whether path names a test file, which only go test
+// compiles
+func isTestName(path string) bool {
+	return strings.HasSuffix(path, "_test.go")
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+)
+
+// Default limits beyond which files are skipped rather than parsed. A
+// single huge generated file can dominate a run or exhaust memory.
+const (
+	DefaultMaxFileSize   = 5 << 20
+	DefaultMaxLineLength = 64 << 10
+)
+
+// ErrOversize is wrapped by the error of files skipped for exceeding
+// MaxFileSize or MaxLineLength
+var ErrOversize = errors.New("file exceeds size limits")
+
+// maxFileSize returns the configured file size limit
+func (a *ASTAnalyzer) maxFileSize() int64 {
+	if a.MaxFileSize > 0 {
+		return a.MaxFileSize
+	}
+	return DefaultMaxFileSize
+}
+
+// maxLineLength returns the configured line length limit
+func (a *ASTAnalyzer) maxLineLength() int {
+	if a.MaxLineLength > 0 {
+		return a.MaxLineLength
+	}
+	return DefaultMaxLineLength
+}
+
+// checkFileSize rejects files larger than MaxFileSize unless Force is set
+func (a *ASTAnalyzer) checkFileSize(size int64) error {
+	if limit := a.maxFileSize(); !a.Force && size > limit {
+		return fmt.Errorf("%w: %d bytes, limit %d", ErrOversize, size, limit)
+	}
+	return nil
+}
+
+// checkLineLength rejects sources with a line longer than MaxLineLength
+// unless Force is set
+func (a *ASTAnalyzer) checkLineLength(src []byte) error {
+	if a.Force {
+		return nil
+	}
+	limit := a.maxLineLength()
+	for line := 1; len(src) > 0; line++ {
+		end := bytes.IndexByte(src, '\n')
+		if end < 0 {
+			end = len(src)
+		}
+		if end > limit {
+			return fmt.Errorf("%w: line %d is %d bytes, limit %d", ErrOversize, line, end, limit)
+		}
+		src = src[min(end+1, len(src)):]
+	}
+	return nil
+}
+
+// oversize reports whether the file at path exceeds MaxFileSize or
+// MaxLineLength, so that the passes over a directory skip the files the
+// benchmark skips. Only files longer than the line limit are read, since
+// shorter ones cannot hold a longer line.
+func (a *ASTAnalyzer) oversize(path string) bool {
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+	if a.checkFileSize(info.Size()) != nil {
+		return true
+	}
+	if a.Force || info.Size() <= int64(a.maxLineLength()) {
+		return false
+	}
+	src, err := os.ReadFile(path)
+	return err == nil && a.checkLineLength(src) != nil
+}
+
+// skippedParse returns the result of a file skipped by a size guard. It is
+// neither a success nor a failure and is not observed by the metrics.
+func (a *ASTAnalyzer) skippedParse(filePath string, size int64, err error) ParseResult {
+	err = fmt.Errorf("%s: %w", filePath, err)
+	return ParseResult{
+		FilePath:        filePath,
+		Generated:       isGeneratedName(filePath),
+		IsTest:          isTestName(filePath),
+		SkippedOversize: true,
+		Error:           err,
+		ErrorMessage:    err.Error(),
+		FileSizeBytes:   size,
+	}
+}
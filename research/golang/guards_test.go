@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSizeGuards(t *testing.T) {
+	small := "package a\n\nfunc A() {}\n"
+	big := "package a\n\nfunc B() {}\n" + strings.Repeat("// padding\n", 100)
+	long := "package a\n\nfunc C() {}\n// " + strings.Repeat("x", 300) + "\n"
+
+	tests := []struct {
+		name          string
+		maxFileSize   int64
+		maxLineLength int
+		force         bool
+		skipped       int
+		skippedBytes  int64
+		functions     int // Of the parsed files, as the passes see them
+	}{
+		{name: "defaults", functions: 3},
+		{name: "file size", maxFileSize: 500, skipped: 1, skippedBytes: int64(len(big)), functions: 2},
+		{name: "line length", maxLineLength: 100, skipped: 1, skippedBytes: int64(len(long)), functions: 2},
+		{name: "both", maxFileSize: 500, maxLineLength: 100, skipped: 2, skippedBytes: int64(len(big) + len(long)), functions: 1},
+		{name: "force", maxFileSize: 500, maxLineLength: 100, force: true, functions: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeTree(t, map[string]string{"small.go": small, "big.go": big, "long.go": long})
+			a := quietAnalyzer()
+			a.MaxFileSize = tt.maxFileSize
+			a.MaxLineLength = tt.maxLineLength
+			a.Force = tt.force
+
+			results, err := a.BenchmarkDirectory(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			s := summarizeResults(results, false)
+			if s.TotalFiles != 3 || s.Skipped != tt.skipped || s.SkippedBytes != tt.skippedBytes {
+				t.Errorf("summary: %d files, %d skipped of %d bytes; want 3, %d, %d",
+					s.TotalFiles, s.Skipped, s.SkippedBytes, tt.skipped, tt.skippedBytes)
+			}
+			if s.Successful != 3-tt.skipped || s.Failed != 0 {
+				t.Errorf("summary: %d successful, %d failed; want %d, 0", s.Successful, s.Failed, 3-tt.skipped)
+			}
+			if s.Functions != tt.functions {
+				t.Errorf("summary counts %d functions, want %d", s.Functions, tt.functions)
+			}
+
+			// Skipped files count in no average
+			var parsedBytes int64
+			for _, r := range results {
+				if r.SkippedOversize {
+					if r.Success || r.NumFunctions != 0 {
+						t.Errorf("%s skipped but counted: %+v", r.FilePath, r)
+					}
+					continue
+				}
+				parsedBytes += r.FileSizeBytes
+			}
+			if s.TotalBytes != parsedBytes {
+				t.Errorf("summary sizes %d bytes, want %d of parsed files", s.TotalBytes, parsedBytes)
+			}
+
+			m, err := a.APISurface(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Functions != tt.functions {
+				t.Errorf("APISurface counts %d functions, want %d", m.Functions, tt.functions)
+			}
+		})
+	}
+}
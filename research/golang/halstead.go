@@ -0,0 +1,65 @@
+package main
+
+import (
+	"go/ast"
+	"math"
+)
+
+// HalsteadMetrics holds Halstead's software science measures of a function
+// body. Operators are the unary and binary operators it applies; operands
+// are the identifiers and literals it names.
+type HalsteadMetrics struct {
+	DistinctOperators int     `json:"distinct_operators"` // n1
+	DistinctOperands  int     `json:"distinct_operands"`  // n2
+	TotalOperators    int     `json:"total_operators"`    // N1
+	TotalOperands     int     `json:"total_operands"`     // N2
+	Volume            float64 `json:"volume"`             // N * log2(n)
+	Difficulty        float64 `json:"difficulty"`         // n1/2 * N2/n2
+	Effort            float64 `json:"effort"`             // Difficulty * Volume
+}
+
+// halstead computes the Halstead metrics of a function body
+func halstead(body *ast.BlockStmt) HalsteadMetrics {
+	var m HalsteadMetrics
+	if body == nil {
+		return m
+	}
+
+	operators := make(map[string]bool)
+	operands := make(map[string]bool)
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.BinaryExpr:
+			operators[x.Op.String()] = true
+			m.TotalOperators++
+		case *ast.UnaryExpr:
+			// Distinct from the binary operator spelled the same, e.g. -x and a-b
+			operators["unary "+x.Op.String()] = true
+			m.TotalOperators++
+		case *ast.Ident:
+			operands[x.Name] = true
+			m.TotalOperands++
+		case *ast.BasicLit:
+			operands[x.Value] = true
+			m.TotalOperands++
+		}
+		return true
+	})
+	m.DistinctOperators = len(operators)
+	m.DistinctOperands = len(operands)
+	m.derive()
+	return m
+}
+
+// derive computes volume, difficulty and effort from the counts
+func (m *HalsteadMetrics) derive() {
+	vocabulary := m.DistinctOperators + m.DistinctOperands
+	length := m.TotalOperators + m.TotalOperands
+	if vocabulary > 0 {
+		m.Volume = float64(length) * math.Log2(float64(vocabulary))
+	}
+	if m.DistinctOperands > 0 {
+		m.Difficulty = float64(m.DistinctOperators) / 2 * float64(m.TotalOperands) / float64(m.DistinctOperands)
+	}
+	m.Effort = m.Difficulty * m.Volume
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"container/heap"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ResultHandler receives the result of every file benchmarked by
+// BenchmarkDirectory. Results arrive in path order, one at a time.
+type ResultHandler interface {
+	Handle(ParseResult)
+}
+
+// retainHandler is the default handler: it keeps every result on the
+// analyzer for PrintSummary and the exports
+type retainHandler struct {
+	a *ASTAnalyzer
+}
+
+// Handle appends result to the analyzer's results
+func (h retainHandler) Handle(result ParseResult) {
+	h.a.mu.Lock()
+	h.a.results = append(h.a.results, result)
+	h.a.mu.Unlock()
+}
+
+// DefaultSlowestFiles is the number of slowest files AggregateHandler
+// remembers when created with a non-positive limit
+const DefaultSlowestFiles = 10
+
+// SlowParse is a file remembered by AggregateHandler for its parse time
+type SlowParse struct {
+	FilePath  string
+	ParseTime time.Duration
+}
+
+// AggregateHandler keeps running totals and the slowest files instead of
+// the results themselves, so memory stays flat however many files are
+// benchmarked. PrintSummary reports its totals; the exports and analyses
+// that need per-file results see none. Like the recorded results, the
+// totals cover the current run: an analyzer starting a new run resets the
+// handler it is configured with.
+type AggregateHandler struct {
+	// IncludeGenerated counts generated files in the totals, as
+	// ASTAnalyzer.IncludeGenerated does
+	IncludeGenerated bool
+
+	summary RunSummary
+	slowest slowHeap
+	limit   int
+}
+
+// NewAggregateHandler creates a handler remembering the limit slowest
+// files
+func NewAggregateHandler(limit int) *AggregateHandler {
+	if limit <= 0 {
+		limit = DefaultSlowestFiles
+	}
+	return &AggregateHandler{limit: limit}
+}
+
+// Handle adds result to the totals
+func (h *AggregateHandler) Handle(result ParseResult) {
+	h.summary.add(result, h.IncludeGenerated)
+	if !result.Success {
+		return
+	}
+
+	file := SlowParse{FilePath: result.FilePath, ParseTime: result.ParseTime}
+	if len(h.slowest) < h.limit {
+		heap.Push(&h.slowest, file)
+	} else if file.ParseTime > h.slowest[0].ParseTime {
+		h.slowest[0] = file
+		heap.Fix(&h.slowest, 0)
+	}
+}
+
+// reset drops the totals and slowest files, for a new run
+func (h *AggregateHandler) reset() {
+	h.summary = RunSummary{}
+	h.slowest = nil
+}
+
+// Summary returns the totals of the results handled so far
+func (h *AggregateHandler) Summary() RunSummary {
+	return h.summary
+}
+
+// Slowest returns the slowest successfully parsed files, slowest first
+func (h *AggregateHandler) Slowest() []SlowParse {
+	files := append([]SlowParse(nil), h.slowest...)
+	sort.Slice(files, func(i, j int) bool { return files[i].ParseTime > files[j].ParseTime })
+	return files
+}
+
+// slowHeap is a min-heap of parse times, so the fastest of the slowest
+// files is the one replaced
+type slowHeap []SlowParse
+
+func (h slowHeap) Len() int           { return len(h) }
+func (h slowHeap) Less(i, j int) bool { return h[i].ParseTime < h[j].ParseTime }
+func (h slowHeap) Swap(i, j int)      { h[i], h[j] = h[j], h[i] }
+func (h *slowHeap) Push(x any)        { *h = append(*h, x.(SlowParse)) }
+func (h *slowHeap) Pop() any {
+	old := *h
+	x := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return x
+}
+
+// resultHandler returns the configured handler or the default one
+func (a *ASTAnalyzer) resultHandler() ResultHandler {
+	if a.Handler != nil {
+		return a.Handler
+	}
+	return retainHandler{a}
+}
+
+// printSlowest lists the slowest files remembered by an AggregateHandler
+func (a *ASTAnalyzer) printSlowest() {
+	h, ok := a.Handler.(*AggregateHandler)
+	if !ok || len(h.slowest) == 0 {
+		return
+	}
+
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println("SLOWEST FILES")
+	fmt.Println(strings.Repeat("=", 70))
+	for _, f := range h.Slowest() {
+		fmt.Printf("  %-50s %8.2fms\n", outputPath(a.outputRoot(), f.FilePath),
+			float64(f.ParseTime.Microseconds())/1000.0)
+	}
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println()
+}
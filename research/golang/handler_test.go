@@ -0,0 +1,102 @@
+package main
+
+import (
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestAggregateHandler(t *testing.T) {
+	const numFiles = 2000
+	dir := generatedTree(t, numFiles)
+
+	retained := quietAnalyzer()
+	if _, err := retained.BenchmarkDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	want := retained.Summarize()
+
+	tests := []struct {
+		name  string
+		limit int
+		kept  int
+	}{
+		{name: "default limit", limit: 0, kept: DefaultSlowestFiles},
+		{name: "one", limit: 1, kept: 1},
+		{name: "more than parsed", limit: 5000, kept: numFiles - numFiles/10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := quietAnalyzer()
+			h := NewAggregateHandler(tt.limit)
+			a.Handler = h
+
+			base := a.fset.Base()
+			var before runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+
+			results, err := a.BenchmarkDirectory(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+
+			// Nothing per file is kept on the analyzer
+			if results != nil || len(a.results) != 0 || len(a.BuildExport().Files) != 0 {
+				t.Errorf("%d results returned, %d recorded", len(results), len(a.results))
+			}
+			if a.fset.Base() != base {
+				t.Error("the shared FileSet grew")
+			}
+			if tt.limit == 0 {
+				// Live heap may grow by the slowest files and the totals, not
+				// by anything per file
+				if grown := int64(after.HeapAlloc) - int64(before.HeapAlloc); grown > 256<<10 {
+					t.Errorf("live heap grew by %d bytes over %d files", grown, numFiles)
+				}
+			}
+
+			got := h.Summary()
+			if got.TotalFiles != want.TotalFiles || got.Successful != want.Successful || got.Failed != want.Failed ||
+				got.Functions != want.Functions || got.TotalBytes != want.TotalBytes || got.TotalLines != want.TotalLines {
+				t.Errorf("aggregate summary %+v, want %+v", got, want)
+			}
+			if got := a.Summarize(); got.TotalFiles != numFiles {
+				t.Errorf("analyzer summary counts %d files, want %d", got.TotalFiles, numFiles)
+			}
+
+			slowest := h.Slowest()
+			if len(slowest) != tt.kept {
+				t.Fatalf("kept %d slowest files, want %d", len(slowest), tt.kept)
+			}
+			for i := 1; i < len(slowest); i++ {
+				if slowest[i].ParseTime > slowest[i-1].ParseTime {
+					t.Errorf("slowest files out of order at %d", i)
+				}
+			}
+		})
+	}
+}
+
+func TestAggregateHandlerSlowest(t *testing.T) {
+	h := NewAggregateHandler(3)
+	for i, ms := range []int{5, 1, 9, 3, 7, 2, 8} {
+		h.Handle(ParseResult{FilePath: string(rune('a' + i)), Success: true, ParseTime: time.Duration(ms) * time.Millisecond})
+	}
+	h.Handle(ParseResult{FilePath: "failed", ParseTime: time.Second})
+
+	var got []string
+	for _, f := range h.Slowest() {
+		got = append(got, f.FilePath)
+	}
+	if want := []string{"c", "g", "e"}; len(got) != 3 || got[0] != want[0] || got[1] != want[1] || got[2] != want[2] {
+		t.Errorf("slowest %v, want %v", got, want)
+	}
+	if s := h.Summary(); s.TotalFiles != 8 || s.Failed != 1 {
+		t.Errorf("summary %+v", s)
+	}
+}
@@ -0,0 +1,469 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/importer"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FindImplementations returns the concrete types declared under dir that
+// implement the named interface, as "pkg.T", or "*pkg.T" when only the
+// pointer type does. The interface is either declared in one of those
+// packages, such as "Shape", or qualified by an import path, such as
+// "io.Reader". Generic types are skipped since they only implement
+// interfaces once instantiated. Packages are type-checked from source and
+// type errors are tolerated.
+func (a *ASTAnalyzer) FindImplementations(dir string, interfaceName string) ([]string, error) {
+	files, err := a.goFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byPackage := make(map[string][]*ast.File)
+	for _, path := range files {
+		f, err := a.cache.Parse(path)
+		if err != nil {
+			continue
+		}
+		key := filepath.Dir(path) + ":" + f.Name.Name
+		byPackage[key] = append(byPackage[key], f)
+	}
+
+	keys := make([]string, 0, len(byPackage))
+	for key := range byPackage {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	imp := importer.ForCompiler(a.fset, "source", nil)
+	conf := types.Config{Importer: imp, Error: func(error) {}}
+	pkgs := make([]*types.Package, 0, len(keys))
+	for _, key := range keys {
+		pkgFiles := byPackage[key]
+		pkg, _ := conf.Check(pkgFiles[0].Name.Name, a.fset, pkgFiles, nil)
+		pkgs = append(pkgs, pkg)
+	}
+
+	iface, err := lookupInterface(pkgs, imp, interfaceName)
+	if err != nil {
+		return nil, err
+	}
+
+	qualifier := func(p *types.Package) string { return p.Name() }
+	var impls []string
+	for _, pkg := range pkgs {
+		scope := pkg.Scope()
+		for _, name := range scope.Names() {
+			tn, ok := scope.Lookup(name).(*types.TypeName)
+			if !ok || tn.IsAlias() {
+				continue
+			}
+			named, ok := tn.Type().(*types.Named)
+			if !ok || named.TypeParams().Len() > 0 || types.IsInterface(named) {
+				continue
+			}
+			switch {
+			case types.Implements(named, iface):
+				impls = append(impls, types.TypeString(named, qualifier))
+			case types.Implements(types.NewPointer(named), iface):
+				impls = append(impls, types.TypeString(types.NewPointer(named), qualifier))
+			}
+		}
+	}
+	return impls, nil
+}
+
+// lookupInterface resolves an interface name against the checked packages,
+// or against an import path when the name is qualified
+func lookupInterface(pkgs []*types.Package, imp types.Importer, name string) (*types.Interface, error) {
+	var obj types.Object
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		path, local := name[:i], name[i+1:]
+		for _, pkg := range pkgs {
+			if pkg.Path() == path || pkg.Name() == path {
+				obj = pkg.Scope().Lookup(local)
+				break
+			}
+		}
+		if obj == nil {
+			pkg, err := imp.Import(path)
+			if err != nil {
+				return nil, fmt.Errorf("interface %s: %w", name, err)
+			}
+			obj = pkg.Scope().Lookup(local)
+		}
+	} else {
+		for _, pkg := range pkgs {
+			if obj = pkg.Scope().Lookup(name); obj != nil {
+				break
+			}
+		}
+	}
+
+	if _, ok := obj.(*types.TypeName); !ok {
+		return nil, fmt.Errorf("interface %s not found", name)
+	}
+	iface, ok := obj.Type().Underlying().(*types.Interface)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an interface", name)
+	}
+	return iface, nil
+}
+
+// builtinInterfaces are common interfaces of the standard library, keyed
+// as they are written in code, so that InterfaceImplementations can match
+// them without type-checking
+var builtinInterfaces = map[string][]MethodInfo{
+	"error":                      {errorMethod},
+	"fmt.Stringer":               {stringMethod},
+	"fmt.GoStringer":             {{Name: "GoString", Results: []string{"string"}}},
+	"io.Reader":                  {readMethod},
+	"io.Writer":                  {writeMethod},
+	"io.Closer":                  {closeMethod},
+	"io.Seeker":                  {seekMethod},
+	"io.ReadWriter":              {readMethod, writeMethod},
+	"io.ReadCloser":              {readMethod, closeMethod},
+	"io.WriteCloser":             {writeMethod, closeMethod},
+	"io.ReadWriteCloser":         {readMethod, writeMethod, closeMethod},
+	"io.ReadSeeker":              {readMethod, seekMethod},
+	"io.ReaderAt":                {{Name: "ReadAt", Params: []ParamInfo{{Name: "p", Type: "[]byte"}, {Name: "off", Type: "int64"}}, Results: []string{"int", "error"}}},
+	"io.ReaderFrom":              {{Name: "ReadFrom", Params: []ParamInfo{{Name: "r", Type: "io.Reader"}}, Results: []string{"int64", "error"}}},
+	"io.WriterTo":                {{Name: "WriteTo", Params: []ParamInfo{{Name: "w", Type: "io.Writer"}}, Results: []string{"int64", "error"}}},
+	"io.StringWriter":            {{Name: "WriteString", Params: []ParamInfo{{Name: "s", Type: "string"}}, Results: []string{"int", "error"}}},
+	"io.ByteReader":              {{Name: "ReadByte", Results: []string{"byte", "error"}}},
+	"sort.Interface":             {{Name: "Len", Results: []string{"int"}}, {Name: "Less", Params: []ParamInfo{{Name: "i", Type: "int"}, {Name: "j", Type: "int"}}, Results: []string{"bool"}}, {Name: "Swap", Params: []ParamInfo{{Name: "i", Type: "int"}, {Name: "j", Type: "int"}}}},
+	"json.Marshaler":             {{Name: "MarshalJSON", Results: []string{"[]byte", "error"}}},
+	"json.Unmarshaler":           {{Name: "UnmarshalJSON", Params: []ParamInfo{{Type: "[]byte"}}, Results: []string{"error"}}},
+	"encoding.TextMarshaler":     {{Name: "MarshalText", Results: []string{"[]byte", "error"}}},
+	"encoding.TextUnmarshaler":   {{Name: "UnmarshalText", Params: []ParamInfo{{Name: "text", Type: "[]byte"}}, Results: []string{"error"}}},
+	"encoding.BinaryMarshaler":   {{Name: "MarshalBinary", Results: []string{"[]byte", "error"}}},
+	"encoding.BinaryUnmarshaler": {{Name: "UnmarshalBinary", Params: []ParamInfo{{Name: "data", Type: "[]byte"}}, Results: []string{"error"}}},
+	"flag.Value":                 {stringMethod, {Name: "Set", Params: []ParamInfo{{Type: "string"}}, Results: []string{"error"}}},
+	"http.Handler":               {{Name: "ServeHTTP", Params: []ParamInfo{{Type: "http.ResponseWriter"}, {Type: "*http.Request"}}}},
+}
+
+// Methods shared by several builtinInterfaces
+var (
+	errorMethod  = MethodInfo{Name: "Error", Results: []string{"string"}}
+	stringMethod = MethodInfo{Name: "String", Results: []string{"string"}}
+	readMethod   = MethodInfo{Name: "Read", Params: []ParamInfo{{Name: "p", Type: "[]byte"}}, Results: []string{"int", "error"}}
+	writeMethod  = MethodInfo{Name: "Write", Params: []ParamInfo{{Name: "p", Type: "[]byte"}}, Results: []string{"int", "error"}}
+	closeMethod  = MethodInfo{Name: "Close", Results: []string{"error"}}
+	seekMethod   = MethodInfo{Name: "Seek", Params: []ParamInfo{{Name: "offset", Type: "int64"}, {Name: "whence", Type: "int"}}, Results: []string{"int64", "error"}}
+)
+
+// InterfaceImplementations matches the structs declared under dir against
+// the interfaces declared there and the builtinInterfaces, without
+// type-checking. It maps every local interface, as "pkg.I", and every
+// builtin interface that is implemented, as written, to the sorted structs
+// satisfying it: "pkg.T" when T's method set does and "*pkg.T" when only
+// the pointer's does. Methods match by name and by parameter and result
+// types, normalized so that a package's types compare equal whether
+// written inside it or qualified from another package. Methods promoted
+// through embedded fields of the same package count. Generic types,
+// methodless interfaces and interfaces whose method set cannot be told
+// from the tree, such as constraints or ones embedding unknown
+// interfaces, are left out.
+func (a *ASTAnalyzer) InterfaceImplementations(dir string) (map[string][]string, error) {
+	types, err := a.extractTypes(dir)
+	if err != nil {
+		return nil, err
+	}
+	m, err := a.newImplementationMatcher(dir, types)
+	if err != nil {
+		return nil, err
+	}
+
+	interfaces := make(map[string]map[string]string)
+	for name, methods := range builtinInterfaces {
+		interfaces[name] = m.methodSet(methods, "")
+	}
+	impls := make(map[string][]string)
+	for _, t := range types {
+		if t.Kind != InterfaceKind || len(t.TypeParams) > 0 {
+			continue
+		}
+		if methods, ok := m.interfaceMethods(t, map[string]bool{}); ok && len(methods) > 0 {
+			name := m.qualifiedName(t)
+			interfaces[name] = methods
+			impls[name] = []string{}
+		}
+	}
+
+	for _, t := range types {
+		if t.Kind != StructKind || len(t.TypeParams) > 0 {
+			continue
+		}
+		value, pointer := m.structMethodSets(t)
+		for name, methods := range interfaces {
+			switch {
+			case satisfies(value, methods):
+				impls[name] = append(impls[name], m.qualifiedName(t))
+			case satisfies(pointer, methods):
+				impls[name] = append(impls[name], "*"+m.qualifiedName(t))
+			}
+		}
+	}
+
+	for name := range impls {
+		sort.Strings(impls[name])
+	}
+	return impls, nil
+}
+
+// satisfies reports whether a method set holds every method of iface,
+// both given as method name to normalized signature
+func satisfies(methodSet, iface map[string]string) bool {
+	for name, signature := range iface {
+		if methodSet[name] != signature {
+			return false
+		}
+	}
+	return true
+}
+
+// implementationMatcher holds what InterfaceImplementations needs to know
+// about the packages under its directory
+type implementationMatcher struct {
+	byName   map[string]TypeInfo        // By package directory and type name
+	names    map[string]string          // Package directory to package name
+	declared map[string]map[string]bool // Package directory to declared type names
+}
+
+// newImplementationMatcher indexes types and the declarations of the
+// non-test files under dir
+func (a *ASTAnalyzer) newImplementationMatcher(dir string, types []TypeInfo) (*implementationMatcher, error) {
+	files, err := a.goFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	m := &implementationMatcher{
+		byName:   make(map[string]TypeInfo, len(types)),
+		names:    make(map[string]string),
+		declared: make(map[string]map[string]bool),
+	}
+	for _, t := range types {
+		m.byName[t.Package+"."+t.Name] = t
+	}
+	for _, path := range files {
+		if isTestName(path) {
+			continue
+		}
+		f, err := a.cache.Parse(path)
+		if err != nil {
+			continue
+		}
+		pkgDir := filepath.Dir(path)
+		m.names[pkgDir] = f.Name.Name
+		if m.declared[pkgDir] == nil {
+			m.declared[pkgDir] = make(map[string]bool)
+		}
+		for _, decl := range f.Decls {
+			if gen, ok := decl.(*ast.GenDecl); ok {
+				for _, spec := range gen.Specs {
+					if ts, ok := spec.(*ast.TypeSpec); ok {
+						m.declared[pkgDir][ts.Name.Name] = true
+					}
+				}
+			}
+		}
+	}
+	return m, nil
+}
+
+// qualifiedName names a type as "pkg.T"
+func (m *implementationMatcher) qualifiedName(t TypeInfo) string {
+	return m.names[t.Package] + "." + t.Name
+}
+
+// methodSet normalizes the signatures of methods declared in pkgDir, or
+// in the standard library when pkgDir is empty
+func (m *implementationMatcher) methodSet(methods []MethodInfo, pkgDir string) map[string]string {
+	set := make(map[string]string, len(methods))
+	for _, method := range methods {
+		set[method.Name] = m.signature(method, pkgDir)
+	}
+	return set
+}
+
+// signature returns the parameter and result types of a method declared
+// in pkgDir with every type normalized
+func (m *implementationMatcher) signature(method MethodInfo, pkgDir string) string {
+	params := make([]string, len(method.Params))
+	for i, p := range method.Params {
+		params[i] = m.normalizeType(p.Type, pkgDir)
+	}
+	results := make([]string, len(method.Results))
+	for i, r := range method.Results {
+		results[i] = m.normalizeType(r, pkgDir)
+	}
+	return "(" + strings.Join(params, ",") + ")(" + strings.Join(results, ",") + ")"
+}
+
+// typeAliases maps predeclared aliases to the types they stand for
+var typeAliases = map[string]string{
+	"byte": "uint8",
+	"rune": "int32",
+}
+
+// normalizeType rewrites a type written in pkgDir so that it reads the
+// same from any package: types declared in pkgDir are qualified by the
+// package name, and predeclared aliases and interface{} are resolved
+func (m *implementationMatcher) normalizeType(typ, pkgDir string) string {
+	typ = strings.ReplaceAll(typ, "interface{}", "any")
+	declared := m.declared[pkgDir]
+	pkgName := m.names[pkgDir]
+
+	var b strings.Builder
+	for i := 0; i < len(typ); {
+		if !isIdentByte(typ[i]) {
+			b.WriteByte(typ[i])
+			i++
+			continue
+		}
+		j := i
+		for j < len(typ) && isIdentByte(typ[j]) {
+			j++
+		}
+		ident := typ[i:j]
+		qualified := i > 0 && typ[i-1] == '.' || j < len(typ) && typ[j] == '.'
+		switch {
+		case qualified:
+			b.WriteString(ident)
+		case declared[ident]:
+			b.WriteString(pkgName + "." + ident)
+		case typeAliases[ident] != "":
+			b.WriteString(typeAliases[ident])
+		default:
+			b.WriteString(ident)
+		}
+		i = j
+	}
+	return b.String()
+}
+
+// interfaceMethods returns the normalized method set of an interface,
+// including the methods of the interfaces it embeds, and reports false
+// when it cannot be known
+func (m *implementationMatcher) interfaceMethods(t TypeInfo, seen map[string]bool) (map[string]string, bool) {
+	key := t.Package + "." + t.Name
+	if seen[key] {
+		return nil, false
+	}
+	seen[key] = true
+
+	set := m.methodSet(t.Methods, t.Package)
+	for _, embed := range t.Embeds {
+		var embedded map[string]string
+		if methods, ok := builtinInterfaces[embed]; ok {
+			embedded = m.methodSet(methods, "")
+		} else if embed == "any" {
+			continue
+		} else {
+			inner, ok := m.lookupInterface(t.Package, embed)
+			if !ok {
+				return nil, false
+			}
+			if embedded, ok = m.interfaceMethods(inner, seen); !ok {
+				return nil, false
+			}
+		}
+		for name, signature := range embedded {
+			set[name] = signature
+		}
+	}
+	return set, true
+}
+
+// lookupInterface finds an interface embedded in package pkgDir, either
+// declared there or qualified by the name of another package in the tree
+func (m *implementationMatcher) lookupInterface(pkgDir, embed string) (TypeInfo, bool) {
+	if name := embeddedTypeName(embed); name != "" {
+		t, ok := m.byName[pkgDir+"."+name]
+		return t, ok && t.Kind == InterfaceKind
+	}
+	pkgName, name, ok := strings.Cut(embed, ".")
+	if !ok {
+		return TypeInfo{}, false
+	}
+	for dir, n := range m.names {
+		if n != pkgName {
+			continue
+		}
+		if t, ok := m.byName[dir+"."+name]; ok && t.Kind == InterfaceKind {
+			return t, true
+		}
+	}
+	return TypeInfo{}, false
+}
+
+// structMethodSets returns the normalized method sets of the struct t and
+// of *t. As in the language, methods with pointer receivers only belong
+// to the pointer's set, unless promoted through an embedded pointer.
+func (m *implementationMatcher) structMethodSets(t TypeInfo) (value, pointer map[string]string) {
+	value = make(map[string]string)
+	pointer = make(map[string]string)
+	add := func(method MethodInfo, pkgDir string, inValueSet bool) {
+		signature := m.signature(method, pkgDir)
+		pointer[method.Name] = signature
+		if inValueSet {
+			value[method.Name] = signature
+		}
+	}
+
+	for _, method := range t.Methods {
+		add(method, t.Package, !method.PointerReceiver)
+	}
+	for _, name := range promotedMethods(t, m.byName) {
+		if method, inValueSet, ok := m.promotedMethod(t, name); ok {
+			add(method, t.Package, inValueSet)
+		}
+	}
+	return value, pointer
+}
+
+// promotedMethod finds the declaration of a method promoted into t, at the
+// shallowest depth as promotedMethods does, and reports whether it belongs
+// to t's value method set: it does when declared with a value receiver or
+// on an interface, or when reached through an embedded pointer
+func (m *implementationMatcher) promotedMethod(t TypeInfo, name string) (MethodInfo, bool, bool) {
+	type embedding struct {
+		embed      string
+		viaPointer bool
+	}
+
+	var level []embedding
+	for _, embed := range t.Embeds {
+		level = append(level, embedding{embed, false})
+	}
+	seen := map[string]bool{t.Name: true}
+	for len(level) > 0 {
+		var next []embedding
+		for _, e := range level {
+			typeName := embeddedTypeName(e.embed)
+			embedded, ok := m.byName[t.Package+"."+typeName]
+			if typeName == "" || !ok || seen[typeName] {
+				continue
+			}
+			seen[typeName] = true
+
+			viaPointer := e.viaPointer || strings.HasPrefix(e.embed, "*")
+			for _, method := range embedded.Methods {
+				if method.Name == name {
+					inValueSet := !method.PointerReceiver || viaPointer || embedded.Kind == InterfaceKind
+					return method, inValueSet, true
+				}
+			}
+			for _, embed := range embedded.Embeds {
+				next = append(next, embedding{embed, viaPointer})
+			}
+		}
+		level = next
+	}
+	return MethodInfo{}, false, false
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Finding categories reported by FindImportCycles
+const (
+	CategoryImportCycle     = "import-cycle"
+	CategoryTestImportCycle = "test-import-cycle"
+	CategoryNearImportCycle = "near-import-cycle"
+)
+
+// Import cycle kinds reported in ImportCycle.Kind
+const (
+	CycleKind     = "cycle"
+	TestCycleKind = "test"
+	NearCycleKind = "near"
+)
+
+// ImportCycle is a cycle in the package import graph, or a near cycle
+type ImportCycle struct {
+	// Kind is CycleKind for a cycle among non-test files, which the
+	// compiler rejects; TestCycleKind for one that only closes through the
+	// imports of test files, counting package foo_test as part of foo; and
+	// NearCycleKind for a package depending on a package in a parent
+	// directory, which a single import of the child by its parent would
+	// turn into a cycle.
+	Kind string
+
+	// Path lists the packages along the cycle, starting and ending with
+	// the same one, or the dependency path from child to parent for a near
+	// cycle. Packages are named as in ImportGraph.
+	Path []string
+
+	// FilePath, Line and Column locate the import of Path[1] by Path[0],
+	// or for a test cycle the first import along Path made by a test file
+	FilePath string
+	Line     int
+	Column   int
+}
+
+// importSites records where each package edge is first imported
+type importSites map[[2]string]token.Position
+
+// FindImportCycles reports the import cycles among the packages under dir,
+// built as BuildImportGraph does, along with the cycles that only test
+// files close and the near cycles. Each strongly connected component is
+// reported once, by its shortest cycle through its first package. Cycles
+// come first, then test cycles, then near cycles, each ordered by path.
+// The cycles are recorded as findings and for the structured exports.
+func (a *ASTAnalyzer) FindImportCycles(dir string) ([]ImportCycle, error) {
+	g, err := a.BuildImportGraph(dir)
+	if err != nil {
+		return nil, err
+	}
+	files, err := a.goFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Test edges join the package's own; package foo_test importing foo
+	// is no edge at all
+	withTests := make(map[string][]string)
+	for importer, imports := range g.Edges {
+		withTests[importer] = append([]string(nil), imports...)
+	}
+	sites := make(importSites)
+	for _, path := range files {
+		f, err := a.cache.Parse(path)
+		if err != nil {
+			continue
+		}
+		importer := g.localImportPath(dir, filepath.Dir(path))
+		for _, spec := range f.Imports {
+			imported, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || imported == cgoImportPath || imported == importer {
+				continue
+			}
+			edge := [2]string{importer, imported}
+			if _, ok := sites[edge]; !ok || !isTestName(path) && isTestName(sites[edge].Filename) {
+				sites[edge] = a.fset.Position(spec.Pos())
+			}
+			if isTestName(path) {
+				withTests[importer] = append(withTests[importer], imported)
+			}
+		}
+	}
+	for importer, imports := range withTests {
+		withTests[importer] = sortedUnique(imports)
+	}
+
+	cycles := findCycles(g.Edges, CycleKind, sites, nil)
+	seen := make(map[string]bool)
+	for _, c := range cycles {
+		for _, pkg := range c.Path {
+			seen[pkg] = true
+		}
+	}
+	cycles = append(cycles, findCycles(withTests, TestCycleKind, sites, seen)...)
+	cycles = append(cycles, g.nearCycles(sites)...)
+
+	findings := make([]Finding, len(cycles))
+	for i, c := range cycles {
+		findings[i] = c.finding()
+	}
+	a.addFindings(findings...)
+	a.mu.Lock()
+	a.importCycles = append(a.importCycles, cycles...)
+	a.mu.Unlock()
+	return cycles, nil
+}
+
+// findCycles returns one cycle of the given kind per strongly connected
+// component of edges, skipping components containing a package in skip
+func findCycles(edges map[string][]string, kind string, sites importSites, skip map[string]bool) []ImportCycle {
+	ids := make([]string, 0, len(edges))
+	for id := range edges {
+		ids = append(ids, id)
+	}
+
+	var cycles []ImportCycle
+	for _, component := range stronglyConnectedComponents(ids, edges) {
+		if len(component) < 2 || skip[component[0]] {
+			continue
+		}
+		members := make(map[string]bool, len(component))
+		for _, id := range component {
+			members[id] = true
+		}
+		path := shortestPath(edges, component[0], component[0], members)
+		cycles = append(cycles, newImportCycle(kind, path, sites))
+	}
+	return cycles
+}
+
+// shortestPath finds the shortest path from one package to another along
+// edges, staying among allowed packages when allowed is not nil. A path
+// from a package to itself is a cycle. It returns nil when there is none.
+func shortestPath(edges map[string][]string, from, to string, allowed map[string]bool) []string {
+	prev := make(map[string]string)
+	queue := []string{from}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, next := range edges[id] {
+			if allowed != nil && !allowed[next] {
+				continue
+			}
+			if next == to {
+				path := []string{to}
+				for at := id; at != from; at = prev[at] {
+					path = append(path, at)
+				}
+				path = append(path, from)
+				for i, j := 0, len(path)-1; i < j; i, j = i+1, j-1 {
+					path[i], path[j] = path[j], path[i]
+				}
+				return path
+			}
+			if _, visited := prev[next]; !visited && next != from {
+				prev[next] = id
+				queue = append(queue, next)
+			}
+		}
+	}
+	return nil
+}
+
+// nearCycles returns the local packages that depend on a package in a
+// parent directory, by their shortest dependency path
+func (g *ImportGraph) nearCycles(sites importSites) []ImportCycle {
+	var local []*ImportGraphNode
+	for _, node := range g.SortedNodes() {
+		if !node.External {
+			local = append(local, node)
+		}
+	}
+
+	var cycles []ImportCycle
+	for _, child := range local {
+		for _, parent := range local {
+			rel, err := filepath.Rel(parent.Dir, child.Dir)
+			if parent.Dir == "" || err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+				continue
+			}
+			if path := shortestPath(g.Edges, child.ImportPath, parent.ImportPath, nil); path != nil {
+				cycles = append(cycles, newImportCycle(NearCycleKind, path, sites))
+			}
+		}
+	}
+	return cycles
+}
+
+// newImportCycle locates a cycle by the import of its first edge, or for
+// a test cycle by the first import from a test file
+func newImportCycle(kind string, path []string, sites importSites) ImportCycle {
+	site := sites[[2]string{path[0], path[1]}]
+	if kind == TestCycleKind {
+		for i := 1; i < len(path); i++ {
+			if s := sites[[2]string{path[i-1], path[i]}]; isTestName(s.Filename) {
+				site = s
+				break
+			}
+		}
+	}
+	return ImportCycle{Kind: kind, Path: path, FilePath: site.Filename, Line: site.Line, Column: site.Column}
+}
+
+// finding describes the cycle as a finding
+func (c ImportCycle) finding() Finding {
+	f := Finding{
+		FilePath:   c.FilePath,
+		Line:       c.Line,
+		Column:     c.Column,
+		Identifier: c.Path[0],
+	}
+	path := strings.Join(c.Path, " → ")
+	switch c.Kind {
+	case CycleKind:
+		f.Category = CategoryImportCycle
+		f.Message = fmt.Sprintf("import cycle: %s", path)
+	case TestCycleKind:
+		f.Category = CategoryTestImportCycle
+		f.Message = fmt.Sprintf("import cycle through tests: %s", path)
+		f.Suggestion = "move the tests closing the cycle to another package"
+	case NearCycleKind:
+		parent := c.Path[len(c.Path)-1]
+		f.Category = CategoryNearImportCycle
+		f.Message = fmt.Sprintf("%s depends on its parent: %s; importing %s from %s would create a cycle", c.Path[0], path, c.Path[0], parent)
+	}
+	return f
+}
+
+// sortCycles orders cycles by kind, as FindImportCycles returns them, and
+// then by path
+func sortCycles(cycles []ImportCycle) {
+	rank := map[string]int{CycleKind: 0, TestCycleKind: 1, NearCycleKind: 2}
+	sort.SliceStable(cycles, func(i, j int) bool {
+		if cycles[i].Kind != cycles[j].Kind {
+			return rank[cycles[i].Kind] < rank[cycles[j].Kind]
+		}
+		return strings.Join(cycles[i].Path, "\x00") < strings.Join(cycles[j].Path, "\x00")
+	})
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ImportGraphNode is a package in the import graph
+type ImportGraphNode struct {
+	ImportPath string
+	Dir        string // Empty for packages outside the analyzed tree
+	Files      int
+	Functions  int
+	External   bool
+}
+
+// ImportGraph is the package dependency graph of an analyzed tree. Local
+// packages are identified by import path when the tree has a go.mod and by
+// directory otherwise.
+type ImportGraph struct {
+	Module string
+	Nodes  map[string]*ImportGraphNode
+	Edges  map[string][]string // Importer to sorted, unique imports
+}
+
+// BuildImportGraph builds the package import graph of all non-test Go
+// files under dir
+func (a *ASTAnalyzer) BuildImportGraph(dir string) (*ImportGraph, error) {
+	files, err := a.goFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	g := &ImportGraph{
+		Module: modulePath(dir),
+		Nodes:  make(map[string]*ImportGraphNode),
+		Edges:  make(map[string][]string),
+	}
+
+	for _, path := range files {
+		if isTestName(path) {
+			continue
+		}
+		f, err := a.cache.Parse(path)
+		if err != nil {
+			continue
+		}
+
+		pkgDir := filepath.Dir(path)
+		importPath := g.localImportPath(dir, pkgDir)
+		node, ok := g.Nodes[importPath]
+		if !ok || node.External {
+			node = &ImportGraphNode{ImportPath: importPath, Dir: pkgDir}
+			g.Nodes[importPath] = node
+		}
+		node.Files++
+
+		for _, spec := range f.Imports {
+			imported, err := strconv.Unquote(spec.Path.Value)
+			if err != nil || imported == cgoImportPath {
+				continue
+			}
+			if _, ok := g.Nodes[imported]; !ok {
+				g.Nodes[imported] = &ImportGraphNode{ImportPath: imported, External: true}
+			}
+			g.Edges[importPath] = append(g.Edges[importPath], imported)
+		}
+	}
+
+	// Imports of local packages seen before their files were walked
+	for path, node := range g.Nodes {
+		if node.External && node.Dir == "" && g.isLocal(path) {
+			node.External = false
+		}
+	}
+
+	// Function counts come from the full parse results when available
+	for _, r := range a.results {
+		if node, ok := g.Nodes[g.localImportPath(dir, filepath.Dir(r.FilePath))]; ok {
+			node.Functions += r.NumFunctions + r.NumMethods
+		}
+	}
+
+	for importer, imports := range g.Edges {
+		g.Edges[importer] = sortedUnique(imports)
+	}
+	a.mu.Lock()
+	a.importGraph = g
+	a.mu.Unlock()
+	return g, nil
+}
+
+// localImportPath returns the identifier of a package directory under root
+func (g *ImportGraph) localImportPath(root, pkgDir string) string {
+	rel, err := filepath.Rel(root, pkgDir)
+	if err != nil {
+		return filepath.ToSlash(pkgDir)
+	}
+	rel = filepath.ToSlash(rel)
+	if g.Module == "" {
+		return rel
+	}
+	if rel == "." {
+		return g.Module
+	}
+	return g.Module + "/" + rel
+}
+
+// isLocal reports whether an import path belongs to the analyzed module
+func (g *ImportGraph) isLocal(path string) bool {
+	return g.Module != "" && (path == g.Module || strings.HasPrefix(path, g.Module+"/"))
+}
+
+// SortedNodes returns the graph's nodes ordered by import path
+func (g *ImportGraph) SortedNodes() []*ImportGraphNode {
+	nodes := make([]*ImportGraphNode, 0, len(g.Nodes))
+	for _, node := range g.Nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		return nodes[i].ImportPath < nodes[j].ImportPath
+	})
+	return nodes
+}
+
+// modulePath reads the module path from dir/go.mod, returning "" when
+// there is none
+func modulePath(dir string) string {
+	f, err := os.Open(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return ""
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if rest, ok := strings.CutPrefix(line, "module"); ok {
+			return strings.Trim(strings.TrimSpace(rest), `"`)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// printInitFuncs prints the init functions per package and per file. Init
+// functions run before main, so heavy ones slow down every start.
+func (a *ASTAnalyzer) printInitFuncs(results []ParseResult) {
+	root := a.outputRoot()
+	var files []ParseResult
+	for _, r := range results {
+		if len(r.InitFuncs) > 0 {
+			files = append(files, r)
+		}
+	}
+	if len(files) == 0 {
+		return
+	}
+
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println("INIT FUNCTIONS")
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Printf("%-50s %6s\n", "Package", "Inits")
+	for _, s := range summarizePackages(files) {
+		fmt.Printf("%-50s %6d\n", outputPath(root, s.Path), s.InitFuncs)
+	}
+	fmt.Println()
+	fmt.Printf("%-50s %6s  %s\n", "File", "Inits", "Lines")
+	for _, r := range files {
+		fmt.Printf("%-50s %6d  %s\n", outputPath(root, r.FilePath), len(r.InitFuncs), strings.Trim(fmt.Sprint(positionLines(r.InitFuncs)), "[]"))
+	}
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println()
+}
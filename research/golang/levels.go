@@ -0,0 +1,63 @@
+package main
+
+import (
+	"errors"
+	"sort"
+)
+
+// ErrNoMain is returned by BuildLevels when dir declares no main function
+var ErrNoMain = errors.New("no main function")
+
+// Level is one step of the game's progression through the call graph:
+// the functions first reached after Depth calls from main. Each function
+// is a stage the player unlocks by clearing the level before it.
+type Level struct {
+	Depth  int
+	Stages []*CallGraphNode // Ordered by ID
+}
+
+// BuildLevels builds the call graph of dir and groups the functions
+// reachable from main by call depth: level 0 holds main, level 1 what main
+// calls, and so on. Each function appears once, at the shallowest depth
+// that reaches it. Unresolved callees have nothing to play and are left
+// out, as are functions main never reaches.
+func (a *ASTAnalyzer) BuildLevels(dir string) ([]Level, error) {
+	cg, err := a.BuildCallGraph(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var frontier []string
+	for id, node := range cg.Nodes {
+		if node.Name == "main" && node.Receiver == "" && !node.External {
+			frontier = append(frontier, id)
+		}
+	}
+	if len(frontier) == 0 {
+		return nil, ErrNoMain
+	}
+
+	seen := make(map[string]bool)
+	for _, id := range frontier {
+		seen[id] = true
+	}
+
+	var levels []Level
+	for depth := 0; len(frontier) > 0; depth++ {
+		sort.Strings(frontier)
+		level := Level{Depth: depth}
+		var next []string
+		for _, id := range frontier {
+			level.Stages = append(level.Stages, cg.Nodes[id])
+			for _, callee := range cg.Edges[id] {
+				if !seen[callee] && !cg.Nodes[callee].External {
+					seen[callee] = true
+					next = append(next, callee)
+				}
+			}
+		}
+		levels = append(levels, level)
+		frontier = next
+	}
+	return levels, nil
+}
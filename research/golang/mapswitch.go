@@ -0,0 +1,96 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// DefaultMinMapSwitchCases is the number of cases from which a switch of
+// trivial cases is reported as a map lookup in disguise
+const DefaultMinMapSwitchCases = 5
+
+// minMapSwitchCases returns the configured case threshold or its default
+func (a *ASTAnalyzer) minMapSwitchCases() int {
+	if a.MinMapSwitchCases > 0 {
+		return a.MinMapSwitchCases
+	}
+	return DefaultMinMapSwitchCases
+}
+
+// isMapifiableSwitch reports whether sw switches on a value with at least
+// minCases cases that all map constants to a constant, either by returning
+// it or by assigning it to the same variable, so that a map lookup could
+// replace it. The default clause may do anything, since a failed lookup
+// can take its place.
+func isMapifiableSwitch(sw *ast.SwitchStmt, minCases int) bool {
+	if sw.Tag == nil || sw.Body == nil {
+		return false
+	}
+
+	cases := 0
+	target := "" // Assigned variable, or "return"
+	for _, stmt := range sw.Body.List {
+		clause := stmt.(*ast.CaseClause)
+		if clause.List == nil {
+			continue
+		}
+		for _, value := range clause.List {
+			if !isConstantExpr(value) {
+				return false
+			}
+		}
+		dest, ok := trivialCaseBody(clause.Body)
+		if !ok || target != "" && dest != target {
+			return false
+		}
+		target = dest
+		cases++
+	}
+	return cases >= minCases
+}
+
+// trivialCaseBody reports whether body is a single statement returning
+// constants or assigning one, and returns "return" or the assigned
+// variable
+func trivialCaseBody(body []ast.Stmt) (string, bool) {
+	if len(body) != 1 {
+		return "", false
+	}
+	switch s := body[0].(type) {
+	case *ast.ReturnStmt:
+		if len(s.Results) == 0 {
+			return "", false
+		}
+		for _, result := range s.Results {
+			if !isConstantExpr(result) {
+				return "", false
+			}
+		}
+		return "return", true
+	case *ast.AssignStmt:
+		if s.Tok != token.ASSIGN || len(s.Lhs) != 1 || len(s.Rhs) != 1 || !isConstantExpr(s.Rhs[0]) {
+			return "", false
+		}
+		return exprToString(s.Lhs[0]), true
+	}
+	return "", false
+}
+
+// isConstantExpr reports whether e looks like a constant: a literal, a
+// possibly negated one, or a name such as a declared constant, true or
+// nil, optionally qualified by a package
+func isConstantExpr(e ast.Expr) bool {
+	switch x := e.(type) {
+	case *ast.BasicLit, *ast.Ident:
+		return true
+	case *ast.UnaryExpr:
+		_, ok := x.X.(*ast.BasicLit)
+		return ok && (x.Op == token.SUB || x.Op == token.ADD)
+	case *ast.SelectorExpr:
+		_, ok := x.X.(*ast.Ident)
+		return ok
+	case *ast.ParenExpr:
+		return isConstantExpr(x.X)
+	}
+	return false
+}
@@ -0,0 +1,37 @@
+package main
+
+import "strings"
+
+// ExtractMethodSets groups the methods of a file by receiver type. Keys are
+// bare type names, so pointer and value receivers of the same type, and
+// generic receivers such as *List[T], share one entry.
+func (a *ASTAnalyzer) ExtractMethodSets(filePath string) (map[string][]FunctionInfo, error) {
+	functions, err := a.ExtractFunctions(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupMethods(functions), nil
+}
+
+// groupMethods groups the methods among functions by bare receiver type
+func groupMethods(functions []FunctionInfo) map[string][]FunctionInfo {
+	methodSets := make(map[string][]FunctionInfo)
+	for _, fn := range functions {
+		if fn.Receiver == "" {
+			continue
+		}
+		typeName := receiverTypeName(fn.Receiver)
+		methodSets[typeName] = append(methodSets[typeName], fn)
+	}
+	return methodSets
+}
+
+// receiverTypeName strips the pointer and type arguments from a receiver
+func receiverTypeName(receiver string) string {
+	name := strings.TrimPrefix(receiver, "*")
+	if i := strings.Index(name, "["); i >= 0 {
+		name = name[:i]
+	}
+	return name
+}
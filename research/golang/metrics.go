@@ -0,0 +1,192 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// parseDurationBuckets are the upper bounds, in seconds, of the parse
+// duration histogram
+var parseDurationBuckets = []float64{
+	0.0001, 0.00025, 0.0005, 0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25,
+}
+
+// Metrics holds the analyzer's Prometheus metrics. Counters and the
+// histogram are updated atomically, so parses may run concurrently.
+// Per-package gauges are labeled by package directory only; file paths
+// would make the series count grow with the tree.
+type Metrics struct {
+	filesParsed atomic.Int64
+	parseErrors atomic.Int64
+
+	buckets   []atomic.Int64 // Parses per bucket, not cumulative
+	count     atomic.Int64
+	sumNanos  atomic.Int64
+	lagNanos  atomic.Int64
+	lastParse atomic.Int64 // Unix nanoseconds
+
+	mu       sync.Mutex
+	packages map[string]packageMetrics // Totals per package directory
+}
+
+// packageMetrics are the counts behind one package's gauges
+type packageMetrics struct {
+	files      int
+	functions  int
+	structs    int
+	interfaces int
+}
+
+// NewMetrics creates an empty metrics set
+func NewMetrics() *Metrics {
+	return &Metrics{
+		buckets:  make([]atomic.Int64, len(parseDurationBuckets)+1),
+		packages: make(map[string]packageMetrics),
+	}
+}
+
+// ObserveParse records one parse. Successful parses add to their package's
+// gauges; callers re-parsing a file must Forget its previous result.
+func (m *Metrics) ObserveParse(r ParseResult) {
+	m.filesParsed.Add(1)
+	m.lastParse.Store(time.Now().UnixNano())
+	if !r.Success {
+		m.parseErrors.Add(1)
+		return
+	}
+
+	seconds := r.ParseTime.Seconds()
+	bucket := sort.SearchFloat64s(parseDurationBuckets, seconds)
+	m.buckets[bucket].Add(1)
+	m.count.Add(1)
+	m.sumNanos.Add(r.ParseTime.Nanoseconds())
+
+	m.addToPackage(r, 1)
+}
+
+// Forget removes an earlier result's contribution to the package gauges,
+// for files that were re-parsed or deleted. Results taken from the cache
+// were never observed and are ignored.
+func (m *Metrics) Forget(r ParseResult) {
+	if r.Success && !r.FromCache {
+		m.addToPackage(r, -1)
+	}
+}
+
+// addToPackage adds sign times the counts of r to its package
+func (m *Metrics) addToPackage(r ParseResult, sign int) {
+	pkg := filepath.ToSlash(filepath.Dir(r.FilePath))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	p := m.packages[pkg]
+	p.files += sign
+	p.functions += sign * (r.NumFunctions + r.NumMethods)
+	p.structs += sign * r.NumStructs
+	p.interfaces += sign * r.NumInterfaces
+	if p.files == 0 {
+		delete(m.packages, pkg)
+	} else {
+		m.packages[pkg] = p
+	}
+}
+
+// ObserveLag records how far analysis trails the latest file change, for
+// callers that re-analyze files as they change
+func (m *Metrics) ObserveLag(lag time.Duration) {
+	m.lagNanos.Store(lag.Nanoseconds())
+}
+
+// WriteTo writes the metrics in the Prometheus text exposition format
+func (m *Metrics) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	writeMetricHeader(&b, "ast_benchmark_files_parsed_total", "counter", "Go files parsed.")
+	fmt.Fprintf(&b, "ast_benchmark_files_parsed_total %d\n", m.filesParsed.Load())
+	writeMetricHeader(&b, "ast_benchmark_parse_errors_total", "counter", "Go files that failed to parse.")
+	fmt.Fprintf(&b, "ast_benchmark_parse_errors_total %d\n", m.parseErrors.Load())
+
+	writeMetricHeader(&b, "ast_benchmark_parse_duration_seconds", "histogram", "Time to parse one Go file.")
+	var cumulative int64
+	for i, bound := range parseDurationBuckets {
+		cumulative += m.buckets[i].Load()
+		fmt.Fprintf(&b, "ast_benchmark_parse_duration_seconds_bucket{le=\"%s\"} %d\n",
+			strconv.FormatFloat(bound, 'g', -1, 64), cumulative)
+	}
+	cumulative += m.buckets[len(parseDurationBuckets)].Load()
+	fmt.Fprintf(&b, "ast_benchmark_parse_duration_seconds_bucket{le=\"+Inf\"} %d\n", cumulative)
+	fmt.Fprintf(&b, "ast_benchmark_parse_duration_seconds_sum %s\n",
+		strconv.FormatFloat(time.Duration(m.sumNanos.Load()).Seconds(), 'g', -1, 64))
+	fmt.Fprintf(&b, "ast_benchmark_parse_duration_seconds_count %d\n", m.count.Load())
+
+	packages := m.packageTotals()
+	names := make([]string, 0, len(packages))
+	for name := range packages {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	gauges := []struct {
+		name, help string
+		value      func(packageMetrics) int
+	}{
+		{"ast_benchmark_functions", "Functions and methods per package.", func(p packageMetrics) int { return p.functions }},
+		{"ast_benchmark_structs", "Struct types per package.", func(p packageMetrics) int { return p.structs }},
+		{"ast_benchmark_interfaces", "Interface types per package.", func(p packageMetrics) int { return p.interfaces }},
+	}
+	for _, g := range gauges {
+		writeMetricHeader(&b, g.name, "gauge", g.help)
+		for _, name := range names {
+			fmt.Fprintf(&b, "%s{package=\"%s\"} %d\n", g.name, escapeLabelValue(name), g.value(packages[name]))
+		}
+	}
+
+	writeMetricHeader(&b, "ast_benchmark_analysis_lag_seconds", "gauge", "Delay between a file change and its analysis.")
+	fmt.Fprintf(&b, "ast_benchmark_analysis_lag_seconds %s\n",
+		strconv.FormatFloat(time.Duration(m.lagNanos.Load()).Seconds(), 'g', -1, 64))
+	writeMetricHeader(&b, "ast_benchmark_last_parse_timestamp_seconds", "gauge", "Unix time of the latest parse.")
+	fmt.Fprintf(&b, "ast_benchmark_last_parse_timestamp_seconds %d\n", m.lastParse.Load()/int64(time.Second))
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// packageTotals returns a copy of the per-package counts
+func (m *Metrics) packageTotals() map[string]packageMetrics {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	totals := make(map[string]packageMetrics, len(m.packages))
+	for pkg, p := range m.packages {
+		totals[pkg] = p
+	}
+	return totals
+}
+
+// writeMetricHeader writes the HELP and TYPE lines of a metric family
+func writeMetricHeader(b *strings.Builder, name, kind, help string) {
+	fmt.Fprintf(b, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+}
+
+// escapeLabelValue escapes a label value for the text exposition format
+func escapeLabelValue(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
+
+// MetricsHandler serves the analyzer's metrics for Prometheus to scrape
+func (a *ASTAnalyzer) MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		a.metrics.WriteTo(w)
+	})
+}
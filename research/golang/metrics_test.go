@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bufio"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// scrape fetches the analyzer's metrics and returns the samples by series
+func scrape(t *testing.T, a *ASTAnalyzer) map[string]string {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	a.MetricsHandler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("Content-Type %q", ct)
+	}
+
+	samples := make(map[string]string)
+	scanner := bufio.NewScanner(rec.Body)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		i := strings.LastIndexByte(line, ' ')
+		if i < 0 {
+			t.Fatalf("malformed sample %q", line)
+		}
+		samples[line[:i]] = line[i+1:]
+	}
+	return samples
+}
+
+func TestMetricsHandler(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"main.go":        "package main\n\nfunc main() {}\n",
+		"broken.go":      "package main\n\nfunc Broken( {\n",
+		"store/store.go": "package store\n\ntype Store struct{}\n\nfunc New() *Store { return nil }\n\nfunc (s *Store) Get() {}\n",
+		"store/api.go":   "package store\n\ntype Getter interface{ Get() }\n",
+	})
+	a := quietAnalyzer()
+	if _, err := a.BenchmarkDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	root := filepath.ToSlash(dir)
+
+	tests := []struct {
+		series string
+		want   string
+	}{
+		{series: "ast_benchmark_files_parsed_total", want: "4"},
+		{series: "ast_benchmark_parse_errors_total", want: "1"},
+		{series: `ast_benchmark_parse_duration_seconds_bucket{le="+Inf"}`, want: "3"},
+		{series: "ast_benchmark_parse_duration_seconds_count", want: "3"},
+		{series: `ast_benchmark_functions{package="` + root + `"}`, want: "1"},
+		{series: `ast_benchmark_functions{package="` + root + `/store"}`, want: "2"},
+		{series: `ast_benchmark_structs{package="` + root + `/store"}`, want: "1"},
+		{series: `ast_benchmark_interfaces{package="` + root + `/store"}`, want: "1"},
+		{series: `ast_benchmark_interfaces{package="` + root + `"}`, want: "0"},
+		{series: "ast_benchmark_analysis_lag_seconds", want: "0"},
+	}
+
+	samples := scrape(t, a)
+	for _, tt := range tests {
+		t.Run(tt.series, func(t *testing.T) {
+			got, ok := samples[tt.series]
+			if !ok {
+				t.Fatalf("series %s missing", tt.series)
+			}
+			if got != tt.want {
+				t.Errorf("%s = %s, want %s", tt.series, got, tt.want)
+			}
+		})
+	}
+
+	for series := range samples {
+		if strings.Contains(series, ".go") {
+			t.Errorf("series %s is labeled with a file", series)
+		}
+	}
+
+	// A second run replaces the package gauges; the counters keep counting
+	if _, err := a.BenchmarkDirectory(dir); err != nil {
+		t.Fatal(err)
+	}
+	samples = scrape(t, a)
+	if got := samples[`ast_benchmark_functions{package="`+root+`/store"}`]; got != "2" {
+		t.Errorf("store functions after a second run = %s, want 2", got)
+	}
+	if got := samples["ast_benchmark_files_parsed_total"]; got != "8" {
+		t.Errorf("files parsed after a second run = %s, want 8", got)
+	}
+}
+
+func TestEscapeLabelValue(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{in: "example.com/a", want: "example.com/a"},
+		{in: `C:\src`, want: `C:\\src`},
+		{in: `a"b`, want: `a\"b`},
+		{in: "a\nb", want: `a\nb`},
+	}
+	for _, tt := range tests {
+		if got := escapeLabelValue(tt.in); got != tt.want {
+			t.Errorf("escapeLabelValue(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ParseMode selects how much work ParseFile asks of the parser. Parse
+// times are only comparable between runs that used the same mode.
+type ParseMode int
+
+const (
+	// StandardMode keeps comments and resolves objects
+	StandardMode ParseMode = iota
+	// FastMode skips comments and object resolution, which is enough for
+	// counting declarations
+	FastMode
+	// FullMode keeps comments, resolves objects and reports all errors
+	// instead of only the first ten
+	FullMode
+)
+
+// parseModeNames are the names of the modes, as accepted by ParseParseMode
+var parseModeNames = map[ParseMode]string{
+	StandardMode: "standard",
+	FastMode:     "fast",
+	FullMode:     "full",
+}
+
+// String returns the mode's name
+func (m ParseMode) String() string {
+	if name, ok := parseModeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("ParseMode(%d)", int(m))
+}
+
+// parserMode returns the parser flags of the mode
+func (m ParseMode) parserMode() parser.Mode {
+	switch m {
+	case FastMode:
+		return parser.SkipObjectResolution
+	case FullMode:
+		return parser.ParseComments | parser.AllErrors
+	default:
+		return parser.ParseComments
+	}
+}
+
+// ParseParseMode returns the mode with the given name
+func ParseParseMode(name string) (ParseMode, error) {
+	for mode, n := range parseModeNames {
+		if n == name {
+			return mode, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown parse mode %q (want standard, fast or full)", name)
+}
+
+// modeIterations is how many times each parser mode is timed per file
+const modeIterations = 20
+
+// benchmarkModeFlags are the parser flags whose combinations are compared
+var benchmarkModeFlags = []parser.Mode{
+	parser.ParseComments,
+	parser.AllErrors,
+	parser.SkipObjectResolution,
+}
+
+// BenchmarkParserModes parses the same file with every combination of
+// ParseComments, AllErrors and SkipObjectResolution and returns the average
+// parse time per mode
+func BenchmarkParserModes(filePath string) (map[parser.Mode]time.Duration, error) {
+	// Read once so file I/O is excluded from the timings
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	// Warm up so the first mode measured is not penalized
+	if _, err := parser.ParseFile(token.NewFileSet(), filePath, src, parser.ParseComments); err != nil {
+		return nil, err
+	}
+
+	timings := make(map[parser.Mode]time.Duration)
+	for combo := 0; combo < 1<<len(benchmarkModeFlags); combo++ {
+		var mode parser.Mode
+		for i, flag := range benchmarkModeFlags {
+			if combo&(1<<i) != 0 {
+				mode |= flag
+			}
+		}
+
+		var total time.Duration
+		for i := 0; i < modeIterations; i++ {
+			start := time.Now()
+			if _, err := parser.ParseFile(token.NewFileSet(), filePath, src, mode); err != nil {
+				return nil, err
+			}
+			total += time.Since(start)
+		}
+		timings[mode] = total / modeIterations
+	}
+
+	return timings, nil
+}
+
+// PrintParserModes prints parser mode timings relative to mode 0
+func PrintParserModes(filePath string, timings map[parser.Mode]time.Duration) {
+	modes := make([]parser.Mode, 0, len(timings))
+	for mode := range timings {
+		modes = append(modes, mode)
+	}
+	sort.Slice(modes, func(i, j int) bool { return modes[i] < modes[j] })
+
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Printf("PARSER MODES: %s\n", filePath)
+	fmt.Println(strings.Repeat("=", 70))
+
+	baseline := timings[0]
+	for _, mode := range modes {
+		relative := 0.0
+		if baseline > 0 {
+			relative = float64(timings[mode]) / float64(baseline) * 100
+		}
+		fmt.Printf("%-50s %6.3fms %6.1f%%\n",
+			modeString(mode),
+			float64(timings[mode].Microseconds())/1000.0,
+			relative)
+	}
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println()
+}
+
+// modeString returns a readable name for a combination of parser flags
+func modeString(mode parser.Mode) string {
+	names := map[parser.Mode]string{
+		parser.ParseComments:        "ParseComments",
+		parser.AllErrors:            "AllErrors",
+		parser.SkipObjectResolution: "SkipObjectResolution",
+	}
+
+	var parts []string
+	for _, flag := range benchmarkModeFlags {
+		if mode&flag != 0 {
+			parts = append(parts, names[flag])
+		}
+	}
+
+	if len(parts) == 0 {
+		return "0"
+	}
+	return strings.Join(parts, "|")
+}
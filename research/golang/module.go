@@ -0,0 +1,238 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// Loaders the command line chooses between with -loader
+const (
+	// ParserLoader parses every Go file under a directory on its own,
+	// needing nothing but the files
+	ParserLoader = "parser"
+
+	// PackagesLoader loads whole packages with go/packages, which runs the
+	// go command: files are selected by build constraints and types come
+	// from the package rather than from checking each directory
+	PackagesLoader = "packages"
+)
+
+// PackageResults holds what AnalyzeModule found in one package
+type PackageResults struct {
+	ImportPath string
+	Name       string
+	Dir        string
+	Results    []ParseResult // In file order
+
+	// Errors lists the load, parse and type errors of the package. They
+	// are also recorded as warnings; the rest of the module is analyzed
+	// regardless.
+	Errors []string
+}
+
+// AnalyzeModule benchmarks the packages of the module in dir, and their
+// tests, loaded with go/packages instead of by walking the directory, as
+// a new run. Only the files the build constraints select for the host are
+// parsed. Each file is parsed once with comments, timed like ParseSource
+// times it, and its functions are extracted from the same tree; with
+// TypeCheck their types come from the loaded package. Results reach the
+// Handler and the exports like those of BenchmarkDirectory and are
+// returned grouped by package in import path order. Files outside dir,
+// such as those cgo generates, are left out; Runs, MeasureMemory and the
+// result cache do not apply. Only a failure to run the load at all is
+// returned as an error.
+func (a *ASTAnalyzer) AnalyzeModule(dir string) ([]PackageResults, error) {
+	var loaded []PackageResults
+	_, err := a.benchmark(dir, func(fn func(ParseResult) error) error {
+		var err error
+		loaded, err = a.loadModule(dir)
+		if err != nil {
+			return err
+		}
+		for _, pkg := range loaded {
+			for _, result := range pkg.Results {
+				if err := fn(result); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
+	return loaded, err
+}
+
+// parsedFile is a file parsed during a go/packages load
+type parsedFile struct {
+	f      *ast.File
+	result ParseResult
+}
+
+// loadModule loads the packages under dir, builds their results and
+// records their functions
+func (a *ASTAnalyzer) loadModule(dir string) ([]PackageResults, error) {
+	root, err := filepath.Abs(dirOf(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	var mu sync.Mutex
+	parsed := make(map[string]parsedFile)
+	mode := packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles | packages.NeedSyntax
+	if a.TypeCheck {
+		// Dependencies are checked from source, like the source importer of
+		// checkPackage does, since export data ties the load to the
+		// toolchain x/tools was built for
+		mode |= packages.NeedTypes | packages.NeedTypesInfo | packages.NeedImports | packages.NeedDeps
+	}
+	cfg := &packages.Config{
+		Mode:  mode | a.PackagesMode,
+		Dir:   dir,
+		Fset:  a.fset,
+		Tests: true,
+		ParseFile: func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+			if !within(root, filename) {
+				return parser.ParseFile(fset, filename, src, parser.SkipObjectResolution)
+			}
+			start := time.Now()
+			f, err := parser.ParseFile(fset, filename, src, cacheParseMode)
+			var result ParseResult
+			if err != nil {
+				result = a.failedParse(filename, err, 0)
+				result.FileSizeBytes = int64(len(src))
+				result.LineCount = lineCount(src)
+			} else {
+				result = a.astResult(fset, filename, src, f)
+				result.ParseTime = time.Since(start)
+			}
+			mu.Lock()
+			parsed[filename] = parsedFile{f: f, result: result}
+			mu.Unlock()
+			return f, err
+		},
+	}
+	pkgs, err := packages.Load(cfg, "./...")
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(pkgs, func(i, j int) bool { return pkgs[i].ID < pkgs[j].ID })
+
+	// A package and its test variant share files; each is taken once, from
+	// the first package that has it
+	byPath := make(map[string]*PackageResults)
+	var order []string
+	seen := make(map[string]bool)
+	for _, pkg := range pkgs {
+		var pt *packageTypes
+		if a.TypeCheck && pkg.Types != nil {
+			pt = a.loadedPackageTypes(pkg, dir, root)
+		}
+
+		entry := func() *PackageResults {
+			s, ok := byPath[pkg.PkgPath]
+			if !ok {
+				s = &PackageResults{ImportPath: pkg.PkgPath, Name: pkg.Name}
+				byPath[pkg.PkgPath] = s
+				order = append(order, pkg.PkgPath)
+			}
+			return s
+		}
+
+		for _, filename := range pkg.CompiledGoFiles {
+			file, ok := parsed[filename]
+			if seen[filename] || !ok {
+				continue
+			}
+			seen[filename] = true
+			rel, _ := filepath.Rel(root, filename)
+
+			// Paths are written as the walk of BenchmarkDirectory would
+			path := filepath.Join(dir, rel)
+			s := entry()
+			s.Dir = filepath.Dir(path)
+			file.result.FilePath = path
+			file.result.Root = dir
+			s.Results = append(s.Results, file.result)
+
+			if file.f == nil {
+				continue
+			}
+			functions := a.functionInfos(file.f)
+			if pt != nil {
+				a.applyTypes(pt, filename, functions)
+			}
+			a.mu.Lock()
+			a.functions[path] = functions
+			a.lineCounts[path] = file.result.LineCount
+			a.mu.Unlock()
+		}
+
+		if len(pkg.Errors) > 0 {
+			s := entry()
+			for _, e := range pkg.Errors {
+				s.Errors = append(s.Errors, e.Error())
+			}
+		}
+	}
+
+	sort.Strings(order)
+	results := make([]PackageResults, len(order))
+	for i, path := range order {
+		results[i] = *byPath[path]
+		results[i].Errors = sortedUnique(results[i].Errors)
+		sort.Slice(results[i].Results, func(j, k int) bool {
+			return results[i].Results[j].FilePath < results[i].Results[k].FilePath
+		})
+		a.mu.Lock()
+		for _, e := range results[i].Errors {
+			a.warnings = append(a.warnings, fmt.Sprintf("%s: %s", path, e))
+		}
+		a.mu.Unlock()
+	}
+	return results, nil
+}
+
+// within reports whether path lies under the directory root
+func within(root, path string) bool {
+	rel, err := filepath.Rel(root, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// loadedPackageTypes indexes the types go/packages checked for pkg and
+// records them under the package directory as walked from dir, so later
+// analyses of the directory reuse them instead of checking it again
+func (a *ASTAnalyzer) loadedPackageTypes(pkg *packages.Package, dir, root string) *packageTypes {
+	pt := &packageTypes{
+		signatures: packageSignatures(pkg.Types),
+		uses:       make(map[*ast.Ident]types.Object),
+		badFiles:   make(map[string]bool),
+	}
+	if pkg.TypesInfo != nil {
+		pt.uses = pkg.TypesInfo.Uses
+	}
+	for _, e := range pkg.TypeErrors {
+		pt.badFiles[e.Fset.Position(e.Pos).Filename] = true
+	}
+
+	// Test variants hold extra files; the package as built is kept
+	rel, err := filepath.Rel(root, pkg.Dir)
+	if err != nil || pkg.Dir == "" || strings.HasSuffix(pkg.ID, "]") || strings.HasSuffix(pkg.PkgPath, ".test") {
+		return pt
+	}
+	a.typesMu.Lock()
+	defer a.typesMu.Unlock()
+	if a.packageTypes == nil {
+		a.packageTypes = make(map[string]*packageTypes)
+	}
+	a.packageTypes[filepath.Join(dir, rel)+":"+pkg.Name] = pt
+	return pt
+}
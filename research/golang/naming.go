@@ -0,0 +1,232 @@
+package main
+
+import (
+	"go/ast"
+	"reflect"
+	"strings"
+	"unicode"
+)
+
+// NamingIssue describes an identifier that breaks Go naming conventions
+type NamingIssue struct {
+	Symbol     string
+	FilePath   string
+	Line       int
+	Kind       string // "unexported", "initialism" or "getter"
+	Message    string
+	Suggestion string
+}
+
+// commonInitialisms lists words that Go style writes in a consistent case
+var commonInitialisms = []string{
+	"ACL", "API", "ASCII", "CPU", "CSS", "DNS", "EOF", "GUID", "HTML", "HTTP",
+	"HTTPS", "ID", "IP", "JSON", "LHS", "QPS", "RAM", "RHS", "RPC", "SLA",
+	"SMTP", "SQL", "SSH", "TCP", "TLS", "TTL", "UDP", "UI", "UID", "URI",
+	"URL", "UTF8", "UUID", "VM", "XML", "XMPP", "XSRF", "XSS",
+}
+
+// serializationTags are struct tag keys that only apply to exported fields
+var serializationTags = []string{"json", "xml", "yaml", "toml"}
+
+// CheckNaming reports naming-convention violations in all Go files under dir
+func (a *ASTAnalyzer) CheckNaming(dir string) ([]NamingIssue, error) {
+	files, err := a.goFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []NamingIssue
+	for _, path := range files {
+		f, err := a.cache.Parse(path)
+		if err != nil {
+			continue
+		}
+		issues = append(issues, a.checkFileNaming(path, f)...)
+	}
+
+	return issues, nil
+}
+
+// checkFileNaming applies the naming rules to a single parsed file
+func (a *ASTAnalyzer) checkFileNaming(path string, f *ast.File) []NamingIssue {
+	var issues []NamingIssue
+	isTestFile := isTestName(path)
+
+	report := func(name *ast.Ident, kind, message, suggestion string) {
+		issues = append(issues, NamingIssue{
+			Symbol:     name.Name,
+			FilePath:   path,
+			Line:       a.fset.Position(name.Pos()).Line,
+			Kind:       kind,
+			Message:    message,
+			Suggestion: suggestion,
+		})
+	}
+
+	checkInitialisms := func(name *ast.Ident) {
+		if fixed := a.fixInitialisms(name.Name); fixed != name.Name {
+			report(name, "initialism", "initialism should be written in a consistent case", fixed)
+		}
+	}
+
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			checkInitialisms(d.Name)
+
+			// Test functions must be exported or `go test` silently skips them
+			if isTestFile && d.Recv == nil {
+				if fixed, ok := exportedTestName(d); ok {
+					report(d.Name, "unexported", "test function is unexported and will not run", fixed)
+				}
+			}
+
+			// Getters should not carry a Get prefix
+			if d.Recv != nil && isGetter(d) {
+				fixed := strings.TrimPrefix(d.Name.Name, "Get")
+				report(d.Name, "getter", "getter should not use the Get prefix", fixed)
+			}
+
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					checkInitialisms(s.Name)
+					st, ok := s.Type.(*ast.StructType)
+					if !ok {
+						continue
+					}
+					for _, field := range st.Fields.List {
+						for _, name := range field.Names {
+							checkInitialisms(name)
+
+							// Tagged fields are ignored by encoders unless exported
+							if !name.IsExported() && hasSerializationTag(field) {
+								report(name, "unexported", "field has a serialization tag but is unexported", upperFirst(name.Name))
+							}
+						}
+					}
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						checkInitialisms(name)
+					}
+				}
+			}
+		}
+	}
+
+	return issues
+}
+
+// exportedTestName returns the corrected name for test, benchmark and fuzz
+// functions whose prefix is written in lowercase
+func exportedTestName(fn *ast.FuncDecl) (string, bool) {
+	prefixes := map[string]string{
+		"test":      "*testing.T",
+		"benchmark": "*testing.B",
+		"fuzz":      "*testing.F",
+	}
+
+	name := fn.Name.Name
+	for prefix, paramType := range prefixes {
+		if !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+			continue
+		}
+		params := fn.Type.Params.List
+		if len(params) != 1 || exprToString(params[0].Type) != paramType {
+			continue
+		}
+		return upperFirst(name), true
+	}
+
+	return "", false
+}
+
+// isGetter reports whether a method looks like a Get-prefixed accessor
+func isGetter(fn *ast.FuncDecl) bool {
+	name := fn.Name.Name
+	if !strings.HasPrefix(name, "Get") || len(name) == len("Get") {
+		return false
+	}
+	if !unicode.IsUpper(rune(name[len("Get")])) {
+		return false
+	}
+	return fn.Type.Params.NumFields() == 0 && fn.Type.Results.NumFields() == 1
+}
+
+// hasSerializationTag reports whether a struct field carries an encoder tag
+func hasSerializationTag(field *ast.Field) bool {
+	if field.Tag == nil {
+		return false
+	}
+	tag := reflect.StructTag(strings.Trim(field.Tag.Value, "`"))
+	for _, key := range serializationTags {
+		if _, ok := tag.Lookup(key); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// fixInitialisms rewrites mixed-case initialisms such as Url or Http
+func (a *ASTAnalyzer) fixInitialisms(name string) string {
+	words := splitCamelCase(name)
+	for i, word := range words {
+		upper := strings.ToUpper(word)
+		if word == upper || !a.isInitialism(upper) {
+			continue
+		}
+		// A fully lowercase leading word is fine in unexported names
+		if i == 0 && word == strings.ToLower(word) {
+			continue
+		}
+		words[i] = upper
+	}
+	return strings.Join(words, "")
+}
+
+// isInitialism reports whether word is one of the configured initialisms
+func (a *ASTAnalyzer) isInitialism(word string) bool {
+	for _, initialism := range a.Initialisms {
+		if word == initialism {
+			return true
+		}
+	}
+	return false
+}
+
+// splitCamelCase splits an identifier into its camel-case words
+func splitCamelCase(name string) []string {
+	var words []string
+	runes := []rune(name)
+	start := 0
+
+	for i := 1; i < len(runes); i++ {
+		prev, cur := runes[i-1], runes[i]
+		switch {
+		case cur == '_' || prev == '_':
+			words = append(words, string(runes[start:i]))
+			start = i
+		case unicode.IsLower(prev) && unicode.IsUpper(cur):
+			words = append(words, string(runes[start:i]))
+			start = i
+		case unicode.IsUpper(prev) && unicode.IsUpper(cur) &&
+			i+1 < len(runes) && unicode.IsLower(runes[i+1]):
+			// Split "HTTPServer" into "HTTP" and "Server"
+			words = append(words, string(runes[start:i]))
+			start = i
+		}
+	}
+
+	return append(words, string(runes[start:]))
+}
+
+// upperFirst returns s with its first letter upper-cased
+func upperFirst(s string) string {
+	if s == "" {
+		return s
+	}
+	runes := []rune(s)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
@@ -0,0 +1,61 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// PackageSummary aggregates the parse results of one package directory
+type PackageSummary struct {
+	Path          string
+	Files         int
+	Failed        int
+	Generated     int
+	NumFunctions  int
+	NumMethods    int
+	NumInterfaces int
+	NumStructs    int
+	InitFuncs     int
+	ParseTime     time.Duration
+}
+
+// summarizePackages groups results by directory, sorted by path
+func summarizePackages(results []ParseResult) []PackageSummary {
+	byPath := make(map[string]*PackageSummary)
+	for _, r := range results {
+		dir := filepath.Dir(r.FilePath)
+		s, ok := byPath[dir]
+		if !ok {
+			s = &PackageSummary{Path: dir}
+			byPath[dir] = s
+		}
+
+		s.Files++
+		if r.Generated {
+			s.Generated++
+		}
+		if r.SkippedOversize {
+			continue
+		}
+		if !r.Success {
+			s.Failed++
+			continue
+		}
+		s.NumFunctions += r.NumFunctions
+		s.NumMethods += r.NumMethods
+		s.NumInterfaces += r.NumInterfaces
+		s.NumStructs += r.NumStructs
+		s.InitFuncs += len(r.InitFuncs)
+		s.ParseTime += r.ParseTime
+	}
+
+	summaries := make([]PackageSummary, 0, len(byPath))
+	for _, s := range byPath {
+		summaries = append(summaries, *s)
+	}
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Path < summaries[j].Path
+	})
+	return summaries
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"go/ast"
+	"sort"
+)
+
+// isBuiltinCall reports whether call calls the builtin name, judged by
+// syntax alone: a declaration shadowing the builtin goes unnoticed
+func isBuiltinCall(call *ast.CallExpr, name string) bool {
+	ident, ok := ast.Unparen(call.Fun).(*ast.Ident)
+	return ok && ident.Name == name
+}
+
+// callsBuiltin reports whether body calls the builtin name itself, not
+// counting calls made inside function literals
+func callsBuiltin(body ast.Node, name string) bool {
+	found := false
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.FuncLit:
+			return x == body
+		case *ast.CallExpr:
+			if isBuiltinCall(x, name) {
+				found = true
+			}
+		}
+		return !found
+	})
+	return found
+}
+
+// unrecoveredPanics returns the sorted names of the functions of f that
+// call panic, anywhere in their body, without deferring a call that
+// recovers: a function literal calling recover, or a function of the same
+// file that does. Methods are named Type.Method.
+func unrecoveredPanics(f *ast.File) []string {
+	recoverers := make(map[string]bool)
+	for _, decl := range f.Decls {
+		if fn, ok := decl.(*ast.FuncDecl); ok && fn.Recv == nil && fn.Body != nil && callsBuiltin(fn.Body, "recover") {
+			recoverers[fn.Name.Name] = true
+		}
+	}
+
+	var names []string
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+
+		panics, recovers := false, false
+		ast.Inspect(fn.Body, func(n ast.Node) bool {
+			switch x := n.(type) {
+			case *ast.CallExpr:
+				if isBuiltinCall(x, "panic") {
+					panics = true
+				}
+			case *ast.DeferStmt:
+				switch deferred := ast.Unparen(x.Call.Fun).(type) {
+				case *ast.FuncLit:
+					recovers = recovers || callsBuiltin(deferred, "recover")
+				case *ast.Ident:
+					recovers = recovers || recoverers[deferred.Name]
+				}
+			}
+			return true
+		})
+		if !panics || recovers {
+			continue
+		}
+
+		name := fn.Name.Name
+		if fn.Recv != nil && len(fn.Recv.List) > 0 {
+			name = receiverTypeName(exprToString(fn.Recv.List[0].Type)) + "." + name
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,171 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// isPattern reports whether arg holds a ... wildcard
+func isPattern(arg string) bool {
+	return strings.Contains(arg, "...")
+}
+
+// patternBase returns the directory to walk for a pattern: the part of it
+// before the first wildcard, cut back to a whole directory
+func patternBase(pattern string) string {
+	pattern = path.Clean(filepath.ToSlash(pattern))
+	i := strings.Index(pattern, "...")
+	if i < 0 {
+		return filepath.FromSlash(pattern)
+	}
+	j := strings.LastIndex(pattern[:i], "/")
+	if j < 0 {
+		return "."
+	}
+	if j == 0 {
+		return "/"
+	}
+	return filepath.FromSlash(pattern[:j])
+}
+
+// matchPattern returns a function reporting whether a slash-separated
+// directory matches pattern, as cmd/go matches import paths: ... matches
+// any string, a trailing /... also matches the directory before it, and
+// a wildcard does not descend into testdata or directories starting with
+// an underscore
+func matchPattern(pattern string) func(dir string) bool {
+	pattern = path.Clean(filepath.ToSlash(pattern))
+	re := regexp.QuoteMeta(pattern)
+	re = strings.ReplaceAll(re, `\.\.\.`, `.*`)
+	if strings.HasSuffix(re, `/.*`) {
+		re = strings.TrimSuffix(re, `/.*`) + `(/.*)?`
+	}
+	reg := regexp.MustCompile(`^` + re + `$`)
+
+	base := filepath.ToSlash(patternBase(pattern))
+	return func(dir string) bool {
+		dir = path.Clean(dir)
+		if !reg.MatchString(dir) {
+			return false
+		}
+		rel := strings.TrimPrefix(strings.TrimPrefix(dir, base), "/")
+		if base == "." {
+			rel = dir
+		}
+		for _, elem := range strings.Split(rel, "/") {
+			if elem == "testdata" || strings.HasPrefix(elem, "_") {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// ExpandPatterns returns the directories holding Go files that patterns
+// match, sorted and each once. Patterns are directories in which ...
+// stands for any string, like the package patterns of the go command:
+// "./internal/..." is internal and every directory below it. A pattern
+// without a wildcard names its directory alone.
+func (a *ASTAnalyzer) ExpandPatterns(patterns []string) ([]string, error) {
+	var dirs []string
+	for _, pattern := range patterns {
+		sources, err := a.patternSources(pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range sources {
+			dirs = append(dirs, filepath.Dir(file.path))
+		}
+	}
+	return sortedUnique(dirs), nil
+}
+
+// patternSources returns the Go files of the directories pattern matches
+func (a *ASTAnalyzer) patternSources(pattern string) ([]sourceFile, error) {
+	fsys, root, err := diskFS(patternBase(pattern))
+	if err != nil {
+		return nil, err
+	}
+	files, err := a.fsSources(fsys, root, "")
+	if err != nil {
+		return nil, err
+	}
+
+	match := matchPattern(pattern)
+	var sources []sourceFile
+	for _, file := range files {
+		if match(filepath.ToSlash(filepath.Dir(file.path))) {
+			sources = append(sources, file)
+		}
+	}
+	sort.Slice(sources, func(i, j int) bool { return sources[i].path < sources[j].path })
+	return sources, nil
+}
+
+// BenchmarkPatterns benchmarks the directories that patterns match, as
+// ExpandPatterns expands them, in one run. Each pattern is a root tagged
+// with the directory it is walked from; a file matched by several patterns
+// is benchmarked for the first.
+func (a *ASTAnalyzer) BenchmarkPatterns(patterns []string) error {
+	return a.BenchmarkPatternsContext(context.Background(), patterns)
+}
+
+// BenchmarkPatternsContext is BenchmarkPatterns with cancellation, keeping
+// the partial results like BenchmarkDirectoriesContext does
+func (a *ASTAnalyzer) BenchmarkPatternsContext(ctx context.Context, patterns []string) error {
+	start := time.Now()
+	a.newRun()
+	seen := make(map[string]bool)
+	var err error
+	for _, pattern := range patterns {
+		var sources []sourceFile
+		if sources, err = a.patternSources(pattern); err != nil {
+			err = fmt.Errorf("%s: %w", pattern, err)
+			break
+		}
+		var fresh []sourceFile
+		for _, file := range sources {
+			if !seen[file.path] {
+				seen[file.path] = true
+				fresh = append(fresh, file)
+			}
+		}
+
+		base := patternBase(pattern)
+		_, err = a.benchmarkRoot(base, func(fn func(ParseResult) error) error {
+			return a.streamSources(ctx, base, fresh, true, fn)
+		})
+		if err != nil {
+			err = fmt.Errorf("%s: %w", pattern, err)
+			break
+		}
+	}
+
+	a.mu.Lock()
+	a.startTime = start
+	if len(patterns) > 1 {
+		a.rootDir = strings.Join(patterns, ",")
+	}
+	a.mu.Unlock()
+	return err
+}
+
+// patternBases returns the directories patterns are walked from, each
+// once, for the passes that take directories
+func patternBases(patterns []string) []string {
+	var bases []string
+	seen := make(map[string]bool)
+	for _, pattern := range patterns {
+		if base := patternBase(pattern); !seen[base] {
+			seen[base] = true
+			bases = append(bases, base)
+		}
+	}
+	return bases
+}
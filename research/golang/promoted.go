@@ -0,0 +1,208 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// MethodSet is the effective method set of a struct: its declared methods
+// and those promoted through its embedded fields
+type MethodSet struct {
+	Type    string
+	Package string // Package directory
+	Methods []EffectiveMethod
+
+	// Ambiguous lists the methods reached through several embedded fields
+	// at the same depth, which the language does not promote
+	Ambiguous []AmbiguousMethod
+}
+
+// EffectiveMethod is a method in a method set
+type EffectiveMethod struct {
+	Name string `json:"name"`
+
+	// Via is the path of embedded fields the method is promoted through,
+	// as a selector would spell it, such as "B" or "B.C"; empty for a
+	// method declared on the type itself
+	Via   string `json:"via,omitempty"`
+	Depth int    `json:"depth"` // Length of Via in fields
+}
+
+// Promoted reports whether the method comes from an embedded field
+func (m EffectiveMethod) Promoted() bool {
+	return m.Via != ""
+}
+
+// AmbiguousMethod is a method name that several embedded fields provide
+// at the shallowest depth it is found
+type AmbiguousMethod struct {
+	Name  string   `json:"name"`
+	Depth int      `json:"depth"`
+	Via   []string `json:"via"` // Every path that reaches it, sorted
+}
+
+// embeddedTypeName returns the bare name of an embedded type as written,
+// or "" for types from other packages, which cannot be resolved here
+func embeddedTypeName(embed string) string {
+	name := strings.TrimPrefix(embed, "*")
+	if i := strings.Index(name, "["); i >= 0 {
+		name = name[:i]
+	}
+	if strings.Contains(name, ".") {
+		return ""
+	}
+	return name
+}
+
+// promotedMethods returns the sorted names of the methods promoted into
+// the struct t through its embedded fields
+func promotedMethods(t TypeInfo, byName map[string]TypeInfo) []string {
+	var promoted []string
+	for _, m := range effectiveMethodSet(t, byName).Methods {
+		if m.Promoted() {
+			promoted = append(promoted, m.Name)
+		}
+	}
+	return promoted
+}
+
+// embedPath is an embedded field reached from the struct and the fields
+// leading to it
+type embedPath struct {
+	embed string
+	via   []string
+}
+
+// effectiveMethodSet resolves the method set of the struct t. Types are
+// looked up in byName, keyed by package directory and type name, so only
+// embeds declared in the same package are followed. As in the language, a
+// selector at a shallower depth shadows deeper ones, and a method reached
+// twice at the same depth is ambiguous and not promoted. Methods are
+// sorted by name.
+func effectiveMethodSet(t TypeInfo, byName map[string]TypeInfo) MethodSet {
+	set := MethodSet{Type: t.Name, Package: t.Package}
+
+	// Selectors of the struct itself: fields, embedded field names and
+	// declared methods
+	resolved := make(map[string]bool)
+	for _, field := range t.Fields {
+		resolved[field.Name] = true
+	}
+	for _, m := range t.Methods {
+		resolved[m.Name] = true
+		set.Methods = append(set.Methods, EffectiveMethod{Name: m.Name})
+	}
+
+	seen := map[string]bool{t.Name: true}
+	var level []embedPath
+	for _, embed := range t.Embeds {
+		resolved[embeddedTypeName(embed)] = true
+		level = append(level, embedPath{embed: embed})
+	}
+	for depth := 1; len(level) > 0; depth++ {
+		methods := make(map[string][]string) // Paths reaching each method
+		selectors := make(map[string]bool)
+		var next []embedPath
+		var entered []string
+		for _, p := range level {
+			// A type reached twice at one depth counts twice, so its
+			// methods become ambiguous; types from shallower depths were
+			// already shadowed
+			name := embeddedTypeName(p.embed)
+			embedded, ok := byName[t.Package+"."+name]
+			if name == "" || !ok || seen[name] {
+				continue
+			}
+			entered = append(entered, name)
+			via := append(append([]string(nil), p.via...), name)
+
+			for _, m := range embedded.Methods {
+				methods[m.Name] = append(methods[m.Name], strings.Join(via, "."))
+			}
+			for _, field := range embedded.Fields {
+				selectors[field.Name] = true
+			}
+			for _, e := range embedded.Embeds {
+				selectors[embeddedTypeName(e)] = true
+				next = append(next, embedPath{embed: e, via: via})
+			}
+		}
+
+		for name, paths := range methods {
+			switch {
+			case resolved[name]:
+			case len(paths) > 1:
+				sort.Strings(paths)
+				set.Ambiguous = append(set.Ambiguous, AmbiguousMethod{Name: name, Depth: depth, Via: paths})
+			case !selectors[name]:
+				set.Methods = append(set.Methods, EffectiveMethod{Name: name, Via: paths[0], Depth: depth})
+			}
+		}
+		for name := range methods {
+			resolved[name] = true
+		}
+		for name := range selectors {
+			resolved[name] = true
+		}
+		for _, name := range entered {
+			seen[name] = true
+		}
+		level = next
+	}
+
+	sort.Slice(set.Methods, func(i, j int) bool { return set.Methods[i].Name < set.Methods[j].Name })
+	sort.Slice(set.Ambiguous, func(i, j int) bool { return set.Ambiguous[i].Name < set.Ambiguous[j].Name })
+	return set
+}
+
+// EffectiveMethodSet returns the method set of a struct recorded by
+// ExtractTypes, with each method marked as declared or promoted and the
+// ambiguous promotions listed apart. typeName is a type name, qualified
+// with its package directory as "dir.Name" when several packages declare
+// it.
+func (a *ASTAnalyzer) EffectiveMethodSet(typeName string) (MethodSet, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	byName := make(map[string]TypeInfo, len(a.types))
+	var matches []TypeInfo
+	for _, t := range a.types {
+		if _, ok := byName[t.Package+"."+t.Name]; ok {
+			continue
+		}
+		byName[t.Package+"."+t.Name] = t
+		if t.Kind == StructKind && (t.Name == typeName || t.Package+"."+t.Name == typeName) {
+			matches = append(matches, t)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return MethodSet{}, fmt.Errorf("struct %q not found", typeName)
+	case 1:
+		return effectiveMethodSet(matches[0], byName), nil
+	default:
+		return MethodSet{}, fmt.Errorf("struct %q is declared in %d packages; qualify it with its package directory", typeName, len(matches))
+	}
+}
+
+// methodSets returns the method sets of the recorded structs that have
+// any methods, in the order they were recorded
+func (a *ASTAnalyzer) methodSets() []MethodSet {
+	byName := make(map[string]TypeInfo, len(a.types))
+	for _, t := range a.types {
+		byName[t.Package+"."+t.Name] = t
+	}
+	var sets []MethodSet
+	done := make(map[string]bool)
+	for _, t := range a.types {
+		if t.Kind != StructKind || done[t.Package+"."+t.Name] {
+			continue
+		}
+		done[t.Package+"."+t.Name] = true
+		if set := effectiveMethodSet(t, byName); len(set.Methods) > 0 || len(set.Ambiguous) > 0 {
+			sets = append(sets, set)
+		}
+	}
+	return sets
+}
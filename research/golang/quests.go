@@ -0,0 +1,149 @@
+package main
+
+import (
+	"flag"
+	"go/ast"
+	"go/token"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/study-game/research/game"
+)
+
+// taskMarker matches a comment line opening a task: the marker, an
+// optional owner in parentheses and an optional colon
+var taskMarker = regexp.MustCompile(`^(TODO|FIXME|BUG)(?:\(([^)]*)\))?:?(?:\s+|$)(.*)`)
+
+// TaskComments finds the TODO, FIXME and BUG comments of every Go file
+// under dir, test files included. A task's text runs on over the following
+// lines of its comment until another marker. Each task notes the function,
+// method or type declaration it sits in, doc comments included, and its
+// length in lines. Packages and files are relative to dir.
+func (a *ASTAnalyzer) TaskComments(dir string) ([]game.TaskComment, error) {
+	files, err := a.goFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var tasks []game.TaskComment
+	for _, path := range files {
+		f, err := a.cache.Parse(path)
+		if err != nil {
+			continue
+		}
+		pkg := outputPath(dir, filepath.Dir(path))
+		file := outputPath(dir, path)
+		for _, group := range f.Comments {
+			for _, t := range a.groupTasks(group) {
+				t.Package = pkg
+				t.File = file
+				t.Enclosing, t.EnclosingLines = a.enclosingDecl(f, group.Pos())
+				tasks = append(tasks, t)
+			}
+		}
+	}
+	return tasks, nil
+}
+
+// groupTasks returns the tasks of one comment group, with their marker,
+// owner, text and line
+func (a *ASTAnalyzer) groupTasks(group *ast.CommentGroup) []game.TaskComment {
+	var tasks []game.TaskComment
+	for _, c := range group.List {
+		line := a.fset.Position(c.Pos()).Line
+		text := strings.TrimPrefix(strings.TrimPrefix(c.Text, "//"), "/*")
+		text = strings.TrimSuffix(text, "*/")
+		for i, l := range strings.Split(text, "\n") {
+			l = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(l), "*"))
+			if m := taskMarker.FindStringSubmatch(l); m != nil {
+				tasks = append(tasks, game.TaskComment{Marker: m[1], Owner: m[2], Text: m[3], Line: line + i})
+			} else if len(tasks) > 0 && l != "" {
+				tasks[len(tasks)-1].Text += " " + l
+			}
+		}
+	}
+	return tasks
+}
+
+// enclosingDecl returns the name and length in lines of the function or
+// type declaration of f around pos, or "" when pos is outside them all
+func (a *ASTAnalyzer) enclosingDecl(f *ast.File, pos token.Pos) (string, int) {
+	lines := func(from, to token.Pos) int {
+		return a.fset.Position(to).Line - a.fset.Position(from).Line + 1
+	}
+	for _, decl := range f.Decls {
+		start := decl.Pos()
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			if pos < start || pos > d.End() {
+				continue
+			}
+			name := d.Name.Name
+			if d.Recv != nil && len(d.Recv.List) > 0 {
+				name = receiverTypeName(exprToString(d.Recv.List[0].Type)) + "." + name
+			}
+			return name, lines(d.Pos(), d.End())
+		case *ast.GenDecl:
+			if d.Tok != token.TYPE {
+				continue
+			}
+			if d.Doc != nil {
+				start = d.Doc.Pos()
+			}
+			if pos < start || pos > d.End() {
+				continue
+			}
+			for _, spec := range d.Specs {
+				ts := spec.(*ast.TypeSpec)
+				if len(d.Specs) == 1 || pos >= ts.Pos() && pos <= ts.End() {
+					return ts.Name.Name, lines(ts.Pos(), ts.End())
+				}
+			}
+		}
+	}
+	return "", 0
+}
+
+// runQuests runs the quests command: it writes the quests of the TODO
+// comments under the directories given in args as JSON. With -previous,
+// the quests of an earlier log that are gone are listed as completed.
+func runQuests(args []string, w io.Writer) error {
+	flags := flag.NewFlagSet("quests", flag.ExitOnError)
+	previous := flags.String("previous", "", "quest log of an earlier run, whose resolved quests are listed as completed")
+	flags.Parse(args)
+	dirs := flags.Args()
+	if len(dirs) == 0 {
+		dirs = []string{"."}
+	}
+
+	analyzer := NewASTAnalyzer()
+	var tasks []game.TaskComment
+	for _, dir := range dirs {
+		t, err := analyzer.TaskComments(dir)
+		if err != nil {
+			return err
+		}
+		tasks = append(tasks, t...)
+	}
+	log := &game.QuestLog{Quests: game.GenerateQuests(tasks), Completed: []game.Quest{}}
+
+	if *previous != "" {
+		f, err := os.Open(*previous)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		earlier, err := game.ReadQuestLog(f)
+		if err != nil {
+			return err
+		}
+		log.Completed = game.CompletedQuests(earlier.Quests, log.Quests)
+	}
+	return log.WriteJSON(w)
+}
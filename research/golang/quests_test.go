@@ -0,0 +1,54 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/study-game/research/game"
+)
+
+func TestQuestRoomsInDungeon(t *testing.T) {
+	dir := writeTree(t, map[string]string{
+		"store/store.go": "package store\n\n// TODO: close the file\nfunc Open() {}\n",
+		"store/store_test.go": "package store\n\nimport \"testing\"\n\n" +
+			"// FIXME: cover the error path\nfunc TestOpen(t *testing.T) { Open() }\n",
+	})
+	a := quietAnalyzer()
+	tasks, err := a.TaskComments(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dungeon, err := a.Dungeon(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rooms := make(map[string]bool)
+	for _, floor := range dungeon.Floors {
+		for _, room := range floor.Rooms {
+			rooms[room.ID] = true
+		}
+	}
+
+	tests := []struct {
+		file string
+		room bool
+	}{
+		{file: "store/store.go", room: true},
+		{file: "store/store_test.go"},
+	}
+	quests := game.GenerateQuests(tasks)
+	if len(quests) != len(tests) {
+		t.Fatalf("got %d quests, want %d", len(quests), len(tests))
+	}
+	for i, tt := range tests {
+		q := quests[i]
+		if q.File != tt.file {
+			t.Fatalf("quest %d is in %s, want %s", i, q.File, tt.file)
+		}
+		if tt.room && !rooms[q.Location.Room] {
+			t.Errorf("quest in %s points at room %q, which the dungeon does not have", q.File, q.Location.Room)
+		}
+		if !tt.room && q.Location.Room != "" {
+			t.Errorf("quest in %s points at room %q, want none", q.File, q.Location.Room)
+		}
+	}
+}
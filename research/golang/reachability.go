@@ -0,0 +1,215 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"strings"
+)
+
+// CategoryUnreachable marks functions no call path from main or an init
+// function leads to
+const CategoryUnreachable = "unreachable"
+
+// Reachability is the result of walking the call graph from the program's
+// entry points
+type Reachability struct {
+	// Entries are the main and init functions, in ID order
+	Entries []string
+
+	// Depth maps every reachable function to the number of calls on the
+	// shortest path from an entry point, which itself has depth 0
+	Depth map[string]int
+
+	// Unreachable lists the declared functions and methods no path
+	// reaches, in ID order
+	Unreachable []string
+}
+
+// Reachability builds the call graph of dir and finds the functions that
+// main and the init functions can reach, and how deep. Functions that
+// package-level variables are initialized with, by call or by name, are
+// reached at depth 1. Calls through function values are resolved
+// conservatively where the value can be seen: a function of the package
+// named without being called, as when passed as a callback or assigned to
+// a variable, counts as called by the function naming it. Methods called
+// through interfaces are not followed. Unreachable functions are recorded
+// as findings, and functions already extracted get their Reachable and
+// ReachDepth set. It returns ErrNoMain when dir has no main function.
+func (a *ASTAnalyzer) Reachability(dir string) (*Reachability, error) {
+	cg, err := a.BuildCallGraph(dir)
+	if err != nil {
+		return nil, err
+	}
+	refs, initRefs, err := a.functionReferences(dir, cg)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &Reachability{Depth: make(map[string]int)}
+	hasMain := false
+	r.Entries = cg.declaredIDs(func(id string) bool {
+		node := cg.Nodes[id]
+		return node.Receiver == "" && (node.Name == "main" || node.Name == "init")
+	})
+	for _, id := range r.Entries {
+		hasMain = hasMain || cg.Nodes[id].Name == "main"
+	}
+	if !hasMain {
+		return nil, ErrNoMain
+	}
+
+	frontier := append([]string(nil), r.Entries...)
+	for _, id := range frontier {
+		r.Depth[id] = 0
+	}
+	for depth := 1; len(frontier) > 0; depth++ {
+		var next []string
+		visit := func(id string) {
+			if _, seen := r.Depth[id]; seen || cg.Nodes[id] == nil || cg.Nodes[id].External {
+				return
+			}
+			r.Depth[id] = depth
+			next = append(next, id)
+		}
+		if depth == 1 {
+			for _, id := range initRefs {
+				visit(id)
+			}
+		}
+		for _, id := range frontier {
+			for _, callee := range cg.Edges[id] {
+				visit(callee)
+			}
+			for _, callee := range refs[id] {
+				visit(callee)
+			}
+		}
+		frontier = next
+	}
+
+	r.Unreachable = cg.declaredIDs(func(id string) bool {
+		_, ok := r.Depth[id]
+		return !ok
+	})
+	var findings []Finding
+	for _, id := range r.Unreachable {
+		node := cg.Nodes[id]
+		findings = append(findings, Finding{
+			Category:   CategoryUnreachable,
+			FilePath:   node.File,
+			Line:       node.Line,
+			Identifier: node.ID,
+			Message:    fmt.Sprintf("%s is not reachable from main or init", node.ID),
+			Suggestion: "remove it or call it",
+		})
+	}
+	a.addFindings(findings...)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.reachability = r
+	for path, functions := range a.functions {
+		for i := range functions {
+			fn := &functions[i]
+			id := functionNodeID(filepath.Dir(path), receiverTypeName(fn.Receiver), fn.Name)
+			fn.ReachDepth, fn.Reachable = r.Depth[id]
+		}
+	}
+	return r, nil
+}
+
+// functionReferences finds the functions of each package that are named
+// without being called: by each function, keyed by caller ID, and by the
+// initializers of package-level variables
+func (a *ASTAnalyzer) functionReferences(dir string, cg *CallGraph) (map[string][]string, []string, error) {
+	files, err := a.goFiles(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	refs := make(map[string][]string)
+	var initRefs []string
+	for _, path := range files {
+		if isTestName(path) {
+			continue
+		}
+		f, err := a.cache.Parse(path)
+		if err != nil {
+			continue
+		}
+		pkgDir := filepath.Dir(path)
+		for _, decl := range f.Decls {
+			switch d := decl.(type) {
+			case *ast.FuncDecl:
+				if d.Body == nil {
+					continue
+				}
+				receiver := ""
+				if d.Recv != nil && len(d.Recv.List) > 0 {
+					receiver = receiverTypeName(exprToString(d.Recv.List[0].Type))
+				}
+				caller := functionNodeID(pkgDir, receiver, d.Name.Name)
+				refs[caller] = append(refs[caller], namedFunctions(cg, pkgDir, d.Body, localNames(d), false)...)
+			case *ast.GenDecl:
+				initRefs = append(initRefs, namedFunctions(cg, pkgDir, d, nil, true)...)
+			}
+		}
+	}
+
+	for caller, callees := range refs {
+		refs[caller] = sortedUnique(callees)
+	}
+	return refs, sortedUnique(initRefs), nil
+}
+
+// namedFunctions returns the IDs of the package's functions that node
+// names, skipping names shadowed by locals. Called names count only when
+// calls is set.
+func namedFunctions(cg *CallGraph, pkgDir string, node ast.Node, locals map[string]bool, calls bool) []string {
+	called := make(map[*ast.Ident]bool)
+	var ids []string
+	ast.Inspect(node, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.CallExpr:
+			if ident, ok := ast.Unparen(x.Fun).(*ast.Ident); ok {
+				called[ident] = true
+			}
+		case *ast.Ident:
+			if locals[x.Name] || called[x] && !calls {
+				return true
+			}
+			id := functionNodeID(pkgDir, "", x.Name)
+			if node, ok := cg.Nodes[id]; ok && !node.External {
+				ids = append(ids, id)
+			}
+		}
+		return true
+	})
+	return ids
+}
+
+// printReachability prints how much of the program its entry points reach
+func (a *ASTAnalyzer) printReachability() {
+	r := a.reachability
+	if r == nil {
+		return
+	}
+
+	maxDepth := 0
+	for _, depth := range r.Depth {
+		maxDepth = max(maxDepth, depth)
+	}
+
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println("REACHABILITY")
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Printf("Entry points:       %s\n", strings.Join(r.Entries, ", "))
+	fmt.Printf("Reachable:          %d (max depth %d)\n", len(r.Depth), maxDepth)
+	fmt.Printf("Unreachable:        %d\n", len(r.Unreachable))
+	for _, id := range r.Unreachable {
+		fmt.Printf("  %s\n", id)
+	}
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println()
+}
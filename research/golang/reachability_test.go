@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestReachability(t *testing.T) {
+	tests := []struct {
+		name        string
+		src         string
+		depth       map[string]int // By ID relative to the fixture package
+		unreachable []string
+		wantErr     error
+	}{
+		{
+			name: "unreachable helper",
+			src: `package main
+
+func main() { run() }
+
+func run() { step() }
+
+func step() {}
+
+func helper() { step() }
+`,
+			depth:       map[string]int{"main": 0, "run": 1, "step": 2},
+			unreachable: []string{"helper"},
+		},
+		{
+			name: "init and variable initializers",
+			src: `package main
+
+var table = build()
+
+var hook = onExit
+
+func init() { setup() }
+
+func main() {}
+
+func build() int { return 0 }
+
+func onExit() {}
+
+func setup() {}
+
+func unused() {}
+`,
+			depth:       map[string]int{"init": 0, "main": 0, "build": 1, "onExit": 1, "setup": 1},
+			unreachable: []string{"unused"},
+		},
+		{
+			name: "callbacks and methods",
+			src: `package main
+
+type T struct{}
+
+func (t *T) Run() { t.step() }
+
+func (t *T) step() {}
+
+func (t *T) Orphan() {}
+
+func main() {
+	apply(visit)
+	var t T
+	t.Run()
+}
+
+func apply(fn func()) { fn() }
+
+func visit() {}
+`,
+			depth:       map[string]int{"main": 0, "apply": 1, "visit": 1, "T.Run": 1, "T.step": 2},
+			unreachable: []string{"T.Orphan"},
+		},
+		{
+			name:    "no main",
+			src:     "package lib\n\nfunc F() {}\n",
+			wantErr: ErrNoMain,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeTree(t, map[string]string{"main.go": tt.src})
+			prefix := filepath.ToSlash(dir) + "."
+			a := quietAnalyzer()
+			if _, err := a.ExtractFunctions(filepath.Join(dir, "main.go")); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := a.Reachability(dir)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Reachability() error = %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+
+			depth := make(map[string]int)
+			for id, d := range r.Depth {
+				depth[strings.TrimPrefix(id, prefix)] = d
+			}
+			if !reflect.DeepEqual(depth, tt.depth) {
+				t.Errorf("depths %v, want %v", depth, tt.depth)
+			}
+			var unreachable []string
+			for _, id := range r.Unreachable {
+				unreachable = append(unreachable, strings.TrimPrefix(id, prefix))
+			}
+			if !reflect.DeepEqual(unreachable, tt.unreachable) {
+				t.Errorf("unreachable %v, want %v", unreachable, tt.unreachable)
+			}
+
+			// Each unreachable function is a finding
+			findings := a.BuildExport().Findings
+			if len(findings) != len(tt.unreachable) {
+				t.Fatalf("%d findings, want %d", len(findings), len(tt.unreachable))
+			}
+			for i, f := range findings {
+				if f.Category != CategoryUnreachable || f.Identifier != prefix+tt.unreachable[i] {
+					t.Errorf("finding %+v", f)
+				}
+			}
+
+			// Extracted functions carry their depth
+			for _, fn := range a.functions[filepath.Join(dir, "main.go")] {
+				id := fn.Name
+				if fn.Receiver != "" {
+					id = receiverTypeName(fn.Receiver) + "." + fn.Name
+				}
+				want, reachable := tt.depth[id]
+				if fn.Reachable != reachable || fn.ReachDepth != want {
+					t.Errorf("%s: Reachable %v at depth %d, want %v at %d", id, fn.Reachable, fn.ReachDepth, reachable, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// CategoryReceiver marks methods whose receiver name differs from the rest
+// of their type's methods
+const CategoryReceiver = "receiver"
+
+// ReceiverIssue describes a type whose methods name their receiver
+// inconsistently
+type ReceiverIssue struct {
+	Type      string
+	Package   string        // Package directory
+	Names     []string      // Receiver names in use, most common first
+	Uses      []ReceiverUse // Every named receiver of the type
+	Suggested string        // The most common name
+}
+
+// ReceiverUse is one method's receiver name
+type ReceiverUse struct {
+	Name     string
+	Method   string
+	FilePath string
+	Line     int
+}
+
+// CheckReceiverConsistency reports the types under dir whose methods use
+// more than one receiver name, and records a finding for every method
+// that deviates from the most common one. Unnamed and blank receivers are
+// ignored.
+func (a *ASTAnalyzer) CheckReceiverConsistency(dir string) ([]ReceiverIssue, error) {
+	files, err := a.goFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	// Methods are grouped by package directory and receiver type
+	uses := make(map[string][]ReceiverUse)
+	for _, path := range files {
+		f, err := a.cache.Parse(path)
+		if err != nil {
+			continue
+		}
+		for _, fn := range a.extractFunctions(f) {
+			if fn.Receiver == "" || fn.ReceiverName == "" || fn.ReceiverName == "_" {
+				continue
+			}
+			key := filepath.Dir(path) + "\x00" + receiverTypeName(fn.Receiver)
+			uses[key] = append(uses[key], ReceiverUse{
+				Name:     fn.ReceiverName,
+				Method:   fn.Name,
+				FilePath: path,
+				Line:     fn.LineStart,
+			})
+		}
+	}
+
+	var issues []ReceiverIssue
+	for key, list := range uses {
+		counts := make(map[string]int)
+		for _, u := range list {
+			counts[u.Name]++
+		}
+		if len(counts) < 2 {
+			continue
+		}
+
+		names := make([]string, 0, len(counts))
+		for name := range counts {
+			names = append(names, name)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if counts[names[i]] != counts[names[j]] {
+				return counts[names[i]] > counts[names[j]]
+			}
+			return names[i] < names[j]
+		})
+
+		pkg, typeName, _ := strings.Cut(key, "\x00")
+		issues = append(issues, ReceiverIssue{
+			Type:      typeName,
+			Package:   pkg,
+			Names:     names,
+			Uses:      list,
+			Suggested: names[0],
+		})
+	}
+	sort.Slice(issues, func(i, j int) bool {
+		if issues[i].Package != issues[j].Package {
+			return issues[i].Package < issues[j].Package
+		}
+		return issues[i].Type < issues[j].Type
+	})
+
+	for _, issue := range issues {
+		for _, u := range issue.Uses {
+			if u.Name == issue.Suggested {
+				continue
+			}
+			a.addFindings(Finding{
+				Category:   CategoryReceiver,
+				FilePath:   u.FilePath,
+				Line:       u.Line,
+				Identifier: u.Name,
+				Message: fmt.Sprintf("%s.%s names its receiver %s, other methods use %s",
+					issue.Type, u.Method, u.Name, issue.Suggested),
+				Suggestion: issue.Suggested,
+			})
+		}
+	}
+
+	return issues, nil
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"slices"
+	"sort"
+)
+
+// FindRecursionCycles builds the call graph of dir and returns its
+// recursive function groups: each strongly connected component with more
+// than one function, plus every function calling itself. A group is a list
+// of call graph IDs in sorted order; groups are ordered by their first ID.
+func (a *ASTAnalyzer) FindRecursionCycles(dir string) ([][]string, error) {
+	cg, err := a.BuildCallGraph(dir)
+	if err != nil {
+		return nil, err
+	}
+	return cg.RecursionCycles(), nil
+}
+
+// RecursionCycles returns the recursive groups of the graph: the strongly
+// connected components that contain a call cycle
+func (cg *CallGraph) RecursionCycles() [][]string {
+	var cycles [][]string
+	for _, component := range cg.StronglyConnectedComponents() {
+		if len(component) == 1 && !slices.Contains(cg.Edges[component[0]], component[0]) {
+			continue
+		}
+		cycles = append(cycles, component)
+	}
+	return cycles
+}
+
+// StronglyConnectedComponents partitions the graph's nodes into strongly
+// connected components with Tarjan's algorithm. Every node is in exactly
+// one component, most of them alone. Components are sorted and ordered by
+// their first ID.
+func (cg *CallGraph) StronglyConnectedComponents() [][]string {
+	ids := make([]string, 0, len(cg.Nodes))
+	for id := range cg.Nodes {
+		ids = append(ids, id)
+	}
+	return stronglyConnectedComponents(ids, cg.Edges)
+}
+
+// stronglyConnectedComponents runs Tarjan's algorithm over the nodes ids
+// of any graph, returning sorted components ordered by their first ID
+func stronglyConnectedComponents(ids []string, edges map[string][]string) [][]string {
+	sort.Strings(ids)
+	t := tarjan{
+		edges:   edges,
+		index:   make(map[string]int),
+		lowlink: make(map[string]int),
+		onStack: make(map[string]bool),
+	}
+	for _, id := range ids {
+		if _, visited := t.index[id]; !visited {
+			t.connect(id)
+		}
+	}
+
+	for _, component := range t.components {
+		sort.Strings(component)
+	}
+	sort.Slice(t.components, func(i, j int) bool { return t.components[i][0] < t.components[j][0] })
+	return t.components
+}
+
+// tarjan holds the state of one run of Tarjan's algorithm
+type tarjan struct {
+	edges      map[string][]string
+	next       int
+	index      map[string]int
+	lowlink    map[string]int
+	stack      []string
+	onStack    map[string]bool
+	components [][]string
+}
+
+// connect visits id and emits the component it roots, if any
+func (t *tarjan) connect(id string) {
+	t.index[id] = t.next
+	t.lowlink[id] = t.next
+	t.next++
+	t.stack = append(t.stack, id)
+	t.onStack[id] = true
+
+	for _, callee := range t.edges[id] {
+		if _, visited := t.index[callee]; !visited {
+			t.connect(callee)
+			t.lowlink[id] = min(t.lowlink[id], t.lowlink[callee])
+		} else if t.onStack[callee] {
+			t.lowlink[id] = min(t.lowlink[id], t.index[callee])
+		}
+	}
+
+	if t.lowlink[id] != t.index[id] {
+		return
+	}
+	var component []string
+	for {
+		top := t.stack[len(t.stack)-1]
+		t.stack = t.stack[:len(t.stack)-1]
+		t.onStack[top] = false
+		component = append(component, top)
+		if top == id {
+			break
+		}
+	}
+	t.components = append(t.components, component)
+}
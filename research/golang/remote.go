@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// ErrNoGit is returned when cloning a remote repository without a git
+// binary on the PATH
+var ErrNoGit = errors.New("git not found in PATH")
+
+// remotePrefixes start the arguments treated as repository URLs
+var remotePrefixes = []string{"https://", "http://", "ssh://", "git://", "git@"}
+
+// RemoteCheckout is a shallow clone of a remote repository
+type RemoteCheckout struct {
+	URL    string
+	Ref    string // As requested; empty for the default branch
+	Commit string // Resolved commit SHA
+	Dir    string // Temporary directory holding the clone
+}
+
+// isRemoteURL reports whether a command line argument names a remote
+// repository rather than a local directory
+func isRemoteURL(arg string) bool {
+	for _, prefix := range remotePrefixes {
+		if strings.HasPrefix(arg, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// CloneRemote shallow-clones url at ref, a branch, tag or commit, into a
+// new temporary directory. An empty ref clones the default branch. The
+// clone is fetched with depth 1, so any ref the server allows fetching
+// works, commits included. Callers remove the clone when done.
+func CloneRemote(ctx context.Context, url, ref string) (*RemoteCheckout, error) {
+	git, err := exec.LookPath("git")
+	if err != nil {
+		return nil, ErrNoGit
+	}
+	dir, err := os.MkdirTemp("", "ast-benchmark-clone-")
+	if err != nil {
+		return nil, err
+	}
+
+	fetchRef := ref
+	if fetchRef == "" {
+		fetchRef = "HEAD"
+	}
+	steps := [][]string{
+		{"init", "--quiet"},
+		{"remote", "add", "origin", url},
+		{"fetch", "--quiet", "--depth", "1", "origin", fetchRef},
+		{"checkout", "--quiet", "FETCH_HEAD"},
+	}
+	for _, args := range steps {
+		if _, err := runGit(ctx, git, dir, args...); err != nil {
+			os.RemoveAll(dir)
+			return nil, fmt.Errorf("cloning %s: %w", url, err)
+		}
+	}
+
+	commit, err := runGit(ctx, git, dir, "rev-parse", "HEAD")
+	if err != nil {
+		os.RemoveAll(dir)
+		return nil, fmt.Errorf("cloning %s: %w", url, err)
+	}
+	return &RemoteCheckout{URL: url, Ref: ref, Commit: commit, Dir: dir}, nil
+}
+
+// runGit runs git in dir and returns its trimmed output. Prompts are
+// disabled, so a repository needing credentials fails instead of hanging;
+// errors carry git's own message.
+func runGit(ctx context.Context, git, dir string, args ...string) (string, error) {
+	cmd := exec.CommandContext(ctx, git, args...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return "", fmt.Errorf("git %s: %s", args[0], message)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// Remove deletes the clone
+func (c *RemoteCheckout) Remove() error {
+	return os.RemoveAll(c.Dir)
+}
+
+// BenchmarkRemote shallow-clones the repository at url and ref and
+// benchmarks it like a local directory. The clone is removed afterwards
+// unless KeepClone is set. The resolved commit is recorded in the run
+// metadata.
+func (a *ASTAnalyzer) BenchmarkRemote(url, ref string) error {
+	checkout, err := CloneRemote(context.Background(), url, ref)
+	if err != nil {
+		return err
+	}
+	if !a.KeepClone {
+		defer checkout.Remove()
+	}
+
+	a.RecordRemote(checkout)
+	_, err = a.BenchmarkDirectory(checkout.Dir)
+	return err
+}
+
+// RecordRemote adds a cloned repository to the metadata of the next run,
+// for callers that clone with CloneRemote and benchmark the clone
+// themselves. Each run reports only the clones recorded before it started.
+func (a *ASTAnalyzer) RecordRemote(c *RemoteCheckout) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.nextRemotes = append(a.nextRemotes, *c)
+}
+
+// remoteSummary is remoteMetadata for callers not holding a.mu
+func (a *ASTAnalyzer) remoteSummary() (repository, commit string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return a.remoteMetadata()
+}
+
+// remoteMetadata returns the URLs and commits of the recorded clones,
+// comma-separated like the directories of a multi-root run. Callers hold
+// a.mu.
+func (a *ASTAnalyzer) remoteMetadata() (repository, commit string) {
+	urls := make([]string, len(a.remotes))
+	commits := make([]string, len(a.remotes))
+	for i, c := range a.remotes {
+		urls[i] = c.URL
+		commits[i] = c.Commit
+	}
+	return strings.Join(urls, ","), strings.Join(commits, ",")
+}
@@ -0,0 +1,100 @@
+package main
+
+import (
+	"bytes"
+	"go/parser"
+	"sort"
+	"strings"
+)
+
+// ReparseRange updates the functions recorded for filePath after an edit
+// of lines startLine to endLine, counted in the new src, and returns the
+// file's functions. Only the functions the edit touches are re-extracted:
+// the edited lines are widened to the boundaries of the functions, doc
+// comments included, that the previous extraction found there, and just
+// that span is parsed. Functions above it are kept and those below it are
+// shifted by the change in line count. It is meant for live typing and
+// not exact: when the span does not parse on its own, or nothing was
+// extracted from the file before, the whole of src is parsed instead.
+// Resolved types are kept only by a full parse.
+func (a *ASTAnalyzer) ReparseRange(filePath string, src []byte, startLine, endLine int) ([]FunctionInfo, error) {
+	a.mu.Lock()
+	old, ok := a.functions[filePath]
+	oldLines, counted := a.lineCounts[filePath]
+	a.mu.Unlock()
+	if !ok || !counted {
+		return a.ExtractFunctionsFromSource(filePath, src)
+	}
+
+	lines := bytes.SplitAfter(src, []byte("\n"))
+	if len(lines) > 0 && len(lines[len(lines)-1]) == 0 {
+		lines = lines[:len(lines)-1]
+	}
+	delta := len(lines) - oldLines
+
+	// Widen the edit, in new line numbers, to the functions it overlaps in
+	// the old ones: the edit covered old lines startLine to endLine-delta
+	start, end := startLine, endLine
+	oldEnd := max(endLine-delta, startLine-1)
+	var before, after []FunctionInfo
+	for _, fn := range old {
+		first := fn.LineStart - docLines(fn)
+		switch {
+		case fn.LineEnd < startLine:
+			before = append(before, fn)
+		case first > oldEnd:
+			fn.LineStart += delta
+			fn.LineEnd += delta
+			after = append(after, fn)
+		default:
+			start = min(start, first)
+			end = max(end, fn.LineEnd+delta)
+		}
+	}
+	start = max(start, 1)
+	end = min(end, len(lines))
+	if start > end {
+		return a.replaceFunctions(filePath, len(lines), before, nil, after), nil
+	}
+
+	// The span is parsed after a package clause and blank lines, so that
+	// its positions are those of the whole file
+	var fragment bytes.Buffer
+	if start > 1 {
+		fragment.WriteString("package p")
+		fragment.WriteString(strings.Repeat("\n", start-1))
+	}
+	for _, line := range lines[start-1 : end] {
+		fragment.Write(line)
+	}
+	f, err := parser.ParseFile(a.fset, filePath, fragment.Bytes(), parser.ParseComments)
+	if err != nil {
+		return a.ExtractFunctionsFromSource(filePath, src)
+	}
+	return a.replaceFunctions(filePath, len(lines), before, a.functionInfos(f), after), nil
+}
+
+// replaceFunctions records the functions of a file from the parts kept
+// and re-extracted by ReparseRange, in line order
+func (a *ASTAnalyzer) replaceFunctions(filePath string, lineCount int, parts ...[]FunctionInfo) []FunctionInfo {
+	var functions []FunctionInfo
+	for _, part := range parts {
+		functions = append(functions, part...)
+	}
+	sort.SliceStable(functions, func(i, j int) bool { return functions[i].LineStart < functions[j].LineStart })
+
+	a.mu.Lock()
+	a.functions[filePath] = functions
+	a.lineCounts[filePath] = lineCount
+	a.mu.Unlock()
+	return functions
+}
+
+// docLines returns how many lines a function's doc comment spans, as far
+// as its text tells
+func docLines(fn FunctionInfo) int {
+	if fn.DocComment == "" {
+		return 0
+	}
+	return strings.Count(strings.TrimSuffix(fn.DocComment, "\n"), "\n") + 1
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"bufio"
+	"encoding/gob"
+	"errors"
+	"fmt"
+	"go/token"
+	"io"
+	"os"
+	"time"
+)
+
+// resultCacheMagic opens every result cache file
+const resultCacheMagic = "ast-benchmark-cache"
+
+// resultCacheVersion is bumped whenever the encoded types change. Caches
+// written with another version are rejected rather than decoded.
+const resultCacheVersion = 7
+
+// ErrCacheVersion is returned by LoadCache for caches written by an
+// incompatible version of the analyzer
+var ErrCacheVersion = errors.New("incompatible cache version")
+
+// ErrNotCache is returned by LoadCache when the file is not a result cache
+// at all. Callers should not overwrite such a file with SaveCache.
+var ErrNotCache = errors.New("not a result cache")
+
+// resultCacheHeader is encoded ahead of the entries so that the version
+// can be checked before anything else is decoded
+type resultCacheHeader struct {
+	Magic   string
+	Version int
+	Mode    ParseMode // Parse times depend on the mode they were taken in
+}
+
+// cachedResult is the persisted form of one file's ParseResult and
+// extracted functions, shared by the gob cache and SaveResults. ParseResult
+// itself is not encoded because its Error and AST fields cannot be.
+type cachedResult struct {
+	Hash          string        `json:"hash"`
+	ParseTime     time.Duration `json:"parse_time_ns"`
+	NumFunctions  int           `json:"num_functions"`
+	NumMethods    int           `json:"num_methods"`
+	NumInterfaces int           `json:"num_interfaces"`
+	NumStructs    int           `json:"num_structs"`
+	Generated     bool          `json:"generated"`
+	Success       bool          `json:"success"`
+	ErrorMessage  string        `json:"error,omitempty"`
+	FileSizeBytes int64         `json:"size_bytes"`
+	LineCount     int           `json:"line_count"`
+	NodeCount     int           `json:"node_count"`
+	UsesCgo       bool          `json:"uses_cgo,omitempty"`
+	CgoCalls      int           `json:"cgo_calls,omitempty"`
+
+	MapifiableSwitches []token.Position `json:"mapifiable_switches,omitempty"`
+	StringConcatInLoop []token.Position `json:"string_concat_in_loop,omitempty"`
+	PanicSites         []token.Position `json:"panic_sites,omitempty"`
+	RecoverSites       []token.Position `json:"recover_sites,omitempty"`
+	UnrecoveredPanics  []string         `json:"unrecovered_panics,omitempty"`
+	InitFuncs          []token.Position `json:"init_funcs,omitempty"`
+
+	Functions []FunctionInfo `json:"functions,omitempty"`
+}
+
+// SaveCache writes the results and extracted functions of the current run
+// to path, keyed by file path and content hash. Hashes are taken when the
+// cache is saved, so save right after the run. The file is replaced
+// atomically, so an interrupted save never leaves a truncated cache.
+func (a *ASTAnalyzer) SaveCache(path string) error {
+	entries := make(map[string]cachedResult, len(a.results))
+	for _, r := range a.results {
+		if entry, ok := a.cacheEntry(r); ok {
+			entries[r.FilePath] = entry
+		}
+	}
+
+	tmp := path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := gob.NewEncoder(w)
+	err = enc.Encode(resultCacheHeader{Magic: resultCacheMagic, Version: resultCacheVersion, Mode: a.Mode})
+	if err == nil {
+		err = enc.Encode(entries)
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// cacheEntry returns the persisted form of r, hashing its file now.
+// Skipped files and files that can no longer be read have none.
+func (a *ASTAnalyzer) cacheEntry(r ParseResult) (cachedResult, bool) {
+	// Skipped files depend on the limits of the run, not the content
+	if r.SkippedOversize {
+		return cachedResult{}, false
+	}
+	hash := contentHash(r.FilePath)
+	if hash == "" {
+		return cachedResult{}, false
+	}
+	return cachedResult{
+		Hash:          hash,
+		ParseTime:     r.ParseTime,
+		NumFunctions:  r.NumFunctions,
+		NumMethods:    r.NumMethods,
+		NumInterfaces: r.NumInterfaces,
+		NumStructs:    r.NumStructs,
+		Generated:     r.Generated,
+		Success:       r.Success,
+		ErrorMessage:  r.ErrorMessage,
+		FileSizeBytes: r.FileSizeBytes,
+		LineCount:     r.LineCount,
+		NodeCount:     r.NodeCount,
+		UsesCgo:       r.UsesCgo,
+		CgoCalls:      r.CgoCalls,
+
+		MapifiableSwitches: r.MapifiableSwitches,
+		StringConcatInLoop: r.StringConcatInLoop,
+		PanicSites:         r.PanicSites,
+		RecoverSites:       r.RecoverSites,
+		UnrecoveredPanics:  r.UnrecoveredPanics,
+		InitFuncs:          r.InitFuncs,
+
+		Functions: a.functions[r.FilePath],
+	}, true
+}
+
+// LoadCache reads a cache written by SaveCache. Files whose content hash
+// still matches are then taken from the cache instead of being parsed. A
+// missing cache is not an error. A cache that is corrupt, truncated or of
+// another version is rejected with an error and leaves the analyzer
+// without a cache, so callers can warn and carry on with a full parse.
+func (a *ASTAnalyzer) LoadCache(path string) error {
+	a.resultCache = nil
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	dec := gob.NewDecoder(bufio.NewReader(f))
+	var header resultCacheHeader
+	err = dec.Decode(&header)
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+		return fmt.Errorf("reading cache header: %w", err)
+	}
+	if err != nil || header.Magic != resultCacheMagic {
+		return fmt.Errorf("%s: %w", path, ErrNotCache)
+	}
+	if header.Version != resultCacheVersion {
+		return fmt.Errorf("%w: got %d, want %d", ErrCacheVersion, header.Version, resultCacheVersion)
+	}
+	if header.Mode != a.Mode {
+		return fmt.Errorf("cache was written in %s mode, running in %s mode", header.Mode, a.Mode)
+	}
+
+	var entries map[string]cachedResult
+	if err := dec.Decode(&entries); err != nil {
+		return fmt.Errorf("reading cache entries: %w", err)
+	}
+	a.resultCache = entries
+	return nil
+}
+
+// cachedParse returns the cached result for filePath when its content is
+// unchanged, restoring its extracted functions
+func (a *ASTAnalyzer) cachedParse(filePath string) (ParseResult, bool) {
+	entry, ok := a.resultCache[filePath]
+	if !ok || entry.Hash != contentHash(filePath) {
+		return ParseResult{}, false
+	}
+
+	result := ParseResult{
+		FilePath:      filePath,
+		ParseTime:     entry.ParseTime,
+		NumFunctions:  entry.NumFunctions,
+		NumMethods:    entry.NumMethods,
+		NumInterfaces: entry.NumInterfaces,
+		NumStructs:    entry.NumStructs,
+		Generated:     entry.Generated,
+		IsTest:        isTestName(filePath),
+		Success:       entry.Success,
+		ErrorMessage:  entry.ErrorMessage,
+		FileSizeBytes: entry.FileSizeBytes,
+		LineCount:     entry.LineCount,
+		NodeCount:     entry.NodeCount,
+		UsesCgo:       entry.UsesCgo,
+		CgoCalls:      entry.CgoCalls,
+		FromCache:     true,
+
+		MapifiableSwitches: entry.MapifiableSwitches,
+		StringConcatInLoop: entry.StringConcatInLoop,
+		PanicSites:         entry.PanicSites,
+		RecoverSites:       entry.RecoverSites,
+		UnrecoveredPanics:  entry.UnrecoveredPanics,
+		InitFuncs:          entry.InitFuncs,
+	}
+	if !entry.Success {
+		result.Error = errors.New(entry.ErrorMessage)
+	}
+	if entry.Functions != nil {
+		a.mu.Lock()
+		a.functions[filePath] = entry.Functions
+		a.mu.Unlock()
+	}
+	return result, true
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// BenchmarkDirectories benchmarks several roots in one run. Each result is
+// tagged with its root so PrintSummary can break the totals down per root.
+func (a *ASTAnalyzer) BenchmarkDirectories(dirs []string) error {
+	return a.BenchmarkDirectoriesContext(context.Background(), dirs)
+}
+
+// BenchmarkDirectoriesContext is BenchmarkDirectories with cancellation.
+// Like BenchmarkDirectoryContext, it keeps the partial results when ctx
+// is done and returns an error wrapping ctx.Err().
+func (a *ASTAnalyzer) BenchmarkDirectoriesContext(ctx context.Context, dirs []string) error {
+	start := time.Now()
+	a.newRun()
+	var err error
+	for _, dir := range dirs {
+		_, err = a.benchmarkRoot(dir, func(fn func(ParseResult) error) error {
+			return a.StreamDirectory(ctx, dir, fn)
+		})
+		if err != nil {
+			err = fmt.Errorf("%s: %w", dir, err)
+			break
+		}
+	}
+
+	a.mu.Lock()
+	a.startTime = start
+	if len(dirs) > 1 {
+		a.rootDir = strings.Join(dirs, ",")
+	}
+	a.mu.Unlock()
+	return err
+}
+
+// rootSummary aggregates the results found under one root
+type rootSummary struct {
+	root      string
+	files     int
+	failed    int
+	functions int
+	methods   int
+	parseTime time.Duration
+}
+
+// printRootSummary prints a per-root table when results span several roots
+func printRootSummary(results []ParseResult) {
+	var roots []*rootSummary
+	byRoot := make(map[string]*rootSummary)
+	for _, r := range results {
+		s, ok := byRoot[r.Root]
+		if !ok {
+			s = &rootSummary{root: r.Root}
+			byRoot[r.Root] = s
+			roots = append(roots, s)
+		}
+		s.files++
+		if r.SkippedOversize {
+			continue
+		}
+		if !r.Success {
+			s.failed++
+			continue
+		}
+		s.functions += r.NumFunctions
+		s.methods += r.NumMethods
+		s.parseTime += r.ParseTime
+	}
+
+	if len(roots) < 2 {
+		return
+	}
+
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println("PER ROOT")
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Printf("%-30s %6s %6s %6s %7s %10s\n", "Root", "Files", "Failed", "Funcs", "Methods", "Time")
+	for _, s := range roots {
+		fmt.Printf("%-30s %6d %6d %6d %7d %8.2fms\n",
+			s.root,
+			s.files,
+			s.failed,
+			s.functions,
+			s.methods,
+			float64(s.parseTime.Microseconds())/1000.0)
+	}
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println()
+}
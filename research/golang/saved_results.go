@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// savedResultsVersion is bumped whenever the SaveResults format changes
+const savedResultsVersion = 4
+
+// savedResults is the JSON document written by SaveResults
+type savedResults struct {
+	Version   int           `json:"version"`
+	ParseMode string        `json:"parse_mode"`
+	SavedAt   time.Time     `json:"saved_at"`
+	Results   []savedResult `json:"results"`
+}
+
+// savedResult is one file's entry in a savedResults document
+type savedResult struct {
+	Path string `json:"path"`
+	cachedResult
+}
+
+// SaveResults writes the results accumulated so far to path as JSON, each
+// with the content hash of its file. Unlike SaveCache, the output is
+// meant to be read by people and other tools too. Saving after an
+// interrupted run and loading with LoadResults resumes the session. The
+// file is replaced atomically.
+func (a *ASTAnalyzer) SaveResults(path string) error {
+	a.mu.Lock()
+	doc := savedResults{
+		Version:   savedResultsVersion,
+		ParseMode: a.Mode.String(),
+		SavedAt:   time.Now(),
+		Results:   make([]savedResult, 0, len(a.results)),
+	}
+	for _, r := range a.results {
+		if entry, ok := a.cacheEntry(r); ok {
+			doc.Results = append(doc.Results, savedResult{Path: r.FilePath, cachedResult: entry})
+		}
+	}
+	a.mu.Unlock()
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0o644); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// LoadResults reads results written by SaveResults. The next benchmark
+// takes every file whose content hash still matches from them instead of
+// parsing it again, so a run resumes where the saved one stopped. Loaded
+// results add to a cache from LoadCache rather than replacing it. A
+// missing file is not an error.
+func (a *ASTAnalyzer) LoadResults(path string) error {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var doc savedResults
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	if doc.Version != savedResultsVersion {
+		return fmt.Errorf("%w: got %d, want %d", ErrCacheVersion, doc.Version, savedResultsVersion)
+	}
+	if doc.ParseMode != a.Mode.String() {
+		return fmt.Errorf("results were saved in %s mode, running in %s mode", doc.ParseMode, a.Mode)
+	}
+
+	if a.resultCache == nil {
+		a.resultCache = make(map[string]cachedResult, len(doc.Results))
+	}
+	for _, r := range doc.Results {
+		a.resultCache[r.Path] = r.cachedResult
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// maxUploadBytes caps the body of a /summary request holding an archive
+const maxUploadBytes = 64 << 20
+
+// zipMagic opens every zip archive
+const zipMagic = "PK\x03\x04"
+
+// APIHandler serves the analyzer over HTTP for tools such as a web UI:
+//
+//	POST /analyze  body: Go source, ?name= the file name (default input.go);
+//	               returns the file's functions as the JSON export writes them
+//	POST /summary  body: a directory path on the server, or a zip archive;
+//	               returns the JSON export of benchmarking it
+//
+// Every request is served by an analyzer of its own, so requests may run
+// concurrently. Errors are returned as {"error": "..."}. /summary reads
+// any directory the server can, so the handler is meant for local use.
+func APIHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/analyze", postOnly(serveAnalyze))
+	mux.HandleFunc("/summary", postOnly(serveSummary))
+	return mux
+}
+
+// StartServer serves APIHandler on addr until the server fails
+func StartServer(addr string) error {
+	fmt.Fprintf(os.Stderr, "Serving the analyzer on http://%s (POST /analyze, POST /summary)\n", addr)
+	return http.ListenAndServe(addr, APIHandler())
+}
+
+// postOnly rejects requests to h that are not POSTs
+func postOnly(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			w.Header().Set("Allow", http.MethodPost)
+			writeJSONError(w, http.StatusMethodNotAllowed, errors.New("use POST"))
+			return
+		}
+		h(w, r)
+	}
+}
+
+// serveAnalyze parses the Go source in the request body and returns its
+// functions
+func serveAnalyze(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("name")
+	if name == "" {
+		name = "input.go"
+	}
+	src, err := io.ReadAll(http.MaxBytesReader(w, r.Body, DefaultMaxFileSize))
+	if err != nil {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+
+	analyzer := newServerAnalyzer()
+	result := analyzer.ParseSource(name, src)
+	if !result.Success {
+		writeJSONError(w, http.StatusBadRequest, result.Error)
+		return
+	}
+	functions, err := analyzer.ExtractFunctionsFromSource(name, src)
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	exported := make([]ExportFunction, 0, len(functions))
+	for _, fn := range functions {
+		exported = append(exported, newExportFunction(name, fn))
+	}
+	writeJSON(w, exported)
+}
+
+// serveSummary benchmarks the directory named by the request body, or the
+// zip archive it holds, and returns the JSON export
+func serveSummary(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxUploadBytes))
+	if err != nil {
+		writeJSONError(w, http.StatusRequestEntityTooLarge, err)
+		return
+	}
+
+	analyzer := newServerAnalyzer()
+	if bytes.HasPrefix(body, []byte(zipMagic)) {
+		err = analyzer.BenchmarkZip(bytes.NewReader(body), int64(len(body)))
+	} else {
+		dir := strings.TrimSpace(string(body))
+		if dir == "" {
+			writeJSONError(w, http.StatusBadRequest, errors.New("body must be a directory path or a zip archive"))
+			return
+		}
+		// Functions are extracted from files on disk, so only for a path
+		if _, err = analyzer.BenchmarkDirectory(dir); err == nil {
+			err = extractAllFunctions(analyzer)
+		}
+	}
+	if err != nil {
+		writeJSONError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	analyzer.ExportJSON(w)
+}
+
+// newServerAnalyzer creates the analyzer of one request, which reports no
+// progress
+func newServerAnalyzer() *ASTAnalyzer {
+	analyzer := NewASTAnalyzer()
+	analyzer.Quiet = true
+	analyzer.Progress = io.Discard
+	return analyzer
+}
+
+// writeJSON writes v as the JSON response
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeJSONError writes err as a JSON error response with status
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]string{"error": err.Error()})
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// ShadowIssue describes a := declaration that shadows an outer variable
+type ShadowIssue struct {
+	Name     string
+	Function string
+	Inner    token.Position // The shadowing declaration
+	Outer    token.Position // The declaration being shadowed
+}
+
+// FindShadowedVariables reports := declarations inside functions that
+// re-declare a name from an enclosing block that is still in scope
+func (a *ASTAnalyzer) FindShadowedVariables(filePath string) ([]ShadowIssue, error) {
+	f, err := a.cache.Parse(filePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var issues []ShadowIssue
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+
+		w := &shadowWalker{fset: a.fset, function: fn.Name.Name}
+		w.walkFunc(fn.Recv, fn.Type, fn.Body)
+		issues = append(issues, w.issues...)
+	}
+
+	return issues, nil
+}
+
+// shadowWalker tracks the block scopes of a single function
+type shadowWalker struct {
+	fset     *token.FileSet
+	function string
+	scopes   []map[string]token.Pos
+	issues   []ShadowIssue
+}
+
+func (w *shadowWalker) push() {
+	w.scopes = append(w.scopes, make(map[string]token.Pos))
+}
+
+func (w *shadowWalker) pop() {
+	w.scopes = w.scopes[:len(w.scopes)-1]
+}
+
+// declare adds a name to the innermost scope without checking for shadowing
+func (w *shadowWalker) declare(ident *ast.Ident) {
+	if ident.Name == "_" {
+		return
+	}
+	w.scopes[len(w.scopes)-1][ident.Name] = ident.Pos()
+}
+
+// define handles a name introduced by :=, reporting it when it shadows a
+// name from an enclosing scope
+func (w *shadowWalker) define(ident *ast.Ident) {
+	if ident.Name == "_" {
+		return
+	}
+
+	// Redefinition in the same scope is an assignment, not a declaration
+	current := w.scopes[len(w.scopes)-1]
+	if _, ok := current[ident.Name]; ok {
+		return
+	}
+
+	for i := len(w.scopes) - 2; i >= 0; i-- {
+		if outer, ok := w.scopes[i][ident.Name]; ok {
+			w.issues = append(w.issues, ShadowIssue{
+				Name:     ident.Name,
+				Function: w.function,
+				Inner:    w.fset.Position(ident.Pos()),
+				Outer:    w.fset.Position(outer),
+			})
+			break
+		}
+	}
+
+	current[ident.Name] = ident.Pos()
+}
+
+// walkFunc walks a function or function literal. Parameters share the
+// scope of the outermost block of the body.
+func (w *shadowWalker) walkFunc(recv *ast.FieldList, typ *ast.FuncType, body *ast.BlockStmt) {
+	w.push()
+	defer w.pop()
+
+	for _, list := range []*ast.FieldList{recv, typ.Params, typ.Results} {
+		if list == nil {
+			continue
+		}
+		for _, field := range list.List {
+			for _, name := range field.Names {
+				w.declare(name)
+			}
+		}
+	}
+
+	for _, stmt := range body.List {
+		w.walkStmt(stmt)
+	}
+}
+
+// walkExpr looks for function literals, which open their own scopes
+func (w *shadowWalker) walkExpr(node ast.Node) {
+	if node == nil {
+		return
+	}
+	ast.Inspect(node, func(n ast.Node) bool {
+		if lit, ok := n.(*ast.FuncLit); ok {
+			w.walkFunc(nil, lit.Type, lit.Body)
+			return false
+		}
+		return true
+	})
+}
+
+func (w *shadowWalker) walkStmt(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case nil:
+		return
+
+	case *ast.BlockStmt:
+		w.push()
+		for _, inner := range s.List {
+			w.walkStmt(inner)
+		}
+		w.pop()
+
+	case *ast.AssignStmt:
+		for _, rhs := range s.Rhs {
+			w.walkExpr(rhs)
+		}
+		if s.Tok != token.DEFINE {
+			for _, lhs := range s.Lhs {
+				w.walkExpr(lhs)
+			}
+			return
+		}
+		for _, lhs := range s.Lhs {
+			if ident, ok := lhs.(*ast.Ident); ok {
+				w.define(ident)
+			}
+		}
+
+	case *ast.DeclStmt:
+		gen, ok := s.Decl.(*ast.GenDecl)
+		if !ok {
+			return
+		}
+		for _, spec := range gen.Specs {
+			switch sp := spec.(type) {
+			case *ast.ValueSpec:
+				for _, value := range sp.Values {
+					w.walkExpr(value)
+				}
+				for _, name := range sp.Names {
+					w.declare(name)
+				}
+			case *ast.TypeSpec:
+				w.declare(sp.Name)
+			}
+		}
+
+	case *ast.IfStmt:
+		w.push()
+		w.walkStmt(s.Init)
+		w.walkExpr(s.Cond)
+		w.walkStmt(s.Body)
+		w.walkStmt(s.Else)
+		w.pop()
+
+	case *ast.ForStmt:
+		w.push()
+		w.walkStmt(s.Init)
+		w.walkExpr(s.Cond)
+		w.walkStmt(s.Post)
+		w.walkStmt(s.Body)
+		w.pop()
+
+	case *ast.RangeStmt:
+		w.walkExpr(s.X)
+		w.push()
+		if s.Tok == token.DEFINE {
+			for _, expr := range []ast.Expr{s.Key, s.Value} {
+				if ident, ok := expr.(*ast.Ident); ok {
+					w.define(ident)
+				}
+			}
+		}
+		w.walkStmt(s.Body)
+		w.pop()
+
+	case *ast.SwitchStmt:
+		w.push()
+		w.walkStmt(s.Init)
+		w.walkExpr(s.Tag)
+		for _, clause := range s.Body.List {
+			cc := clause.(*ast.CaseClause)
+			w.push()
+			for _, expr := range cc.List {
+				w.walkExpr(expr)
+			}
+			for _, inner := range cc.Body {
+				w.walkStmt(inner)
+			}
+			w.pop()
+		}
+		w.pop()
+
+	case *ast.TypeSwitchStmt:
+		w.push()
+		w.walkStmt(s.Init)
+
+		// The symbol in "v := x.(type)" is declared in every clause, but it
+		// is checked once, at the position it is written
+		var symbol *ast.Ident
+		w.push()
+		if assign, ok := s.Assign.(*ast.AssignStmt); ok && len(assign.Lhs) == 1 {
+			w.walkExpr(assign.Rhs[0])
+			if symbol, _ = assign.Lhs[0].(*ast.Ident); symbol != nil {
+				w.define(symbol)
+			}
+		} else {
+			w.walkStmt(s.Assign)
+		}
+
+		for _, clause := range s.Body.List {
+			cc := clause.(*ast.CaseClause)
+			w.push()
+			if symbol != nil {
+				w.declare(symbol)
+			}
+			for _, inner := range cc.Body {
+				w.walkStmt(inner)
+			}
+			w.pop()
+		}
+		w.pop()
+		w.pop()
+
+	case *ast.SelectStmt:
+		for _, clause := range s.Body.List {
+			cc := clause.(*ast.CommClause)
+			w.push()
+			w.walkStmt(cc.Comm)
+			for _, inner := range cc.Body {
+				w.walkStmt(inner)
+			}
+			w.pop()
+		}
+
+	case *ast.LabeledStmt:
+		w.walkStmt(s.Stmt)
+
+	default:
+		w.walkExpr(s)
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestFindShadowedVariables(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string // name:inner line:outer line
+	}{
+		{
+			name: "type switch symbol reported once",
+			src: `package main
+
+func f(x any) {
+	v := 0
+	switch v := x.(type) {
+	case int:
+		_ = v
+	case string:
+		_ = v
+	default:
+		_ = v
+	}
+	_ = v
+}
+`,
+			want: []string{"v:5:4"},
+		},
+		{
+			name: "type switch symbol without outer name",
+			src: `package main
+
+func f(x any) {
+	switch v := x.(type) {
+	case int:
+		_ = v
+	case string:
+		_ = v
+	}
+}
+`,
+		},
+		{
+			name: "shadowing inside a type switch clause",
+			src: `package main
+
+func f(x any, n int) {
+	switch v := x.(type) {
+	case int:
+		if n := v; n > 0 {
+		}
+	case string:
+		_ = v
+	}
+}
+`,
+			want: []string{"n:6:3"},
+		},
+		{
+			name: "blocks and range",
+			src: `package main
+
+func f(items []int) {
+	err := error(nil)
+	for _, item := range items {
+		err := item
+		_ = err
+	}
+	{
+		items := 1
+		_ = items
+	}
+	_ = err
+}
+`,
+			want: []string{"err:6:4", "items:10:3"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeTree(t, map[string]string{"main.go": tt.src})
+			issues, err := quietAnalyzer().FindShadowedVariables(filepath.Join(dir, "main.go"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, issue := range issues {
+				got = append(got, fmt.Sprintf("%s:%d:%d", issue.Name, issue.Inner.Line, issue.Outer.Line))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("issues = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import "strings"
+
+// Signature returns the function's declaration as one canonical line, e.g.
+// "func (c *Calculator) Add(a int, b int) int". Every parameter is written
+// with its own type and unnamed parameters are written as the type alone.
+func (fn FunctionInfo) Signature() string {
+	var b strings.Builder
+	b.WriteString("func ")
+	if fn.Receiver != "" {
+		b.WriteString("(")
+		if fn.ReceiverName != "" {
+			b.WriteString(fn.ReceiverName + " ")
+		}
+		b.WriteString(fn.Receiver + ") ")
+	}
+	b.WriteString(fn.Name)
+
+	if len(fn.TypeParams) > 0 {
+		b.WriteString("[" + joinParams(fn.TypeParams) + "]")
+	}
+	b.WriteString("(" + joinParams(fn.Params) + ")")
+
+	switch len(fn.Results) {
+	case 0:
+	case 1:
+		b.WriteString(" " + fn.Results[0])
+	default:
+		b.WriteString(" (" + strings.Join(fn.Results, ", ") + ")")
+	}
+	return b.String()
+}
+
+// joinParams renders parameters as "name type" pairs separated by ", "
+func joinParams(params []ParamInfo) string {
+	parts := make([]string, len(params))
+	for i, p := range params {
+		parts[i] = strings.TrimSpace(p.Name + " " + p.Type)
+	}
+	return strings.Join(parts, ", ")
+}
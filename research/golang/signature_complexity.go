@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CategorySignature marks functions with too many parameters or results
+const CategorySignature = "signature"
+
+// Default thresholds for CheckSignatureComplexity: eight parameters or four
+// results are worth a second look
+const (
+	DefaultMaxParams  = 7
+	DefaultMaxResults = 3
+)
+
+// CheckSignatureComplexity returns the functions under dir that take more
+// than maxParams parameters or return more than maxResults values, and
+// records a finding for each. The receiver is not counted as a parameter.
+func (a *ASTAnalyzer) CheckSignatureComplexity(dir string, maxParams, maxResults int) ([]FunctionInfo, error) {
+	files, err := a.goFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var flagged []FunctionInfo
+	for _, path := range files {
+		f, err := a.cache.Parse(path)
+		if err != nil {
+			continue
+		}
+
+		for _, fn := range a.extractFunctions(f) {
+			var problems []string
+			if len(fn.Params) > maxParams {
+				problems = append(problems, fmt.Sprintf("%d parameters (max %d)", len(fn.Params), maxParams))
+			}
+			if len(fn.Results) > maxResults {
+				problems = append(problems, fmt.Sprintf("%d results (max %d)", len(fn.Results), maxResults))
+			}
+			if len(problems) == 0 {
+				continue
+			}
+
+			flagged = append(flagged, fn)
+			a.addFindings(Finding{
+				Category:   CategorySignature,
+				FilePath:   path,
+				Line:       fn.LineStart,
+				Identifier: fn.Name,
+				Message:    fn.Name + " has " + strings.Join(problems, " and "),
+				Suggestion: "group related parameters or results into a struct",
+			})
+		}
+	}
+
+	return flagged, nil
+}
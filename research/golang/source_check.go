@@ -0,0 +1,88 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/scanner"
+	"go/token"
+	"io"
+	"unicode/utf8"
+)
+
+// sniffSize is how much of a file is read to detect binary content before
+// the rest is loaded
+const sniffSize = 8 << 10
+
+// ErrNotGoSource is wrapped by the errors of files that are rejected
+// before parsing because they cannot be Go source
+var ErrNotGoSource = errors.New("not Go source")
+
+// readGoSource reads a file for parsing. Binary files are rejected after
+// reading their first few kilobytes, so large non-Go files are not loaded
+// in full.
+func readGoSource(file sourceFile) ([]byte, error) {
+	f, err := file.fsys.Open(file.name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	head := make([]byte, sniffSize)
+	n, err := io.ReadFull(f, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	head = head[:n]
+	if bytes.IndexByte(head, 0) >= 0 {
+		return nil, fmt.Errorf("%s: %w: file contains NUL bytes", file.path, ErrNotGoSource)
+	}
+	if n < sniffSize {
+		return head, nil
+	}
+
+	rest, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	return append(head, rest...), nil
+}
+
+// checkGoSource rejects content that cannot be Go source: binary data,
+// invalid UTF-8, and files whose first token is not the package clause.
+// The parser would report these with messages about the first bad token,
+// which say little about the actual problem.
+func checkGoSource(src []byte) error {
+	if bytes.IndexByte(src, 0) >= 0 {
+		return fmt.Errorf("%w: file contains NUL bytes", ErrNotGoSource)
+	}
+	if !utf8.Valid(src) {
+		return fmt.Errorf("%w: file is not valid UTF-8", ErrNotGoSource)
+	}
+
+	// Scan just the first token, skipping comments, a byte order mark and
+	// whitespace
+	fset := token.NewFileSet()
+	var s scanner.Scanner
+	s.Init(fset.AddFile("", -1, len(src)), src, nil, 0)
+	_, tok, lit := s.Scan()
+	if tok != token.PACKAGE {
+		found := tok.String()
+		if lit != "" && tok != token.EOF {
+			found = fmt.Sprintf("%q", truncate(lit, 20))
+		}
+		return fmt.Errorf("%w: expected package clause, found %s", ErrNotGoSource, found)
+	}
+	return nil
+}
+
+// truncate shortens s to at most n bytes without splitting a rune
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n] + "…"
+}
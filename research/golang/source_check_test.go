@@ -0,0 +1,69 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestCheckGoSource(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		wantErr bool
+	}{
+		{name: "package clause", src: "package a\n"},
+		{name: "comments and byte order mark first", src: "\ufeff// Package a\n/* doc */\npackage a\n"},
+		{name: "empty", src: "", wantErr: true},
+		{name: "no package clause", src: "func A() {}\n", wantErr: true},
+		{name: "NUL bytes", src: "package a\x00", wantErr: true},
+		{name: "invalid UTF-8", src: "package a\n\xff\xfe\n", wantErr: true},
+		{name: "prose", src: "This is not Go\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkGoSource([]byte(tt.src))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("checkGoSource() = %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil && !errors.Is(err, ErrNotGoSource) {
+				t.Errorf("error %v does not wrap ErrNotGoSource", err)
+			}
+		})
+	}
+}
+
+// fuzzSeeds are the inputs the fuzz targets start from: valid source,
+// the empty file, a file without package clause and binary garbage
+var fuzzSeeds = []string{
+	"package a\n\nfunc A(x int) (int, error) { return x, nil }\n",
+	"",
+	"func A() {}\n",
+	"\xff\x00\x01",
+	"package a\n\nfunc B( {\n",
+}
+
+func FuzzParseSource(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, src []byte) {
+		result := quietAnalyzer().ParseSource("fuzz.go", src)
+		if !result.Success && result.Error == nil {
+			t.Errorf("failed parse without error")
+		}
+	})
+}
+
+func FuzzExtractFunctionsFromSource(f *testing.F) {
+	for _, seed := range fuzzSeeds {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, src []byte) {
+		functions, _ := quietAnalyzer().ExtractFunctionsFromSource("fuzz.go", src)
+		for _, fn := range functions {
+			if fn.LineStart < 1 || fn.LineEnd < fn.LineStart {
+				t.Errorf("%s has lines %d-%d", fn.Name, fn.LineStart, fn.LineEnd)
+			}
+		}
+	})
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"go/parser"
+	"sort"
+)
+
+// ExtractFunctionsFromSource extracts function metadata from src, read as
+// the content of name, and records it under name like ExtractFunctions.
+// Positions resolve through the analyzer's FileSet, so line numbers are
+// those of src. Lines are counted in src itself, since the parser returns
+// a file without positions when src has no package clause.
+func (a *ASTAnalyzer) ExtractFunctionsFromSource(name string, src []byte) ([]FunctionInfo, error) {
+	f, err := parser.ParseFile(a.fset, name, src, parser.ParseComments)
+	if f == nil {
+		return nil, err
+	}
+
+	functions := a.extractFunctions(f)
+	a.mu.Lock()
+	a.functions[name] = functions
+	a.lineCounts[name] = lineCount(src)
+	a.mu.Unlock()
+	return functions, err
+}
+
+// ParseSources parses a set of in-memory files, keyed by name, and returns
+// their results in name order with the totals of the batch. Like
+// ParseSource, it does not record the results on the analyzer.
+func (a *ASTAnalyzer) ParseSources(sources map[string][]byte) ([]ParseResult, RunSummary) {
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	results := make([]ParseResult, len(names))
+	var summary RunSummary
+	for i, name := range names {
+		results[i] = a.ParseSource(name, sources[name])
+		summary.add(results[i], a.IncludeGenerated)
+	}
+	return results, summary
+}
@@ -0,0 +1,67 @@
+package main
+
+import "testing"
+
+func TestExtractFunctionsFromSource(t *testing.T) {
+	tests := []struct {
+		name      string
+		src       string
+		functions []string
+		lines     int
+		wantErr   bool
+	}{
+		{name: "functions", src: "package a\n\nfunc A() {}\n\nfunc (T) B() {}\n", functions: []string{"A", "B"}, lines: 5},
+		{name: "empty", src: "", wantErr: true},
+		{name: "no package clause", src: "func A() {}\n", lines: 1, wantErr: true},
+		{name: "binary", src: "\xff\x00\x01", lines: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := quietAnalyzer()
+			functions, err := a.ExtractFunctionsFromSource("a.go", []byte(tt.src))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ExtractFunctionsFromSource() error = %v, want error %v", err, tt.wantErr)
+			}
+			if len(functions) != len(tt.functions) {
+				t.Fatalf("got %d functions, want %d", len(functions), len(tt.functions))
+			}
+			for i, fn := range functions {
+				if fn.Name != tt.functions[i] {
+					t.Errorf("function %d is %s, want %s", i, fn.Name, tt.functions[i])
+				}
+			}
+			if got := a.lineCounts["a.go"]; got != tt.lines {
+				t.Errorf("line count = %d, want %d", got, tt.lines)
+			}
+		})
+	}
+}
+
+func TestExtractFunctionsFromSourceLines(t *testing.T) {
+	src := "package a\n\n// A does nothing\nfunc A() {\n}\n"
+	functions, err := quietAnalyzer().ExtractFunctionsFromSource("a.go", []byte(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(functions) != 1 || functions[0].LineStart != 4 || functions[0].LineEnd != 5 {
+		t.Errorf("got %+v, want A on lines 4-5", functions)
+	}
+}
+
+func TestParseSources(t *testing.T) {
+	results, summary := quietAnalyzer().ParseSources(map[string][]byte{
+		"b.go": []byte("package a\n\nfunc B() {}\n"),
+		"a.go": []byte("package a\n\nfunc A() {}\nfunc C() {}\n"),
+		"c.go": []byte("not go"),
+	})
+	if len(results) != 3 || results[0].FilePath != "a.go" || results[1].FilePath != "b.go" {
+		t.Fatalf("results not in name order: %+v", results)
+	}
+	if summary.TotalFiles != 3 || summary.Successful != 2 || summary.Failed != 1 {
+		t.Errorf("summary = %d files, %d successful, %d failed; want 3, 2, 1",
+			summary.TotalFiles, summary.Successful, summary.Failed)
+	}
+	if summary.Functions != 3 {
+		t.Errorf("summary counts %d functions, want 3", summary.Functions)
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+)
+
+// stringConcatsInLoops locates the += statements in the bodies of for and
+// range loops of f that append to a string, which allocate a new string
+// every iteration where a strings.Builder would not. Without types, a
+// statement counts as appending to a string when its value has a string
+// literal operand, is a string conversion or fmt.Sprint call, or when its
+// target was declared in the function as a string: with a string type, a
+// string literal, or as a string parameter. Function literals inside a
+// loop are not its body.
+func stringConcatsInLoops(fset *token.FileSet, f *ast.File) []token.Position {
+	var positions []token.Position
+	for _, decl := range f.Decls {
+		fn, ok := decl.(*ast.FuncDecl)
+		if !ok || fn.Body == nil {
+			continue
+		}
+		strs := stringVars(fn)
+		var walk func(n ast.Node, inLoop bool)
+		walk = func(n ast.Node, inLoop bool) {
+			ast.Inspect(n, func(n ast.Node) bool {
+				switch x := n.(type) {
+				case *ast.ForStmt:
+					if x.Init != nil {
+						walk(x.Init, inLoop)
+					}
+					walk(x.Body, true)
+					return false
+				case *ast.RangeStmt:
+					walk(x.Body, true)
+					return false
+				case *ast.FuncLit:
+					walk(x.Body, false)
+					return false
+				case *ast.AssignStmt:
+					if inLoop && isStringConcat(x, strs) {
+						positions = append(positions, fset.Position(x.Pos()))
+					}
+				}
+				return true
+			})
+		}
+		walk(fn.Body, false)
+	}
+	return positions
+}
+
+// isStringConcat reports whether s is a += statement appending to a string
+func isStringConcat(s *ast.AssignStmt, strs map[string]bool) bool {
+	if s.Tok != token.ADD_ASSIGN || len(s.Lhs) != 1 || len(s.Rhs) != 1 {
+		return false
+	}
+	if id, ok := s.Lhs[0].(*ast.Ident); ok && strs[id.Name] {
+		return true
+	}
+	return isStringExpr(s.Rhs[0])
+}
+
+// isStringExpr reports whether expr is visibly a string: a string literal,
+// a string conversion, a call to fmt.Sprint, Sprintf or Sprintln, or a sum
+// with such an operand
+func isStringExpr(expr ast.Expr) bool {
+	switch e := expr.(type) {
+	case *ast.BasicLit:
+		return e.Kind == token.STRING
+	case *ast.ParenExpr:
+		return isStringExpr(e.X)
+	case *ast.BinaryExpr:
+		return e.Op == token.ADD && (isStringExpr(e.X) || isStringExpr(e.Y))
+	case *ast.CallExpr:
+		switch fun := e.Fun.(type) {
+		case *ast.Ident:
+			return fun.Name == "string" && len(e.Args) == 1
+		case *ast.SelectorExpr:
+			pkg, ok := fun.X.(*ast.Ident)
+			return ok && pkg.Name == "fmt" &&
+				(fun.Sel.Name == "Sprint" || fun.Sel.Name == "Sprintf" || fun.Sel.Name == "Sprintln")
+		}
+	}
+	return false
+}
+
+// stringVars returns the names declared in fn as strings: parameters and
+// variables of type string, and variables first assigned a string
+func stringVars(fn *ast.FuncDecl) map[string]bool {
+	strs := make(map[string]bool)
+	isString := func(typ ast.Expr) bool {
+		id, ok := typ.(*ast.Ident)
+		return ok && id.Name == "string"
+	}
+	for _, field := range fn.Type.Params.List {
+		if isString(field.Type) {
+			for _, name := range field.Names {
+				strs[name.Name] = true
+			}
+		}
+	}
+	ast.Inspect(fn.Body, func(n ast.Node) bool {
+		switch x := n.(type) {
+		case *ast.ValueSpec:
+			for i, name := range x.Names {
+				if isString(x.Type) || x.Type == nil && i < len(x.Values) && isStringExpr(x.Values[i]) {
+					strs[name.Name] = true
+				}
+			}
+		case *ast.AssignStmt:
+			if x.Tok != token.DEFINE || len(x.Lhs) != len(x.Rhs) {
+				break
+			}
+			for i, lhs := range x.Lhs {
+				if id, ok := lhs.(*ast.Ident); ok && isStringExpr(x.Rhs[i]) {
+					strs[id.Name] = true
+				}
+			}
+		}
+		return true
+	})
+	return strs
+}
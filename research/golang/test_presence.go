@@ -0,0 +1,242 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// PackageTestStats compares test code against non-test code in one package
+type PackageTestStats struct {
+	Package        string // Package directory
+	CodeFiles      int
+	TestFiles      int
+	CodeFunctions  int
+	TestFunctions  int // Test, Benchmark, Fuzz and Example functions
+	CodeStatements int
+	TestStatements int
+	// UntestedFiles lists non-test files none of whose declared identifiers
+	// are referenced from a test function. This is a structural heuristic,
+	// not coverage data.
+	UntestedFiles []string
+}
+
+// StatementRatio returns test statements per non-test statement
+func (s PackageTestStats) StatementRatio() float64 {
+	if s.CodeStatements == 0 {
+		return 0
+	}
+	return float64(s.TestStatements) / float64(s.CodeStatements)
+}
+
+// AnalyzeTestPresence computes per-package test-to-code statistics for all
+// Go files under dir and records them for PrintSummary and the exports,
+// next to those recorded for other directories
+func (a *ASTAnalyzer) AnalyzeTestPresence(dir string) ([]PackageTestStats, error) {
+	files, err := a.goFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	byPackage := make(map[string][]string)
+	for _, path := range files {
+		pkgDir := filepath.Dir(path)
+		byPackage[pkgDir] = append(byPackage[pkgDir], path)
+	}
+
+	pkgDirs := make([]string, 0, len(byPackage))
+	for pkgDir := range byPackage {
+		pkgDirs = append(pkgDirs, pkgDir)
+	}
+	sort.Strings(pkgDirs)
+
+	var stats []PackageTestStats
+	for _, pkgDir := range pkgDirs {
+		s, err := a.packageTestStats(pkgDir, byPackage[pkgDir])
+		if err != nil {
+			return nil, err
+		}
+		stats = append(stats, s)
+	}
+
+	a.recordTestStats(stats)
+	return stats, nil
+}
+
+// recordTestStats records stats for PrintSummary in place of any recorded
+// for the same packages, keeping those of other directories
+func (a *ASTAnalyzer) recordTestStats(stats []PackageTestStats) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	replaced := make(map[string]bool, len(stats))
+	for _, s := range stats {
+		replaced[s.Package] = true
+	}
+	kept := stats
+	for _, s := range a.testStats {
+		if !replaced[s.Package] {
+			kept = append(kept, s)
+		}
+	}
+	a.testStats = append([]PackageTestStats(nil), kept...)
+	sort.Slice(a.testStats, func(i, j int) bool { return a.testStats[i].Package < a.testStats[j].Package })
+}
+
+// packageTestStats computes the statistics for the files of one package
+func (a *ASTAnalyzer) packageTestStats(pkgDir string, paths []string) (PackageTestStats, error) {
+	stats := PackageTestStats{Package: pkgDir}
+
+	// Declared identifiers per non-test file
+	declared := make(map[string][]string)
+	var codeFiles []string
+
+	// Identifiers referenced from test functions in the package
+	referenced := make(map[string]bool)
+
+	for _, path := range paths {
+		f, err := a.cache.Parse(path)
+		if err != nil {
+			continue
+		}
+
+		if !isTestName(path) {
+			stats.CodeFiles++
+			codeFiles = append(codeFiles, path)
+			declared[path] = declaredIdentifiers(f)
+			for _, decl := range f.Decls {
+				if fn, ok := decl.(*ast.FuncDecl); ok {
+					stats.CodeFunctions++
+					stats.CodeStatements += countStatements(fn.Body)
+				}
+			}
+			continue
+		}
+
+		stats.TestFiles++
+		for _, decl := range f.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok {
+				continue
+			}
+			stats.TestStatements += countStatements(fn.Body)
+			if !isTestFunction(fn) {
+				continue
+			}
+			stats.TestFunctions++
+			ast.Inspect(fn.Body, func(n ast.Node) bool {
+				if ident, ok := n.(*ast.Ident); ok {
+					referenced[ident.Name] = true
+				}
+				return true
+			})
+		}
+	}
+
+	for _, path := range codeFiles {
+		tested := false
+		for _, name := range declared[path] {
+			if referenced[name] {
+				tested = true
+				break
+			}
+		}
+		if !tested && len(declared[path]) > 0 {
+			stats.UntestedFiles = append(stats.UntestedFiles, path)
+		}
+	}
+
+	return stats, nil
+}
+
+// declaredIdentifiers returns the names of top-level declarations and
+// methods in a file
+func declaredIdentifiers(f *ast.File) []string {
+	var names []string
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil && (d.Name.Name == "init" || d.Name.Name == "main") {
+				continue
+			}
+			names = append(names, d.Name.Name)
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					names = append(names, s.Name.Name)
+				case *ast.ValueSpec:
+					for _, name := range s.Names {
+						if name.Name != "_" {
+							names = append(names, name.Name)
+						}
+					}
+				}
+			}
+		}
+	}
+	return names
+}
+
+// isTestFunction reports whether fn is a Test, Benchmark, Fuzz or Example
+// function recognized by go test
+func isTestFunction(fn *ast.FuncDecl) bool {
+	if fn.Recv != nil {
+		return false
+	}
+	for _, prefix := range []string{"Test", "Benchmark", "Fuzz", "Example"} {
+		if strings.HasPrefix(fn.Name.Name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// countStatements counts the statements in a function body, not counting
+// the block statements that merely group them
+func countStatements(body *ast.BlockStmt) int {
+	if body == nil {
+		return 0
+	}
+
+	count := 0
+	ast.Inspect(body, func(n ast.Node) bool {
+		switch n.(type) {
+		case *ast.BlockStmt:
+		case ast.Stmt:
+			count++
+		}
+		return true
+	})
+	return count
+}
+
+// printTestPresence prints the per-package test presence table
+func (a *ASTAnalyzer) printTestPresence() {
+	a.mu.Lock()
+	stats := append([]PackageTestStats(nil), a.testStats...)
+	a.mu.Unlock()
+	if len(stats) == 0 {
+		return
+	}
+
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println("TEST PRESENCE")
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Printf("%-30s %6s %6s %7s %9s\n", "Package", "Funcs", "Tests", "Ratio", "Untested")
+	for _, s := range stats {
+		presence := fmt.Sprintf("%6d", s.TestFunctions)
+		if s.TestFiles == 0 {
+			presence = fmt.Sprintf("%6s", "none")
+		}
+		fmt.Printf("%-30s %6d %s %7.2f %9d\n",
+			s.Package,
+			s.CodeFunctions,
+			presence,
+			s.StatementRatio(),
+			len(s.UntestedFiles))
+	}
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println()
+}
@@ -0,0 +1,41 @@
+// Command bosses is the fixture of the boss generator tests
+package main
+
+import (
+	"fmt"
+
+	"example.com/bosses/store"
+)
+
+const (
+	maxRetries = 3
+	banner     = "bosses"
+)
+
+func main() {
+	fmt.Println(banner)
+	for i := 0; i < maxRetries; i++ {
+		if err := process(i); err != nil {
+			fmt.Println(err)
+		}
+	}
+}
+
+// process has the same name as a function of package store
+func process(n int) error {
+	for i := 0; i < n; i++ {
+		if i%2 == 0 {
+			switch {
+			case i > 10:
+				return store.Save(i)
+			case i > 5:
+				validate(i)
+			}
+		}
+	}
+	return nil
+}
+
+func validate(n int) bool {
+	return n > 0
+}
@@ -0,0 +1,29 @@
+// Package store is the fixture package of the boss generator tests
+package store
+
+import "errors"
+
+// ErrFull is returned when the store holds too much
+var ErrFull = errors.New("store full")
+
+const capacity = 10
+
+// Save stores n
+func Save(n int) error {
+	if n > capacity {
+		return process(n)
+	}
+	return nil
+}
+
+func process(n int) error {
+	for n > capacity {
+		if n%2 == 0 {
+			if n%3 == 0 {
+				return ErrFull
+			}
+		}
+		n--
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+package cache
+
+import "example.com/sarif/store"
+
+// Warm fills the cache
+func Warm() {}
+
+// Size is the capacity of the store's cache
+var Size = store.N
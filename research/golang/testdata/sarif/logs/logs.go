@@ -0,0 +1,4 @@
+package logs
+
+// Print writes a log line
+func Print(string) {}
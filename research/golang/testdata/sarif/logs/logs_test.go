@@ -0,0 +1,9 @@
+package logs
+
+import (
+	"testing"
+
+	"example.com/sarif/util"
+)
+
+func TestPrint(t *testing.T) { Print(util.Format()) }
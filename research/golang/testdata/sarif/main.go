@@ -0,0 +1,9 @@
+package main
+
+import "example.com/sarif/store"
+
+func main() {
+	store.Open()
+}
+
+func orphan() {}
@@ -0,0 +1,32 @@
+package store
+
+import "example.com/sarif/cache"
+
+var max_size = 1
+
+// StoreConfig configures a store
+type StoreConfig struct{}
+
+// N is the number of shards
+const N = 4
+
+// Open opens the store
+func Open() {
+	cache.Warm()
+	var s Store
+	s.Get()
+	s.Put()
+	s.Del()
+}
+
+// Store holds values
+type Store struct{}
+
+func (s *Store) Get() {}
+
+func (s *Store) Put() {}
+
+func (st *Store) Del() {}
+
+// ParseUrl parses a store address
+func ParseUrl(scheme, host, user, pass, path, query, fragment, port string) {}
@@ -0,0 +1,6 @@
+package sub
+
+import "example.com/sarif/store"
+
+// Reopen opens the parent store again
+func Reopen() { store.Open() }
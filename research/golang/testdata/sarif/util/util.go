@@ -0,0 +1,9 @@
+package util
+
+import "example.com/sarif/logs"
+
+// Format formats a log line
+func Format() string {
+	logs.Print("formatting")
+	return ""
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"fmt"
+	"go/parser"
+	"go/token"
+	"math"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DefaultMaxTimingVariation is the relative standard deviation above which
+// a repeated timing is reported as unreliable
+const DefaultMaxTimingVariation = 0.25
+
+// ParseTiming summarizes the repeated timed parses of one file
+type ParseTiming struct {
+	Runs   int
+	Min    time.Duration
+	Median time.Duration
+	P95    time.Duration // Nearest-rank 95th percentile
+	StdDev time.Duration
+}
+
+// newParseTiming summarizes a set of samples
+func newParseTiming(samples []time.Duration) ParseTiming {
+	sorted := append([]time.Duration(nil), samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+
+	n := len(sorted)
+	t := ParseTiming{Runs: n, Min: sorted[0]}
+	if n%2 == 1 {
+		t.Median = sorted[n/2]
+	} else {
+		t.Median = (sorted[n/2-1] + sorted[n/2]) / 2
+	}
+	t.P95 = sorted[int(math.Ceil(0.95*float64(n)))-1]
+
+	var mean float64
+	for _, s := range sorted {
+		mean += float64(s)
+	}
+	mean /= float64(n)
+	var variance float64
+	for _, s := range sorted {
+		variance += (float64(s) - mean) * (float64(s) - mean)
+	}
+	t.StdDev = time.Duration(math.Sqrt(variance / float64(n)))
+	return t
+}
+
+// Variation returns the standard deviation relative to the median
+func (t ParseTiming) Variation() float64 {
+	if t.Median == 0 {
+		return 0
+	}
+	return float64(t.StdDev) / float64(t.Median)
+}
+
+// timeParses parses src once as a warmup and then a.Runs more times,
+// timing only the parser. Every run uses a fresh FileSet so each one does
+// the same work, whatever was parsed before it.
+func (a *ASTAnalyzer) timeParses(filePath string, src []byte) ParseTiming {
+	mode := a.Mode.parserMode()
+	parser.ParseFile(token.NewFileSet(), filePath, src, mode)
+
+	samples := make([]time.Duration, a.Runs)
+	for i := range samples {
+		fset := token.NewFileSet()
+		start := time.Now()
+		parser.ParseFile(fset, filePath, src, mode)
+		samples[i] = time.Since(start)
+	}
+	return newParseTiming(samples)
+}
+
+// maxTimingVariation returns the configured variation threshold
+func (a *ASTAnalyzer) maxTimingVariation() float64 {
+	if a.MaxTimingVariation > 0 {
+		return a.MaxTimingVariation
+	}
+	return DefaultMaxTimingVariation
+}
+
+// printTimingReliability lists the files whose repeated timings vary too
+// much to compare across runs
+func (a *ASTAnalyzer) printTimingReliability(results []ParseResult) {
+	if a.Runs < 2 {
+		return
+	}
+
+	limit := a.maxTimingVariation()
+	var unreliable []ParseResult
+	for _, r := range results {
+		if r.Timing != nil && r.Timing.Variation() > limit {
+			unreliable = append(unreliable, r)
+		}
+	}
+	sort.Slice(unreliable, func(i, j int) bool {
+		return unreliable[i].Timing.Variation() > unreliable[j].Timing.Variation()
+	})
+
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Printf("TIMING (%d runs per file, median reported)\n", a.Runs)
+	fmt.Println(strings.Repeat("=", 70))
+	if len(unreliable) == 0 {
+		fmt.Printf("All timings within %.0f%% relative deviation\n", limit*100)
+	} else {
+		fmt.Printf("Unreliable measurements (deviation above %.0f%%): %d\n", limit*100, len(unreliable))
+		for _, r := range unreliable {
+			t := r.Timing
+			fmt.Printf("  %-40s median %8.2fms  p95 %8.2fms  dev %3.0f%%\n",
+				outputPath(a.outputRoot(), r.FilePath),
+				float64(t.Median.Microseconds())/1000.0,
+				float64(t.P95.Microseconds())/1000.0,
+				t.Variation()*100)
+		}
+	}
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println()
+}
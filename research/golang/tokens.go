@@ -0,0 +1,136 @@
+package main
+
+import (
+	"fmt"
+	"go/parser"
+	"go/scanner"
+	"go/token"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// TokenStats tokenizes a file with go/scanner, without building a tree,
+// and counts each kind of token. Comments are counted; the semicolons the
+// scanner inserts at line ends count as SEMICOLON.
+func TokenStats(filePath string) (map[token.Token]int, error) {
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, err
+	}
+	counts := make(map[token.Token]int)
+	err = scanTokens(filePath, src, func(tok token.Token) { counts[tok]++ })
+	return counts, err
+}
+
+// scanTokens hands the kind of every token in src to fn. Lexical errors
+// do not stop the scan; the first is returned.
+func scanTokens(filePath string, src []byte, fn func(token.Token)) error {
+	fset := token.NewFileSet()
+	file := fset.AddFile(filePath, -1, len(src))
+
+	var errs scanner.ErrorList
+	var s scanner.Scanner
+	s.Init(file, src, errs.Add, scanner.ScanComments)
+	for {
+		_, tok, _ := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		fn(tok)
+	}
+	return errs.Err()
+}
+
+// ScanComparison splits the cost of parsing a file into lexing and
+// building the tree
+type ScanComparison struct {
+	Tokens    int
+	ScanTime  time.Duration // Average time to tokenize the file
+	ParseTime time.Duration // Average time to parse it, comments included
+}
+
+// TreeShare returns the fraction of parse time not spent lexing
+func (c ScanComparison) TreeShare() float64 {
+	if c.ParseTime <= 0 || c.ScanTime >= c.ParseTime {
+		return 0
+	}
+	return float64(c.ParseTime-c.ScanTime) / float64(c.ParseTime)
+}
+
+// CompareScanParse times tokenizing a file with go/scanner against parsing
+// it with go/parser, averaging modeIterations runs of each after a warmup.
+// The file is read once so I/O is excluded.
+func CompareScanParse(filePath string) (ScanComparison, error) {
+	src, err := os.ReadFile(filePath)
+	if err != nil {
+		return ScanComparison{}, err
+	}
+
+	var c ScanComparison
+	if err := scanTokens(filePath, src, func(token.Token) { c.Tokens++ }); err != nil {
+		return ScanComparison{}, err
+	}
+	if _, err := parser.ParseFile(token.NewFileSet(), filePath, src, parser.ParseComments); err != nil {
+		return ScanComparison{}, err
+	}
+
+	var scan, parse time.Duration
+	for i := 0; i < modeIterations; i++ {
+		start := time.Now()
+		scanTokens(filePath, src, func(token.Token) {})
+		scan += time.Since(start)
+
+		start = time.Now()
+		parser.ParseFile(token.NewFileSet(), filePath, src, parser.ParseComments)
+		parse += time.Since(start)
+	}
+	c.ScanTime = scan / modeIterations
+	c.ParseTime = parse / modeIterations
+	return c, nil
+}
+
+// tokenKindsShown is how many token kinds PrintTokenStats lists
+const tokenKindsShown = 10
+
+// PrintTokenStats prints the scan and parse times of a file followed by
+// its most frequent token kinds
+func PrintTokenStats(filePath string, counts map[token.Token]int, c ScanComparison) {
+	toks := make([]token.Token, 0, len(counts))
+	for tok := range counts {
+		toks = append(toks, tok)
+	}
+	sort.Slice(toks, func(i, j int) bool {
+		if counts[toks[i]] != counts[toks[j]] {
+			return counts[toks[i]] > counts[toks[j]]
+		}
+		return toks[i] < toks[j]
+	})
+
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Printf("SCAN VS PARSE: %s\n", filePath)
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Printf("Tokens:             %d\n", c.Tokens)
+	fmt.Printf("Scan time:          %.3fms\n", float64(c.ScanTime.Microseconds())/1000.0)
+	fmt.Printf("Parse time:         %.3fms\n", float64(c.ParseTime.Microseconds())/1000.0)
+	fmt.Printf("Building the tree:  %.1f%% of parse time\n", 100*c.TreeShare())
+	fmt.Println()
+	for i, tok := range toks {
+		if i == tokenKindsShown {
+			fmt.Printf("... and %d more kinds\n", len(toks)-i)
+			break
+		}
+		fmt.Printf("%-20s %d\n", tokenName(tok), counts[tok])
+	}
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println()
+}
+
+// tokenName names a token kind: operators by their symbol, others by kind
+func tokenName(tok token.Token) string {
+	if tok.IsOperator() {
+		return fmt.Sprintf("%q", tok.String())
+	}
+	return tok.String()
+}
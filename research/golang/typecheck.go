@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"go/ast"
+	"go/build"
+	"go/importer"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// packageTypes holds the signatures of one type-checked package and the
+// objects its identifiers refer to
+type packageTypes struct {
+	signatures map[string]*types.Signature // By function name or Type.Method
+	uses       map[*ast.Ident]types.Object
+	badFiles   map[string]bool // Files with type errors
+}
+
+// resolveTypes fills in the resolved parameter and result types of the
+// functions extracted from f. Files with type errors keep their syntactic
+// types only.
+func (a *ASTAnalyzer) resolveTypes(f *ast.File, functions []FunctionInfo) {
+	filePath := a.fset.Position(f.Package).Filename
+	a.applyTypes(a.checkPackage(filePath, f), filePath, functions)
+}
+
+// applyTypes fills in the resolved types of the functions of filePath from
+// the checked package pt
+func (a *ASTAnalyzer) applyTypes(pt *packageTypes, filePath string, functions []FunctionInfo) {
+	if pt.badFiles[filePath] {
+		return
+	}
+
+	resolved := false
+	for i := range functions {
+		fn := &functions[i]
+		key := fn.Name
+		if fn.Receiver != "" {
+			key = receiverTypeName(fn.Receiver) + "." + fn.Name
+		}
+		sig, ok := pt.signatures[key]
+		if !ok {
+			continue
+		}
+		fn.ResolvedParams = tupleTypes(sig.Params(), sig.Variadic())
+		fn.ResolvedResults = tupleTypes(sig.Results(), false)
+		resolved = true
+	}
+
+	if resolved {
+		a.mu.Lock()
+		a.typesResolved = true
+		a.mu.Unlock()
+	}
+}
+
+// tupleTypes returns the fully qualified types of a parameter or result
+// list, writing a variadic last parameter as ...T
+func tupleTypes(tuple *types.Tuple, variadic bool) []string {
+	list := make([]string, tuple.Len())
+	for i := range list {
+		t := tuple.At(i).Type()
+		if variadic && i == len(list)-1 {
+			if slice, ok := t.(*types.Slice); ok {
+				list[i] = "..." + types.TypeString(slice.Elem(), nil)
+				continue
+			}
+		}
+		list[i] = types.TypeString(t, nil)
+	}
+	return list
+}
+
+// checkPackage type-checks the package of the file f at filePath, once
+// per directory and package name. The package is made of the files in
+// the same directory with the same package name that match the build
+// constraints of the host; a file not on disk is checked alone. Imports
+// are type-checked from source.
+func (a *ASTAnalyzer) checkPackage(filePath string, f *ast.File) *packageTypes {
+	dir := filepath.Dir(filePath)
+	key := dir + ":" + f.Name.Name
+
+	a.typesMu.Lock()
+	defer a.typesMu.Unlock()
+	if pt, ok := a.packageTypes[key]; ok {
+		return pt
+	}
+
+	files := []*ast.File{f}
+	if _, err := os.Stat(filePath); err == nil {
+		files = a.packageFiles(dir, f.Name.Name)
+	}
+
+	pt := &packageTypes{
+		uses:     make(map[*ast.Ident]types.Object),
+		badFiles: make(map[string]bool),
+	}
+	if a.typeImporter == nil {
+		a.typeImporter = importer.ForCompiler(a.fset, "source", nil)
+	}
+	conf := types.Config{
+		Importer: a.typeImporter,
+		Error: func(err error) {
+			var typeErr types.Error
+			if errors.As(err, &typeErr) {
+				pt.badFiles[typeErr.Fset.Position(typeErr.Pos).Filename] = true
+			}
+		},
+	}
+	info := &types.Info{Uses: pt.uses}
+	pkg, _ := conf.Check(packageImportPath(dir, f.Name.Name), a.fset, files, info)
+
+	pt.signatures = packageSignatures(pkg)
+
+	if a.packageTypes == nil {
+		a.packageTypes = make(map[string]*packageTypes)
+	}
+	a.packageTypes[key] = pt
+	return pt
+}
+
+// packageSignatures returns the signatures of the functions and methods
+// declared in pkg, by function name or Type.Method
+func packageSignatures(pkg *types.Package) map[string]*types.Signature {
+	signatures := make(map[string]*types.Signature)
+	scope := pkg.Scope()
+	for _, name := range scope.Names() {
+		switch obj := scope.Lookup(name).(type) {
+		case *types.Func:
+			signatures[name] = obj.Type().(*types.Signature)
+		case *types.TypeName:
+			named, ok := obj.Type().(*types.Named)
+			if !ok || obj.IsAlias() {
+				continue
+			}
+			for i := 0; i < named.NumMethods(); i++ {
+				m := named.Method(i)
+				signatures[name+"."+m.Name()] = m.Type().(*types.Signature)
+			}
+		}
+	}
+	return signatures
+}
+
+// packageImportPath returns the import path of the package in dir, from
+// the nearest go.mod above it, or name outside a module
+func packageImportPath(dir, name string) string {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return name
+	}
+	for root := abs; ; root = filepath.Dir(root) {
+		if module := modulePath(root); module != "" {
+			rel, err := filepath.Rel(root, abs)
+			if err != nil || rel == "." {
+				return module
+			}
+			return module + "/" + filepath.ToSlash(rel)
+		}
+		if filepath.Dir(root) == root {
+			return name
+		}
+	}
+}
+
+// packageFiles parses the files of package name in dir, skipping those
+// excluded by build constraints
+func (a *ASTAnalyzer) packageFiles(dir, name string) []*ast.File {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var files []*ast.File
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".go") {
+			continue
+		}
+		if match, err := build.Default.MatchFile(dir, entry.Name()); err != nil || !match {
+			continue
+		}
+		f, _ := a.cache.Parse(filepath.Join(dir, entry.Name()))
+		if f != nil && f.Name.Name == name {
+			files = append(files, f)
+		}
+	}
+	return files
+}
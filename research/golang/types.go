@@ -0,0 +1,217 @@
+package main
+
+import (
+	"go/ast"
+	"go/token"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Type kinds reported in TypeInfo.Kind
+const (
+	StructKind    = "struct"
+	InterfaceKind = "interface"
+)
+
+// TypeInfo represents an extracted struct or interface declaration
+type TypeInfo struct {
+	Name       string
+	Package    string // Package directory
+	Kind       string // StructKind or InterfaceKind
+	TypeParams []ParamInfo
+	Fields     []ParamInfo       // Named struct fields
+	Embeds     []string          // Embedded types, as written
+	Tags       map[string]string // Struct tags of named fields, unquoted, by field name
+	Methods    []MethodInfo      // Interface methods, or the struct's declared methods
+	FilePath   string
+	Line       int
+
+	// PromotedMethods names the methods a struct gains from its embedded
+	// fields, for embeds declared in the same package
+	PromotedMethods []string
+}
+
+// MethodInfo is a method signature of a struct or interface
+type MethodInfo struct {
+	Name            string
+	Params          []ParamInfo
+	Results         []string
+	PointerReceiver bool // Declared on *T, so only *T has the method
+}
+
+// signatureKey identifies a method by name and parameter and result types,
+// ignoring parameter names
+func (m MethodInfo) signatureKey() string {
+	types := make([]string, len(m.Params))
+	for i, p := range m.Params {
+		types[i] = p.Type
+	}
+	return m.Name + "(" + strings.Join(types, ",") + ")(" + strings.Join(m.Results, ",") + ")"
+}
+
+// ExtractTypes extracts the structs and interfaces of all non-test Go files
+// under dir, attaching each struct's methods, and records them for
+// ExportMermaid. Types are ordered by package and name.
+func (a *ASTAnalyzer) ExtractTypes(dir string) ([]TypeInfo, error) {
+	types, err := a.extractTypes(dir)
+	if err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	a.types = append(a.types, types...)
+	a.mu.Unlock()
+	return types, nil
+}
+
+// extractTypes is ExtractTypes without recording the types
+func (a *ASTAnalyzer) extractTypes(dir string) ([]TypeInfo, error) {
+	files, err := a.goFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var types []TypeInfo
+	methods := make(map[string][]MethodInfo) // Package directory and type name
+	for _, path := range files {
+		if isTestName(path) {
+			continue
+		}
+		f, err := a.cache.Parse(path)
+		if err != nil {
+			continue
+		}
+		pkgDir := filepath.Dir(path)
+
+		for _, fn := range a.extractFunctions(f) {
+			if fn.Receiver == "" {
+				continue
+			}
+			key := pkgDir + "." + receiverTypeName(fn.Receiver)
+			methods[key] = append(methods[key], MethodInfo{
+				Name:            fn.Name,
+				Params:          fn.Params,
+				Results:         fn.Results,
+				PointerReceiver: strings.HasPrefix(fn.Receiver, "*"),
+			})
+		}
+
+		for _, decl := range f.Decls {
+			gen, ok := decl.(*ast.GenDecl)
+			if !ok || gen.Tok != token.TYPE {
+				continue
+			}
+			for _, spec := range gen.Specs {
+				if info, ok := a.typeInfo(spec.(*ast.TypeSpec)); ok {
+					info.Package = pkgDir
+					info.FilePath = path
+					types = append(types, info)
+				}
+			}
+		}
+	}
+
+	byName := make(map[string]TypeInfo, len(types))
+	for i := range types {
+		if types[i].Kind == StructKind {
+			types[i].Methods = methods[types[i].Package+"."+types[i].Name]
+		}
+		byName[types[i].Package+"."+types[i].Name] = types[i]
+	}
+	for i := range types {
+		if types[i].Kind == StructKind {
+			types[i].PromotedMethods = promotedMethods(types[i], byName)
+		}
+	}
+
+	sort.SliceStable(types, func(i, j int) bool {
+		if types[i].Package != types[j].Package {
+			return types[i].Package < types[j].Package
+		}
+		return types[i].Name < types[j].Name
+	})
+	return types, nil
+}
+
+// typeInfo describes a struct or interface type spec, reporting false for
+// other kinds of type
+func (a *ASTAnalyzer) typeInfo(ts *ast.TypeSpec) (TypeInfo, bool) {
+	info := TypeInfo{
+		Name: ts.Name.Name,
+		Line: a.fset.Position(ts.Pos()).Line,
+	}
+	if ts.TypeParams != nil {
+		for _, field := range ts.TypeParams.List {
+			constraint := exprToString(field.Type)
+			for _, name := range field.Names {
+				info.TypeParams = append(info.TypeParams, ParamInfo{Name: name.Name, Type: constraint})
+			}
+		}
+	}
+
+	switch t := ts.Type.(type) {
+	case *ast.StructType:
+		info.Kind = StructKind
+		for _, field := range t.Fields.List {
+			typeStr := exprToString(field.Type)
+			if len(field.Names) == 0 {
+				info.Embeds = append(info.Embeds, typeStr)
+				continue
+			}
+			for _, name := range field.Names {
+				info.Fields = append(info.Fields, ParamInfo{Name: name.Name, Type: typeStr})
+			}
+			if field.Tag == nil {
+				continue
+			}
+			if tag, err := strconv.Unquote(field.Tag.Value); err == nil {
+				if info.Tags == nil {
+					info.Tags = make(map[string]string)
+				}
+				for _, name := range field.Names {
+					info.Tags[name.Name] = tag
+				}
+			}
+		}
+	case *ast.InterfaceType:
+		info.Kind = InterfaceKind
+		for _, field := range t.Methods.List {
+			fn, ok := field.Type.(*ast.FuncType)
+			if !ok {
+				// Embedded interfaces and type set elements
+				info.Embeds = append(info.Embeds, exprToString(field.Type))
+				continue
+			}
+			for _, name := range field.Names {
+				info.Methods = append(info.Methods, funcTypeMethod(name.Name, fn))
+			}
+		}
+	default:
+		return TypeInfo{}, false
+	}
+	return info, true
+}
+
+// funcTypeMethod converts an interface method's signature
+func funcTypeMethod(name string, fn *ast.FuncType) MethodInfo {
+	m := MethodInfo{Name: name}
+	if fn.Params != nil {
+		for _, field := range fn.Params.List {
+			typeStr := exprToString(field.Type)
+			if len(field.Names) == 0 {
+				m.Params = append(m.Params, ParamInfo{Type: typeStr})
+				continue
+			}
+			for _, n := range field.Names {
+				m.Params = append(m.Params, ParamInfo{Name: n.Name, Type: typeStr})
+			}
+		}
+	}
+	if fn.Results != nil {
+		for _, field := range fn.Results.List {
+			m.Results = append(m.Results, exprToString(field.Type))
+		}
+	}
+	return m
+}
@@ -0,0 +1,313 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"go/token"
+	"path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Finding categories reported by UnusedExports
+const (
+	CategoryUnusedExport   = "unused-export"
+	CategoryTestOnlyExport = "test-only-export"
+)
+
+// DefaultPublicAPI exempts the module root package when PublicAPI is empty
+var DefaultPublicAPI = []string{"."}
+
+// UnusedExport is an exported function, type, constant or variable that
+// no other package of the module refers to
+type UnusedExport struct {
+	Name     string
+	Kind     string // "func", "type", "const" or "var"
+	Package  string // Named as in ImportGraph
+	FilePath string
+	Line     int
+
+	// TestOnly marks exports that only test files of other packages, or
+	// the package's own external tests, refer to
+	TestOnly bool
+}
+
+// PackageUnusedExports lists the unused exports of one package
+type PackageUnusedExports struct {
+	Package string
+	Exports int // Exported declarations checked
+	Unused  []UnusedExport
+}
+
+// exportKey identifies an exported declaration by package and name
+type exportKey struct {
+	pkg, name string
+}
+
+// UnusedExports finds the exported package-level functions, types,
+// constants and variables under dir that no other package under dir
+// refers to, through a qualified identifier or a dot import. Methods and
+// fields are not checked. Main packages, generated files and packages
+// matching PublicAPI are exempt. Packages are ranked by their number of
+// unused exports, most first, and every unused export is recorded as a
+// finding and for PrintSummary.
+func (a *ASTAnalyzer) UnusedExports(dir string) ([]PackageUnusedExports, error) {
+	g, err := a.BuildImportGraph(dir)
+	if err != nil {
+		return nil, err
+	}
+	paths, err := a.goFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make(map[string]*ast.File, len(paths))
+	names := make(map[string]string) // Package name by package
+	var parsed []string
+	for _, p := range paths {
+		f, err := a.cache.Parse(p)
+		if err != nil {
+			continue
+		}
+		files[p] = f
+		parsed = append(parsed, p)
+		if !isTestName(p) {
+			names[g.localImportPath(dir, filepath.Dir(p))] = f.Name.Name
+		}
+	}
+
+	var declared []UnusedExport
+	for _, p := range parsed {
+		f := files[p]
+		pkg := g.localImportPath(dir, filepath.Dir(p))
+		if isTestName(p) || f.Name.Name == "main" || isGeneratedFile(p, f) || a.isPublicAPI(dir, filepath.Dir(p)) {
+			continue
+		}
+		for _, d := range exportedDecls(f) {
+			pos := a.fset.Position(d.ident.Pos())
+			declared = append(declared, UnusedExport{
+				Name:     d.ident.Name,
+				Kind:     d.kind,
+				Package:  pkg,
+				FilePath: p,
+				Line:     pos.Line,
+			})
+		}
+	}
+
+	used := make(map[exportKey]bool)
+	usedByTests := make(map[exportKey]bool)
+	for _, p := range parsed {
+		refs := used
+		if isTestName(p) {
+			refs = usedByTests
+		}
+		for key := range packageReferences(files[p], names) {
+			refs[key] = true
+		}
+	}
+
+	byPackage := make(map[string]*PackageUnusedExports)
+	for _, e := range declared {
+		s, ok := byPackage[e.Package]
+		if !ok {
+			s = &PackageUnusedExports{Package: e.Package}
+			byPackage[e.Package] = s
+		}
+		s.Exports++
+		key := exportKey{e.Package, e.Name}
+		if used[key] {
+			continue
+		}
+		e.TestOnly = usedByTests[key]
+		s.Unused = append(s.Unused, e)
+	}
+
+	var report []PackageUnusedExports
+	var findings []Finding
+	for _, s := range byPackage {
+		if len(s.Unused) == 0 {
+			continue
+		}
+		for _, e := range s.Unused {
+			findings = append(findings, e.finding())
+		}
+		report = append(report, *s)
+	}
+	sort.Slice(report, func(i, j int) bool {
+		if len(report[i].Unused) != len(report[j].Unused) {
+			return len(report[i].Unused) > len(report[j].Unused)
+		}
+		return report[i].Package < report[j].Package
+	})
+
+	a.addFindings(findings...)
+	a.mu.Lock()
+	a.unusedExports = report
+	a.mu.Unlock()
+	return report, nil
+}
+
+// isPublicAPI reports whether the package in pkgDir matches a PublicAPI
+// pattern, matched with path.Match against its directory relative to dir
+func (a *ASTAnalyzer) isPublicAPI(dir, pkgDir string) bool {
+	patterns := a.PublicAPI
+	if len(patterns) == 0 {
+		patterns = DefaultPublicAPI
+	}
+	rel, err := filepath.Rel(dirOf(dir), pkgDir)
+	if err != nil {
+		return false
+	}
+	rel = filepath.ToSlash(rel)
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, rel); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// exportedDecl is an exported package-level name and what it declares
+type exportedDecl struct {
+	ident *ast.Ident
+	kind  string
+}
+
+// exportedDecls returns the exported functions, types, constants and
+// variables declared at the top level of f
+func exportedDecls(f *ast.File) []exportedDecl {
+	var decls []exportedDecl
+	for _, decl := range f.Decls {
+		switch d := decl.(type) {
+		case *ast.FuncDecl:
+			if d.Recv == nil && d.Name.IsExported() {
+				decls = append(decls, exportedDecl{d.Name, "func"})
+			}
+		case *ast.GenDecl:
+			for _, spec := range d.Specs {
+				switch s := spec.(type) {
+				case *ast.TypeSpec:
+					if s.Name.IsExported() {
+						decls = append(decls, exportedDecl{s.Name, "type"})
+					}
+				case *ast.ValueSpec:
+					kind := "var"
+					if d.Tok == token.CONST {
+						kind = "const"
+					}
+					for _, name := range s.Names {
+						if name.IsExported() {
+							decls = append(decls, exportedDecl{name, kind})
+						}
+					}
+				}
+			}
+		}
+	}
+	return decls
+}
+
+// packageReferences returns the exported names of local packages that f
+// refers to. Qualified identifiers count when their qualifier is an
+// import, not a local variable; names of dot-imported packages count when
+// the parser left them unresolved. A package cannot import itself, so
+// every reference comes from another package, counting package foo_test
+// apart from foo.
+func packageReferences(f *ast.File, names map[string]string) map[exportKey]bool {
+	imports := make(map[string]string) // Import path by the name it is used under
+	var dotImports []string
+	for _, spec := range f.Imports {
+		imported, err := strconv.Unquote(spec.Path.Value)
+		if err != nil {
+			continue
+		}
+		name, ok := names[imported]
+		if !ok {
+			continue
+		}
+		if spec.Name != nil {
+			name = spec.Name.Name
+		}
+		switch name {
+		case "_":
+		case ".":
+			dotImports = append(dotImports, imported)
+		default:
+			imports[name] = imported
+		}
+	}
+	if len(imports) == 0 && len(dotImports) == 0 {
+		return nil
+	}
+
+	refs := make(map[exportKey]bool)
+	ast.Inspect(f, func(n ast.Node) bool {
+		switch n := n.(type) {
+		case *ast.SelectorExpr:
+			if x, ok := n.X.(*ast.Ident); ok && x.Obj == nil {
+				if imported, ok := imports[x.Name]; ok {
+					refs[exportKey{imported, n.Sel.Name}] = true
+					return false
+				}
+			}
+		case *ast.Ident:
+			if n.Obj == nil && n.IsExported() {
+				for _, imported := range dotImports {
+					refs[exportKey{imported, n.Name}] = true
+				}
+			}
+		}
+		return true
+	})
+	return refs
+}
+
+// finding describes the unused export as a finding
+func (e UnusedExport) finding() Finding {
+	f := Finding{
+		Category:   CategoryUnusedExport,
+		FilePath:   e.FilePath,
+		Line:       e.Line,
+		Identifier: e.Name,
+		Message:    fmt.Sprintf("exported %s %s is not used outside %s", e.Kind, e.Name, e.Package),
+		Suggestion: "unexport or remove it",
+	}
+	if e.TestOnly {
+		f.Category = CategoryTestOnlyExport
+		f.Message = fmt.Sprintf("exported %s %s is only used by tests outside %s", e.Kind, e.Name, e.Package)
+		f.Suggestion = "unexport it and move the tests into the package"
+	}
+	return f
+}
+
+// printUnusedExports prints the packages with unused exports, most first
+func (a *ASTAnalyzer) printUnusedExports() {
+	if len(a.unusedExports) == 0 {
+		return
+	}
+
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println("UNUSED EXPORTS")
+	fmt.Println(strings.Repeat("=", 70))
+	for _, s := range a.unusedExports {
+		testOnly := 0
+		for _, e := range s.Unused {
+			if e.TestOnly {
+				testOnly++
+			}
+		}
+		fmt.Printf("%s: %d of %d unused, %d only by tests\n", s.Package, len(s.Unused), s.Exports, testOnly)
+		for _, e := range s.Unused {
+			note := ""
+			if e.TestOnly {
+				note = " (tests only)"
+			}
+			fmt.Printf("  %-6s %s%s\n", e.Kind, e.Name, note)
+		}
+	}
+	fmt.Println(strings.Repeat("=", 70))
+	fmt.Println()
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"os"
+	"sort"
+	"time"
+)
+
+// DefaultWatchInterval is how often Watch polls when WatchInterval is zero
+const DefaultWatchInterval = 500 * time.Millisecond
+
+// fileState is what Watch compares to notice a changed file
+type fileState struct {
+	modTime time.Time
+	size    int64
+	root    string
+}
+
+// Watch polls dir for added and modified Go files and re-parses only those,
+// replacing their entries in the analyzer's results and calling onChange
+// with the new results. Files removed from disk are dropped from the
+// results. Unchanged content is served from a cache loaded with LoadCache.
+// Polling avoids a file notification dependency; the interval is
+// WatchInterval. Watch blocks until ctx is done.
+func (a *ASTAnalyzer) Watch(ctx context.Context, dir string, onChange func([]ParseResult)) error {
+	return a.WatchDirectories(ctx, []string{dir}, onChange)
+}
+
+// WatchDirectories is Watch for several roots
+func (a *ASTAnalyzer) WatchDirectories(ctx context.Context, dirs []string, onChange func([]ParseResult)) error {
+	interval := a.WatchInterval
+	if interval <= 0 {
+		interval = DefaultWatchInterval
+	}
+
+	known, err := a.snapshotFiles(dirs)
+	if err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+
+		current, err := a.snapshotFiles(dirs)
+		if err != nil {
+			return err
+		}
+
+		var changed []ParseResult
+		for path, state := range current {
+			old, ok := known[path]
+			if ok && old.modTime.Equal(state.modTime) && old.size == state.size {
+				continue
+			}
+			result, cached := a.cachedParse(path)
+			if !cached {
+				result = a.ParseFile(path)
+			}
+			result.Root = state.root
+			a.replaceResult(result)
+			a.metrics.ObserveLag(time.Since(state.modTime))
+			changed = append(changed, result)
+		}
+		for path := range known {
+			if _, ok := current[path]; !ok {
+				a.removeResult(path)
+			}
+		}
+		known = current
+
+		if len(changed) > 0 {
+			sort.Slice(changed, func(i, j int) bool { return changed[i].FilePath < changed[j].FilePath })
+			onChange(changed)
+		}
+	}
+}
+
+// snapshotFiles records the state of every Go file under dirs that the
+// benchmark would parse
+func (a *ASTAnalyzer) snapshotFiles(dirs []string) (map[string]fileState, error) {
+	states := make(map[string]fileState)
+	for _, dir := range dirs {
+		files, err := a.goFiles(dir)
+		if err != nil {
+			return nil, err
+		}
+		for _, path := range files {
+			info, err := os.Stat(path)
+			if err != nil {
+				// Treat a file removed since the walk as already gone
+				continue
+			}
+			states[path] = fileState{modTime: info.ModTime(), size: info.Size(), root: dir}
+		}
+	}
+	return states, nil
+}
+
+// replaceResult stores result in place of any earlier result for the same
+// file, dropping functions extracted from the old version
+func (a *ASTAnalyzer) replaceResult(result ParseResult) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if !result.FromCache {
+		delete(a.functions, result.FilePath)
+	}
+	for i := range a.results {
+		if a.results[i].FilePath == result.FilePath {
+			a.metrics.Forget(a.results[i])
+			a.results[i] = result
+			return
+		}
+	}
+	a.results = append(a.results, result)
+}
+
+// removeResult forgets everything recorded for a deleted file
+func (a *ASTAnalyzer) removeResult(path string) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	delete(a.functions, path)
+	for i := range a.results {
+		if a.results[i].FilePath == path {
+			a.metrics.Forget(a.results[i])
+			a.results = append(a.results[:i], a.results[i+1:]...)
+			return
+		}
+	}
+}